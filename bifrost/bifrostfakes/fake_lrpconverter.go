@@ -10,6 +10,22 @@ import (
 )
 
 type FakeLRPConverter struct {
+	ConvertImageStub        func(string, *opi.PrivateRegistry) (string, string, error)
+	convertImageMutex       sync.RWMutex
+	convertImageArgsForCall []struct {
+		arg1 string
+		arg2 *opi.PrivateRegistry
+	}
+	convertImageReturns struct {
+		result1 string
+		result2 string
+		result3 error
+	}
+	convertImageReturnsOnCall map[int]struct {
+		result1 string
+		result2 string
+		result3 error
+	}
 	ConvertLRPStub        func(cf.DesireLRPRequest) (opi.LRP, error)
 	convertLRPMutex       sync.RWMutex
 	convertLRPArgsForCall []struct {
@@ -27,6 +43,74 @@ type FakeLRPConverter struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeLRPConverter) ConvertImage(arg1 string, arg2 *opi.PrivateRegistry) (string, string, error) {
+	fake.convertImageMutex.Lock()
+	ret, specificReturn := fake.convertImageReturnsOnCall[len(fake.convertImageArgsForCall)]
+	fake.convertImageArgsForCall = append(fake.convertImageArgsForCall, struct {
+		arg1 string
+		arg2 *opi.PrivateRegistry
+	}{arg1, arg2})
+	stub := fake.ConvertImageStub
+	fakeReturns := fake.convertImageReturns
+	fake.recordInvocation("ConvertImage", []interface{}{arg1, arg2})
+	fake.convertImageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeLRPConverter) ConvertImageCallCount() int {
+	fake.convertImageMutex.RLock()
+	defer fake.convertImageMutex.RUnlock()
+	return len(fake.convertImageArgsForCall)
+}
+
+func (fake *FakeLRPConverter) ConvertImageCalls(stub func(string, *opi.PrivateRegistry) (string, string, error)) {
+	fake.convertImageMutex.Lock()
+	defer fake.convertImageMutex.Unlock()
+	fake.ConvertImageStub = stub
+}
+
+func (fake *FakeLRPConverter) ConvertImageArgsForCall(i int) (string, *opi.PrivateRegistry) {
+	fake.convertImageMutex.RLock()
+	defer fake.convertImageMutex.RUnlock()
+	argsForCall := fake.convertImageArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLRPConverter) ConvertImageReturns(result1 string, result2 string, result3 error) {
+	fake.convertImageMutex.Lock()
+	defer fake.convertImageMutex.Unlock()
+	fake.ConvertImageStub = nil
+	fake.convertImageReturns = struct {
+		result1 string
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeLRPConverter) ConvertImageReturnsOnCall(i int, result1 string, result2 string, result3 error) {
+	fake.convertImageMutex.Lock()
+	defer fake.convertImageMutex.Unlock()
+	fake.ConvertImageStub = nil
+	if fake.convertImageReturnsOnCall == nil {
+		fake.convertImageReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 string
+			result3 error
+		})
+	}
+	fake.convertImageReturnsOnCall[i] = struct {
+		result1 string
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeLRPConverter) ConvertLRP(arg1 cf.DesireLRPRequest) (opi.LRP, error) {
 	fake.convertLRPMutex.Lock()
 	ret, specificReturn := fake.convertLRPReturnsOnCall[len(fake.convertLRPArgsForCall)]
@@ -94,6 +178,8 @@ func (fake *FakeLRPConverter) ConvertLRPReturnsOnCall(i int, result1 opi.LRP, re
 func (fake *FakeLRPConverter) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.convertImageMutex.RLock()
+	defer fake.convertImageMutex.RUnlock()
 	fake.convertLRPMutex.RLock()
 	defer fake.convertLRPMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -61,6 +61,29 @@ type FakeLRPDesirer struct {
 		result1 []*opi.LRP
 		result2 error
 	}
+	RestartStub        func(opi.LRPIdentifier) error
+	restartMutex       sync.RWMutex
+	restartArgsForCall []struct {
+		arg1 opi.LRPIdentifier
+	}
+	restartReturns struct {
+		result1 error
+	}
+	restartReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RolloutStub        func(opi.LRPIdentifier, string) error
+	rolloutMutex       sync.RWMutex
+	rolloutArgsForCall []struct {
+		arg1 opi.LRPIdentifier
+		arg2 string
+	}
+	rolloutReturns struct {
+		result1 error
+	}
+	rolloutReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StopStub        func(opi.LRPIdentifier) error
 	stopMutex       sync.RWMutex
 	stopArgsForCall []struct {
@@ -346,6 +369,129 @@ func (fake *FakeLRPDesirer) ListReturnsOnCall(i int, result1 []*opi.LRP, result2
 	}{result1, result2}
 }
 
+func (fake *FakeLRPDesirer) Restart(arg1 opi.LRPIdentifier) error {
+	fake.restartMutex.Lock()
+	ret, specificReturn := fake.restartReturnsOnCall[len(fake.restartArgsForCall)]
+	fake.restartArgsForCall = append(fake.restartArgsForCall, struct {
+		arg1 opi.LRPIdentifier
+	}{arg1})
+	stub := fake.RestartStub
+	fakeReturns := fake.restartReturns
+	fake.recordInvocation("Restart", []interface{}{arg1})
+	fake.restartMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLRPDesirer) RestartCallCount() int {
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	return len(fake.restartArgsForCall)
+}
+
+func (fake *FakeLRPDesirer) RestartCalls(stub func(opi.LRPIdentifier) error) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = stub
+}
+
+func (fake *FakeLRPDesirer) RestartArgsForCall(i int) opi.LRPIdentifier {
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	argsForCall := fake.restartArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLRPDesirer) RestartReturns(result1 error) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = nil
+	fake.restartReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLRPDesirer) RestartReturnsOnCall(i int, result1 error) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = nil
+	if fake.restartReturnsOnCall == nil {
+		fake.restartReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.restartReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLRPDesirer) Rollout(arg1 opi.LRPIdentifier, arg2 string) error {
+	fake.rolloutMutex.Lock()
+	ret, specificReturn := fake.rolloutReturnsOnCall[len(fake.rolloutArgsForCall)]
+	fake.rolloutArgsForCall = append(fake.rolloutArgsForCall, struct {
+		arg1 opi.LRPIdentifier
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.RolloutStub
+	fakeReturns := fake.rolloutReturns
+	fake.recordInvocation("Rollout", []interface{}{arg1, arg2})
+	fake.rolloutMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLRPDesirer) RolloutCallCount() int {
+	fake.rolloutMutex.RLock()
+	defer fake.rolloutMutex.RUnlock()
+	return len(fake.rolloutArgsForCall)
+}
+
+func (fake *FakeLRPDesirer) RolloutCalls(stub func(opi.LRPIdentifier, string) error) {
+	fake.rolloutMutex.Lock()
+	defer fake.rolloutMutex.Unlock()
+	fake.RolloutStub = stub
+}
+
+func (fake *FakeLRPDesirer) RolloutArgsForCall(i int) (opi.LRPIdentifier, string) {
+	fake.rolloutMutex.RLock()
+	defer fake.rolloutMutex.RUnlock()
+	argsForCall := fake.rolloutArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLRPDesirer) RolloutReturns(result1 error) {
+	fake.rolloutMutex.Lock()
+	defer fake.rolloutMutex.Unlock()
+	fake.RolloutStub = nil
+	fake.rolloutReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLRPDesirer) RolloutReturnsOnCall(i int, result1 error) {
+	fake.rolloutMutex.Lock()
+	defer fake.rolloutMutex.Unlock()
+	fake.RolloutStub = nil
+	if fake.rolloutReturnsOnCall == nil {
+		fake.rolloutReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.rolloutReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeLRPDesirer) Stop(arg1 opi.LRPIdentifier) error {
 	fake.stopMutex.Lock()
 	ret, specificReturn := fake.stopReturnsOnCall[len(fake.stopArgsForCall)]
@@ -541,6 +687,10 @@ func (fake *FakeLRPDesirer) Invocations() map[string][][]interface{} {
 	defer fake.getInstancesMutex.RUnlock()
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	fake.rolloutMutex.RLock()
+	defer fake.rolloutMutex.RUnlock()
 	fake.stopMutex.RLock()
 	defer fake.stopMutex.RUnlock()
 	fake.stopInstanceMutex.RLock()
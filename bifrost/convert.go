@@ -9,18 +9,24 @@ import (
 	"code.cloudfoundry.org/eirini/models/cf"
 	"code.cloudfoundry.org/eirini/opi"
 	"code.cloudfoundry.org/lager"
+	"github.com/containers/image/docker/reference"
 	"github.com/containers/image/types"
 	"github.com/pkg/errors"
 )
 
-const DockerHubHost = "index.docker.io/v1/"
+const (
+	DockerHubHost     = "index.docker.io/v1/"
+	internalRouterKey = "internal-router"
+)
 
 var dockerRX = regexp.MustCompile(`([a-zA-Z0-9.-]+)(:([0-9]+))?/(\S+/\S+)`) //nolint:gochecknoglobals
 
 type lifecycleOptions struct {
 	command         []string
+	sidecars        []opi.Sidecar
 	env             map[string]string
 	image           string
+	originalImage   string
 	privateRegistry *opi.PrivateRegistry
 	runsAsRoot      bool
 }
@@ -30,16 +36,20 @@ type OPIConverter struct {
 	registryIP           string
 	imageMetadataFetcher ImageMetadataFetcher
 	imageRefParser       ImageRefParser
+	imageDigestFetcher   ImageDigestFetcher
+	imageAdmitter        ImageAdmitter
 	allowRunImageAsRoot  bool
 	stagerConfig         eirini.StagerConfig
 }
 
-func NewOPIConverter(logger lager.Logger, registryIP string, imageMetadataFetcher ImageMetadataFetcher, imageRefParser ImageRefParser, allowRunImageAsRoot bool, stagerConfig eirini.StagerConfig) *OPIConverter {
+func NewOPIConverter(logger lager.Logger, registryIP string, imageMetadataFetcher ImageMetadataFetcher, imageRefParser ImageRefParser, imageDigestFetcher ImageDigestFetcher, imageAdmitter ImageAdmitter, allowRunImageAsRoot bool, stagerConfig eirini.StagerConfig) *OPIConverter {
 	return &OPIConverter{
 		logger:               logger,
 		registryIP:           registryIP,
 		imageMetadataFetcher: imageMetadataFetcher,
 		imageRefParser:       imageRefParser,
+		imageDigestFetcher:   imageDigestFetcher,
+		imageAdmitter:        imageAdmitter,
 		allowRunImageAsRoot:  allowRunImageAsRoot,
 		stagerConfig:         stagerConfig,
 	}
@@ -53,16 +63,21 @@ func (c *OPIConverter) ConvertLRP(request cf.DesireLRPRequest) (opi.LRP, error)
 	var port int32
 	if len(request.Ports) != 0 {
 		port = request.Ports[0]
-		env[eirini.EnvCFInstanceAddr] = fmt.Sprintf("0.0.0.0:%d", port)
-		env[eirini.EnvCFInstancePort] = fmt.Sprintf("%d", port)
-		env[eirini.EnvCFInstancePorts] = fmt.Sprintf(`[{"external":%d,"internal":%d}]`, port, port)
+		env = mergeMaps(env, portEnv(port))
 	}
 
 	healthcheck := opi.Healtcheck{
-		Type:      request.HealthCheckType,
-		Endpoint:  request.HealthCheckHTTPEndpoint,
-		TimeoutMs: request.HealthCheckTimeoutMs,
-		Port:      port,
+		Type:                      request.HealthCheckType,
+		Endpoint:                  request.HealthCheckHTTPEndpoint,
+		TimeoutMs:                 request.HealthCheckTimeoutMs,
+		StartTimeoutMs:            request.StartTimeoutMs,
+		InvocationTimeoutMs:       request.HealthCheckInvocationTimeoutMs,
+		IntervalMs:                request.HealthCheckIntervalMs,
+		Scheme:                    request.HealthCheckHTTPScheme,
+		Host:                      request.HealthCheckHTTPHost,
+		LivenessFailureThreshold:  request.HealthCheckLivenessFailures,
+		ReadinessFailureThreshold: request.HealthCheckReadinessFailures,
+		Port:                      port,
 	}
 
 	lrpLifecycleOptions, err := c.getLifecycleOptions(request)
@@ -85,34 +100,63 @@ func (c *OPIConverter) ConvertLRP(request cf.DesireLRPRequest) (opi.LRP, error)
 		return opi.LRP{}, err
 	}
 
+	internalRoutes, err := getInternalRoutes(request.Routes)
+	if err != nil {
+		return opi.LRP{}, err
+	}
+
+	egressRules, err := getEgressRules(request)
+	if err != nil {
+		return opi.LRP{}, err
+	}
+
 	return opi.LRP{
-		AppName:                request.AppName,
-		AppGUID:                request.AppGUID,
-		AppURIs:                routes,
-		LastUpdated:            request.LastUpdated,
-		OrgName:                request.OrganizationName,
-		OrgGUID:                request.OrganizationGUID,
-		SpaceName:              request.SpaceName,
-		SpaceGUID:              request.SpaceGUID,
-		LRPIdentifier:          identifier,
-		ProcessType:            request.ProcessType,
-		Image:                  lrpLifecycleOptions.image,
-		TargetInstances:        request.NumInstances,
-		Command:                lrpLifecycleOptions.command,
-		Env:                    mergeMaps(request.Environment, env, lrpLifecycleOptions.env),
-		Health:                 healthcheck,
-		Ports:                  request.Ports,
-		MemoryMB:               request.MemoryMB,
-		DiskMB:                 request.DiskMB,
-		CPUWeight:              request.CPUWeight,
-		VolumeMounts:           convertVolumeMounts(request),
-		LRP:                    request.LRP,
-		UserDefinedAnnotations: request.UserDefinedAnnotations,
-		PrivateRegistry:        lrpLifecycleOptions.privateRegistry,
-		RunsAsRoot:             lrpLifecycleOptions.runsAsRoot,
+		AppName:                       request.AppName,
+		AppGUID:                       request.AppGUID,
+		AppURIs:                       routes,
+		InternalRoutes:                internalRoutes,
+		LastUpdated:                   request.LastUpdated,
+		OrgName:                       request.OrganizationName,
+		OrgGUID:                       request.OrganizationGUID,
+		SpaceName:                     request.SpaceName,
+		SpaceGUID:                     request.SpaceGUID,
+		LRPIdentifier:                 identifier,
+		ProcessType:                   request.ProcessType,
+		Image:                         lrpLifecycleOptions.image,
+		OriginalImage:                 lrpLifecycleOptions.originalImage,
+		TargetInstances:               request.NumInstances,
+		Command:                       lrpLifecycleOptions.command,
+		Env:                           mergeMaps(request.Environment, env, lrpLifecycleOptions.env),
+		Health:                        healthcheck,
+		Ports:                         request.Ports,
+		MemoryMB:                      request.MemoryMB,
+		DiskMB:                        request.DiskMB,
+		CPUWeight:                     request.CPUWeight,
+		VolumeMounts:                  convertVolumeMounts(request),
+		PlacementTags:                 request.PlacementTags,
+		EgressRules:                   egressRules,
+		Sidecars:                      lrpLifecycleOptions.sidecars,
+		LRP:                           request.LRP,
+		UserDefinedAnnotations:        request.UserDefinedAnnotations,
+		PrivateRegistry:               lrpLifecycleOptions.privateRegistry,
+		RunsAsRoot:                    lrpLifecycleOptions.runsAsRoot,
+		UpdateStrategy:                convertUpdateStrategy(request.UpdateStrategy),
+		Autoscaling:                   convertAutoscalingPolicy(request.Autoscaling),
+		TerminationGracePeriodSeconds: request.TerminationGracePeriodSeconds,
 	}, nil
 }
 
+func convertUpdateStrategy(strategy *cf.UpdateStrategy) opi.UpdateStrategy {
+	if strategy == nil {
+		return opi.UpdateStrategy{}
+	}
+
+	return opi.UpdateStrategy{
+		MaxUnavailable:  strategy.MaxUnavailable,
+		CanaryInstances: strategy.CanaryInstances,
+	}
+}
+
 func (c *OPIConverter) ConvertTask(taskGUID string, request cf.TaskRequest) (opi.Task, error) {
 	c.logger.Debug("convert-task", lager.Data{"app-id": request.AppGUID, "staging-guid": taskGUID})
 
@@ -147,6 +191,11 @@ func (c *OPIConverter) ConvertTask(taskGUID string, request cf.TaskRequest) (opi
 		task.Command = lifecycle.Command
 		task.Image = lifecycle.Image
 
+		err := c.imageAdmitter.Admit(lifecycle.Image, registrySystemContext(lifecycle.RegistryUsername, lifecycle.RegistryPassword))
+		if err != nil {
+			return opi.Task{}, errors.Wrap(err, "failed to admit image")
+		}
+
 		if lifecycle.RegistryUsername != "" || lifecycle.RegistryPassword != "" {
 			task.PrivateRegistry = &opi.PrivateRegistry{
 				Server:   parseRegistryHost(lifecycle.Image),
@@ -246,6 +295,63 @@ func (c *OPIConverter) getImageUser(lifecycle *cf.DockerLifecycle) (string, erro
 	return imgMetadata.User, nil
 }
 
+// ConvertImage admits an image that is changed by an update and pins it to
+// its digest, the same way ConvertLRP does on desire. It returns the image to
+// run and the original reference if the image got pinned.
+func (c *OPIConverter) ConvertImage(image string, privateRegistry *opi.PrivateRegistry) (string, string, error) {
+	sysCtx := types.SystemContext{}
+	if privateRegistry != nil {
+		sysCtx = registrySystemContext(privateRegistry.Username, privateRegistry.Password)
+	}
+
+	if err := c.imageAdmitter.Admit(image, sysCtx); err != nil {
+		return "", "", errors.Wrap(err, "failed to admit image")
+	}
+
+	if c.imageDigestFetcher == nil {
+		return image, "", nil
+	}
+
+	pinned, err := c.pinImageDigest(image, sysCtx)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to pin image digest")
+	}
+
+	if pinned == image {
+		return image, "", nil
+	}
+
+	return pinned, image, nil
+}
+
+func (c *OPIConverter) pinImageDigest(image string, sysCtx types.SystemContext) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse image name")
+	}
+
+	if _, ok := named.(reference.Digested); ok {
+		return image, nil
+	}
+
+	dockerRef, err := c.imageRefParser.Parse(image)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse image ref")
+	}
+
+	digest, err := c.imageDigestFetcher.Fetch(dockerRef, sysCtx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to fetch image digest")
+	}
+
+	pinned := reference.FamiliarName(named) + "@" + digest
+	if _, err = reference.ParseNormalizedNamed(pinned); err != nil {
+		return "", errors.Wrapf(err, "invalid image digest %q", digest)
+	}
+
+	return pinned, nil
+}
+
 func getRequestedRoutes(request cf.DesireLRPRequest) ([]opi.Route, error) {
 	jsonRoutes := request.Routes
 	if jsonRoutes == nil {
@@ -268,6 +374,56 @@ func getRequestedRoutes(request cf.DesireLRPRequest) ([]opi.Route, error) {
 	return routes, nil
 }
 
+func getInternalRoutes(routes map[string]json.RawMessage) ([]opi.InternalRoute, error) {
+	internalRouterRoutes, ok := routes[internalRouterKey]
+	if !ok {
+		return []opi.InternalRoute{}, nil
+	}
+
+	var internal struct {
+		InternalRoutes []opi.InternalRoute `json:"internal_routes"`
+	}
+
+	if err := json.Unmarshal(internalRouterRoutes, &internal); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal internal routes")
+	}
+
+	if internal.InternalRoutes == nil {
+		return []opi.InternalRoute{}, nil
+	}
+
+	return internal.InternalRoutes, nil
+}
+
+func getEgressRules(request cf.DesireLRPRequest) ([]opi.EgressRule, error) {
+	egressRules := []opi.EgressRule{}
+
+	for _, rawRule := range request.EgressRules {
+		var rule cf.EgressRule
+		if err := json.Unmarshal(rawRule, &rule); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal egress rule")
+		}
+
+		egressRule := opi.EgressRule{
+			Protocol:     rule.Protocol,
+			Destinations: rule.Destinations,
+			Ports:        rule.Ports,
+		}
+
+		if rule.PortRange != nil {
+			egressRule.PortRange = &opi.PortRange{Start: rule.PortRange.Start, End: rule.PortRange.End}
+		}
+
+		if rule.IcmpInfo != nil {
+			egressRule.ICMPInfo = &opi.ICMPInfo{Type: rule.IcmpInfo.Type, Code: rule.IcmpInfo.Code}
+		}
+
+		egressRules = append(egressRules, egressRule)
+	}
+
+	return egressRules, nil
+}
+
 func (c *OPIConverter) imageURI(dropletGUID, dropletHash string) string {
 	return fmt.Sprintf("%s/cloudfoundry/%s:%s", c.registryIP, dropletGUID, dropletHash)
 }
@@ -317,18 +473,37 @@ func (c *OPIConverter) getLifecycleOptions(request cf.DesireLRPRequest) (*lifecy
 		lifecycle := request.Lifecycle.DockerLifecycle
 		options.image = lifecycle.Image
 		options.command = lifecycle.Command
-		options.runsAsRoot, err = c.isAllowedToRunAsRoot(lifecycle)
+		options.sidecars = convertDockerSidecars(request.Sidecars)
 
+		// the image is admitted before anything else, so that denied
+		// registries never get to see the registry credentials
+		err = c.imageAdmitter.Admit(lifecycle.Image, registrySystemContext(lifecycle.RegistryUsername, lifecycle.RegistryPassword))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to admit image")
+		}
+
+		options.runsAsRoot, err = c.isAllowedToRunAsRoot(lifecycle)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to verify if docker image needs root user")
 		}
 
+		if c.imageDigestFetcher != nil {
+			options.image, err = c.pinImageDigest(lifecycle.Image, registrySystemContext(lifecycle.RegistryUsername, lifecycle.RegistryPassword))
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to pin image digest")
+			}
+
+			if options.image != lifecycle.Image {
+				options.originalImage = lifecycle.Image
+			}
+		}
+
 		registryUsername := lifecycle.RegistryUsername
 		registryPassword := lifecycle.RegistryPassword
 
 		if registryUsername != "" || registryPassword != "" {
 			options.privateRegistry = &opi.PrivateRegistry{
-				Server:   parseRegistryHost(options.image),
+				Server:   parseRegistryHost(lifecycle.Image),
 				Username: registryUsername,
 				Password: registryPassword,
 			}
@@ -338,15 +513,9 @@ func (c *OPIConverter) getLifecycleOptions(request cf.DesireLRPRequest) (*lifecy
 		lifecycle := request.Lifecycle.BuildpackLifecycle
 
 		options.image = c.imageURI(lifecycle.DropletGUID, lifecycle.DropletHash)
-		options.command = []string{"dumb-init", "--", "/lifecycle/launch"}
-		options.env = map[string]string{
-			"HOME":          "/home/vcap/app",
-			"PATH":          "/usr/local/bin:/usr/bin:/bin",
-			"USER":          "vcap",
-			"PWD":           "/home/vcap/app",
-			"TMPDIR":        "/home/vcap/tmp",
-			"START_COMMAND": lifecycle.StartCommand,
-		}
+		options.command = buildpackLaunchCommand()
+		options.env = buildpackEnv(lifecycle.StartCommand)
+		options.sidecars = convertBuildpackSidecars(options.command, request.Sidecars)
 		options.runsAsRoot = false
 
 	default:
@@ -356,6 +525,70 @@ func (c *OPIConverter) getLifecycleOptions(request cf.DesireLRPRequest) (*lifecy
 	return options, nil
 }
 
+func buildpackLaunchCommand() []string {
+	return []string{"dumb-init", "--", "/lifecycle/launch"}
+}
+
+func buildpackEnv(startCommand string) map[string]string {
+	return map[string]string{
+		"HOME":          "/home/vcap/app",
+		"PATH":          "/usr/local/bin:/usr/bin:/bin",
+		"USER":          "vcap",
+		"PWD":           "/home/vcap/app",
+		"TMPDIR":        "/home/vcap/tmp",
+		"START_COMMAND": startCommand,
+	}
+}
+
+func portEnv(port int32) map[string]string {
+	return map[string]string{
+		eirini.EnvCFInstanceAddr:  fmt.Sprintf("0.0.0.0:%d", port),
+		eirini.EnvCFInstancePort:  fmt.Sprintf("%d", port),
+		eirini.EnvCFInstancePorts: fmt.Sprintf(`[{"external":%d,"internal":%d}]`, port, port),
+	}
+}
+
+func convertDockerSidecars(sidecars []cf.Sidecar) []opi.Sidecar {
+	result := []opi.Sidecar{}
+	for _, s := range sidecars {
+		result = append(result, opi.Sidecar{
+			Name:     s.Name,
+			Command:  []string{"/bin/sh", "-c", s.Command},
+			MemoryMB: s.MemoryMB,
+			Env:      s.Environment,
+		})
+	}
+
+	return result
+}
+
+func convertBuildpackSidecars(launchCommand []string, sidecars []cf.Sidecar) []opi.Sidecar {
+	result := []opi.Sidecar{}
+	for _, s := range sidecars {
+		result = append(result, opi.Sidecar{
+			Name:     s.Name,
+			Command:  launchCommand,
+			MemoryMB: s.MemoryMB,
+			Env:      mergeMaps(s.Environment, map[string]string{"START_COMMAND": s.Command}),
+		})
+	}
+
+	return result
+}
+
+func convertAutoscalingPolicy(policy *cf.AutoscalingPolicy) *opi.AutoscalingPolicy {
+	if policy == nil || policy.MaxInstances == 0 {
+		return nil
+	}
+
+	return &opi.AutoscalingPolicy{
+		MinInstances:        policy.MinInstances,
+		MaxInstances:        policy.MaxInstances,
+		TargetCPUPercent:    policy.TargetCPUPercent,
+		TargetMemoryPercent: policy.TargetMemoryPercent,
+	}
+}
+
 func convertVolumeMounts(request cf.DesireLRPRequest) []opi.VolumeMount {
 	volumeMounts := []opi.VolumeMount{}
 	for _, vm := range request.VolumeMounts {
@@ -373,5 +606,83 @@ func (c *OPIConverter) validateRequest(request cf.DesireLRPRequest) error {
 		return errors.New("DiskMB cannot be 0")
 	}
 
+	if err := validateSidecars(request); err != nil {
+		return err
+	}
+
+	if s := request.UpdateStrategy; s != nil && (s.MaxUnavailable < 0 || s.CanaryInstances < 0) {
+		return errors.New("update strategy values cannot be negative")
+	}
+
+	if err := validateAutoscalingPolicy(request.Autoscaling); err != nil {
+		return err
+	}
+
+	if request.TerminationGracePeriodSeconds < 0 {
+		return errors.New("termination grace period cannot be negative")
+	}
+
+	return validateHealthCheck(request.HealthCheckType, request.HealthCheckHTTPScheme)
+}
+
+func validateAutoscalingPolicy(policy *cf.AutoscalingPolicy) error {
+	if policy == nil || policy.MaxInstances == 0 {
+		return nil
+	}
+
+	if policy.MinInstances < 0 || policy.MaxInstances < policy.MinInstances {
+		return errors.New("autoscaling max instances must be greater than or equal to min instances")
+	}
+
+	if policy.TargetCPUPercent < 0 || policy.TargetMemoryPercent < 0 {
+		return errors.New("autoscaling targets cannot be negative")
+	}
+
+	if policy.TargetCPUPercent == 0 && policy.TargetMemoryPercent == 0 {
+		return errors.New("autoscaling requires a target cpu or memory percent")
+	}
+
+	return nil
+}
+
+func validateHealthCheck(healthCheckType, scheme string) error {
+	switch healthCheckType {
+	case "", opi.HealthCheckTypeHTTP, opi.HealthCheckTypePort, opi.HealthCheckTypeProcess, opi.HealthCheckTypeNone:
+	default:
+		return fmt.Errorf("unsupported health check type %q", healthCheckType)
+	}
+
+	switch scheme {
+	case "", "http", "https":
+	default:
+		return fmt.Errorf("unsupported health check scheme %q", scheme)
+	}
+
+	return nil
+}
+
+func validateSidecars(request cf.DesireLRPRequest) error {
+	var sidecarsMemoryMB int64
+
+	for _, s := range request.Sidecars {
+		if s.MemoryMB <= 0 {
+			return fmt.Errorf("sidecar %q must specify a memory limit", s.Name)
+		}
+
+		sidecarsMemoryMB += s.MemoryMB
+	}
+
+	if len(request.Sidecars) > 0 {
+		return validateSidecarsMemory(sidecarsMemoryMB, request.MemoryMB)
+	}
+
+	return nil
+}
+
+func validateSidecarsMemory(sidecarsMemoryMB, memoryMB int64) error {
+	if sidecarsMemoryMB >= memoryMB {
+		return fmt.Errorf("sidecars memory (%dMB) must be less than the process memory (%dMB)", sidecarsMemoryMB, memoryMB)
+	}
+
 	return nil
 }
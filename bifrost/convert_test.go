@@ -27,6 +27,9 @@ var _ = Describe("OPI Converter", func() {
 		converter           *bifrost.OPIConverter
 		imgMetadataFetcher  *bifrostfakes.FakeImageMetadataFetcher
 		imgRefParser        *bifrostfakes.FakeImageRefParser
+		imgDigestFetcher    *bifrostfakes.FakeImageDigestFetcher
+		imgAdmitter         *bifrostfakes.FakeImageAdmitter
+		pinImageDigests     bool
 		allowRunImageAsRoot bool
 		stagerConfig        eirini.StagerConfig
 	)
@@ -35,6 +38,9 @@ var _ = Describe("OPI Converter", func() {
 		logger = lagertest.NewTestLogger("converter-test")
 		imgMetadataFetcher = new(bifrostfakes.FakeImageMetadataFetcher)
 		imgRefParser = new(bifrostfakes.FakeImageRefParser)
+		imgDigestFetcher = new(bifrostfakes.FakeImageDigestFetcher)
+		imgAdmitter = new(bifrostfakes.FakeImageAdmitter)
+		pinImageDigests = false
 		allowRunImageAsRoot = false
 		stagerConfig = eirini.StagerConfig{
 			EiriniAddress:   "http://opi.cf.internal",
@@ -45,11 +51,18 @@ var _ = Describe("OPI Converter", func() {
 	})
 
 	JustBeforeEach(func() {
+		var digestFetcher bifrost.ImageDigestFetcher
+		if pinImageDigests {
+			digestFetcher = imgDigestFetcher.Spy
+		}
+
 		converter = bifrost.NewOPIConverter(
 			logger,
 			registryIP,
 			imgMetadataFetcher.Spy,
 			imgRefParser.Spy,
+			digestFetcher,
+			imgAdmitter,
 			allowRunImageAsRoot,
 			stagerConfig,
 		)
@@ -95,10 +108,17 @@ var _ = Describe("OPI Converter", func() {
 				Environment: map[string]string{
 					"VAR_FROM_CC": "val from cc",
 				},
-				HealthCheckType:         "http",
-				HealthCheckHTTPEndpoint: "/heat",
-				HealthCheckTimeoutMs:    400,
-				Ports:                   []int32{8000, 8888},
+				HealthCheckType:                "http",
+				HealthCheckHTTPEndpoint:        "/heat",
+				HealthCheckTimeoutMs:           400,
+				StartTimeoutMs:                 60000,
+				HealthCheckInvocationTimeoutMs: 2000,
+				HealthCheckIntervalMs:          5000,
+				HealthCheckHTTPScheme:          "https",
+				HealthCheckHTTPHost:            "heat.example.com",
+				HealthCheckLivenessFailures:    6,
+				HealthCheckReadinessFailures:   2,
+				Ports:                          []int32{8000, 8888},
 				Routes: map[string]json.RawMessage{
 					"cf-router": rawJSON,
 				},
@@ -112,6 +132,11 @@ var _ = Describe("OPI Converter", func() {
 						MountDir: "/path/two",
 					},
 				},
+				PlacementTags: []string{"isolated"},
+				EgressRules: []json.RawMessage{
+					json.RawMessage(`{"protocol":"tcp","destinations":["10.0.0.0/8"],"ports":[443],"port_range":{"start":8000,"end":8080},"log":false}`),
+					json.RawMessage(`{"protocol":"icmp","destinations":["0.0.0.0/0"],"icmp_info":{"type":8,"code":0}}`),
+				},
 				LRP: "full LRP request",
 				UserDefinedAnnotations: map[string]string{
 					"prometheus.io/scrape": "scrape",
@@ -204,6 +229,30 @@ var _ = Describe("OPI Converter", func() {
 			))
 		})
 
+		It("should not set internal routes", func() {
+			Expect(lrp.InternalRoutes).To(BeEmpty())
+		})
+
+		Context("when internal routes are provided", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Routes["internal-router"] = json.RawMessage(`{"internal_routes":[{"hostname":"bumblebee.apps.internal"}]}`)
+			})
+
+			It("sets the internal routes", func() {
+				Expect(lrp.InternalRoutes).To(ConsistOf(opi.InternalRoute{Hostname: "bumblebee.apps.internal"}))
+			})
+		})
+
+		Context("when the internal routes are malformed", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Routes["internal-router"] = json.RawMessage(`{"internal_routes":`)
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to unmarshal internal routes")))
+			})
+		})
+
 		It("should set the ports", func() {
 			Expect(lrp.Ports).To(Equal([]int32{8000, 8888}))
 		})
@@ -221,6 +270,36 @@ var _ = Describe("OPI Converter", func() {
 			}))
 		})
 
+		It("should set the placement tags", func() {
+			Expect(lrp.PlacementTags).To(Equal([]string{"isolated"}))
+		})
+
+		It("should set the egress rules", func() {
+			Expect(lrp.EgressRules).To(Equal([]opi.EgressRule{
+				{
+					Protocol:     "tcp",
+					Destinations: []string{"10.0.0.0/8"},
+					Ports:        []int32{443},
+					PortRange:    &opi.PortRange{Start: 8000, End: 8080},
+				},
+				{
+					Protocol:     "icmp",
+					Destinations: []string{"0.0.0.0/0"},
+					ICMPInfo:     &opi.ICMPInfo{Type: 8, Code: 0},
+				},
+			}))
+		})
+
+		Context("when the egress rules are malformed", func() {
+			BeforeEach(func() {
+				desireLRPRequest.EgressRules = []json.RawMessage{json.RawMessage(`{"protocol":`)}
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to unmarshal egress rule")))
+			})
+		})
+
 		It("should set the LRP request", func() {
 			Expect(lrp.LRP).To(Equal("full LRP request"))
 		})
@@ -229,6 +308,105 @@ var _ = Describe("OPI Converter", func() {
 			Expect(lrp.UserDefinedAnnotations["prometheus.io/scrape"]).To(Equal("scrape"))
 		})
 
+		It("should not set an update strategy", func() {
+			Expect(lrp.UpdateStrategy).To(BeZero())
+		})
+
+		Context("when an update strategy is provided", func() {
+			BeforeEach(func() {
+				desireLRPRequest.UpdateStrategy = &cf.UpdateStrategy{MaxUnavailable: 3, CanaryInstances: 1}
+			})
+
+			It("should set the update strategy", func() {
+				Expect(lrp.UpdateStrategy).To(Equal(opi.UpdateStrategy{MaxUnavailable: 3, CanaryInstances: 1}))
+			})
+		})
+
+		Context("when the update strategy is negative", func() {
+			BeforeEach(func() {
+				desireLRPRequest.UpdateStrategy = &cf.UpdateStrategy{CanaryInstances: -1}
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError("update strategy values cannot be negative"))
+			})
+		})
+
+		It("should not set an autoscaling policy", func() {
+			Expect(lrp.Autoscaling).To(BeNil())
+		})
+
+		Context("when an autoscaling policy is provided", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Autoscaling = &cf.AutoscalingPolicy{MinInstances: 2, MaxInstances: 8, TargetCPUPercent: 75}
+			})
+
+			It("should set the autoscaling policy", func() {
+				Expect(lrp.Autoscaling).To(Equal(&opi.AutoscalingPolicy{MinInstances: 2, MaxInstances: 8, TargetCPUPercent: 75}))
+			})
+		})
+
+		Context("when the autoscaling policy has no max instances", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Autoscaling = &cf.AutoscalingPolicy{TargetCPUPercent: 75}
+			})
+
+			It("should not autoscale the app", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lrp.Autoscaling).To(BeNil())
+			})
+		})
+
+		Context("when the autoscaling max instances are less than the min instances", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Autoscaling = &cf.AutoscalingPolicy{MinInstances: 3, MaxInstances: 2, TargetCPUPercent: 75}
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError("autoscaling max instances must be greater than or equal to min instances"))
+			})
+		})
+
+		Context("when the autoscaling policy has no target", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Autoscaling = &cf.AutoscalingPolicy{MaxInstances: 2}
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError("autoscaling requires a target cpu or memory percent"))
+			})
+		})
+
+		Context("when an autoscaling target is negative", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Autoscaling = &cf.AutoscalingPolicy{MaxInstances: 2, TargetMemoryPercent: -1}
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError("autoscaling targets cannot be negative"))
+			})
+		})
+
+		Context("when a termination grace period is provided", func() {
+			BeforeEach(func() {
+				desireLRPRequest.TerminationGracePeriodSeconds = 45
+			})
+
+			It("should set the termination grace period", func() {
+				Expect(lrp.TerminationGracePeriodSeconds).To(Equal(int64(45)))
+			})
+		})
+
+		Context("when the termination grace period is negative", func() {
+			BeforeEach(func() {
+				desireLRPRequest.TerminationGracePeriodSeconds = -1
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError("termination grace period cannot be negative"))
+			})
+		})
+
 		Context("when no ports are specified", func() {
 			BeforeEach(func() {
 				desireLRPRequest.Ports = []int32{}
@@ -259,6 +437,60 @@ var _ = Describe("OPI Converter", func() {
 			})
 		})
 
+		Context("when a sidecar has no memory limit", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Sidecars = []cf.Sidecar{{Name: "agent", Command: "./agent"}}
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError(`sidecar "agent" must specify a memory limit`))
+			})
+		})
+
+		Context("when the sidecars need as much memory as the process", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Sidecars = []cf.Sidecar{
+					{Name: "agent", Command: "./agent", MemoryMB: 400},
+					{Name: "other", Command: "./other", MemoryMB: 56},
+				}
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError("sidecars memory (456MB) must be less than the process memory (456MB)"))
+			})
+		})
+
+		Context("when the health check type is process", func() {
+			BeforeEach(func() {
+				desireLRPRequest.HealthCheckType = "process"
+			})
+
+			It("succeeds", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lrp.Health.Type).To(Equal("process"))
+			})
+		})
+
+		Context("when the health check type is not supported", func() {
+			BeforeEach(func() {
+				desireLRPRequest.HealthCheckType = "grpc"
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError(`unsupported health check type "grpc"`))
+			})
+		})
+
+		Context("when the health check scheme is not supported", func() {
+			BeforeEach(func() {
+				desireLRPRequest.HealthCheckHTTPScheme = "ftp"
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError(`unsupported health check scheme "ftp"`))
+			})
+		})
+
 		Context("When the app is using docker lifecycle", func() {
 			BeforeEach(func() {
 				desireLRPRequest.Lifecycle = cf.Lifecycle{
@@ -277,12 +509,38 @@ var _ = Describe("OPI Converter", func() {
 				Expect(lrp.Command).To(Equal([]string{"command-in-docker"}))
 			})
 
+			Context("when the app has sidecars", func() {
+				BeforeEach(func() {
+					desireLRPRequest.Sidecars = []cf.Sidecar{
+						{Name: "agent", Command: "./agent --verbose", MemoryMB: 56, Environment: map[string]string{"FOO": "bar"}},
+					}
+				})
+
+				It("should run the sidecar command in a shell", func() {
+					Expect(lrp.Sidecars).To(Equal([]opi.Sidecar{
+						{
+							Name:     "agent",
+							Command:  []string{"/bin/sh", "-c", "./agent --verbose"},
+							MemoryMB: 56,
+							Env:      map[string]string{"FOO": "bar"},
+						},
+					}))
+				})
+			})
+
 			It("sets the healthcheck information", func() {
 				health := lrp.Health
 				Expect(health.Type).To(Equal("http"))
 				Expect(health.Port).To(Equal(int32(8000)))
 				Expect(health.Endpoint).To(Equal("/heat"))
 				Expect(health.TimeoutMs).To(Equal(uint(400)))
+				Expect(health.StartTimeoutMs).To(Equal(uint(60000)))
+				Expect(health.InvocationTimeoutMs).To(Equal(uint(2000)))
+				Expect(health.IntervalMs).To(Equal(uint(5000)))
+				Expect(health.Scheme).To(Equal("https"))
+				Expect(health.Host).To(Equal("heat.example.com"))
+				Expect(health.LivenessFailureThreshold).To(Equal(int32(6)))
+				Expect(health.ReadinessFailureThreshold).To(Equal(int32(2)))
 			})
 
 			It("shouldn't set privateRegistry information", func() {
@@ -334,6 +592,125 @@ var _ = Describe("OPI Converter", func() {
 				})
 			})
 
+			It("should admit the image", func() {
+				Expect(imgAdmitter.AdmitCallCount()).To(Equal(1))
+				img, _ := imgAdmitter.AdmitArgsForCall(0)
+				Expect(img).To(Equal("the-image-url"))
+			})
+
+			Context("when the image is rejected", func() {
+				BeforeEach(func() {
+					imgAdmitter.AdmitReturns(fmt.Errorf("image is too big: %w", eirini.ErrImageRejected))
+				})
+
+				It("fails with the rejection", func() {
+					Expect(err).To(MatchError(ContainSubstring("image is too big")))
+					Expect(errors.Is(err, eirini.ErrImageRejected)).To(BeTrue())
+				})
+			})
+
+			It("should not pin the image digest", func() {
+				Expect(imgDigestFetcher.CallCount()).To(BeZero())
+				Expect(lrp.OriginalImage).To(BeEmpty())
+			})
+
+			Context("when image digest pinning is enabled", func() {
+				BeforeEach(func() {
+					pinImageDigests = true
+					imgRefParser.Returns("//some-docker-image-ref", nil)
+					imgDigestFetcher.Returns("sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0", nil)
+					desireLRPRequest.Lifecycle = cf.Lifecycle{
+						DockerLifecycle: &cf.DockerLifecycle{
+							Image:            "my-registry.io:5000/repo/the-image:not-latest",
+							Command:          []string{"command-in-docker"},
+							RegistryUsername: "super-user",
+							RegistryPassword: "super-password",
+						},
+					}
+				})
+
+				It("should fetch the digest using the registry credentials", func() {
+					Expect(imgRefParser.ArgsForCall(0)).To(Equal("my-registry.io:5000/repo/the-image:not-latest"))
+					Expect(imgDigestFetcher.CallCount()).To(Equal(1))
+					dockerRef, sysCtx := imgDigestFetcher.ArgsForCall(0)
+					Expect(dockerRef).To(Equal("//some-docker-image-ref"))
+					Expect(sysCtx.DockerAuthConfig.Username).To(Equal("super-user"))
+					Expect(sysCtx.DockerAuthConfig.Password).To(Equal("super-password"))
+				})
+
+				It("should pin the image to the digest", func() {
+					Expect(lrp.Image).To(Equal("my-registry.io:5000/repo/the-image@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"))
+				})
+
+				It("should keep the original image reference", func() {
+					Expect(lrp.OriginalImage).To(Equal("my-registry.io:5000/repo/the-image:not-latest"))
+				})
+
+				It("should keep the registry server of the original image", func() {
+					Expect(lrp.PrivateRegistry.Server).To(Equal("my-registry.io"))
+				})
+
+				It("should admit the requested image with the registry credentials", func() {
+					img, sysCtx := imgAdmitter.AdmitArgsForCall(0)
+					Expect(img).To(Equal("my-registry.io:5000/repo/the-image:not-latest"))
+					Expect(sysCtx.DockerAuthConfig.Username).To(Equal("super-user"))
+					Expect(sysCtx.DockerAuthConfig.Password).To(Equal("super-password"))
+				})
+
+				Context("and the image is rejected", func() {
+					BeforeEach(func() {
+						imgAdmitter.AdmitReturns(fmt.Errorf("registry is denied: %w", eirini.ErrImageRejected))
+					})
+
+					It("should not contact the registry", func() {
+						Expect(errors.Is(err, eirini.ErrImageRejected)).To(BeTrue())
+						Expect(imgDigestFetcher.CallCount()).To(BeZero())
+					})
+				})
+
+				Context("and the image is on the docker hub", func() {
+					BeforeEach(func() {
+						desireLRPRequest.Lifecycle.DockerLifecycle.Image = "busybox"
+					})
+
+					It("should keep the short image name", func() {
+						Expect(lrp.Image).To(Equal("busybox@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"))
+					})
+				})
+
+				Context("and the image is already pinned", func() {
+					BeforeEach(func() {
+						desireLRPRequest.Lifecycle.DockerLifecycle.Image = "busybox@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"
+					})
+
+					It("should not fetch the digest", func() {
+						Expect(imgDigestFetcher.CallCount()).To(BeZero())
+						Expect(lrp.Image).To(Equal("busybox@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"))
+						Expect(lrp.OriginalImage).To(BeEmpty())
+					})
+				})
+
+				Context("and fetching the digest fails", func() {
+					BeforeEach(func() {
+						imgDigestFetcher.Returns("", errors.New("boom"))
+					})
+
+					It("fails", func() {
+						Expect(err).To(MatchError(ContainSubstring("failed to pin image digest")))
+					})
+				})
+
+				Context("and the registry returns an invalid digest", func() {
+					BeforeEach(func() {
+						imgDigestFetcher.Returns("not-a-digest", nil)
+					})
+
+					It("fails", func() {
+						Expect(err).To(MatchError(ContainSubstring(`invalid image digest "not-a-digest"`)))
+					})
+				})
+			})
+
 			Context("when running docker images with root user is allowed", func() {
 				BeforeEach(func() {
 					allowRunImageAsRoot = true
@@ -462,6 +839,11 @@ var _ = Describe("OPI Converter", func() {
 					Expect(health.Port).To(Equal(int32(8000)))
 					Expect(health.Endpoint).To(Equal("/heat"))
 					Expect(health.TimeoutMs).To(Equal(uint(400)))
+					Expect(health.StartTimeoutMs).To(Equal(uint(60000)))
+					Expect(health.InvocationTimeoutMs).To(Equal(uint(2000)))
+					Expect(health.IntervalMs).To(Equal(uint(5000)))
+					Expect(health.Scheme).To(Equal("https"))
+					Expect(health.Host).To(Equal("heat.example.com"))
 				})
 
 				It("should convert droplet apps via the special registry URL", func() {
@@ -477,6 +859,25 @@ var _ = Describe("OPI Converter", func() {
 					Expect(lrp.Env).To(HaveKeyWithValue("START_COMMAND", "start me"))
 				})
 
+				Context("when the app has sidecars", func() {
+					BeforeEach(func() {
+						desireLRPRequest.Sidecars = []cf.Sidecar{
+							{Name: "agent", Command: "./agent --verbose", MemoryMB: 56, Environment: map[string]string{"FOO": "bar"}},
+						}
+					})
+
+					It("should run the sidecar command via the launcher", func() {
+						Expect(lrp.Sidecars).To(Equal([]opi.Sidecar{
+							{
+								Name:     "agent",
+								Command:  []string{"dumb-init", "--", "/lifecycle/launch"},
+								MemoryMB: 56,
+								Env:      map[string]string{"FOO": "bar", "START_COMMAND": "./agent --verbose"},
+							},
+						}))
+					})
+				})
+
 				It("assumes that the pod should not run as root", func() {
 					Expect(lrp.RunsAsRoot).To(BeFalse())
 				})
@@ -484,6 +885,94 @@ var _ = Describe("OPI Converter", func() {
 		})
 	})
 
+	Describe("Convert an updated image", func() {
+		var (
+			image           string
+			originalImage   string
+			privateRegistry *opi.PrivateRegistry
+		)
+
+		BeforeEach(func() {
+			privateRegistry = &opi.PrivateRegistry{Username: "super-user", Password: "super-password"}
+			imgRefParser.Returns("//some-docker-image-ref", nil)
+			imgDigestFetcher.Returns("sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0", nil)
+		})
+
+		JustBeforeEach(func() {
+			image, originalImage, err = converter.ConvertImage("my-registry.io:5000/repo/the-image:not-latest", privateRegistry)
+		})
+
+		It("should keep the image as it is", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(image).To(Equal("my-registry.io:5000/repo/the-image:not-latest"))
+			Expect(originalImage).To(BeEmpty())
+		})
+
+		It("should admit the image with the registry credentials", func() {
+			Expect(imgAdmitter.AdmitCallCount()).To(Equal(1))
+			img, sysCtx := imgAdmitter.AdmitArgsForCall(0)
+			Expect(img).To(Equal("my-registry.io:5000/repo/the-image:not-latest"))
+			Expect(sysCtx.DockerAuthConfig.Username).To(Equal("super-user"))
+			Expect(sysCtx.DockerAuthConfig.Password).To(Equal("super-password"))
+		})
+
+		Context("when the image is rejected", func() {
+			BeforeEach(func() {
+				pinImageDigests = true
+				imgAdmitter.AdmitReturns(fmt.Errorf("registry is denied: %w", eirini.ErrImageRejected))
+			})
+
+			It("fails with the rejection", func() {
+				Expect(err).To(MatchError(ContainSubstring("registry is denied")))
+				Expect(errors.Is(err, eirini.ErrImageRejected)).To(BeTrue())
+			})
+
+			It("should not contact the registry", func() {
+				Expect(imgDigestFetcher.CallCount()).To(BeZero())
+			})
+		})
+
+		Context("when image digest pinning is enabled", func() {
+			BeforeEach(func() {
+				pinImageDigests = true
+			})
+
+			It("should fetch the digest using the registry credentials", func() {
+				Expect(imgDigestFetcher.CallCount()).To(Equal(1))
+				dockerRef, sysCtx := imgDigestFetcher.ArgsForCall(0)
+				Expect(dockerRef).To(Equal("//some-docker-image-ref"))
+				Expect(sysCtx.DockerAuthConfig.Username).To(Equal("super-user"))
+				Expect(sysCtx.DockerAuthConfig.Password).To(Equal("super-password"))
+			})
+
+			It("should pin the image to the digest and keep the original reference", func() {
+				Expect(image).To(Equal("my-registry.io:5000/repo/the-image@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"))
+				Expect(originalImage).To(Equal("my-registry.io:5000/repo/the-image:not-latest"))
+			})
+
+			Context("and the image is public", func() {
+				BeforeEach(func() {
+					privateRegistry = nil
+				})
+
+				It("should fetch the digest anonymously", func() {
+					_, sysCtx := imgDigestFetcher.ArgsForCall(0)
+					Expect(sysCtx.DockerAuthConfig).To(BeNil())
+				})
+			})
+
+			Context("and fetching the digest fails", func() {
+				BeforeEach(func() {
+					imgDigestFetcher.Returns("", errors.New("boom"))
+				})
+
+				It("fails", func() {
+					Expect(err).To(MatchError(ContainSubstring("failed to pin image digest")))
+				})
+			})
+		})
+	})
+
 	Describe("Convert Task", func() {
 		var (
 			taskRequest cf.TaskRequest
@@ -581,6 +1070,25 @@ var _ = Describe("OPI Converter", func() {
 					Expect(task.PrivateRegistry.Password).To(Equal("12345"))
 					Expect(task.PrivateRegistry.Server).To(Equal("private-registry"))
 				})
+
+				It("admits the image with the registry credentials", func() {
+					Expect(imgAdmitter.AdmitCallCount()).To(Equal(1))
+					img, sysCtx := imgAdmitter.AdmitArgsForCall(0)
+					Expect(img).To(Equal("private-registry/some/image"))
+					Expect(sysCtx.DockerAuthConfig.Username).To(Equal("bob"))
+					Expect(sysCtx.DockerAuthConfig.Password).To(Equal("12345"))
+				})
+			})
+
+			When("the docker image is rejected", func() {
+				BeforeEach(func() {
+					imgAdmitter.AdmitReturns(fmt.Errorf("registry is denied: %w", eirini.ErrImageRejected))
+				})
+
+				It("fails with the rejection", func() {
+					Expect(err).To(MatchError(ContainSubstring("registry is denied")))
+					Expect(errors.Is(err, eirini.ErrImageRejected)).To(BeTrue())
+				})
 			})
 		})
 	})
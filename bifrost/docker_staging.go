@@ -14,6 +14,7 @@ import (
 
 //counterfeiter:generate . ImageMetadataFetcher
 //counterfeiter:generate . ImageRefParser
+//counterfeiter:generate . ImageDigestFetcher
 
 type ImageMetadataFetcher func(string, types.SystemContext) (*v1.ImageConfig, error)
 
@@ -21,6 +22,12 @@ func (f ImageMetadataFetcher) Fetch(dockerRef string, sysCtx types.SystemContext
 	return f(dockerRef, sysCtx)
 }
 
+type ImageDigestFetcher func(string, types.SystemContext) (string, error)
+
+func (f ImageDigestFetcher) Fetch(dockerRef string, sysCtx types.SystemContext) (string, error) {
+	return f(dockerRef, sysCtx)
+}
+
 type ImageRefParser func(string) (string, error)
 
 func (f ImageRefParser) Parse(img string) (string, error) {
@@ -31,6 +38,8 @@ type DockerStaging struct {
 	Logger               lager.Logger
 	ImageMetadataFetcher ImageMetadataFetcher
 	ImageRefParser       ImageRefParser
+	ImageAdmitter        ImageAdmitter
+	SignatureVerifier    ImageSignatureVerifier
 	StagingCompleter     StagingCompleter
 }
 
@@ -67,7 +76,23 @@ func (s DockerStaging) TransferStaging(ctx context.Context, stagingGUID string,
 		Annotation: fmt.Sprintf(`{"completion_callback": "%s"}`, request.CompletionCallback),
 	}
 
-	imageConfig, err := s.getImageConfig(request.Lifecycle.DockerLifecycle)
+	lifecycle := request.Lifecycle.DockerLifecycle
+	sysCtx := registrySystemContext(lifecycle.RegistryUsername, lifecycle.RegistryPassword)
+
+	if err := s.ImageAdmitter.Admit(lifecycle.Image, sysCtx); err != nil {
+		logger.Error("failed-to-admit-image", err)
+
+		return s.respondWithFailure(taskCallbackResponse, errors.Wrap(err, "failed to admit image"))
+	}
+
+	verifiedImage, err := s.SignatureVerifier.Verify(request.OrgGUID, lifecycle.Image, sysCtx)
+	if err != nil {
+		logger.Error("failed-to-verify-image-signature", err)
+
+		return s.respondWithFailure(taskCallbackResponse, errors.Wrap(err, "failed to verify image signature"))
+	}
+
+	imageConfig, err := s.getImageConfig(lifecycle)
 	if err != nil {
 		logger.Error("failed-to-get-image-config", err)
 
@@ -81,7 +106,7 @@ func (s DockerStaging) TransferStaging(ctx context.Context, stagingGUID string,
 		return s.respondWithFailure(taskCallbackResponse, errors.Wrap(err, "failed to parse exposed ports"))
 	}
 
-	stagingResult, err := buildStagingResult(request.Lifecycle.DockerLifecycle.Image, ports)
+	stagingResult, err := buildStagingResult(verifiedImage, ports)
 	if err != nil {
 		logger.Error("failed-to-build-staging-result", err)
 
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/bifrost/bifrostfakes"
 	"code.cloudfoundry.org/eirini/models/cf"
@@ -19,6 +21,8 @@ var _ = Describe("DockerStager", func() {
 		stager           bifrost.DockerStaging
 		fetcher          *bifrostfakes.FakeImageMetadataFetcher
 		parser           *bifrostfakes.FakeImageRefParser
+		admitter         *bifrostfakes.FakeImageAdmitter
+		verifier         *bifrostfakes.FakeImageSignatureVerifier
 		stagingCompleter *bifrostfakes.FakeStagingCompleter
 	)
 
@@ -31,9 +35,13 @@ var _ = Describe("DockerStager", func() {
 		BeforeEach(func() {
 			fetcher = new(bifrostfakes.FakeImageMetadataFetcher)
 			parser = new(bifrostfakes.FakeImageRefParser)
+			admitter = new(bifrostfakes.FakeImageAdmitter)
+			verifier = new(bifrostfakes.FakeImageSignatureVerifier)
+			verifier.VerifyReturns("docker.io/eirini/some-app@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0", nil)
 			stagingCompleter = new(bifrostfakes.FakeStagingCompleter)
 			stagingRequest = cf.StagingRequest{
 				CompletionCallback: "the-completion-callback/call/me",
+				OrgGUID:            "org-guid",
 				Lifecycle: cf.StagingLifecycle{
 					DockerLifecycle: &cf.StagingDockerLifecycle{
 						Image: "eirini/some-app:some-tag",
@@ -55,6 +63,8 @@ var _ = Describe("DockerStager", func() {
 				Logger:               lagertest.NewTestLogger(""),
 				ImageMetadataFetcher: fetcher.Spy,
 				ImageRefParser:       parser.Spy,
+				ImageAdmitter:        admitter,
+				SignatureVerifier:    verifier,
 				StagingCompleter:     stagingCompleter,
 			}
 
@@ -89,11 +99,55 @@ var _ = Describe("DockerStager", func() {
 			Expect(json.Unmarshal([]byte(taskCompletedRequest.Result), &payload)).To(Succeed())
 
 			Expect(payload.LifecycleType).To(Equal("docker"))
-			Expect(payload.LifecycleMetadata.DockerImage).To(Equal("eirini/some-app:some-tag"))
+			Expect(payload.LifecycleMetadata.DockerImage).To(Equal("docker.io/eirini/some-app@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"))
 			Expect(payload.ProcessTypes.Web).To(BeEmpty())
 			Expect(payload.ExecutionMetadata).To(Equal(`{"cmd":[],"ports":[{"Port":8888,"Protocol":"tcp"}]}`))
 		})
 
+		It("should admit the image", func() {
+			Expect(admitter.AdmitCallCount()).To(Equal(1))
+			img, _ := admitter.AdmitArgsForCall(0)
+			Expect(img).To(Equal("eirini/some-app:some-tag"))
+		})
+
+		Context("when the image is rejected", func() {
+			BeforeEach(func() {
+				admitter.AdmitReturns(fmt.Errorf(`registry of image "eirini/some-app:some-tag" is not allowed: %w`, eirini.ErrImageRejected))
+			})
+
+			It("should fail staging with the reason", func() {
+				Expect(stagingErr).ToNot(HaveOccurred())
+				Expect(fetcher.CallCount()).To(BeZero())
+				Expect(stagingCompleter.CompleteStagingCallCount()).To(Equal(1))
+
+				taskCallbackResponse := stagingCompleter.CompleteStagingArgsForCall(0)
+				Expect(taskCallbackResponse.Failed).To(BeTrue())
+				Expect(taskCallbackResponse.FailureReason).To(ContainSubstring(`registry of image "eirini/some-app:some-tag" is not allowed`))
+			})
+		})
+
+		It("should verify the image signature for the org", func() {
+			Expect(verifier.VerifyCallCount()).To(Equal(1))
+			orgGUID, img, _ := verifier.VerifyArgsForCall(0)
+			Expect(orgGUID).To(Equal("org-guid"))
+			Expect(img).To(Equal("eirini/some-app:some-tag"))
+		})
+
+		Context("when the image signature cannot be verified", func() {
+			BeforeEach(func() {
+				verifier.VerifyReturns("", fmt.Errorf(`image "eirini/some-app:some-tag" is not signed by a trusted key: %w`, eirini.ErrImageRejected))
+			})
+
+			It("should fail staging with the reason", func() {
+				Expect(stagingErr).ToNot(HaveOccurred())
+				Expect(stagingCompleter.CompleteStagingCallCount()).To(Equal(1))
+
+				taskCallbackResponse := stagingCompleter.CompleteStagingArgsForCall(0)
+				Expect(taskCallbackResponse.Failed).To(BeTrue())
+				Expect(taskCallbackResponse.FailureReason).To(ContainSubstring(`failed to verify image signature: image "eirini/some-app:some-tag" is not signed by a trusted key`))
+			})
+		})
+
 		Context("when the image is from a private registry", func() {
 			BeforeEach(func() {
 				stagingRequest.Lifecycle.DockerLifecycle.Image = "private-registry.io/user/repo"
@@ -105,6 +159,18 @@ var _ = Describe("DockerStager", func() {
 				Expect(stagingErr).ToNot(HaveOccurred())
 			})
 
+			It("should admit the image with the registry credentials", func() {
+				_, ctx := admitter.AdmitArgsForCall(0)
+				Expect(ctx.DockerAuthConfig.Username).To(Equal("some-user"))
+				Expect(ctx.DockerAuthConfig.Password).To(Equal("thepasswrd"))
+			})
+
+			It("should verify the signature with the registry credentials", func() {
+				_, _, ctx := verifier.VerifyArgsForCall(0)
+				Expect(ctx.DockerAuthConfig.Username).To(Equal("some-user"))
+				Expect(ctx.DockerAuthConfig.Password).To(Equal("thepasswrd"))
+			})
+
 			It("should provide the correct credentials", func() {
 				Expect(fetcher.CallCount()).To(Equal(1))
 				_, ctx := fetcher.ArgsForCall(0)
@@ -0,0 +1,106 @@
+package bifrost
+
+import (
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"github.com/containers/image/docker/reference"
+	"github.com/containers/image/types"
+	"github.com/pkg/errors"
+)
+
+const bytesPerMB = 1000 * 1000
+
+//counterfeiter:generate . ImageAdmitter
+//counterfeiter:generate . ImageSizeFetcher
+
+type ImageAdmitter interface {
+	Admit(image string, sysCtx types.SystemContext) error
+}
+
+type ImageSizeFetcher func(string, types.SystemContext) (int64, error)
+
+func (f ImageSizeFetcher) Fetch(dockerRef string, sysCtx types.SystemContext) (int64, error) {
+	return f(dockerRef, sysCtx)
+}
+
+type ImagePolicyAdmitter struct {
+	policy           eirini.ImagePolicy
+	imageRefParser   ImageRefParser
+	imageSizeFetcher ImageSizeFetcher
+}
+
+func NewImagePolicyAdmitter(policy eirini.ImagePolicy, imageRefParser ImageRefParser, imageSizeFetcher ImageSizeFetcher) *ImagePolicyAdmitter {
+	return &ImagePolicyAdmitter{
+		policy:           policy,
+		imageRefParser:   imageRefParser,
+		imageSizeFetcher: imageSizeFetcher,
+	}
+}
+
+func (a *ImagePolicyAdmitter) Admit(image string, sysCtx types.SystemContext) error {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse image name")
+	}
+
+	if matchesRegistry(named, a.policy.DeniedRegistries) {
+		return errors.Wrapf(eirini.ErrImageRejected, "registry of image %q is denied", image)
+	}
+
+	if len(a.policy.AllowedRegistries) > 0 && !matchesRegistry(named, a.policy.AllowedRegistries) {
+		return errors.Wrapf(eirini.ErrImageRejected, "registry of image %q is not allowed", image)
+	}
+
+	if _, ok := named.(reference.Digested); a.policy.RequireDigest && !ok {
+		return errors.Wrapf(eirini.ErrImageRejected, "image %q must be referenced by digest", image)
+	}
+
+	if a.policy.MaxImageSizeMB > 0 {
+		return a.admitSize(image, sysCtx)
+	}
+
+	return nil
+}
+
+func (a *ImagePolicyAdmitter) admitSize(image string, sysCtx types.SystemContext) error {
+	dockerRef, err := a.imageRefParser.Parse(image)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse image ref")
+	}
+
+	size, err := a.imageSizeFetcher.Fetch(dockerRef, sysCtx)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch image size")
+	}
+
+	if size > a.policy.MaxImageSizeMB*bytesPerMB {
+		return errors.Wrapf(eirini.ErrImageRejected, "image %q is %dMB, which exceeds the maximum of %dMB",
+			image, (size+bytesPerMB-1)/bytesPerMB, a.policy.MaxImageSizeMB)
+	}
+
+	return nil
+}
+
+func matchesRegistry(named reference.Named, registries []string) bool {
+	name := named.Name()
+
+	for _, registry := range registries {
+		registry = strings.TrimSuffix(registry, "/")
+
+		if name == registry || strings.HasPrefix(name, registry+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func registrySystemContext(username, password string) types.SystemContext {
+	return types.SystemContext{
+		DockerAuthConfig: &types.DockerAuthConfig{
+			Username: username,
+			Password: password,
+		},
+	}
+}
@@ -0,0 +1,178 @@
+package bifrost_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/bifrost"
+	"code.cloudfoundry.org/eirini/bifrost/bifrostfakes"
+	"github.com/containers/image/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ImagePolicyAdmitter", func() {
+	var (
+		policy       eirini.ImagePolicy
+		refParser    *bifrostfakes.FakeImageRefParser
+		sizeFetcher  *bifrostfakes.FakeImageSizeFetcher
+		image        string
+		sysCtx       types.SystemContext
+		admissionErr error
+	)
+
+	BeforeEach(func() {
+		policy = eirini.ImagePolicy{}
+		refParser = new(bifrostfakes.FakeImageRefParser)
+		refParser.Returns("//docker.io/library/busybox:latest", nil)
+		sizeFetcher = new(bifrostfakes.FakeImageSizeFetcher)
+		image = "busybox:latest"
+		sysCtx = types.SystemContext{
+			DockerAuthConfig: &types.DockerAuthConfig{Username: "user", Password: "pass"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		admitter := bifrost.NewImagePolicyAdmitter(policy, refParser.Spy, sizeFetcher.Spy)
+		admissionErr = admitter.Admit(image, sysCtx)
+	})
+
+	It("admits any image by default", func() {
+		Expect(admissionErr).NotTo(HaveOccurred())
+		Expect(sizeFetcher.CallCount()).To(BeZero())
+	})
+
+	When("the image name is invalid", func() {
+		BeforeEach(func() {
+			image = "this is invalid"
+		})
+
+		It("fails", func() {
+			Expect(admissionErr).To(MatchError(ContainSubstring("failed to parse image name")))
+		})
+	})
+
+	When("registries are allowed", func() {
+		BeforeEach(func() {
+			policy.AllowedRegistries = []string{"registry.example.com", "docker.io/library/"}
+		})
+
+		It("admits images from the docker hub library", func() {
+			Expect(admissionErr).NotTo(HaveOccurred())
+		})
+
+		When("the image is from an allowed registry", func() {
+			BeforeEach(func() {
+				image = "registry.example.com/team/app:v1"
+			})
+
+			It("admits it", func() {
+				Expect(admissionErr).NotTo(HaveOccurred())
+			})
+		})
+
+		When("the image is from a registry that only shares a prefix", func() {
+			BeforeEach(func() {
+				image = "registry.example.com.evil.io/team/app:v1"
+			})
+
+			It("rejects it", func() {
+				Expect(errors.Is(admissionErr, eirini.ErrImageRejected)).To(BeTrue())
+				Expect(admissionErr).To(MatchError(ContainSubstring(`registry of image "registry.example.com.evil.io/team/app:v1" is not allowed`)))
+			})
+		})
+
+		When("the image is from another docker hub user", func() {
+			BeforeEach(func() {
+				image = "someone/app"
+			})
+
+			It("rejects it", func() {
+				Expect(errors.Is(admissionErr, eirini.ErrImageRejected)).To(BeTrue())
+			})
+		})
+
+		When("the registry is also denied", func() {
+			BeforeEach(func() {
+				policy.DeniedRegistries = []string{"docker.io"}
+			})
+
+			It("rejects the image", func() {
+				Expect(errors.Is(admissionErr, eirini.ErrImageRejected)).To(BeTrue())
+				Expect(admissionErr).To(MatchError(ContainSubstring(`registry of image "busybox:latest" is denied`)))
+			})
+		})
+	})
+
+	When("digests are required", func() {
+		BeforeEach(func() {
+			policy.RequireDigest = true
+		})
+
+		It("rejects tagged images", func() {
+			Expect(errors.Is(admissionErr, eirini.ErrImageRejected)).To(BeTrue())
+			Expect(admissionErr).To(MatchError(ContainSubstring(`image "busybox:latest" must be referenced by digest`)))
+		})
+
+		When("the image is referenced by digest", func() {
+			BeforeEach(func() {
+				image = "busybox@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"
+			})
+
+			It("admits it", func() {
+				Expect(admissionErr).NotTo(HaveOccurred())
+			})
+		})
+	})
+
+	When("the image size is limited", func() {
+		BeforeEach(func() {
+			policy.MaxImageSizeMB = 100
+			sizeFetcher.Returns(100*1000*1000, nil)
+		})
+
+		It("fetches the size using the registry credentials", func() {
+			Expect(refParser.ArgsForCall(0)).To(Equal("busybox:latest"))
+			Expect(sizeFetcher.CallCount()).To(Equal(1))
+			dockerRef, ctx := sizeFetcher.ArgsForCall(0)
+			Expect(dockerRef).To(Equal("//docker.io/library/busybox:latest"))
+			Expect(ctx).To(Equal(sysCtx))
+		})
+
+		It("admits images up to the limit", func() {
+			Expect(admissionErr).NotTo(HaveOccurred())
+		})
+
+		When("the image exceeds the limit", func() {
+			BeforeEach(func() {
+				sizeFetcher.Returns(100*1000*1000+1, nil)
+			})
+
+			It("rejects it", func() {
+				Expect(errors.Is(admissionErr, eirini.ErrImageRejected)).To(BeTrue())
+				Expect(admissionErr).To(MatchError(ContainSubstring(`image "busybox:latest" is 101MB, which exceeds the maximum of 100MB`)))
+			})
+		})
+
+		When("parsing the image ref fails", func() {
+			BeforeEach(func() {
+				refParser.Returns("", errors.New("boom"))
+			})
+
+			It("fails", func() {
+				Expect(admissionErr).To(MatchError(ContainSubstring("failed to parse image ref")))
+				Expect(errors.Is(admissionErr, eirini.ErrImageRejected)).To(BeFalse())
+			})
+		})
+
+		When("fetching the size fails", func() {
+			BeforeEach(func() {
+				sizeFetcher.Returns(0, errors.New("boom"))
+			})
+
+			It("fails", func() {
+				Expect(admissionErr).To(MatchError(ContainSubstring("failed to fetch image size")))
+			})
+		})
+	})
+})
@@ -0,0 +1,157 @@
+package bifrost
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/stager/docker"
+	"github.com/containers/image/types"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+//counterfeiter:generate . ImageSignatureFetcher
+//counterfeiter:generate . ImageSignatureVerifier
+
+type ImageSignatureFetcher func(string, types.SystemContext) (string, []docker.Signature, error)
+
+func (f ImageSignatureFetcher) Fetch(dockerRef string, sysCtx types.SystemContext) (string, []docker.Signature, error) {
+	return f(dockerRef, sysCtx)
+}
+
+// ImageSignatureVerifier returns the image pinned to the digest that has
+// been verified, so that a tag moved after staging is never run.
+type ImageSignatureVerifier interface {
+	Verify(orgGUID, image string, sysCtx types.SystemContext) (string, error)
+}
+
+type signaturePayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+type TrustPolicyVerifier struct {
+	trustedKeys      map[string][]*ecdsa.PublicKey
+	imageRefParser   ImageRefParser
+	signatureFetcher ImageSignatureFetcher
+}
+
+func NewTrustPolicyVerifier(policies []eirini.SignatureTrustPolicy, imageRefParser ImageRefParser, signatureFetcher ImageSignatureFetcher) (*TrustPolicyVerifier, error) {
+	trustedKeys := map[string][]*ecdsa.PublicKey{}
+
+	for _, policy := range policies {
+		for _, pemKey := range policy.PublicKeys {
+			key, err := parsePublicKey(pemKey)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid public key in trust policy for org %q", policy.OrgGUID)
+			}
+
+			trustedKeys[policy.OrgGUID] = append(trustedKeys[policy.OrgGUID], key)
+		}
+	}
+
+	return &TrustPolicyVerifier{
+		trustedKeys:      trustedKeys,
+		imageRefParser:   imageRefParser,
+		signatureFetcher: signatureFetcher,
+	}, nil
+}
+
+func (v *TrustPolicyVerifier) Verify(orgGUID, image string, sysCtx types.SystemContext) (string, error) {
+	keys, ok := v.trustedKeys[orgGUID]
+	if !ok {
+		keys = v.trustedKeys[""]
+	}
+
+	if len(keys) == 0 {
+		return image, nil
+	}
+
+	dockerRef, err := v.imageRefParser.Parse(image)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse image ref")
+	}
+
+	repository, err := repositoryName(strings.TrimPrefix(dockerRef, "//"))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse image ref")
+	}
+
+	digest, signatures, err := v.signatureFetcher.Fetch(dockerRef, sysCtx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to fetch image signatures")
+	}
+
+	for _, signature := range signatures {
+		if signsImage(signature, repository, digest) && isSignedByAny(signature, keys) {
+			return repository + "@" + digest, nil
+		}
+	}
+
+	return "", errors.Wrapf(eirini.ErrImageRejected, "image %q is not signed by a trusted key", image)
+}
+
+func parsePublicKey(pemKey string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("failed to decode PEM block")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse public key")
+	}
+
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("unsupported public key type %T", key)
+	}
+
+	return ecdsaKey, nil
+}
+
+func signsImage(signature docker.Signature, repository, digest string) bool {
+	var payload signaturePayload
+	if err := json.Unmarshal(signature.Payload, &payload); err != nil {
+		return false
+	}
+
+	signedRepository, err := repositoryName(payload.Critical.Identity.DockerReference)
+	if err != nil {
+		return false
+	}
+
+	return signedRepository == repository && payload.Critical.Image.DockerManifestDigest == digest
+}
+
+func repositoryName(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return named.Name(), nil
+}
+
+func isSignedByAny(signature docker.Signature, keys []*ecdsa.PublicKey) bool {
+	hash := sha256.Sum256(signature.Payload)
+
+	for _, key := range keys {
+		if ecdsa.VerifyASN1(key, hash[:], signature.Signature) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,228 @@
+package bifrost_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/bifrost"
+	"code.cloudfoundry.org/eirini/bifrost/bifrostfakes"
+	"code.cloudfoundry.org/eirini/stager/docker"
+	"github.com/containers/image/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TrustPolicyVerifier", func() {
+	const digest = "sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"
+
+	var (
+		trustedKey       *ecdsa.PrivateKey
+		otherKey         *ecdsa.PrivateKey
+		policies         []eirini.SignatureTrustPolicy
+		refParser        *bifrostfakes.FakeImageRefParser
+		signatureFetcher *bifrostfakes.FakeImageSignatureFetcher
+		orgGUID          string
+		sysCtx           types.SystemContext
+		verifier         *bifrost.TrustPolicyVerifier
+		creationErr      error
+		verifiedImage    string
+		verifyErr        error
+	)
+
+	BeforeEach(func() {
+		trustedKey = generateECDSAKey()
+		otherKey = generateECDSAKey()
+		policies = []eirini.SignatureTrustPolicy{
+			{OrgGUID: "secure-org", PublicKeys: []string{encodePublicKey(&otherKey.PublicKey), encodePublicKey(&trustedKey.PublicKey)}},
+		}
+		refParser = new(bifrostfakes.FakeImageRefParser)
+		refParser.Returns("//docker.io/eirini/app:latest", nil)
+		signatureFetcher = new(bifrostfakes.FakeImageSignatureFetcher)
+		signatureFetcher.Returns(digest, []docker.Signature{sign(trustedKey, digest)}, nil)
+		orgGUID = "secure-org"
+		sysCtx = types.SystemContext{
+			DockerAuthConfig: &types.DockerAuthConfig{Username: "user", Password: "pass"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		verifier, creationErr = bifrost.NewTrustPolicyVerifier(policies, refParser.Spy, signatureFetcher.Spy)
+		if creationErr == nil {
+			verifiedImage, verifyErr = verifier.Verify(orgGUID, "eirini/app:latest", sysCtx)
+		}
+	})
+
+	It("accepts images signed by a trusted key", func() {
+		Expect(creationErr).NotTo(HaveOccurred())
+		Expect(verifyErr).NotTo(HaveOccurred())
+	})
+
+	It("pins the image to the verified digest", func() {
+		Expect(verifiedImage).To(Equal("docker.io/eirini/app@" + digest))
+	})
+
+	It("fetches the signatures of the image", func() {
+		Expect(refParser.ArgsForCall(0)).To(Equal("eirini/app:latest"))
+		Expect(signatureFetcher.CallCount()).To(Equal(1))
+		dockerRef, ctx := signatureFetcher.ArgsForCall(0)
+		Expect(dockerRef).To(Equal("//docker.io/eirini/app:latest"))
+		Expect(ctx).To(Equal(sysCtx))
+	})
+
+	When("the org has no trust policy", func() {
+		BeforeEach(func() {
+			orgGUID = "other-org"
+		})
+
+		It("does not verify the image", func() {
+			Expect(verifyErr).NotTo(HaveOccurred())
+			Expect(signatureFetcher.CallCount()).To(BeZero())
+			Expect(verifiedImage).To(Equal("eirini/app:latest"))
+		})
+
+		When("there is a default trust policy", func() {
+			BeforeEach(func() {
+				policies = append(policies, eirini.SignatureTrustPolicy{PublicKeys: []string{encodePublicKey(&otherKey.PublicKey)}})
+			})
+
+			It("verifies the image against the default keys", func() {
+				Expect(errors.Is(verifyErr, eirini.ErrImageRejected)).To(BeTrue())
+			})
+		})
+	})
+
+	When("the image is not signed", func() {
+		BeforeEach(func() {
+			signatureFetcher.Returns(digest, []docker.Signature{}, nil)
+		})
+
+		It("rejects the image", func() {
+			Expect(errors.Is(verifyErr, eirini.ErrImageRejected)).To(BeTrue())
+			Expect(verifyErr).To(MatchError(ContainSubstring(`image "eirini/app:latest" is not signed by a trusted key`)))
+		})
+	})
+
+	When("the image is signed by an untrusted key", func() {
+		BeforeEach(func() {
+			signatureFetcher.Returns(digest, []docker.Signature{sign(generateECDSAKey(), digest)}, nil)
+		})
+
+		It("rejects the image", func() {
+			Expect(errors.Is(verifyErr, eirini.ErrImageRejected)).To(BeTrue())
+		})
+	})
+
+	When("the signature is for a different digest", func() {
+		BeforeEach(func() {
+			signatureFetcher.Returns(digest, []docker.Signature{sign(trustedKey, "sha256:0000000000000000000000000000000000000000000000000000000000000000")}, nil)
+		})
+
+		It("rejects the image", func() {
+			Expect(errors.Is(verifyErr, eirini.ErrImageRejected)).To(BeTrue())
+		})
+	})
+
+	When("the signature is for a different repository", func() {
+		BeforeEach(func() {
+			signatureFetcher.Returns(digest, []docker.Signature{signFor(trustedKey, "index.docker.io/attacker/app", digest)}, nil)
+		})
+
+		It("rejects the image", func() {
+			Expect(errors.Is(verifyErr, eirini.ErrImageRejected)).To(BeTrue())
+		})
+	})
+
+	When("the payload has been tampered with", func() {
+		BeforeEach(func() {
+			signature := sign(trustedKey, digest)
+			signature.Payload = append(signature.Payload, ' ')
+			signatureFetcher.Returns(digest, []docker.Signature{signature}, nil)
+		})
+
+		It("rejects the image", func() {
+			Expect(errors.Is(verifyErr, eirini.ErrImageRejected)).To(BeTrue())
+		})
+	})
+
+	When("fetching the signatures fails", func() {
+		BeforeEach(func() {
+			signatureFetcher.Returns("", nil, errors.New("boom"))
+		})
+
+		It("fails", func() {
+			Expect(verifyErr).To(MatchError(ContainSubstring("failed to fetch image signatures")))
+		})
+	})
+
+	When("parsing the image ref fails", func() {
+		BeforeEach(func() {
+			refParser.Returns("", errors.New("boom"))
+		})
+
+		It("fails", func() {
+			Expect(verifyErr).To(MatchError(ContainSubstring("failed to parse image ref")))
+		})
+	})
+
+	When("a public key is not PEM encoded", func() {
+		BeforeEach(func() {
+			policies[0].PublicKeys = []string{"not-a-key"}
+		})
+
+		It("fails to create the verifier", func() {
+			Expect(creationErr).To(MatchError(ContainSubstring(`invalid public key in trust policy for org "secure-org": failed to decode PEM block`)))
+		})
+	})
+
+	When("a public key is not an ECDSA key", func() {
+		BeforeEach(func() {
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			policies[0].PublicKeys = []string{encodePublicKey(&rsaKey.PublicKey)}
+		})
+
+		It("fails to create the verifier", func() {
+			Expect(creationErr).To(MatchError(ContainSubstring("unsupported public key type *rsa.PublicKey")))
+		})
+	})
+})
+
+func generateECDSAKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	return key
+}
+
+func encodePublicKey(key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func sign(key *ecdsa.PrivateKey, digest string) docker.Signature {
+	return signFor(key, "index.docker.io/eirini/app", digest)
+}
+
+func signFor(key *ecdsa.PrivateKey, dockerReference, digest string) docker.Signature {
+	payload := []byte(fmt.Sprintf(
+		`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`,
+		dockerReference,
+		digest,
+	))
+	hash := sha256.Sum256(payload)
+
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	Expect(err).NotTo(HaveOccurred())
+
+	return docker.Signature{Payload: payload, Signature: signature}
+}
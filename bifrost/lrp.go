@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s"
 	"code.cloudfoundry.org/eirini/models/cf"
 	"code.cloudfoundry.org/eirini/opi"
@@ -16,6 +17,7 @@ import (
 
 type LRPConverter interface {
 	ConvertLRP(request cf.DesireLRPRequest) (opi.LRP, error)
+	ConvertImage(image string, privateRegistry *opi.PrivateRegistry) (string, string, error)
 }
 
 type LRPDesirer interface {
@@ -26,6 +28,8 @@ type LRPDesirer interface {
 	Update(lrp *opi.LRP) error
 	Stop(identifier opi.LRPIdentifier) error
 	StopInstance(identifier opi.LRPIdentifier, index uint) error
+	Rollout(identifier opi.LRPIdentifier, action string) error
+	Restart(identifier opi.LRPIdentifier) error
 }
 
 type LRPNamespacer interface {
@@ -70,6 +74,7 @@ func toDesiredLRPSchedulingInfo(lrps []*opi.LRP) []cf.DesiredLRPSchedulingInfo {
 		info.GUID = l.LRPIdentifier.GUID
 		info.Version = l.LRPIdentifier.Version
 		info.Annotation = l.LastUpdated
+		info.OwnedByCRD = l.OwnedByCRD
 		infos = append(infos, info)
 	}
 
@@ -95,11 +100,41 @@ func (l *LRP) Update(ctx context.Context, request cf.UpdateDesiredLRPRequest) er
 		return err
 	}
 
-	lrp.Image = request.Update.Image
+	lrp.InternalRoutes, err = getInternalRoutes(request.Update.Routes)
+	if err != nil {
+		return err
+	}
+
+	if err = l.updateImage(lrp, request.Update.Image); err != nil {
+		return err
+	}
+
+	if err = applyUpdate(lrp, request.Update); err != nil {
+		return errors.Wrap(eirini.ErrInvalidUpdate, err.Error())
+	}
 
 	return errors.Wrap(l.Desirer.Update(lrp), "failed to update")
 }
 
+// updateImage only converts images that actually change, so that resending
+// the current tag does not re-pin it to a newer digest and restart the app.
+func (l *LRP) updateImage(lrp *opi.LRP, image string) error {
+	switch {
+	case image == lrp.Image || image == lrp.OriginalImage:
+		return nil
+	case image == "" || isBuildpackLRP(lrp):
+		lrp.Image = image
+
+		return nil
+	}
+
+	var err error
+
+	lrp.Image, lrp.OriginalImage, err = l.Converter.ConvertImage(image, lrp.PrivateRegistry)
+
+	return errors.Wrap(err, "failed to convert image")
+}
+
 func (l *LRP) GetApp(ctx context.Context, identifier opi.LRPIdentifier) (cf.DesiredLRP, error) {
 	lrp, err := l.Desirer.Get(identifier)
 	if err != nil {
@@ -123,6 +158,19 @@ func (l *LRP) GetApp(ctx context.Context, identifier opi.LRPIdentifier) (cf.Desi
 		desiredLRP.Routes = lrpRoutes
 	}
 
+	if len(lrp.InternalRoutes) > 0 {
+		data, err := json.Marshal(map[string][]opi.InternalRoute{"internal_routes": lrp.InternalRoutes})
+		if err != nil {
+			return cf.DesiredLRP{}, errors.Wrap(err, "failed to marshal internal routes")
+		}
+
+		if desiredLRP.Routes == nil {
+			desiredLRP.Routes = map[string]json.RawMessage{}
+		}
+
+		desiredLRP.Routes[internalRouterKey] = data
+	}
+
 	return desiredLRP, nil
 }
 
@@ -138,6 +186,14 @@ func (l *LRP) StopInstance(ctx context.Context, identifier opi.LRPIdentifier, in
 	return nil
 }
 
+func (l *LRP) Rollout(ctx context.Context, identifier opi.LRPIdentifier, action string) error {
+	return errors.Wrap(l.Desirer.Rollout(identifier, action), "failed to roll out app")
+}
+
+func (l *LRP) Restart(ctx context.Context, identifier opi.LRPIdentifier) error {
+	return errors.Wrap(l.Desirer.Restart(identifier), "failed to restart app")
+}
+
 func (l *LRP) GetInstances(ctx context.Context, identifier opi.LRPIdentifier) ([]*cf.Instance, error) {
 	opiInstances, err := l.Desirer.GetInstances(identifier)
 	if err != nil {
@@ -151,6 +207,7 @@ func (l *LRP) GetInstances(ctx context.Context, identifier opi.LRPIdentifier) ([
 			Index:          i.Index,
 			State:          i.State,
 			PlacementError: i.PlacementError,
+			UpdatePending:  i.UpdatePending,
 		})
 	}
 
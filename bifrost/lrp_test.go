@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/bifrost/bifrostfakes"
 	"code.cloudfoundry.org/eirini/models/cf"
@@ -228,6 +230,9 @@ var _ = Describe("Bifrost LRP", func() {
 			}, nil)
 
 			lrpDesirer.UpdateReturns(nil)
+			lrpConverter.ConvertImageStub = func(image string, _ *opi.PrivateRegistry) (string, string, error) {
+				return image, "", nil
+			}
 		})
 
 		JustBeforeEach(func() {
@@ -257,6 +262,79 @@ var _ = Describe("Bifrost LRP", func() {
 			Expect(lrp.Image).To(Equal("the/image"))
 		})
 
+		Context("when the image changes", func() {
+			BeforeEach(func() {
+				lrpDesirer.GetReturns(&opi.LRP{
+					Image:           "the/image@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0",
+					OriginalImage:   "the/image",
+					PrivateRegistry: &opi.PrivateRegistry{Username: "user", Password: "pass"},
+				}, nil)
+				updateRequest.Update.Image = "the/new-image"
+				lrpConverter.ConvertImageStub = nil
+				lrpConverter.ConvertImageReturns("the/new-image@sha256:17ba0e8e0c95b2e6a4a1d0dc9b8e33c4bc8fd3e5ab5a0bcb5c1e0e9e7f0c7d3a", "the/new-image", nil)
+			})
+
+			It("should convert the new image with the registry credentials", func() {
+				Expect(lrpConverter.ConvertImageCallCount()).To(Equal(1))
+				image, privateRegistry := lrpConverter.ConvertImageArgsForCall(0)
+				Expect(image).To(Equal("the/new-image"))
+				Expect(privateRegistry).To(Equal(&opi.PrivateRegistry{Username: "user", Password: "pass"}))
+			})
+
+			It("should pin the new image to its digest", func() {
+				lrp := lrpDesirer.UpdateArgsForCall(0)
+				Expect(lrp.Image).To(Equal("the/new-image@sha256:17ba0e8e0c95b2e6a4a1d0dc9b8e33c4bc8fd3e5ab5a0bcb5c1e0e9e7f0c7d3a"))
+				Expect(lrp.OriginalImage).To(Equal("the/new-image"))
+			})
+
+			Context("and the new image is denied", func() {
+				BeforeEach(func() {
+					lrpConverter.ConvertImageReturns("", "", fmt.Errorf("registry is denied: %w", eirini.ErrImageRejected))
+				})
+
+				It("should reject the update", func() {
+					Expect(errors.Is(err, eirini.ErrImageRejected)).To(BeTrue())
+					Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+				})
+			})
+
+			Context("and converting the image fails", func() {
+				BeforeEach(func() {
+					lrpConverter.ConvertImageReturns("", "", errors.New("boom"))
+				})
+
+				It("should not update the app", func() {
+					Expect(err).To(MatchError(ContainSubstring("failed to convert image")))
+					Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+				})
+			})
+
+			Context("to the image reference it was pinned from", func() {
+				BeforeEach(func() {
+					updateRequest.Update.Image = "the/image"
+				})
+
+				It("should keep the pinned image", func() {
+					Expect(lrpConverter.ConvertImageCallCount()).To(BeZero())
+					lrp := lrpDesirer.UpdateArgsForCall(0)
+					Expect(lrp.Image).To(Equal("the/image@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"))
+					Expect(lrp.OriginalImage).To(Equal("the/image"))
+				})
+			})
+		})
+
+		Context("when the app is a buildpack app", func() {
+			BeforeEach(func() {
+				lrpDesirer.GetReturns(&opi.LRP{Command: []string{"dumb-init", "--", "/lifecycle/launch"}}, nil)
+			})
+
+			It("should not convert the droplet image", func() {
+				Expect(lrpConverter.ConvertImageCallCount()).To(BeZero())
+				lrp := lrpDesirer.UpdateArgsForCall(0)
+				Expect(lrp.Image).To(Equal("the/image"))
+			})
+		})
+
 		Context("when the update fails", func() {
 			BeforeEach(func() {
 				lrpDesirer.UpdateReturns(errors.New("your app is bad"))
@@ -279,6 +357,304 @@ var _ = Describe("Bifrost LRP", func() {
 			})
 		})
 
+		Context("when internal routes are provided", func() {
+			BeforeEach(func() {
+				updateRequest.Update.Routes["internal-router"] = json.RawMessage(`{"internal_routes":[{"hostname":"app.apps.internal"}]}`)
+			})
+
+			It("should update the internal routes", func() {
+				Expect(lrpDesirer.UpdateCallCount()).To(Equal(1))
+				lrp := lrpDesirer.UpdateArgsForCall(0)
+				Expect(lrp.InternalRoutes).To(ConsistOf(opi.InternalRoute{Hostname: "app.apps.internal"}))
+			})
+		})
+
+		Context("when the internal routes are malformed", func() {
+			BeforeEach(func() {
+				updateRequest.Update.Routes["internal-router"] = json.RawMessage(`[`)
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to unmarshal internal routes")))
+				Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the update changes the process settings", func() {
+			var existingLRP *opi.LRP
+
+			BeforeEach(func() {
+				existingLRP = &opi.LRP{
+					Command: []string{"/bin/app"},
+					Env: map[string]string{
+						"LANG":                 "en_US.UTF-8",
+						"CF_INSTANCE_ADDR":     "0.0.0.0:8080",
+						"CF_INSTANCE_PORT":     "8080",
+						"CF_INSTANCE_PORTS":    `[{"external":8080,"internal":8080}]`,
+						"HOME":                 "/home/user",
+						"USER_DEFINED_ENV_VAR": "old",
+					},
+					Ports:    []int32{8080},
+					MemoryMB: 256,
+					DiskMB:   512,
+					Health: opi.Healtcheck{
+						Type:      "port",
+						Port:      8080,
+						TimeoutMs: 1000,
+					},
+				}
+				lrpDesirer.GetReturns(existingLRP, nil)
+			})
+
+			It("should not change anything that was not requested", func() {
+				lrp := lrpDesirer.UpdateArgsForCall(0)
+				Expect(lrp.Command).To(Equal([]string{"/bin/app"}))
+				Expect(lrp.Env).To(HaveKeyWithValue("USER_DEFINED_ENV_VAR", "old"))
+				Expect(lrp.Ports).To(Equal([]int32{8080}))
+				Expect(lrp.MemoryMB).To(Equal(int64(256)))
+				Expect(lrp.DiskMB).To(Equal(int64(512)))
+				Expect(lrp.Health).To(Equal(opi.Healtcheck{Type: "port", Port: 8080, TimeoutMs: 1000}))
+			})
+
+			When("an autoscaling policy is set", func() {
+				BeforeEach(func() {
+					updateRequest.Update.Autoscaling = &cf.AutoscalingPolicy{MinInstances: 1, MaxInstances: 4, TargetCPUPercent: 50}
+				})
+
+				It("should autoscale the app", func() {
+					lrp := lrpDesirer.UpdateArgsForCall(0)
+					Expect(lrp.Autoscaling).To(Equal(&opi.AutoscalingPolicy{MinInstances: 1, MaxInstances: 4, TargetCPUPercent: 50}))
+				})
+
+				When("the policy is invalid", func() {
+					BeforeEach(func() {
+						updateRequest.Update.Autoscaling.MinInstances = 5
+					})
+
+					It("should return an invalid update error", func() {
+						Expect(errors.Is(err, eirini.ErrInvalidUpdate)).To(BeTrue())
+						Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+					})
+				})
+			})
+
+			When("autoscaling is turned off", func() {
+				BeforeEach(func() {
+					existingLRP.Autoscaling = &opi.AutoscalingPolicy{MinInstances: 1, MaxInstances: 4, TargetCPUPercent: 50}
+					updateRequest.Update.Autoscaling = &cf.AutoscalingPolicy{}
+				})
+
+				It("should stop autoscaling the app", func() {
+					lrp := lrpDesirer.UpdateArgsForCall(0)
+					Expect(lrp.Autoscaling).To(BeNil())
+				})
+			})
+
+			When("memory and disk are changed", func() {
+				BeforeEach(func() {
+					memory, disk := int64(1024), int64(2048)
+					updateRequest.Update.MemoryMB = &memory
+					updateRequest.Update.DiskMB = &disk
+				})
+
+				It("should update them", func() {
+					lrp := lrpDesirer.UpdateArgsForCall(0)
+					Expect(lrp.MemoryMB).To(Equal(int64(1024)))
+					Expect(lrp.DiskMB).To(Equal(int64(2048)))
+				})
+			})
+
+			When("the environment is changed", func() {
+				BeforeEach(func() {
+					updateRequest.Update.Environment = map[string]string{
+						"ANOTHER_ENV_VAR": "new",
+						"LANG":            "de_DE.UTF-8",
+					}
+				})
+
+				It("should replace the user defined environment, keeping the variables set by eirini", func() {
+					lrp := lrpDesirer.UpdateArgsForCall(0)
+					Expect(lrp.Env).To(Equal(map[string]string{
+						"LANG":              "en_US.UTF-8",
+						"CF_INSTANCE_ADDR":  "0.0.0.0:8080",
+						"CF_INSTANCE_PORT":  "8080",
+						"CF_INSTANCE_PORTS": `[{"external":8080,"internal":8080}]`,
+						"ANOTHER_ENV_VAR":   "new",
+					}))
+				})
+			})
+
+			When("the command is changed", func() {
+				BeforeEach(func() {
+					updateRequest.Update.Command = []string{"/bin/app", "--verbose"}
+				})
+
+				It("should update it", func() {
+					lrp := lrpDesirer.UpdateArgsForCall(0)
+					Expect(lrp.Command).To(Equal([]string{"/bin/app", "--verbose"}))
+				})
+			})
+
+			When("the start command is changed", func() {
+				BeforeEach(func() {
+					startCommand := "./app --verbose"
+					updateRequest.Update.StartCommand = &startCommand
+				})
+
+				It("should reject the update, as docker apps have no start command", func() {
+					Expect(err).To(MatchError(ContainSubstring("the start command of a docker app cannot be changed in place")))
+					Expect(errors.Is(err, eirini.ErrInvalidUpdate)).To(BeTrue())
+					Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+				})
+			})
+
+			When("the app is a buildpack app", func() {
+				BeforeEach(func() {
+					existingLRP.Command = []string{"dumb-init", "--", "/lifecycle/launch"}
+					existingLRP.Env["START_COMMAND"] = "./app"
+					existingLRP.Env["PWD"] = "/home/vcap/app"
+				})
+
+				When("the start command is changed", func() {
+					BeforeEach(func() {
+						startCommand := "./app --verbose"
+						updateRequest.Update.StartCommand = &startCommand
+					})
+
+					It("should update it", func() {
+						lrp := lrpDesirer.UpdateArgsForCall(0)
+						Expect(lrp.Env).To(HaveKeyWithValue("START_COMMAND", "./app --verbose"))
+						Expect(lrp.Command).To(Equal([]string{"dumb-init", "--", "/lifecycle/launch"}))
+					})
+				})
+
+				When("the environment is changed", func() {
+					BeforeEach(func() {
+						updateRequest.Update.Environment = map[string]string{"ANOTHER_ENV_VAR": "new"}
+					})
+
+					It("should keep the buildpack environment", func() {
+						lrp := lrpDesirer.UpdateArgsForCall(0)
+						Expect(lrp.Env).To(HaveKeyWithValue("START_COMMAND", "./app"))
+						Expect(lrp.Env).To(HaveKeyWithValue("HOME", "/home/user"))
+						Expect(lrp.Env).To(HaveKeyWithValue("PWD", "/home/vcap/app"))
+						Expect(lrp.Env).To(HaveKeyWithValue("ANOTHER_ENV_VAR", "new"))
+						Expect(lrp.Env).NotTo(HaveKey("USER_DEFINED_ENV_VAR"))
+					})
+				})
+
+				When("the command is changed", func() {
+					BeforeEach(func() {
+						updateRequest.Update.Command = []string{"/bin/app"}
+					})
+
+					It("should reject the update", func() {
+						Expect(err).To(MatchError(ContainSubstring("the command of a buildpack app cannot be changed in place")))
+						Expect(errors.Is(err, eirini.ErrInvalidUpdate)).To(BeTrue())
+						Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+					})
+				})
+			})
+
+			When("the ports are changed", func() {
+				BeforeEach(func() {
+					updateRequest.Update.Ports = []int32{9090, 9091}
+				})
+
+				It("should update the ports, the health check port and the port env vars", func() {
+					lrp := lrpDesirer.UpdateArgsForCall(0)
+					Expect(lrp.Ports).To(Equal([]int32{9090, 9091}))
+					Expect(lrp.Health.Port).To(Equal(int32(9090)))
+					Expect(lrp.Env).To(HaveKeyWithValue("CF_INSTANCE_ADDR", "0.0.0.0:9090"))
+					Expect(lrp.Env).To(HaveKeyWithValue("CF_INSTANCE_PORT", "9090"))
+					Expect(lrp.Env).To(HaveKeyWithValue("CF_INSTANCE_PORTS", `[{"external":9090,"internal":9090}]`))
+					Expect(lrp.Env).To(HaveKeyWithValue("USER_DEFINED_ENV_VAR", "old"))
+				})
+
+				When("all ports are removed", func() {
+					BeforeEach(func() {
+						updateRequest.Update.Ports = []int32{}
+					})
+
+					It("should remove the port env vars", func() {
+						lrp := lrpDesirer.UpdateArgsForCall(0)
+						Expect(lrp.Ports).To(BeEmpty())
+						Expect(lrp.Health.Port).To(BeZero())
+						Expect(lrp.Env).NotTo(HaveKey("CF_INSTANCE_ADDR"))
+						Expect(lrp.Env).NotTo(HaveKey("CF_INSTANCE_PORT"))
+						Expect(lrp.Env).NotTo(HaveKey("CF_INSTANCE_PORTS"))
+					})
+				})
+			})
+
+			When("the health check is changed", func() {
+				BeforeEach(func() {
+					updateRequest.Update.HealthCheck = &cf.HealthCheckUpdate{
+						Type:                "http",
+						HTTPEndpoint:        "/health",
+						TimeoutMs:           2000,
+						StartTimeoutMs:      60000,
+						InvocationTimeoutMs: 500,
+						IntervalMs:          5000,
+						HTTPScheme:          "https",
+						HTTPHost:            "my.host",
+					}
+				})
+
+				It("should update it, keeping the health check port", func() {
+					lrp := lrpDesirer.UpdateArgsForCall(0)
+					Expect(lrp.Health).To(Equal(opi.Healtcheck{
+						Type:                "http",
+						Port:                8080,
+						Endpoint:            "/health",
+						TimeoutMs:           2000,
+						StartTimeoutMs:      60000,
+						InvocationTimeoutMs: 500,
+						IntervalMs:          5000,
+						Scheme:              "https",
+						Host:                "my.host",
+					}))
+				})
+
+				When("the health check type is not supported", func() {
+					BeforeEach(func() {
+						updateRequest.Update.HealthCheck.Type = "magic"
+					})
+
+					It("should reject the update", func() {
+						Expect(err).To(MatchError(ContainSubstring(`unsupported health check type "magic"`)))
+						Expect(errors.Is(err, eirini.ErrInvalidUpdate)).To(BeTrue())
+						Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+					})
+				})
+			})
+
+			When("the disk is set to 0", func() {
+				BeforeEach(func() {
+					disk := int64(0)
+					updateRequest.Update.DiskMB = &disk
+				})
+
+				It("should reject the update", func() {
+					Expect(err).To(MatchError(ContainSubstring("DiskMB cannot be 0")))
+					Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+				})
+			})
+
+			When("the app has sidecars and the memory is lowered below their memory", func() {
+				BeforeEach(func() {
+					existingLRP.Sidecars = []opi.Sidecar{{Name: "agent", MemoryMB: 200}}
+					memory := int64(128)
+					updateRequest.Update.MemoryMB = &memory
+				})
+
+				It("should reject the update", func() {
+					Expect(err).To(MatchError(ContainSubstring("sidecars memory (200MB) must be less than the process memory (128MB)")))
+					Expect(lrpDesirer.UpdateCallCount()).To(BeZero())
+				})
+			})
+		})
+
 		Context("when the app does not exist", func() {
 			BeforeEach(func() {
 				lrpDesirer.GetReturns(nil, errors.New("app does not exist"))
@@ -338,6 +714,23 @@ var _ = Describe("Bifrost LRP", func() {
 				Expect(desiredLRP.Routes).To(HaveKeyWithValue("cf-router", json.RawMessage(`[{"hostname":"route1.io","port":6666},{"hostname":"route2.io","port":9999}]`)))
 				Expect(desiredLRP.Image).To(Equal("the/image"))
 			})
+
+			It("should not return internal routes", func() {
+				desiredLRP, _ := lrpBifrost.GetApp(context.Background(), identifier)
+				Expect(desiredLRP.Routes).NotTo(HaveKey("internal-router"))
+			})
+
+			Context("when the app has internal routes", func() {
+				BeforeEach(func() {
+					lrp.InternalRoutes = []opi.InternalRoute{{Hostname: "app.apps.internal"}}
+				})
+
+				It("should return them as internal-router routes", func() {
+					desiredLRP, getAppErr := lrpBifrost.GetApp(context.Background(), identifier)
+					Expect(getAppErr).NotTo(HaveOccurred())
+					Expect(desiredLRP.Routes).To(HaveKeyWithValue("internal-router", json.RawMessage(`{"internal_routes":[{"hostname":"app.apps.internal"}]}`)))
+				})
+			})
 		})
 
 		Context("when the app does not exist", func() {
@@ -405,6 +798,59 @@ var _ = Describe("Bifrost LRP", func() {
 		})
 	})
 
+	Describe("Roll out an app", func() {
+		JustBeforeEach(func() {
+			err = lrpBifrost.Rollout(context.Background(), opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, "promote")
+		})
+
+		It("should not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should call the desirer with the expected guid and action", func() {
+			identifier, action := lrpDesirer.RolloutArgsForCall(0)
+			Expect(identifier.GUID).To(Equal("guid_1234"))
+			Expect(identifier.Version).To(Equal("version_1234"))
+			Expect(action).To(Equal("promote"))
+		})
+
+		Context("when desirer's rollout fails", func() {
+			BeforeEach(func() {
+				lrpDesirer.RolloutReturns(errors.New("failed-to-roll-out"))
+			})
+
+			It("returns a meaningful error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to roll out app")))
+			})
+		})
+	})
+
+	Describe("Restart an app", func() {
+		JustBeforeEach(func() {
+			err = lrpBifrost.Restart(context.Background(), opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})
+		})
+
+		It("should not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should call the desirer with the expected guid", func() {
+			identifier := lrpDesirer.RestartArgsForCall(0)
+			Expect(identifier.GUID).To(Equal("guid_1234"))
+			Expect(identifier.Version).To(Equal("version_1234"))
+		})
+
+		Context("when desirer's restart fails", func() {
+			BeforeEach(func() {
+				lrpDesirer.RestartReturns(errors.New("failed-to-restart"))
+			})
+
+			It("returns a meaningful error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to restart app")))
+			})
+		})
+	})
+
 	Describe("Get all instances of an app", func() {
 		var (
 			instances    []*cf.Instance
@@ -414,7 +860,7 @@ var _ = Describe("Bifrost LRP", func() {
 		BeforeEach(func() {
 			opiInstances = []*opi.Instance{
 				{Index: 0, Since: 123, State: opi.RunningState},
-				{Index: 1, Since: 345, State: opi.CrashedState},
+				{Index: 1, Since: 345, State: opi.CrashedState, UpdatePending: true},
 				{Index: 2, Since: 678, State: opi.ErrorState, PlacementError: "this is not the place"},
 			}
 
@@ -439,7 +885,7 @@ var _ = Describe("Bifrost LRP", func() {
 		It("should return all running instances", func() {
 			Expect(instances).To(Equal([]*cf.Instance{
 				{Index: 0, Since: 123, State: opi.RunningState},
-				{Index: 1, Since: 345, State: opi.CrashedState},
+				{Index: 1, Since: 345, State: opi.CrashedState, UpdatePending: true},
 				{Index: 2, Since: 678, State: opi.ErrorState, PlacementError: "this is not the place"},
 			}))
 		})
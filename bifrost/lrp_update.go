@@ -0,0 +1,140 @@
+package bifrost
+
+import (
+	"reflect"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/opi"
+	"github.com/pkg/errors"
+)
+
+const envStartCommand = "START_COMMAND"
+
+func applyUpdate(lrp *opi.LRP, update cf.DesiredLRPUpdate) error {
+	if err := validateUpdate(lrp, update); err != nil {
+		return err
+	}
+
+	if update.MemoryMB != nil {
+		lrp.MemoryMB = *update.MemoryMB
+	}
+
+	if update.DiskMB != nil {
+		lrp.DiskMB = *update.DiskMB
+	}
+
+	if update.Environment != nil {
+		lrp.Env = mergeMaps(update.Environment, managedEnv(lrp))
+	}
+
+	if update.Command != nil {
+		lrp.Command = update.Command
+	}
+
+	if update.StartCommand != nil {
+		lrp.Env = mergeMaps(lrp.Env, map[string]string{envStartCommand: *update.StartCommand})
+	}
+
+	if update.Ports != nil {
+		updatePorts(lrp, update.Ports)
+	}
+
+	if update.Autoscaling != nil {
+		lrp.Autoscaling = convertAutoscalingPolicy(update.Autoscaling)
+	}
+
+	if update.HealthCheck != nil {
+		lrp.Health = opi.Healtcheck{
+			Type:                      update.HealthCheck.Type,
+			Endpoint:                  update.HealthCheck.HTTPEndpoint,
+			TimeoutMs:                 update.HealthCheck.TimeoutMs,
+			StartTimeoutMs:            update.HealthCheck.StartTimeoutMs,
+			InvocationTimeoutMs:       update.HealthCheck.InvocationTimeoutMs,
+			IntervalMs:                update.HealthCheck.IntervalMs,
+			Scheme:                    update.HealthCheck.HTTPScheme,
+			Host:                      update.HealthCheck.HTTPHost,
+			LivenessFailureThreshold:  update.HealthCheck.LivenessFailures,
+			ReadinessFailureThreshold: update.HealthCheck.ReadinessFailures,
+			Port:                      lrp.Health.Port,
+		}
+	}
+
+	return nil
+}
+
+func validateUpdate(lrp *opi.LRP, update cf.DesiredLRPUpdate) error {
+	if update.Command != nil && isBuildpackLRP(lrp) {
+		return errors.New("the command of a buildpack app cannot be changed in place, use start_command instead")
+	}
+
+	if update.StartCommand != nil && !isBuildpackLRP(lrp) {
+		return errors.New("the start command of a docker app cannot be changed in place, use command instead")
+	}
+
+	if update.DiskMB != nil && *update.DiskMB == 0 {
+		return errors.New("DiskMB cannot be 0")
+	}
+
+	if update.MemoryMB != nil && len(lrp.Sidecars) > 0 {
+		var sidecarsMemoryMB int64
+		for _, s := range lrp.Sidecars {
+			sidecarsMemoryMB += s.MemoryMB
+		}
+
+		if err := validateSidecarsMemory(sidecarsMemoryMB, *update.MemoryMB); err != nil {
+			return err
+		}
+	}
+
+	if err := validateAutoscalingPolicy(update.Autoscaling); err != nil {
+		return err
+	}
+
+	if update.HealthCheck != nil {
+		return validateHealthCheck(update.HealthCheck.Type, update.HealthCheck.HTTPScheme)
+	}
+
+	return nil
+}
+
+func updatePorts(lrp *opi.LRP, ports []int32) {
+	lrp.Ports = ports
+	lrp.Health.Port = 0
+
+	env := mergeMaps(lrp.Env)
+	delete(env, eirini.EnvCFInstanceAddr)
+	delete(env, eirini.EnvCFInstancePort)
+	delete(env, eirini.EnvCFInstancePorts)
+
+	if len(ports) > 0 {
+		lrp.Health.Port = ports[0]
+		env = mergeMaps(env, portEnv(ports[0]))
+	}
+
+	lrp.Env = env
+}
+
+func managedEnv(lrp *opi.LRP) map[string]string {
+	keys := []string{"LANG", eirini.EnvCFInstanceAddr, eirini.EnvCFInstancePort, eirini.EnvCFInstancePorts}
+
+	if isBuildpackLRP(lrp) {
+		for k := range buildpackEnv("") {
+			keys = append(keys, k)
+		}
+	}
+
+	env := map[string]string{}
+
+	for _, k := range keys {
+		if v, ok := lrp.Env[k]; ok {
+			env[k] = v
+		}
+	}
+
+	return env
+}
+
+func isBuildpackLRP(lrp *opi.LRP) bool {
+	return reflect.DeepEqual(lrp.Command, buildpackLaunchCommand())
+}
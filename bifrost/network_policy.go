@@ -0,0 +1,117 @@
+package bifrost
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/opi"
+	"github.com/pkg/errors"
+)
+
+const (
+	networkPolicyProtocolTCP = "tcp"
+	networkPolicyProtocolUDP = "udp"
+
+	minNetworkPolicyPort = 1
+	maxNetworkPolicyPort = 65535
+)
+
+//counterfeiter:generate . NetworkPolicyDesirer
+//counterfeiter:generate . NetworkPolicyNamespacer
+
+type NetworkPolicyDesirer interface {
+	Create(namespace string, policies []opi.NetworkPolicy) error
+	List() ([]opi.NetworkPolicy, error)
+	Delete(policies []opi.NetworkPolicy) error
+}
+
+type NetworkPolicyNamespacer interface {
+	GetNamespace(requestedNamespace string) (string, error)
+}
+
+type NetworkPolicy struct {
+	Desirer    NetworkPolicyDesirer
+	Namespacer NetworkPolicyNamespacer
+}
+
+func (n *NetworkPolicy) CreatePolicies(ctx context.Context, request cf.NetworkPoliciesRequest) error {
+	policies, err := toOpiNetworkPolicies(request.Policies)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := n.Namespacer.GetNamespace(request.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "invalid namespace")
+	}
+
+	return errors.Wrap(n.Desirer.Create(namespace, policies), "failed to create network policies")
+}
+
+func (n *NetworkPolicy) ListPolicies(ctx context.Context) (cf.NetworkPoliciesResponse, error) {
+	policies, err := n.Desirer.List()
+	if err != nil {
+		return cf.NetworkPoliciesResponse{}, errors.Wrap(err, "failed to list network policies")
+	}
+
+	response := cf.NetworkPoliciesResponse{Policies: []cf.NetworkPolicy{}}
+
+	for _, policy := range policies {
+		response.Policies = append(response.Policies, cf.NetworkPolicy{
+			Source: cf.NetworkPolicySource{ID: policy.SourceAppGUID},
+			Destination: cf.NetworkPolicyDestination{
+				ID:       policy.DestinationAppGUID,
+				Protocol: policy.Protocol,
+				Ports:    cf.PortRange{Start: policy.Ports.Start, End: policy.Ports.End},
+			},
+		})
+	}
+
+	return response, nil
+}
+
+func (n *NetworkPolicy) DeletePolicies(ctx context.Context, request cf.NetworkPoliciesRequest) error {
+	policies, err := toOpiNetworkPolicies(request.Policies)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(n.Desirer.Delete(policies), "failed to delete network policies")
+}
+
+func toOpiNetworkPolicies(policies []cf.NetworkPolicy) ([]opi.NetworkPolicy, error) {
+	opiPolicies := []opi.NetworkPolicy{}
+
+	for _, policy := range policies {
+		if err := validateNetworkPolicy(policy); err != nil {
+			return nil, err
+		}
+
+		opiPolicies = append(opiPolicies, opi.NetworkPolicy{
+			SourceAppGUID:      policy.Source.ID,
+			DestinationAppGUID: policy.Destination.ID,
+			Protocol:           policy.Destination.Protocol,
+			Ports:              opi.PortRange{Start: policy.Destination.Ports.Start, End: policy.Destination.Ports.End},
+		})
+	}
+
+	return opiPolicies, nil
+}
+
+func validateNetworkPolicy(policy cf.NetworkPolicy) error {
+	if policy.Source.ID == "" || policy.Destination.ID == "" {
+		return errors.Wrap(eirini.ErrInvalidNetworkPolicy, "source and destination ids are required")
+	}
+
+	if policy.Destination.Protocol != networkPolicyProtocolTCP && policy.Destination.Protocol != networkPolicyProtocolUDP {
+		return errors.Wrapf(eirini.ErrInvalidNetworkPolicy, "unsupported protocol %q", policy.Destination.Protocol)
+	}
+
+	ports := policy.Destination.Ports
+	if ports.Start < minNetworkPolicyPort || ports.End > maxNetworkPolicyPort || ports.Start > ports.End {
+		return errors.Wrapf(eirini.ErrInvalidNetworkPolicy, "invalid port range %d-%d", ports.Start, ports.End)
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package bifrost_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/bifrost"
+	"code.cloudfoundry.org/eirini/bifrost/bifrostfakes"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/opi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NetworkPolicy", func() {
+	var (
+		err                  error
+		networkPolicyBifrost *bifrost.NetworkPolicy
+		desirer              *bifrostfakes.FakeNetworkPolicyDesirer
+		namespacer           *bifrostfakes.FakeNetworkPolicyNamespacer
+		request              cf.NetworkPoliciesRequest
+	)
+
+	BeforeEach(func() {
+		desirer = new(bifrostfakes.FakeNetworkPolicyDesirer)
+		namespacer = new(bifrostfakes.FakeNetworkPolicyNamespacer)
+		namespacer.GetNamespaceReturns("our-namespace", nil)
+
+		networkPolicyBifrost = &bifrost.NetworkPolicy{
+			Desirer:    desirer,
+			Namespacer: namespacer,
+		}
+
+		request = cf.NetworkPoliciesRequest{
+			Namespace: "requested-namespace",
+			Policies: []cf.NetworkPolicy{
+				{
+					Source: cf.NetworkPolicySource{ID: "app-a"},
+					Destination: cf.NetworkPolicyDestination{
+						ID:       "app-b",
+						Protocol: "tcp",
+						Ports:    cf.PortRange{Start: 8080, End: 8090},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("CreatePolicies", func() {
+		JustBeforeEach(func() {
+			err = networkPolicyBifrost.CreatePolicies(context.Background(), request)
+		})
+
+		It("desires the policies in the namespace of the request", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespacer.GetNamespaceArgsForCall(0)).To(Equal("requested-namespace"))
+			Expect(desirer.CreateCallCount()).To(Equal(1))
+
+			namespace, policies := desirer.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("our-namespace"))
+			Expect(policies).To(ConsistOf(opi.NetworkPolicy{
+				SourceAppGUID:      "app-a",
+				DestinationAppGUID: "app-b",
+				Protocol:           "tcp",
+				Ports:              opi.PortRange{Start: 8080, End: 8090},
+			}))
+		})
+
+		When("the namespace is invalid", func() {
+			BeforeEach(func() {
+				namespacer.GetNamespaceReturns("", errors.New("not allowed"))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("invalid namespace")))
+				Expect(desirer.CreateCallCount()).To(BeZero())
+			})
+		})
+
+		When("the source is missing", func() {
+			BeforeEach(func() {
+				request.Policies[0].Source.ID = ""
+			})
+
+			It("rejects the policy", func() {
+				Expect(errors.Is(err, eirini.ErrInvalidNetworkPolicy)).To(BeTrue())
+				Expect(desirer.CreateCallCount()).To(BeZero())
+			})
+		})
+
+		When("the protocol is not supported", func() {
+			BeforeEach(func() {
+				request.Policies[0].Destination.Protocol = "icmp"
+			})
+
+			It("rejects the policy", func() {
+				Expect(errors.Is(err, eirini.ErrInvalidNetworkPolicy)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring(`unsupported protocol "icmp"`)))
+			})
+		})
+
+		When("the port range is invalid", func() {
+			BeforeEach(func() {
+				request.Policies[0].Destination.Ports = cf.PortRange{Start: 9000, End: 8000}
+			})
+
+			It("rejects the policy", func() {
+				Expect(errors.Is(err, eirini.ErrInvalidNetworkPolicy)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("invalid port range 9000-8000")))
+			})
+		})
+
+		When("no ports are given", func() {
+			BeforeEach(func() {
+				request.Policies[0].Destination.Ports = cf.PortRange{}
+			})
+
+			It("rejects the policy", func() {
+				Expect(errors.Is(err, eirini.ErrInvalidNetworkPolicy)).To(BeTrue())
+			})
+		})
+
+		When("desiring the policies fails", func() {
+			BeforeEach(func() {
+				desirer.CreateReturns(errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to create network policies")))
+			})
+		})
+	})
+
+	Describe("ListPolicies", func() {
+		var response cf.NetworkPoliciesResponse
+
+		BeforeEach(func() {
+			desirer.ListReturns([]opi.NetworkPolicy{
+				{SourceAppGUID: "app-a", DestinationAppGUID: "app-b", Protocol: "udp", Ports: opi.PortRange{Start: 53, End: 53}},
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			response, err = networkPolicyBifrost.ListPolicies(context.Background())
+		})
+
+		It("returns the policies", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Policies).To(ConsistOf(cf.NetworkPolicy{
+				Source: cf.NetworkPolicySource{ID: "app-a"},
+				Destination: cf.NetworkPolicyDestination{
+					ID:       "app-b",
+					Protocol: "udp",
+					Ports:    cf.PortRange{Start: 53, End: 53},
+				},
+			}))
+		})
+
+		When("there are no policies", func() {
+			BeforeEach(func() {
+				desirer.ListReturns(nil, nil)
+			})
+
+			It("returns an empty list", func() {
+				Expect(response.Policies).NotTo(BeNil())
+				Expect(response.Policies).To(BeEmpty())
+			})
+		})
+
+		When("listing fails", func() {
+			BeforeEach(func() {
+				desirer.ListReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to list network policies")))
+			})
+		})
+	})
+
+	Describe("DeletePolicies", func() {
+		JustBeforeEach(func() {
+			err = networkPolicyBifrost.DeletePolicies(context.Background(), request)
+		})
+
+		It("deletes the policies", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(desirer.DeleteCallCount()).To(Equal(1))
+			Expect(desirer.DeleteArgsForCall(0)).To(ConsistOf(opi.NetworkPolicy{
+				SourceAppGUID:      "app-a",
+				DestinationAppGUID: "app-b",
+				Protocol:           "tcp",
+				Ports:              opi.PortRange{Start: 8080, End: 8090},
+			}))
+		})
+
+		When("a policy is invalid", func() {
+			BeforeEach(func() {
+				request.Policies[0].Destination.ID = ""
+			})
+
+			It("rejects the request", func() {
+				Expect(errors.Is(err, eirini.ErrInvalidNetworkPolicy)).To(BeTrue())
+				Expect(desirer.DeleteCallCount()).To(BeZero())
+			})
+		})
+
+		When("deleting fails", func() {
+			BeforeEach(func() {
+				desirer.DeleteReturns(errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to delete network policies")))
+			})
+		})
+	})
+})
@@ -3,6 +3,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
 
 	"k8s.io/client-go/kubernetes"
 
@@ -74,3 +77,15 @@ func GetExistingFile(path, defaultPath, name string) string {
 
 	return path
 }
+
+func RouteDrainPeriod(cfg eirini.GracefulShutdownConfig) time.Duration {
+	return time.Duration(cfg.RouteDrainSeconds) * time.Second
+}
+
+func InternalRoutesDNS(cfg eirini.InternalRoutesDNSConfig) eirini.InternalRoutesDNSConfig {
+	return eirini.InternalRoutesDNSConfig{
+		ConfigMapNamespace: GetOrDefault(cfg.ConfigMapNamespace, eirini.DefaultInternalRoutesDNSNamespace),
+		ConfigMapName:      GetOrDefault(cfg.ConfigMapName, eirini.DefaultInternalRoutesDNSConfigMapName),
+		ClusterDomain:      GetOrDefault(cfg.ClusterDomain, eirini.DefaultClusterDomain),
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"code.cloudfoundry.org/eirini/k8s/client"
 	eirinievent "code.cloudfoundry.org/eirini/k8s/informers/event"
 	"code.cloudfoundry.org/eirini/k8s/reconciler"
+	"code.cloudfoundry.org/eirini/opi"
 	eiriniv1 "code.cloudfoundry.org/eirini/pkg/apis/eirini/v1"
 	eirinischeme "code.cloudfoundry.org/eirini/pkg/generated/clientset/versioned/scheme"
 	"code.cloudfoundry.org/eirini/util"
@@ -28,8 +29,10 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type options struct {
@@ -86,6 +89,10 @@ func main() {
 		ControllerManagedBy(mgr).
 		For(&eiriniv1.LRP{}).
 		Owns(&appsv1.StatefulSet{}).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: reconciler.LRPsReferencingSecret(logger, controllerClient)},
+		).
 		Complete(lrpReconciler)
 	cmdcommons.ExitfIfError(err, "Failed to build LRP reconciler")
 
@@ -131,14 +138,32 @@ func createLRPReconciler(
 		Secrets:                           client.NewSecret(clientset),
 		StatefulSets:                      client.NewStatefulSet(clientset, eiriniCfg.Properties.Namespace, eiriniCfg.Properties.EnableMultiNamespaceSupport),
 		PodDisruptionBudgets:              client.NewPodDisruptionBudget(clientset),
+		NetworkPolicies:                   client.NewNetworkPolicy(clientset),
+		HorizontalPodAutoscalers:          client.NewHorizontalPodAutoscaler(clientset),
+		Services:                          client.NewService(clientset),
+		ConfigMaps:                        client.NewConfigMap(clientset),
 		EventsClient:                      client.NewEvent(clientset, eiriniCfg.Properties.Namespace, eiriniCfg.Properties.EnableMultiNamespaceSupport),
 		StatefulSetToLRPMapper:            k8s.StatefulSetToLRP,
 		RegistrySecretName:                eiriniCfg.Properties.RegistrySecretName,
 		LivenessProbeCreator:              k8s.CreateLivenessProbe,
 		ReadinessProbeCreator:             k8s.CreateReadinessProbe,
+		StartupProbeCreator:               k8s.CreateStartupProbe,
 		Logger:                            logger.Session("stateful-set-desirer"),
 		ApplicationServiceAccount:         eiriniCfg.Properties.ApplicationServiceAccount,
 		AllowAutomountServiceAccountToken: eiriniCfg.Properties.UnsafeAllowAutomountServiceAccountToken,
+		PlacementTagNodePools:             eiriniCfg.Properties.PlacementTagNodePools,
+		TopologySpread:                    eiriniCfg.Properties.TopologySpread,
+		SchedulingPolicies:                eiriniCfg.Properties.SchedulingPolicies,
+		SecurityProfile:                   eiriniCfg.Properties.SecurityProfile,
+		TerminationGracePeriodSeconds:     eiriniCfg.Properties.GracefulShutdown.TerminationGracePeriodSeconds,
+		PreStopDelaySeconds:               eiriniCfg.Properties.GracefulShutdown.PreStopDelaySeconds,
+		PreStopCommand:                    eiriniCfg.Properties.GracefulShutdown.PreStopCommand,
+		RouteDrainPeriod:                  cmdcommons.RouteDrainPeriod(eiriniCfg.Properties.GracefulShutdown),
+		InternalRoutesDNS:                 cmdcommons.InternalRoutesDNS(eiriniCfg.Properties.InternalRoutesDNS),
+		DefaultUpdateStrategy: opi.UpdateStrategy{
+			MaxUnavailable:  eiriniCfg.Properties.UpdateStrategy.MaxUnavailable,
+			CanaryInstances: eiriniCfg.Properties.UpdateStrategy.CanaryInstances,
+		},
 	}
 
 	return reconciler.NewLRP(
@@ -165,6 +190,8 @@ func createTaskReconciler(
 		"",
 		eiriniCfg.Properties.RegistrySecretName,
 		eiriniCfg.Properties.UnsafeAllowAutomountServiceAccountToken,
+		eiriniCfg.Properties.SchedulingPolicies,
+		eiriniCfg.Properties.SecurityProfile,
 	)
 
 	return reconciler.NewTask(logger, controllerClient, taskDesirer, scheme)
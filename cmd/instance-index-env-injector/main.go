@@ -4,15 +4,20 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"code.cloudfoundry.org/eirini"
 	cmdcommons "code.cloudfoundry.org/eirini/cmd"
+	"code.cloudfoundry.org/eirini/k8s/client"
 	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/util"
 	eirinix "code.cloudfoundry.org/eirinix"
 	"code.cloudfoundry.org/lager"
 	"github.com/jessevdk/go-flags"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 type options struct {
@@ -56,9 +61,54 @@ func main() {
 	err = manager.AddExtension(webhook.NewInstanceIndexEnvInjector(log))
 	cmdcommons.ExitfIfError(err, "failed to register the instance index env injector extension")
 
+	if cfg.InstanceIdentity.CACertPath != "" {
+		registerInstanceIdentity(log, manager, cfg)
+	}
+
 	log.Fatal("instance-index-env-injector-errored", manager.Start())
 }
 
+func registerInstanceIdentity(log lager.Logger, manager eirinix.Manager, cfg *eirini.InstanceIndexEnvInjectorConfig) {
+	ca, err := webhook.LoadCertificateAuthority(cfg.InstanceIdentity.CACertPath, cfg.InstanceIdentity.CAKeyPath)
+	cmdcommons.ExitfIfError(err, "failed to load the instance identity CA")
+
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", cfg.ConfigPath)
+	cmdcommons.ExitfIfError(err, "failed to build kubeconfig")
+
+	clientset, err := kubernetes.NewForConfig(kubeConfig)
+	cmdcommons.ExitfIfError(err, "failed to create k8s clientset")
+
+	validityInHours := eirini.InstanceIdentityValidityInHours
+	if cfg.InstanceIdentity.CertValidityInHours != 0 {
+		validityInHours = cfg.InstanceIdentity.CertValidityInHours
+	}
+
+	rotationInterval := eirini.IdentityRotationIntervalInSecs
+	if cfg.InstanceIdentity.RotationCheckIntervalInSecs != 0 {
+		rotationInterval = cfg.InstanceIdentity.RotationCheckIntervalInSecs
+	}
+
+	validity := time.Duration(validityInHours) * time.Hour
+	secrets := client.NewSecret(clientset)
+
+	err = manager.AddExtension(webhook.NewInstanceIdentityInjector(log.Session("instance-identity-injector"), ca, secrets, validity))
+	cmdcommons.ExitfIfError(err, "failed to register the instance identity injector extension")
+
+	namespace := ""
+	if !cfg.EnableMultiNamespaceSupport {
+		namespace = cfg.Namespace
+	}
+
+	pods := client.NewPod(clientset, cfg.Namespace, cfg.EnableMultiNamespaceSupport)
+	rotator := webhook.NewInstanceIdentityRotator(log.Session("instance-identity-rotator"), ca, secrets, pods, namespace, validity)
+	scheduler := &util.TickerTaskScheduler{
+		Ticker: time.NewTicker(time.Duration(rotationInterval) * time.Second),
+		Logger: log.Session("instance-identity-rotator.scheduler"),
+	}
+
+	go scheduler.Schedule(rotator.Rotate)
+}
+
 func readConfigFile(path string) (*eirini.InstanceIndexEnvInjectorConfig, error) {
 	fileBytes, err := ioutil.ReadFile(filepath.Clean(path))
 	if err != nil {
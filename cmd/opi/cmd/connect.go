@@ -1,26 +1,33 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/bifrost/namespacers"
 	cmdcommons "code.cloudfoundry.org/eirini/cmd"
+	"code.cloudfoundry.org/eirini/convergence"
 	"code.cloudfoundry.org/eirini/handler"
 	"code.cloudfoundry.org/eirini/k8s"
 	"code.cloudfoundry.org/eirini/k8s/client"
+	"code.cloudfoundry.org/eirini/opi"
 	"code.cloudfoundry.org/eirini/stager"
 	"code.cloudfoundry.org/eirini/stager/docker"
 	"code.cloudfoundry.org/eirini/util"
+	"code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/tlsconfig"
 	"github.com/spf13/cobra"
 	yaml "gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
 	// For gcp and oidc authentication
@@ -42,12 +49,25 @@ func connect(cmd *cobra.Command, args []string) {
 	dockerStagingBifrost := initDockerStagingBifrost(cfg)
 	taskBifrost := initTaskBifrost(cfg, clientset)
 	bifrost := initLRPBifrost(clientset, cfg)
+	networkPolicyBifrost := initNetworkPolicyBifrost(clientset, cfg)
 
 	handlerLogger := lager.NewLogger("handler")
 	handlerLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
-	handler := handler.New(bifrost, buildpackStagingBifrost, dockerStagingBifrost, taskBifrost, handlerLogger)
+	handler := handler.New(bifrost, buildpackStagingBifrost, dockerStagingBifrost, taskBifrost, networkPolicyBifrost, handlerLogger)
 	handlerLogger.Info("opi-connected")
 
+	if cfg.Properties.Convergence.Enabled {
+		go startConverger(cfg, bifrost)
+	}
+
+	if cfg.Properties.ZeroDowntimeVersionSwitch {
+		go startVersionSwitcher(cfg, clientset, bifrost.Desirer)
+	}
+
+	go startRolloutProgressor(cfg, clientset)
+	go startEnvSecretCollector(cfg, clientset)
+	go startDrainedStatefulSetCollector(cfg, clientset)
+
 	if cfg.Properties.ServePlaintext {
 		servePlaintext(cfg, handler, handlerLogger)
 	}
@@ -87,6 +107,10 @@ func servePlaintext(cfg *eirini.Config, handler http.Handler, logger lager.Logge
 }
 
 func initRetryableJSONClient(cfg *eirini.Config) *util.RetryableJSONClient {
+	return util.NewRetryableJSONClient(initCCHTTPClient(cfg))
+}
+
+func initCCHTTPClient(cfg *eirini.Config) *http.Client {
 	httpClient := http.DefaultClient
 
 	if !cfg.Properties.CCTLSDisabled {
@@ -110,7 +134,7 @@ func initRetryableJSONClient(cfg *eirini.Config) *util.RetryableJSONClient {
 		}
 	}
 
-	return util.NewRetryableJSONClient(httpClient)
+	return httpClient
 }
 
 func initStagingCompleter(cfg *eirini.Config, logger lager.Logger) *stager.CallbackStagingCompleter {
@@ -156,6 +180,8 @@ func initTaskDesirer(cfg *eirini.Config, clientset kubernetes.Interface) *k8s.Ta
 		cfg.Properties.StagingServiceAccount,
 		cfg.Properties.RegistrySecretName,
 		cfg.Properties.UnsafeAllowAutomountServiceAccountToken,
+		cfg.Properties.SchedulingPolicies,
+		cfg.Properties.SecurityProfile,
 	)
 }
 
@@ -194,14 +220,23 @@ func initDockerStagingBifrost(cfg *eirini.Config) *bifrost.DockerStaging {
 	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
 	stagingCompleter := initStagingCompleter(cfg, logger)
 
+	signatureVerifier, err := bifrost.NewTrustPolicyVerifier(cfg.Properties.SignatureTrustPolicies, docker.Parse, docker.FetchSignatures)
+	cmdcommons.ExitfIfError(err, "Failed to create image signature verifier")
+
 	return &bifrost.DockerStaging{
 		Logger:               logger,
 		ImageMetadataFetcher: docker.Fetch,
 		ImageRefParser:       docker.Parse,
+		ImageAdmitter:        initImageAdmitter(cfg),
+		SignatureVerifier:    signatureVerifier,
 		StagingCompleter:     stagingCompleter,
 	}
 }
 
+func initImageAdmitter(cfg *eirini.Config) *bifrost.ImagePolicyAdmitter {
+	return bifrost.NewImagePolicyAdmitter(cfg.Properties.ImagePolicy, docker.Parse, docker.FetchSize)
+}
+
 func initTaskBifrost(cfg *eirini.Config, clientset kubernetes.Interface) *bifrost.Task {
 	converter := initConverter(cfg)
 	taskDesirer := initTaskDesirer(cfg, clientset)
@@ -239,14 +274,31 @@ func initLRPBifrost(clientset kubernetes.Interface, cfg *eirini.Config) *bifrost
 		Secrets:                           client.NewSecret(clientset),
 		StatefulSets:                      client.NewStatefulSet(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
 		PodDisruptionBudgets:              client.NewPodDisruptionBudget(clientset),
+		NetworkPolicies:                   client.NewNetworkPolicy(clientset),
+		HorizontalPodAutoscalers:          client.NewHorizontalPodAutoscaler(clientset),
+		Services:                          client.NewService(clientset),
+		ConfigMaps:                        client.NewConfigMap(clientset),
 		EventsClient:                      client.NewEvent(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
 		StatefulSetToLRPMapper:            k8s.StatefulSetToLRP,
 		RegistrySecretName:                cfg.Properties.RegistrySecretName,
 		LivenessProbeCreator:              k8s.CreateLivenessProbe,
 		ReadinessProbeCreator:             k8s.CreateReadinessProbe,
+		StartupProbeCreator:               k8s.CreateStartupProbe,
 		Logger:                            desireLogger,
 		ApplicationServiceAccount:         cfg.Properties.ApplicationServiceAccount,
 		AllowAutomountServiceAccountToken: cfg.Properties.UnsafeAllowAutomountServiceAccountToken,
+		PlacementTagNodePools:             cfg.Properties.PlacementTagNodePools,
+		TopologySpread:                    cfg.Properties.TopologySpread,
+		SchedulingPolicies:                cfg.Properties.SchedulingPolicies,
+		SecurityProfile:                   cfg.Properties.SecurityProfile,
+		TerminationGracePeriodSeconds:     cfg.Properties.GracefulShutdown.TerminationGracePeriodSeconds,
+		PreStopDelaySeconds:               cfg.Properties.GracefulShutdown.PreStopDelaySeconds,
+		PreStopCommand:                    cfg.Properties.GracefulShutdown.PreStopCommand,
+		RouteDrainPeriod:                  cmdcommons.RouteDrainPeriod(cfg.Properties.GracefulShutdown),
+		InternalRoutesDNS:                 cmdcommons.InternalRoutesDNS(cfg.Properties.InternalRoutesDNS),
+		ZeroDowntimeVersionSwitch:         cfg.Properties.ZeroDowntimeVersionSwitch,
+		OldVersionGracePeriod:             oldVersionGracePeriod(cfg),
+		DefaultUpdateStrategy:             defaultUpdateStrategy(cfg),
 	}
 	converter := initConverter(cfg)
 	namespacer := initNamespacer(cfg)
@@ -258,6 +310,174 @@ func initLRPBifrost(clientset kubernetes.Interface, cfg *eirini.Config) *bifrost
 	}
 }
 
+func initNetworkPolicyBifrost(clientset kubernetes.Interface, cfg *eirini.Config) *bifrost.NetworkPolicy {
+	logger := lager.NewLogger("network-policy-desirer")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	workloadsNamespace := cfg.Properties.Namespace
+	if cfg.Properties.EnableMultiNamespaceSupport {
+		workloadsNamespace = metav1.NamespaceAll
+	}
+
+	desirer := &k8s.AppNetworkPolicyDesirer{
+		NetworkPolicies:    client.NewNetworkPolicy(clientset),
+		StatefulSets:       client.NewStatefulSet(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
+		WorkloadsNamespace: workloadsNamespace,
+		RouterSelector:     cfg.Properties.NetworkPolicyRouters,
+		Logger:             logger,
+	}
+
+	if err := desirer.Resync(); err != nil {
+		logger.Error("failed-to-resync-network-policies", err)
+	}
+
+	return &bifrost.NetworkPolicy{
+		Desirer:    desirer,
+		Namespacer: initNamespacer(cfg),
+	}
+}
+
+func startConverger(cfg *eirini.Config, lrpBifrost *bifrost.LRP) {
+	convergenceCfg := cfg.Properties.Convergence
+
+	logger := lager.NewLogger("converger")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	tlsConfig, err := loggregator.NewIngressTLSConfig(
+		cmdcommons.GetExistingFile(convergenceCfg.LoggregatorCAPath, eirini.LoggregatorCAPath, "Loggregator CA"),
+		cmdcommons.GetExistingFile(convergenceCfg.LoggregatorCertPath, eirini.LoggregatorCertPath, "Loggregator Cert"),
+		cmdcommons.GetExistingFile(convergenceCfg.LoggregatorKeyPath, eirini.LoggregatorKeyPath, "Loggregator Key"),
+	)
+	cmdcommons.ExitfIfError(err, "Failed to create loggregator tls config")
+
+	loggregatorClient, err := loggregator.NewIngressClient(
+		tlsConfig,
+		loggregator.WithAddr(convergenceCfg.LoggregatorAddress),
+		loggregator.WithLogger(log.New(os.Stdout, "loggregator-ingress-client", log.LstdFlags)),
+	)
+	cmdcommons.ExitfIfError(err, "Failed to create Loggregator ingress client")
+
+	var limiter convergence.RateLimiter = convergence.UnlimitedLimiter{}
+	if convergenceCfg.OperationsPerSecond > 0 {
+		limiter = convergence.NewTickerLimiter(convergenceCfg.OperationsPerSecond)
+	}
+
+	maxOperations := eirini.ConvergenceMaxOperationsPerCycle
+	if convergenceCfg.MaxOperationsPerCycle > 0 {
+		maxOperations = convergenceCfg.MaxOperationsPerCycle
+	}
+
+	converger := &convergence.Converger{
+		Fetcher:               convergence.NewCCClient(initCCHTTPClient(cfg), convergenceCfg.CcInternalAPI, convergenceCfg.BatchSize),
+		LRPBifrost:            lrpBifrost,
+		Emitter:               convergence.NewLoggregatorEmitter(loggregatorClient),
+		Limiter:               limiter,
+		MaxOperationsPerCycle: maxOperations,
+		DryRun:                convergenceCfg.DryRun,
+		Logger:                logger,
+	}
+
+	period := eirini.ConvergencePeriodInSecs
+	if convergenceCfg.PeriodInSeconds > 0 {
+		period = convergenceCfg.PeriodInSeconds
+	}
+
+	scheduler := &util.TickerTaskScheduler{
+		Ticker: time.NewTicker(time.Duration(period) * time.Second),
+		Logger: logger.Session("scheduler"),
+	}
+	scheduler.Schedule(func() error {
+		_, convergeErr := converger.Converge(context.Background())
+
+		return convergeErr
+	})
+}
+
+func startVersionSwitcher(cfg *eirini.Config, clientset kubernetes.Interface, stopper k8s.LRPStopper) {
+	logger := lager.NewLogger("version-switcher")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	switcher := &k8s.VersionSwitcher{
+		StatefulSets: client.NewStatefulSet(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
+		Stopper:      stopper,
+		GracePeriod:  oldVersionGracePeriod(cfg),
+		Logger:       logger,
+	}
+
+	scheduler := &util.TickerTaskScheduler{
+		Ticker: time.NewTicker(eirini.VersionSwitchIntervalInSecs * time.Second),
+		Logger: logger.Session("scheduler"),
+	}
+	scheduler.Schedule(switcher.Switch)
+}
+
+func startRolloutProgressor(cfg *eirini.Config, clientset kubernetes.Interface) {
+	logger := lager.NewLogger("rollout-progressor")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	progressor := &k8s.RolloutProgressor{
+		StatefulSets: client.NewStatefulSet(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
+		Pods:         client.NewPod(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
+		Logger:       logger,
+	}
+
+	scheduler := &util.TickerTaskScheduler{
+		Ticker: time.NewTicker(eirini.RolloutProgressIntervalInSecs * time.Second),
+		Logger: logger.Session("scheduler"),
+	}
+	scheduler.Schedule(progressor.Progress)
+}
+
+func startEnvSecretCollector(cfg *eirini.Config, clientset kubernetes.Interface) {
+	logger := lager.NewLogger("env-secret-collector")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	collector := &k8s.EnvSecretCollector{
+		StatefulSets: client.NewStatefulSet(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
+		Pods:         client.NewPod(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
+		Secrets:      client.NewSecret(clientset),
+		Logger:       logger,
+	}
+
+	scheduler := &util.TickerTaskScheduler{
+		Ticker: time.NewTicker(eirini.EnvSecretCollectIntervalInSecs * time.Second),
+		Logger: logger.Session("scheduler"),
+	}
+	scheduler.Schedule(collector.Collect)
+}
+
+func startDrainedStatefulSetCollector(cfg *eirini.Config, clientset kubernetes.Interface) {
+	logger := lager.NewLogger("drained-statefulset-collector")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	collector := &k8s.DrainedStatefulSetCollector{
+		StatefulSets: client.NewStatefulSet(clientset, cfg.Properties.Namespace, cfg.Properties.EnableMultiNamespaceSupport),
+		Logger:       logger,
+	}
+
+	scheduler := &util.TickerTaskScheduler{
+		Ticker: time.NewTicker(eirini.DrainedStatefulSetCollectIntervalInSecs * time.Second),
+		Logger: logger.Session("scheduler"),
+	}
+	scheduler.Schedule(collector.Collect)
+}
+
+func defaultUpdateStrategy(cfg *eirini.Config) opi.UpdateStrategy {
+	return opi.UpdateStrategy{
+		MaxUnavailable:  cfg.Properties.UpdateStrategy.MaxUnavailable,
+		CanaryInstances: cfg.Properties.UpdateStrategy.CanaryInstances,
+	}
+}
+
+func oldVersionGracePeriod(cfg *eirini.Config) time.Duration {
+	gracePeriod := eirini.OldVersionGracePeriodInSecs
+	if cfg.Properties.OldVersionGracePeriodInSeconds > 0 {
+		gracePeriod = cfg.Properties.OldVersionGracePeriodInSeconds
+	}
+
+	return time.Duration(gracePeriod) * time.Second
+}
+
 func initConverter(cfg *eirini.Config) *bifrost.OPIConverter {
 	convertLogger := lager.NewLogger("convert")
 	convertLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
@@ -269,11 +489,18 @@ func initConverter(cfg *eirini.Config) *bifrost.OPIConverter {
 		ExecutorImage:   cfg.Properties.ExecutorImage,
 	}
 
+	var imageDigestFetcher bifrost.ImageDigestFetcher
+	if cfg.Properties.PinImageDigests {
+		imageDigestFetcher = docker.FetchDigest
+	}
+
 	return bifrost.NewOPIConverter(
 		convertLogger,
 		cfg.Properties.RegistryAddress,
 		docker.Fetch,
 		docker.Parse,
+		imageDigestFetcher,
+		initImageAdmitter(cfg),
 		cfg.Properties.AllowRunImageAsRoot,
 		stagerCfg,
 	)
@@ -29,8 +29,9 @@ func main() {
 
 	stager := &StagerSimulator{}
 	task := &TaskSimulator{}
+	networkPolicy := &NetworkPolicySimulator{}
 
-	handler := handler.New(lrpBifrost, stager, stager, task, handlerLogger)
+	handler := handler.New(lrpBifrost, stager, stager, task, networkPolicy, handlerLogger)
 
 	fmt.Println("Starting to listen at 127.0.0.1:8085")
 	handlerLogger.Fatal("simulator-crashed", http.ListenAndServe("127.0.0.1:8085", handler))
@@ -76,12 +77,24 @@ func (d *DesirerSimulator) StopInstance(identifier opi.LRPIdentifier, index uint
 	return nil
 }
 
+func (d *DesirerSimulator) Rollout(identifier opi.LRPIdentifier, action string) error {
+	return nil
+}
+
+func (d *DesirerSimulator) Restart(identifier opi.LRPIdentifier) error {
+	return nil
+}
+
 type ConverterSimulator struct{}
 
 func (c *ConverterSimulator) ConvertLRP(request cf.DesireLRPRequest) (opi.LRP, error) {
 	return opi.LRP{}, nil
 }
 
+func (c *ConverterSimulator) ConvertImage(image string, privateRegistry *opi.PrivateRegistry) (string, string, error) {
+	return image, "", nil
+}
+
 func (c *ConverterSimulator) ConvertTask(taskGUID string, taskRequest cf.TaskRequest) (opi.Task, error) {
 	return opi.Task{}, nil
 }
@@ -117,3 +130,17 @@ func (t *TaskSimulator) TransferTask(ctx context.Context, taskGUID string, reque
 func (t *TaskSimulator) CancelTask(taskGUID string) error {
 	return nil
 }
+
+type NetworkPolicySimulator struct{}
+
+func (n *NetworkPolicySimulator) CreatePolicies(ctx context.Context, request cf.NetworkPoliciesRequest) error {
+	return nil
+}
+
+func (n *NetworkPolicySimulator) ListPolicies(ctx context.Context) (cf.NetworkPoliciesResponse, error) {
+	return cf.NetworkPoliciesResponse{}, nil
+}
+
+func (n *NetworkPolicySimulator) DeletePolicies(ctx context.Context, request cf.NetworkPoliciesRequest) error {
+	return nil
+}
@@ -0,0 +1,123 @@
+package convergence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"github.com/pkg/errors"
+)
+
+const (
+	bulkAppsPath     = "/internal/bulk/apps"
+	DefaultBatchSize = 500
+)
+
+type CCClient struct {
+	httpClient    *http.Client
+	ccInternalAPI string
+	batchSize     int
+}
+
+func NewCCClient(httpClient *http.Client, ccInternalAPI string, batchSize int) *CCClient {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &CCClient{
+		httpClient:    httpClient,
+		ccInternalAPI: ccInternalAPI,
+		batchSize:     batchSize,
+	}
+}
+
+func (c *CCClient) Fingerprints(ctx context.Context) ([]cc_messages.CCDesiredAppFingerprint, error) {
+	fingerprints := []cc_messages.CCDesiredAppFingerprint{}
+	token := json.RawMessage(`{}`)
+
+	for {
+		var response cc_messages.CCDesiredStateFingerprintResponse
+		if err := c.do(ctx, http.MethodGet, c.fingerprintsURL(token), nil, &response); err != nil {
+			return nil, errors.Wrap(err, "failed to fetch fingerprints")
+		}
+
+		fingerprints = append(fingerprints, response.Fingerprints...)
+
+		if len(response.Fingerprints) < c.batchSize || response.CCBulkToken == nil {
+			return fingerprints, nil
+		}
+
+		token = *response.CCBulkToken
+	}
+}
+
+func (c *CCClient) DesiredLRPs(ctx context.Context, processGUIDs []string) ([]cf.DesireLRPRequest, error) {
+	requests := []cf.DesireLRPRequest{}
+
+	for start := 0; start < len(processGUIDs); start += c.batchSize {
+		end := start + c.batchSize
+		if end > len(processGUIDs) {
+			end = len(processGUIDs)
+		}
+
+		body, err := json.Marshal(processGUIDs[start:end])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal process guids")
+		}
+
+		var batch []cf.DesireLRPRequest
+		if err := c.do(ctx, http.MethodPost, c.desiredLRPsURL(), body, &batch); err != nil {
+			return nil, errors.Wrap(err, "failed to fetch desired lrps")
+		}
+
+		requests = append(requests, batch...)
+	}
+
+	return requests, nil
+}
+
+func (c *CCClient) fingerprintsURL(token json.RawMessage) string {
+	query := url.Values{}
+	query.Set("batch_size", strconv.Itoa(c.batchSize))
+	query.Set("format", "fingerprint")
+	query.Set("token", string(token))
+
+	return fmt.Sprintf("%s%s?%s", c.ccInternalAPI, bulkAppsPath, query.Encode())
+}
+
+// desiredLRPsURL asks for the apps in the format CC desires them with, so
+// that they carry the app, space and org names, the process type and every
+// other field a fresh desire needs.
+func (c *CCClient) desiredLRPsURL() string {
+	query := url.Values{}
+	query.Set("format", "eirini")
+
+	return fmt.Sprintf("%s%s?%s", c.ccInternalAPI, bulkAppsPath, query.Encode())
+}
+
+func (c *CCClient) do(ctx context.Context, method, endpoint string, body []byte, response interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed: code %d", resp.StatusCode)
+	}
+
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(response), "failed to decode response")
+}
@@ -0,0 +1,225 @@
+package convergence_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/eirini/convergence"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("CCClient", func() {
+	var (
+		server *ghttp.Server
+		client *convergence.CCClient
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		client = convergence.NewCCClient(http.DefaultClient, server.URL(), 2)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Fingerprints", func() {
+		var (
+			fingerprints []cc_messages.CCDesiredAppFingerprint
+			err          error
+		)
+
+		JustBeforeEach(func() {
+			fingerprints, err = client.Fingerprints(context.Background())
+		})
+
+		When("cc returns several batches", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, "/internal/bulk/apps", "batch_size=2&format=fingerprint&token=%7B%7D"),
+						ghttp.RespondWith(http.StatusOK, `{
+							"fingerprints": [{"process_guid": "a", "etag": "1"}, {"process_guid": "b", "etag": "2"}],
+							"token": {"id":2}
+						}`),
+					),
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, "/internal/bulk/apps", "batch_size=2&format=fingerprint&token=%7B%22id%22%3A2%7D"),
+						ghttp.RespondWith(http.StatusOK, `{
+							"fingerprints": [{"process_guid": "c", "etag": "3"}],
+							"token": {"id": 3}
+						}`),
+					),
+				)
+			})
+
+			It("follows the bulk token until the last batch", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(server.ReceivedRequests()).To(HaveLen(2))
+				Expect(fingerprints).To(Equal([]cc_messages.CCDesiredAppFingerprint{
+					{ProcessGuid: "a", ETag: "1"},
+					{ProcessGuid: "b", ETag: "2"},
+					{ProcessGuid: "c", ETag: "3"},
+				}))
+			})
+		})
+
+		When("cc fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, ""))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("code 500")))
+			})
+		})
+
+		When("cc returns invalid json", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(ghttp.RespondWith(http.StatusOK, "{"))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to decode response")))
+			})
+		})
+	})
+
+	Describe("DesiredLRPs", func() {
+		var (
+			requests []cf.DesireLRPRequest
+			err      error
+		)
+
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, "/internal/bulk/apps", "format=eirini"),
+					ghttp.VerifyJSON(`["buildpack-v1", "docker-v1"]`),
+					ghttp.RespondWith(http.StatusOK, `[
+						{
+							"guid": "buildpack",
+							"version": "v1",
+							"process_guid": "buildpack-v1",
+							"process_type": "web",
+							"app_guid": "buildpack-app",
+							"app_name": "dora",
+							"space_name": "the-space",
+							"organization_name": "the-org",
+							"ports": [8080],
+							"routes": {"cf-router": [{"hostname": "app.example.com", "port": 8080}]},
+							"environment": {"PORT": "8080"},
+							"instances": 2,
+							"last_updated": "1602145162.53",
+							"health_check_type": "http",
+							"health_check_http_endpoint": "/health",
+							"start_timeout_ms": 60000,
+							"memory_mb": 256,
+							"disk_mb": 1024,
+							"cpu_weight": 50,
+							"placement_tags": ["isolated"],
+							"sidecars": [{"name": "envoy", "command": "./envoy", "memory_mb": 32}],
+							"lifecycle": {
+								"buildpack_lifecycle": {
+									"droplet_guid": "the-droplet",
+									"droplet_hash": "f2b9e0a1c3d4",
+									"start_command": "bundle exec rackup"
+								}
+							}
+						},
+						{
+							"guid": "docker",
+							"version": "v1",
+							"process_guid": "docker-v1",
+							"app_name": "dorini",
+							"instances": 1,
+							"lifecycle": {
+								"docker_lifecycle": {
+									"image": "eirini/dorini:latest",
+									"command": ["/dorini"]
+								}
+							}
+						}
+					]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, "/internal/bulk/apps", "format=eirini"),
+					ghttp.VerifyJSON(`["other-v1"]`),
+					ghttp.RespondWith(http.StatusOK, `[{"guid": "other", "version": "v1", "process_guid": "other-v1", "instances": 3}]`),
+				),
+			)
+		})
+
+		JustBeforeEach(func() {
+			requests, err = client.DesiredLRPs(context.Background(), []string{"buildpack-v1", "docker-v1", "other-v1"})
+		})
+
+		It("fetches the desired LRPs in batches", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+			Expect(requests).To(HaveLen(3))
+			Expect(requests[2].ProcessGUID).To(Equal("other-v1"))
+			Expect(requests[2].NumInstances).To(Equal(3))
+		})
+
+		It("returns the full desire request of buildpack apps", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests[0]).To(Equal(cf.DesireLRPRequest{
+				GUID:                    "buildpack",
+				Version:                 "v1",
+				ProcessGUID:             "buildpack-v1",
+				ProcessType:             "web",
+				AppGUID:                 "buildpack-app",
+				AppName:                 "dora",
+				SpaceName:               "the-space",
+				OrganizationName:        "the-org",
+				Ports:                   []int32{8080},
+				Routes:                  map[string]json.RawMessage{"cf-router": json.RawMessage(`[{"hostname": "app.example.com", "port": 8080}]`)},
+				Environment:             map[string]string{"PORT": "8080"},
+				NumInstances:            2,
+				LastUpdated:             "1602145162.53",
+				HealthCheckType:         "http",
+				HealthCheckHTTPEndpoint: "/health",
+				StartTimeoutMs:          60000,
+				MemoryMB:                256,
+				DiskMB:                  1024,
+				CPUWeight:               50,
+				PlacementTags:           []string{"isolated"},
+				Sidecars:                []cf.Sidecar{{Name: "envoy", Command: "./envoy", MemoryMB: 32}},
+				Lifecycle: cf.Lifecycle{
+					BuildpackLifecycle: &cf.BuildpackLifecycle{
+						DropletGUID:  "the-droplet",
+						DropletHash:  "f2b9e0a1c3d4",
+						StartCommand: "bundle exec rackup",
+					},
+				},
+			}))
+		})
+
+		It("returns the full desire request of docker apps", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests[1].AppName).To(Equal("dorini"))
+			Expect(requests[1].Lifecycle).To(Equal(cf.Lifecycle{
+				DockerLifecycle: &cf.DockerLifecycle{
+					Image:   "eirini/dorini:latest",
+					Command: []string{"/dorini"},
+				},
+			}))
+		})
+
+		When("cc fails", func() {
+			BeforeEach(func() {
+				server.SetHandler(0, ghttp.RespondWith(http.StatusInternalServerError, ""))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("code 500")))
+			})
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package convergence_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestConvergence(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Convergence Suite")
+}
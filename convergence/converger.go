@@ -0,0 +1,312 @@
+package convergence
+
+import (
+	"context"
+	"sort"
+
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"github.com/pkg/errors"
+)
+
+// A fingerprint list that shrinks below this fraction of the previous one
+// is treated as a partial response from CC rather than a mass deletion.
+const minFingerprintRatio = 0.5
+
+//counterfeiter:generate . DesiredStateFetcher
+//counterfeiter:generate . LRPBifrost
+//counterfeiter:generate . MetricsEmitter
+//counterfeiter:generate . RateLimiter
+
+type DesiredStateFetcher interface {
+	Fingerprints(ctx context.Context) ([]cc_messages.CCDesiredAppFingerprint, error)
+	DesiredLRPs(ctx context.Context, processGUIDs []string) ([]cf.DesireLRPRequest, error)
+}
+
+type LRPBifrost interface {
+	Transfer(ctx context.Context, request cf.DesireLRPRequest) error
+	Update(ctx context.Context, request cf.UpdateDesiredLRPRequest) error
+	List(ctx context.Context) ([]cf.DesiredLRPSchedulingInfo, error)
+	Stop(ctx context.Context, identifier opi.LRPIdentifier) error
+}
+
+type MetricsEmitter interface {
+	Emit(report Report)
+}
+
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+type Report struct {
+	DryRun   bool
+	Missing  []string
+	Stale    []string
+	Orphaned []string
+	Desired  int
+	Updated  int
+	Stopped  int
+	Failed   int
+	Skipped  int
+}
+
+type Converger struct {
+	Fetcher               DesiredStateFetcher
+	LRPBifrost            LRPBifrost
+	Emitter               MetricsEmitter
+	Limiter               RateLimiter
+	MaxOperationsPerCycle int
+	DryRun                bool
+	Logger                lager.Logger
+
+	lastFingerprintCount int
+}
+
+func (c *Converger) Converge(ctx context.Context) (Report, error) {
+	logger := c.Logger.Session("converge")
+
+	fingerprints, err := c.Fetcher.Fingerprints(ctx)
+	if err != nil {
+		return Report{}, errors.Wrap(err, "failed to fetch desired state from cc")
+	}
+
+	schedulingInfos, err := c.LRPBifrost.List(ctx)
+	if err != nil {
+		return Report{}, errors.Wrap(err, "failed to list desired lrps")
+	}
+
+	report, orphans := diff(fingerprints, schedulingInfos)
+	report.DryRun = c.DryRun
+
+	logger.Info("drift-found", lager.Data{
+		"dry-run":  c.DryRun,
+		"missing":  report.Missing,
+		"stale":    report.Stale,
+		"orphaned": report.Orphaned,
+	})
+
+	if !c.DryRun {
+		budget := c.MaxOperationsPerCycle
+		if budget <= 0 {
+			budget = len(report.Missing) + len(report.Stale) + len(report.Orphaned)
+		}
+
+		budget = c.repair(ctx, logger, &report, budget)
+
+		if c.fingerprintsCollapsed(len(fingerprints)) {
+			logger.Info("skipping-orphan-stops", lager.Data{
+				"fingerprints":          len(fingerprints),
+				"previous-fingerprints": c.lastFingerprintCount,
+				"orphaned":              len(orphans),
+			})
+			report.Skipped += len(orphans)
+		} else {
+			c.stopOrphans(ctx, logger, &report, orphans, budget)
+		}
+
+		logger.Info("drift-repaired", lager.Data{
+			"desired": report.Desired,
+			"updated": report.Updated,
+			"stopped": report.Stopped,
+			"failed":  report.Failed,
+			"skipped": report.Skipped,
+		})
+	}
+
+	c.lastFingerprintCount = len(fingerprints)
+	c.Emitter.Emit(report)
+
+	return report, nil
+}
+
+// An empty fingerprint list never causes orphans to be stopped. A list that
+// has shrunk sharply only does once it has been confirmed by the next cycle.
+func (c *Converger) fingerprintsCollapsed(count int) bool {
+	if count == 0 {
+		return true
+	}
+
+	return float64(count) < float64(c.lastFingerprintCount)*minFingerprintRatio
+}
+
+func (c *Converger) repair(ctx context.Context, logger lager.Logger, report *Report, budget int) int {
+	processGUIDs := append(append([]string{}, report.Missing...), report.Stale...)
+	if len(processGUIDs) > budget {
+		report.Skipped += len(processGUIDs) - budget
+		processGUIDs = processGUIDs[:budget]
+	}
+
+	if len(processGUIDs) == 0 {
+		return budget
+	}
+
+	requests, err := c.Fetcher.DesiredLRPs(ctx, processGUIDs)
+	if err != nil {
+		logger.Error("failed-to-fetch-desired-lrps", err)
+		report.Failed += len(processGUIDs)
+
+		return budget - len(processGUIDs)
+	}
+
+	missing := toSet(report.Missing)
+
+	for _, request := range requests {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			logger.Error("rate-limiter-failed", err)
+
+			break
+		}
+
+		if err := c.repairLRP(ctx, request, missing[request.ProcessGUID]); err != nil {
+			logger.Error("failed-to-repair-lrp", err, lager.Data{"process-guid": request.ProcessGUID})
+			report.Failed++
+
+			continue
+		}
+
+		if missing[request.ProcessGUID] {
+			report.Desired++
+		} else {
+			report.Updated++
+		}
+	}
+
+	return budget - len(processGUIDs)
+}
+
+// repairLRP desires missing LRPs. Stale ones are updated, so that their
+// existing statefulset is patched in place rather than desired again.
+func (c *Converger) repairLRP(ctx context.Context, request cf.DesireLRPRequest, missing bool) error {
+	if missing {
+		return c.LRPBifrost.Transfer(ctx, request)
+	}
+
+	return c.LRPBifrost.Update(ctx, toUpdateRequest(request))
+}
+
+// toUpdateRequest turns the desired state of a stale LRP into an update of
+// every field that can be changed in place.
+func toUpdateRequest(request cf.DesireLRPRequest) cf.UpdateDesiredLRPRequest {
+	update := cf.DesiredLRPUpdate{
+		Instances:   request.NumInstances,
+		Routes:      request.Routes,
+		Annotation:  request.LastUpdated,
+		MemoryMB:    &request.MemoryMB,
+		DiskMB:      &request.DiskMB,
+		Environment: request.Environment,
+		Ports:       request.Ports,
+		Autoscaling: request.Autoscaling,
+		HealthCheck: &cf.HealthCheckUpdate{
+			Type:                request.HealthCheckType,
+			HTTPEndpoint:        request.HealthCheckHTTPEndpoint,
+			TimeoutMs:           request.HealthCheckTimeoutMs,
+			StartTimeoutMs:      request.StartTimeoutMs,
+			InvocationTimeoutMs: request.HealthCheckInvocationTimeoutMs,
+			IntervalMs:          request.HealthCheckIntervalMs,
+			HTTPScheme:          request.HealthCheckHTTPScheme,
+			HTTPHost:            request.HealthCheckHTTPHost,
+			LivenessFailures:    request.HealthCheckLivenessFailures,
+			ReadinessFailures:   request.HealthCheckReadinessFailures,
+		},
+	}
+
+	switch {
+	case request.Lifecycle.DockerLifecycle != nil:
+		update.Image = request.Lifecycle.DockerLifecycle.Image
+		update.Command = request.Lifecycle.DockerLifecycle.Command
+	case request.Lifecycle.BuildpackLifecycle != nil:
+		update.StartCommand = &request.Lifecycle.BuildpackLifecycle.StartCommand
+	}
+
+	return cf.UpdateDesiredLRPRequest{
+		GUID:    request.GUID,
+		Version: request.Version,
+		Update:  update,
+	}
+}
+
+func (c *Converger) stopOrphans(ctx context.Context, logger lager.Logger, report *Report, orphans []opi.LRPIdentifier, budget int) {
+	if len(orphans) > budget {
+		report.Skipped += len(orphans) - budget
+		orphans = orphans[:budget]
+	}
+
+	for _, identifier := range orphans {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			logger.Error("rate-limiter-failed", err)
+
+			return
+		}
+
+		if err := c.LRPBifrost.Stop(ctx, identifier); err != nil {
+			logger.Error("failed-to-stop-lrp", err, lager.Data{"process-guid": identifier.ProcessGUID()})
+			report.Failed++
+
+			continue
+		}
+
+		report.Stopped++
+	}
+}
+
+func diff(fingerprints []cc_messages.CCDesiredAppFingerprint, schedulingInfos []cf.DesiredLRPSchedulingInfo) (Report, []opi.LRPIdentifier) {
+	report := Report{
+		Missing:  []string{},
+		Stale:    []string{},
+		Orphaned: []string{},
+	}
+	existing := map[string]cf.DesiredLRPSchedulingInfo{}
+
+	for _, info := range schedulingInfos {
+		existing[info.ProcessGUID] = info
+	}
+
+	desired := map[string]bool{}
+
+	for _, fingerprint := range fingerprints {
+		desired[fingerprint.ProcessGuid] = true
+
+		info, ok := existing[fingerprint.ProcessGuid]
+
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, fingerprint.ProcessGuid)
+		case info.Annotation != fingerprint.ETag:
+			report.Stale = append(report.Stale, fingerprint.ProcessGuid)
+		}
+	}
+
+	orphans := []opi.LRPIdentifier{}
+
+	for _, info := range schedulingInfos {
+		// LRPs desired through the CRD are never in CC's fingerprints
+		if info.OwnedByCRD {
+			continue
+		}
+
+		if !desired[info.ProcessGUID] {
+			report.Orphaned = append(report.Orphaned, info.ProcessGUID)
+			orphans = append(orphans, opi.LRPIdentifier{GUID: info.GUID, Version: info.Version})
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Stale)
+	sort.Strings(report.Orphaned)
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].ProcessGUID() < orphans[j].ProcessGUID()
+	})
+
+	return report, orphans
+}
+
+func toSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
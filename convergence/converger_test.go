@@ -0,0 +1,421 @@
+package convergence_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/convergence"
+	"code.cloudfoundry.org/eirini/convergence/convergencefakes"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("Converger", func() {
+	var (
+		fetcher    *convergencefakes.FakeDesiredStateFetcher
+		lrpBifrost *convergencefakes.FakeLRPBifrost
+		emitter    *convergencefakes.FakeMetricsEmitter
+		limiter    *convergencefakes.FakeRateLimiter
+		converger  *convergence.Converger
+		report     convergence.Report
+		err        error
+	)
+
+	BeforeEach(func() {
+		fetcher = new(convergencefakes.FakeDesiredStateFetcher)
+		lrpBifrost = new(convergencefakes.FakeLRPBifrost)
+		emitter = new(convergencefakes.FakeMetricsEmitter)
+		limiter = new(convergencefakes.FakeRateLimiter)
+
+		fetcher.FingerprintsReturns([]cc_messages.CCDesiredAppFingerprint{
+			{ProcessGuid: "in-sync-v1", ETag: "1"},
+			{ProcessGuid: "missing-v1", ETag: "2"},
+			{ProcessGuid: "stale-v1", ETag: "3"},
+		}, nil)
+		fetcher.DesiredLRPsStub = func(_ context.Context, processGUIDs []string) ([]cf.DesireLRPRequest, error) {
+			requests := []cf.DesireLRPRequest{}
+			for _, guid := range processGUIDs {
+				requests = append(requests, cf.DesireLRPRequest{
+					GUID:        strings.TrimSuffix(guid, "-v1"),
+					Version:     "v1",
+					ProcessGUID: guid,
+				})
+			}
+
+			return requests, nil
+		}
+
+		lrpBifrost.ListReturns([]cf.DesiredLRPSchedulingInfo{
+			schedulingInfo("in-sync", "v1", "1"),
+			schedulingInfo("stale", "v1", "old"),
+			schedulingInfo("orphaned", "v1", "4"),
+		}, nil)
+
+		converger = &convergence.Converger{
+			Fetcher:    fetcher,
+			LRPBifrost: lrpBifrost,
+			Emitter:    emitter,
+			Limiter:    limiter,
+			Logger:     lagertest.NewTestLogger("converger"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		report, err = converger.Converge(context.Background())
+	})
+
+	It("succeeds", func() {
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("reports the drift between cc and eirini", func() {
+		Expect(report.Missing).To(ConsistOf("missing-v1"))
+		Expect(report.Stale).To(ConsistOf("stale-v1"))
+		Expect(report.Orphaned).To(ConsistOf("orphaned-v1"))
+	})
+
+	It("fetches the desired state of missing and stale LRPs only", func() {
+		Expect(fetcher.DesiredLRPsCallCount()).To(Equal(1))
+		_, processGUIDs := fetcher.DesiredLRPsArgsForCall(0)
+		Expect(processGUIDs).To(ConsistOf("missing-v1", "stale-v1"))
+	})
+
+	It("desires missing LRPs", func() {
+		Expect(lrpBifrost.TransferCallCount()).To(Equal(1))
+		_, request := lrpBifrost.TransferArgsForCall(0)
+		Expect(request.ProcessGUID).To(Equal("missing-v1"))
+		Expect(report.Desired).To(Equal(1))
+	})
+
+	It("updates stale LRPs in place", func() {
+		Expect(lrpBifrost.UpdateCallCount()).To(Equal(1))
+		_, request := lrpBifrost.UpdateArgsForCall(0)
+		Expect(request.GUID).To(Equal("stale"))
+		Expect(request.Version).To(Equal("v1"))
+		Expect(report.Updated).To(Equal(1))
+	})
+
+	When("a stale LRP has changed", func() {
+		BeforeEach(func() {
+			fetcher.FingerprintsReturns([]cc_messages.CCDesiredAppFingerprint{
+				{ProcessGuid: "in-sync-v1", ETag: "1"},
+				{ProcessGuid: "stale-v1", ETag: "3"},
+			}, nil)
+			fetcher.DesiredLRPsStub = nil
+			fetcher.DesiredLRPsReturns([]cf.DesireLRPRequest{{
+				GUID:                    "stale",
+				Version:                 "v1",
+				ProcessGUID:             "stale-v1",
+				AppName:                 "the-app",
+				SpaceName:               "the-space",
+				NumInstances:            3,
+				LastUpdated:             "3",
+				Routes:                  map[string]json.RawMessage{"cf-router": json.RawMessage(`[]`)},
+				Environment:             map[string]string{"FOO": "bar"},
+				Ports:                   []int32{8080},
+				MemoryMB:                256,
+				DiskMB:                  1024,
+				HealthCheckType:         "http",
+				HealthCheckHTTPEndpoint: "/health",
+				StartTimeoutMs:          60000,
+				Lifecycle: cf.Lifecycle{
+					DockerLifecycle: &cf.DockerLifecycle{
+						Image:   "eirini/dorini",
+						Command: []string{"/dorini"},
+					},
+				},
+			}}, nil)
+		})
+
+		It("updates every field that can change in place", func() {
+			memoryMB, diskMB := int64(256), int64(1024)
+
+			_, request := lrpBifrost.UpdateArgsForCall(0)
+			Expect(request).To(Equal(cf.UpdateDesiredLRPRequest{
+				GUID:    "stale",
+				Version: "v1",
+				Update: cf.DesiredLRPUpdate{
+					Instances:   3,
+					Annotation:  "3",
+					Routes:      map[string]json.RawMessage{"cf-router": json.RawMessage(`[]`)},
+					Environment: map[string]string{"FOO": "bar"},
+					Ports:       []int32{8080},
+					MemoryMB:    &memoryMB,
+					DiskMB:      &diskMB,
+					Image:       "eirini/dorini",
+					Command:     []string{"/dorini"},
+					HealthCheck: &cf.HealthCheckUpdate{
+						Type:           "http",
+						HTTPEndpoint:   "/health",
+						StartTimeoutMs: 60000,
+					},
+				},
+			}))
+		})
+
+		It("does not desire it again", func() {
+			Expect(lrpBifrost.TransferCallCount()).To(BeZero())
+		})
+	})
+
+	When("a stale buildpack LRP has changed", func() {
+		BeforeEach(func() {
+			fetcher.DesiredLRPsStub = func(_ context.Context, processGUIDs []string) ([]cf.DesireLRPRequest, error) {
+				return []cf.DesireLRPRequest{{
+					GUID:        "stale",
+					Version:     "v1",
+					ProcessGUID: "stale-v1",
+					Lifecycle: cf.Lifecycle{
+						BuildpackLifecycle: &cf.BuildpackLifecycle{StartCommand: "./server"},
+					},
+				}}, nil
+			}
+		})
+
+		It("updates its start command rather than its command", func() {
+			_, request := lrpBifrost.UpdateArgsForCall(0)
+			Expect(request.Update.StartCommand).To(PointTo(Equal("./server")))
+			Expect(request.Update.Command).To(BeNil())
+			Expect(request.Update.Image).To(BeEmpty())
+		})
+	})
+
+	It("stops orphaned LRPs", func() {
+		Expect(lrpBifrost.StopCallCount()).To(Equal(1))
+		_, identifier := lrpBifrost.StopArgsForCall(0)
+		Expect(identifier).To(Equal(opi.LRPIdentifier{GUID: "orphaned", Version: "v1"}))
+		Expect(report.Stopped).To(Equal(1))
+	})
+
+	When("an LRP has been desired through the CRD", func() {
+		BeforeEach(func() {
+			crdInfo := schedulingInfo("crd", "v1", "5")
+			crdInfo.OwnedByCRD = true
+
+			lrpBifrost.ListReturns([]cf.DesiredLRPSchedulingInfo{
+				schedulingInfo("in-sync", "v1", "1"),
+				schedulingInfo("orphaned", "v1", "4"),
+				crdInfo,
+			}, nil)
+		})
+
+		It("is neither reported nor stopped as an orphan", func() {
+			Expect(report.Orphaned).To(ConsistOf("orphaned-v1"))
+			Expect(lrpBifrost.StopCallCount()).To(Equal(1))
+			_, identifier := lrpBifrost.StopArgsForCall(0)
+			Expect(identifier.GUID).To(Equal("orphaned"))
+		})
+	})
+
+	It("waits for the rate limiter before every operation", func() {
+		Expect(limiter.WaitCallCount()).To(Equal(3))
+	})
+
+	It("emits the report", func() {
+		Expect(emitter.EmitCallCount()).To(Equal(1))
+		Expect(emitter.EmitArgsForCall(0)).To(Equal(report))
+	})
+
+	When("running in dry-run mode", func() {
+		BeforeEach(func() {
+			converger.DryRun = true
+		})
+
+		It("reports the drift", func() {
+			Expect(report.DryRun).To(BeTrue())
+			Expect(report.Missing).To(ConsistOf("missing-v1"))
+			Expect(report.Stale).To(ConsistOf("stale-v1"))
+			Expect(report.Orphaned).To(ConsistOf("orphaned-v1"))
+			Expect(emitter.EmitCallCount()).To(Equal(1))
+		})
+
+		It("does not change anything", func() {
+			Expect(fetcher.DesiredLRPsCallCount()).To(BeZero())
+			Expect(lrpBifrost.TransferCallCount()).To(BeZero())
+			Expect(lrpBifrost.UpdateCallCount()).To(BeZero())
+			Expect(lrpBifrost.StopCallCount()).To(BeZero())
+		})
+	})
+
+	When("the number of operations per cycle is limited", func() {
+		BeforeEach(func() {
+			converger.MaxOperationsPerCycle = 1
+		})
+
+		It("performs at most that many operations", func() {
+			Expect(lrpBifrost.TransferCallCount()).To(Equal(1))
+			Expect(lrpBifrost.UpdateCallCount()).To(BeZero())
+			Expect(lrpBifrost.StopCallCount()).To(BeZero())
+			Expect(report.Skipped).To(Equal(2))
+		})
+	})
+
+	When("cc returns no fingerprints at all", func() {
+		BeforeEach(func() {
+			fetcher.FingerprintsReturns([]cc_messages.CCDesiredAppFingerprint{}, nil)
+		})
+
+		It("reports every LRP as orphaned", func() {
+			Expect(report.Orphaned).To(ConsistOf("in-sync-v1", "stale-v1", "orphaned-v1"))
+		})
+
+		It("does not stop any of them", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrpBifrost.StopCallCount()).To(BeZero())
+			Expect(report.Stopped).To(BeZero())
+			Expect(report.Skipped).To(Equal(3))
+		})
+
+		When("the response stays empty in the next cycle", func() {
+			JustBeforeEach(func() {
+				report, err = converger.Converge(context.Background())
+			})
+
+			It("still does not stop anything", func() {
+				Expect(lrpBifrost.StopCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	When("the fingerprint list collapses compared with the last cycle", func() {
+		BeforeEach(func() {
+			_, err = converger.Converge(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrpBifrost.StopCallCount()).To(Equal(1))
+
+			fetcher.FingerprintsReturns([]cc_messages.CCDesiredAppFingerprint{
+				{ProcessGuid: "in-sync-v1", ETag: "1"},
+			}, nil)
+		})
+
+		It("does not stop the LRPs missing from it", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Orphaned).To(ConsistOf("stale-v1", "orphaned-v1"))
+			Expect(lrpBifrost.StopCallCount()).To(Equal(1))
+			Expect(report.Skipped).To(Equal(2))
+		})
+
+		When("the next cycle confirms the shrunk list", func() {
+			JustBeforeEach(func() {
+				report, err = converger.Converge(context.Background())
+			})
+
+			It("stops the orphans", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lrpBifrost.StopCallCount()).To(Equal(3))
+				Expect(report.Stopped).To(Equal(2))
+			})
+		})
+	})
+
+	When("fetching the fingerprints fails", func() {
+		BeforeEach(func() {
+			fetcher.FingerprintsReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error without changing anything", func() {
+			Expect(err).To(MatchError(ContainSubstring("boom")))
+			Expect(lrpBifrost.TransferCallCount()).To(BeZero())
+			Expect(lrpBifrost.StopCallCount()).To(BeZero())
+			Expect(emitter.EmitCallCount()).To(BeZero())
+		})
+	})
+
+	When("listing the LRPs fails", func() {
+		BeforeEach(func() {
+			lrpBifrost.ListReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error without changing anything", func() {
+			Expect(err).To(MatchError(ContainSubstring("boom")))
+			Expect(lrpBifrost.TransferCallCount()).To(BeZero())
+			Expect(lrpBifrost.StopCallCount()).To(BeZero())
+		})
+	})
+
+	When("fetching the desired LRPs fails", func() {
+		BeforeEach(func() {
+			fetcher.DesiredLRPsStub = nil
+			fetcher.DesiredLRPsReturns(nil, errors.New("boom"))
+		})
+
+		It("counts them as failed and still stops orphans", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrpBifrost.TransferCallCount()).To(BeZero())
+			Expect(lrpBifrost.UpdateCallCount()).To(BeZero())
+			Expect(report.Failed).To(Equal(2))
+			Expect(report.Stopped).To(Equal(1))
+		})
+	})
+
+	When("desiring an LRP fails", func() {
+		BeforeEach(func() {
+			lrpBifrost.TransferReturns(errors.New("boom"))
+		})
+
+		It("carries on with the other LRPs", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrpBifrost.UpdateCallCount()).To(Equal(1))
+			Expect(report.Failed).To(Equal(1))
+			Expect(report.Desired).To(BeZero())
+			Expect(report.Updated).To(Equal(1))
+		})
+	})
+
+	When("updating an LRP fails", func() {
+		BeforeEach(func() {
+			lrpBifrost.UpdateReturns(errors.New("boom"))
+		})
+
+		It("carries on with the other LRPs", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrpBifrost.TransferCallCount()).To(Equal(1))
+			Expect(report.Failed).To(Equal(1))
+			Expect(report.Desired).To(Equal(1))
+			Expect(report.Updated).To(BeZero())
+		})
+	})
+
+	When("stopping an LRP fails", func() {
+		BeforeEach(func() {
+			lrpBifrost.StopReturns(errors.New("boom"))
+		})
+
+		It("counts it as failed", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Failed).To(Equal(1))
+			Expect(report.Stopped).To(BeZero())
+		})
+	})
+
+	When("the rate limiter fails", func() {
+		BeforeEach(func() {
+			limiter.WaitReturns(context.Canceled)
+		})
+
+		It("stops converging", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrpBifrost.TransferCallCount()).To(BeZero())
+			Expect(lrpBifrost.UpdateCallCount()).To(BeZero())
+			Expect(lrpBifrost.StopCallCount()).To(BeZero())
+		})
+	})
+})
+
+func schedulingInfo(guid, version, annotation string) cf.DesiredLRPSchedulingInfo {
+	info := cf.DesiredLRPSchedulingInfo{
+		GUID:       guid,
+		Version:    version,
+		Annotation: annotation,
+	}
+	info.ProcessGUID = guid + "-" + version
+
+	return info
+}
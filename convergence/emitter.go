@@ -0,0 +1,41 @@
+package convergence
+
+import (
+	"code.cloudfoundry.org/eirini/metrics"
+	loggregator "code.cloudfoundry.org/go-loggregator"
+)
+
+const (
+	SourceID  = "eirini-convergence"
+	CountUnit = "count"
+)
+
+type LoggregatorEmitter struct {
+	client metrics.LoggregatorClient
+}
+
+func NewLoggregatorEmitter(client metrics.LoggregatorClient) *LoggregatorEmitter {
+	return &LoggregatorEmitter{
+		client: client,
+	}
+}
+
+func (e *LoggregatorEmitter) Emit(r Report) {
+	dryRun := 0.0
+	if r.DryRun {
+		dryRun = 1
+	}
+
+	e.client.EmitGauge(
+		loggregator.WithGaugeSourceInfo(SourceID, "0"),
+		loggregator.WithGaugeValue("convergence_dry_run", dryRun, CountUnit),
+		loggregator.WithGaugeValue("convergence_lrps_missing", float64(len(r.Missing)), CountUnit),
+		loggregator.WithGaugeValue("convergence_lrps_stale", float64(len(r.Stale)), CountUnit),
+		loggregator.WithGaugeValue("convergence_lrps_orphaned", float64(len(r.Orphaned)), CountUnit),
+		loggregator.WithGaugeValue("convergence_lrps_desired", float64(r.Desired), CountUnit),
+		loggregator.WithGaugeValue("convergence_lrps_updated", float64(r.Updated), CountUnit),
+		loggregator.WithGaugeValue("convergence_lrps_stopped", float64(r.Stopped), CountUnit),
+		loggregator.WithGaugeValue("convergence_lrps_failed", float64(r.Failed), CountUnit),
+		loggregator.WithGaugeValue("convergence_lrps_skipped", float64(r.Skipped), CountUnit),
+	)
+}
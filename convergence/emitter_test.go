@@ -0,0 +1,58 @@
+package convergence_test
+
+import (
+	"code.cloudfoundry.org/eirini/convergence"
+	"code.cloudfoundry.org/eirini/metrics/metricsfakes"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoggregatorEmitter", func() {
+	It("emits drift found and repaired as gauges", func() {
+		fakeClient := new(metricsfakes.FakeLoggregatorClient)
+		emitter := convergence.NewLoggregatorEmitter(fakeClient)
+
+		emitter.Emit(convergence.Report{
+			Missing:  []string{"a", "b"},
+			Stale:    []string{"c"},
+			Orphaned: []string{"d", "e", "f"},
+			Desired:  2,
+			Updated:  1,
+			Stopped:  2,
+			Failed:   1,
+			Skipped:  0,
+		})
+		Expect(fakeClient.EmitGaugeCallCount()).To(Equal(1))
+
+		envelope := &loggregator_v2.Envelope{
+			Message: &loggregator_v2.Envelope_Gauge{
+				Gauge: &loggregator_v2.Gauge{
+					Metrics: map[string]*loggregator_v2.GaugeValue{},
+				},
+			},
+			Tags: map[string]string{},
+		}
+
+		for _, opt := range fakeClient.EmitGaugeArgsForCall(0) {
+			opt(envelope)
+		}
+
+		Expect(envelope.SourceId).To(Equal(convergence.SourceID))
+
+		gauge := func(value float64) *loggregator_v2.GaugeValue {
+			return &loggregator_v2.GaugeValue{Unit: convergence.CountUnit, Value: value}
+		}
+		Expect(envelope.GetGauge().Metrics).To(Equal(map[string]*loggregator_v2.GaugeValue{
+			"convergence_dry_run":       gauge(0),
+			"convergence_lrps_missing":  gauge(2),
+			"convergence_lrps_stale":    gauge(1),
+			"convergence_lrps_orphaned": gauge(3),
+			"convergence_lrps_desired":  gauge(2),
+			"convergence_lrps_updated":  gauge(1),
+			"convergence_lrps_stopped":  gauge(2),
+			"convergence_lrps_failed":   gauge(1),
+			"convergence_lrps_skipped":  gauge(0),
+		}))
+	})
+})
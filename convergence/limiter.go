@@ -0,0 +1,31 @@
+package convergence
+
+import (
+	"context"
+	"time"
+)
+
+type TickerLimiter struct {
+	Ticker *time.Ticker
+}
+
+func NewTickerLimiter(operationsPerSecond float64) *TickerLimiter {
+	return &TickerLimiter{
+		Ticker: time.NewTicker(time.Duration(float64(time.Second) / operationsPerSecond)),
+	}
+}
+
+func (l *TickerLimiter) Wait(ctx context.Context) error {
+	select {
+	case <-l.Ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type UnlimitedLimiter struct{}
+
+func (UnlimitedLimiter) Wait(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -93,6 +93,6 @@ require (
 	k8s.io/code-generator v0.19.3
 	k8s.io/klog v1.0.0
 	k8s.io/metrics v0.19.3
-	k8s.io/utils v0.0.0-20201027101359-01387209bb0d // indirect
+	k8s.io/utils v0.0.0-20201027101359-01387209bb0d
 	sigs.k8s.io/controller-runtime v0.6.3
 )
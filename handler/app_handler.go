@@ -49,6 +49,13 @@ func (a *App) Desire(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 
 	if err := a.lrpBifrost.Transfer(r.Context(), request); err != nil {
 		loggerSession.Error("bifrost-failed", err)
+
+		if errors.Is(err, eirini.ErrImageRejected) {
+			writeUpdateErrorResponse(w, err, http.StatusUnprocessableEntity, loggerSession)
+
+			return
+		}
+
 		w.WriteHeader(http.StatusBadRequest)
 
 		return
@@ -172,7 +179,18 @@ func (a *App) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 
 	if err := a.lrpBifrost.Update(r.Context(), request); err != nil {
 		loggerSession.Error("bifrost-failed", err)
-		writeUpdateErrorResponse(w, err, http.StatusInternalServerError, loggerSession)
+
+		statusCode := http.StatusInternalServerError
+
+		if errors.Is(err, eirini.ErrInvalidUpdate) {
+			statusCode = http.StatusBadRequest
+		}
+
+		if errors.Is(err, eirini.ErrImageRejected) {
+			statusCode = http.StatusUnprocessableEntity
+		}
+
+		writeUpdateErrorResponse(w, err, statusCode, loggerSession)
 	}
 }
 
@@ -221,6 +239,65 @@ func (a *App) StopInstance(w http.ResponseWriter, r *http.Request, ps httprouter
 	}
 }
 
+func (a *App) Rollout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	loggerSession := a.logger.Session("rollout-app", lager.Data{"guid": ps.ByName("process_guid"), "version": ps.ByName("version_guid")})
+
+	var request cf.RolloutRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		loggerSession.Error("json-decoding-failed", err)
+
+		writeUpdateErrorResponse(w, err, http.StatusBadRequest, loggerSession)
+
+		return
+	}
+
+	loggerSession.Debug("requested", lager.Data{"action": request.Action})
+
+	identifier := opi.LRPIdentifier{
+		GUID:    ps.ByName("process_guid"),
+		Version: ps.ByName("version_guid"),
+	}
+
+	if err := a.lrpBifrost.Rollout(r.Context(), identifier, request.Action); err != nil {
+		loggerSession.Error("bifrost-failed", err)
+
+		statusCode := http.StatusInternalServerError
+
+		switch {
+		case errors.Is(err, eirini.ErrInvalidRolloutAction):
+			statusCode = http.StatusBadRequest
+		case errors.Is(err, eirini.ErrNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, eirini.ErrNoRolloutInProgress):
+			statusCode = http.StatusConflict
+		}
+
+		writeUpdateErrorResponse(w, err, statusCode, loggerSession)
+	}
+}
+
+func (a *App) Restart(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	loggerSession := a.logger.Session("restart-app", lager.Data{"guid": ps.ByName("process_guid"), "version": ps.ByName("version_guid")})
+	loggerSession.Debug("requested")
+
+	identifier := opi.LRPIdentifier{
+		GUID:    ps.ByName("process_guid"),
+		Version: ps.ByName("version_guid"),
+	}
+
+	if err := a.lrpBifrost.Restart(r.Context(), identifier); err != nil {
+		loggerSession.Error("bifrost-failed", err)
+
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, eirini.ErrNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		w.WriteHeader(statusCode)
+	}
+}
+
 func writeUpdateErrorResponse(w http.ResponseWriter, err error, statusCode int, loggerSession lager.Logger) {
 	w.WriteHeader(statusCode)
 
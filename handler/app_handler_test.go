@@ -32,7 +32,7 @@ var _ = Describe("AppHandler", func() {
 		lrpBifrost = new(handlerfakes.FakeLRPBifrost)
 		stagingBifrost = new(handlerfakes.FakeStagingBifrost)
 		lager = lagertest.NewTestLogger("app-handler-test")
-		ts = httptest.NewServer(New(lrpBifrost, stagingBifrost, nil, nil, lager))
+		ts = httptest.NewServer(New(lrpBifrost, stagingBifrost, nil, nil, nil, lager))
 	})
 
 	AfterEach(func() {
@@ -172,6 +172,22 @@ var _ = Describe("AppHandler", func() {
 			It("should provide a helpful log message", findLog("app-handler-test.desire-app.bifrost-failed", "myguid"))
 		})
 
+		Context("When the image is rejected by the admission policy", func() {
+			BeforeEach(func() {
+				lrpBifrost.TransferReturns(errors.Wrap(eirini.ErrImageRejected, `registry of image "evil/image" is denied`))
+			})
+
+			It("should return UnprocessableEntity status", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			})
+
+			It("should return the reason", func() {
+				var responseObj cf.DesiredLRPLifecycleResponse
+				Expect(json.NewDecoder(response.Body).Decode(&responseObj)).To(Succeed())
+				Expect(responseObj.Error.Message).To(Equal(`registry of image "evil/image" is denied: image rejected by admission policy`))
+			})
+		})
+
 		Context("when the body is empty", func() {
 			BeforeEach(func() {
 				body = ""
@@ -362,7 +378,7 @@ var _ = Describe("AppHandler", func() {
 
 			instances := []*cf.Instance{
 				{Index: 0, Since: 123, State: "RUNNING"},
-				{Index: 1, Since: 456, State: "RUNNING"},
+				{Index: 1, Since: 456, State: "RUNNING", UpdatePending: true},
 				{Index: 2, Since: 789, State: "UNCLAIMED", PlacementError: "this is not the place"},
 			}
 			lrpBifrost.GetInstancesReturns(instances, nil)
@@ -397,7 +413,8 @@ var _ = Describe("AppHandler", func() {
 						{
 							"index": 1,
 							"since": 456,
-							"state": "RUNNING"
+							"state": "RUNNING",
+							"update_pending": true
 						},
 						{
 							"index": 2,
@@ -497,6 +514,21 @@ var _ = Describe("AppHandler", func() {
 				Expect(request.Version).To(Equal("version-id"))
 				Expect(request.Update.Instances).To(Equal(5))
 			})
+
+			When("the update changes the process settings", func() {
+				BeforeEach(func() {
+					body = `{"guid": "app-id", "version": "version-id", "update": {"memory_mb": 512, "disk_mb": 1024, "environment": {"FOO": "bar"}, "ports": [8080], "health_check": {"type": "http", "http_endpoint": "/health"}}}`
+				})
+
+				It("should translate them", func() {
+					_, request := lrpBifrost.UpdateArgsForCall(0)
+					Expect(*request.Update.MemoryMB).To(Equal(int64(512)))
+					Expect(*request.Update.DiskMB).To(Equal(int64(1024)))
+					Expect(request.Update.Environment).To(Equal(map[string]string{"FOO": "bar"}))
+					Expect(request.Update.Ports).To(Equal([]int32{8080}))
+					Expect(request.Update.HealthCheck).To(Equal(&cf.HealthCheckUpdate{Type: "http", HTTPEndpoint: "/health"}))
+				})
+			})
 		})
 
 		Context("when the json is invalid", func() {
@@ -532,6 +564,30 @@ var _ = Describe("AppHandler", func() {
 			It("shoud return a response object containing the error", func() {
 				verifyResponseObject()
 			})
+
+			When("the update is invalid", func() {
+				BeforeEach(func() {
+					lrpBifrost.UpdateReturns(errors.Wrap(eirini.ErrInvalidUpdate, "DiskMB cannot be 0"))
+				})
+
+				It("should return a 400 Bad Request HTTP status code", func() {
+					Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+				})
+
+				It("shoud return a response object containing the error", func() {
+					verifyResponseObject()
+				})
+			})
+
+			When("the new image is rejected by the admission policy", func() {
+				BeforeEach(func() {
+					lrpBifrost.UpdateReturns(errors.Wrap(eirini.ErrImageRejected, `registry of image "evil/image" is denied`))
+				})
+
+				It("should return UnprocessableEntity status", func() {
+					Expect(response.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+				})
+			})
 		})
 	})
 
@@ -581,6 +637,140 @@ var _ = Describe("AppHandler", func() {
 		})
 	})
 
+	Context("Roll out an app", func() {
+		var (
+			body     string
+			response *http.Response
+		)
+
+		BeforeEach(func() {
+			body = `{"action": "promote"}`
+		})
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest("POST", ts.URL+"/apps/app_1234/version_1234/rollout", strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+
+			client := &http.Client{}
+			response, err = client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should return a 200 HTTP status code", func() {
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should roll out the right app with the requested action", func() {
+			Expect(lrpBifrost.RolloutCallCount()).To(Equal(1))
+			_, identifier, action := lrpBifrost.RolloutArgsForCall(0)
+			Expect(identifier.GUID).To(Equal("app_1234"))
+			Expect(identifier.Version).To(Equal("version_1234"))
+			Expect(action).To(Equal("promote"))
+		})
+
+		Context("when the request body is invalid", func() {
+			BeforeEach(func() {
+				body = "{"
+			})
+
+			It("should return a 400 HTTP status code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+
+			It("should not roll out the app", func() {
+				Expect(lrpBifrost.RolloutCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the action is invalid", func() {
+			BeforeEach(func() {
+				lrpBifrost.RolloutReturns(errors.Wrap(eirini.ErrInvalidRolloutAction, "jump"))
+			})
+
+			It("should return a 400 HTTP status code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the app does not exist", func() {
+			BeforeEach(func() {
+				lrpBifrost.RolloutReturns(errors.Wrap(eirini.ErrNotFound, "boom"))
+			})
+
+			It("should return a 404 HTTP status code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when no rollout is in progress", func() {
+			BeforeEach(func() {
+				lrpBifrost.RolloutReturns(errors.Wrap(eirini.ErrNoRolloutInProgress, "boom"))
+			})
+
+			It("should return a 409 HTTP status code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("when the rollout fails", func() {
+			BeforeEach(func() {
+				lrpBifrost.RolloutReturns(errors.New("something-bad-happened"))
+			})
+
+			It("should return a 500 HTTP status code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+
+			It("should provide a helpful log message", findLog("app-handler-test.rollout-app.bifrost-failed", "app_1234"))
+		})
+	})
+
+	Context("Restart an app", func() {
+		var response *http.Response
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest("POST", ts.URL+"/apps/app_1234/version_1234/restart", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			client := &http.Client{}
+			response, err = client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should return a 200 HTTP status code", func() {
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should restart the right app", func() {
+			Expect(lrpBifrost.RestartCallCount()).To(Equal(1))
+			_, identifier := lrpBifrost.RestartArgsForCall(0)
+			Expect(identifier.GUID).To(Equal("app_1234"))
+			Expect(identifier.Version).To(Equal("version_1234"))
+		})
+
+		Context("when the app does not exist", func() {
+			BeforeEach(func() {
+				lrpBifrost.RestartReturns(errors.Wrap(eirini.ErrNotFound, "boom"))
+			})
+
+			It("should return a 404 HTTP status code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when the restart fails", func() {
+			BeforeEach(func() {
+				lrpBifrost.RestartReturns(errors.New("something-bad-happened"))
+			})
+
+			It("should return a 500 HTTP status code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+
+			It("should provide a helpful log message", findLog("app-handler-test.restart-app.bifrost-failed", "app_1234"))
+		})
+	})
+
 	Context("Stop an app instance", func() {
 		var (
 			path     string
@@ -13,6 +13,7 @@ import (
 //counterfeiter:generate . LRPBifrost
 //counterfeiter:generate . StagingBifrost
 //counterfeiter:generate . TaskBifrost
+//counterfeiter:generate . NetworkPolicyBifrost
 
 type LRPBifrost interface {
 	Transfer(ctx context.Context, request cf.DesireLRPRequest) error
@@ -22,6 +23,8 @@ type LRPBifrost interface {
 	StopInstance(ctx context.Context, identifier opi.LRPIdentifier, index uint) error
 	GetApp(ctx context.Context, identifier opi.LRPIdentifier) (cf.DesiredLRP, error)
 	GetInstances(ctx context.Context, identifier opi.LRPIdentifier) ([]*cf.Instance, error)
+	Rollout(ctx context.Context, identifier opi.LRPIdentifier, action string) error
+	Restart(ctx context.Context, identifier opi.LRPIdentifier) error
 }
 
 type TaskBifrost interface {
@@ -31,6 +34,12 @@ type TaskBifrost interface {
 	CancelTask(taskGUID string) error
 }
 
+type NetworkPolicyBifrost interface {
+	CreatePolicies(ctx context.Context, request cf.NetworkPoliciesRequest) error
+	ListPolicies(ctx context.Context) (cf.NetworkPoliciesResponse, error)
+	DeletePolicies(ctx context.Context, request cf.NetworkPoliciesRequest) error
+}
+
 type StagingBifrost interface {
 	TransferStaging(ctx context.Context, stagingGUID string, request cf.StagingRequest) error
 	CompleteStaging(cf.StagingCompletedRequest) error
@@ -40,16 +49,19 @@ func New(lrpBifrost LRPBifrost,
 	buildpackStagingBifrost StagingBifrost,
 	dockerStagingBifrost StagingBifrost,
 	taskBifrost TaskBifrost,
+	networkPolicyBifrost NetworkPolicyBifrost,
 	lager lager.Logger) http.Handler {
 	handler := httprouter.New()
 
 	appHandler := NewAppHandler(lrpBifrost, lager)
 	stageHandler := NewStageHandler(buildpackStagingBifrost, dockerStagingBifrost, lager)
 	taskHandler := NewTaskHandler(lager, taskBifrost)
+	networkPolicyHandler := NewNetworkPolicyHandler(lager, networkPolicyBifrost)
 
 	registerAppsEndpoints(handler, appHandler)
 	registerStageEndpoints(handler, stageHandler)
 	registerTaskEndpoints(handler, taskHandler)
+	registerNetworkPolicyEndpoints(handler, networkPolicyHandler)
 
 	return handler
 }
@@ -61,6 +73,8 @@ func registerAppsEndpoints(handler *httprouter.Router, appHandler *App) {
 	handler.PUT("/apps/:process_guid/:version_guid/stop", appHandler.Stop)
 	handler.PUT("/apps/:process_guid/:version_guid/stop/:instance", appHandler.StopInstance)
 	handler.GET("/apps/:process_guid/:version_guid/instances", appHandler.GetInstances)
+	handler.POST("/apps/:process_guid/:version_guid/rollout", appHandler.Rollout)
+	handler.POST("/apps/:process_guid/:version_guid/restart", appHandler.Restart)
 	handler.GET("/apps/:process_guid/:version_guid", appHandler.Get)
 }
 
@@ -75,3 +89,9 @@ func registerTaskEndpoints(handler *httprouter.Router, taskHandler *Task) {
 	handler.POST("/tasks/:task_guid", taskHandler.Run)
 	handler.DELETE("/tasks/:task_guid", taskHandler.Cancel)
 }
+
+func registerNetworkPolicyEndpoints(handler *httprouter.Router, networkPolicyHandler *NetworkPolicy) {
+	handler.GET("/network_policies", networkPolicyHandler.List)
+	handler.POST("/network_policies", networkPolicyHandler.Create)
+	handler.POST("/network_policies/delete", networkPolicyHandler.Delete)
+}
@@ -21,6 +21,7 @@ var _ = Describe("Handler", func() {
 		buildpackStagingBifrost *handlerfakes.FakeStagingBifrost
 		dockerStagingBifrost    *handlerfakes.FakeStagingBifrost
 		taskBifrost             *handlerfakes.FakeTaskBifrost
+		networkPolicyBifrost    *handlerfakes.FakeNetworkPolicyBifrost
 		handlerClient           http.Handler
 	)
 
@@ -30,9 +31,10 @@ var _ = Describe("Handler", func() {
 		buildpackStagingBifrost = new(handlerfakes.FakeStagingBifrost)
 		dockerStagingBifrost = new(handlerfakes.FakeStagingBifrost)
 		taskBifrost = new(handlerfakes.FakeTaskBifrost)
+		networkPolicyBifrost = new(handlerfakes.FakeNetworkPolicyBifrost)
 
 		lager := lagertest.NewTestLogger("handler-test")
-		handlerClient = New(lrpBifrost, buildpackStagingBifrost, dockerStagingBifrost, taskBifrost, lager)
+		handlerClient = New(lrpBifrost, buildpackStagingBifrost, dockerStagingBifrost, taskBifrost, networkPolicyBifrost, lager)
 	})
 
 	JustBeforeEach(func() {
@@ -197,5 +199,41 @@ var _ = Describe("Handler", func() {
 				assertEndpoint()
 			})
 		})
+
+		Context("GET /network_policies", func() {
+			BeforeEach(func() {
+				method = "GET"
+				path = "/network_policies"
+				expectedStatus = http.StatusOK
+			})
+
+			It("serves the endpoint", func() {
+				assertEndpoint()
+			})
+		})
+
+		Context("POST /network_policies", func() {
+			BeforeEach(func() {
+				method = "POST"
+				path = "/network_policies"
+				expectedStatus = http.StatusCreated
+			})
+
+			It("serves the endpoint", func() {
+				assertEndpoint()
+			})
+		})
+
+		Context("POST /network_policies/delete", func() {
+			BeforeEach(func() {
+				method = "POST"
+				path = "/network_policies/delete"
+				expectedStatus = http.StatusNoContent
+			})
+
+			It("serves the endpoint", func() {
+				assertEndpoint()
+			})
+		})
 	})
 })
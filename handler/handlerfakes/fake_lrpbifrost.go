@@ -52,6 +52,31 @@ type FakeLRPBifrost struct {
 		result1 []cf.DesiredLRPSchedulingInfo
 		result2 error
 	}
+	RestartStub        func(context.Context, opi.LRPIdentifier) error
+	restartMutex       sync.RWMutex
+	restartArgsForCall []struct {
+		arg1 context.Context
+		arg2 opi.LRPIdentifier
+	}
+	restartReturns struct {
+		result1 error
+	}
+	restartReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RolloutStub        func(context.Context, opi.LRPIdentifier, string) error
+	rolloutMutex       sync.RWMutex
+	rolloutArgsForCall []struct {
+		arg1 context.Context
+		arg2 opi.LRPIdentifier
+		arg3 string
+	}
+	rolloutReturns struct {
+		result1 error
+	}
+	rolloutReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StopStub        func(context.Context, opi.LRPIdentifier) error
 	stopMutex       sync.RWMutex
 	stopArgsForCall []struct {
@@ -299,6 +324,131 @@ func (fake *FakeLRPBifrost) ListReturnsOnCall(i int, result1 []cf.DesiredLRPSche
 	}{result1, result2}
 }
 
+func (fake *FakeLRPBifrost) Restart(arg1 context.Context, arg2 opi.LRPIdentifier) error {
+	fake.restartMutex.Lock()
+	ret, specificReturn := fake.restartReturnsOnCall[len(fake.restartArgsForCall)]
+	fake.restartArgsForCall = append(fake.restartArgsForCall, struct {
+		arg1 context.Context
+		arg2 opi.LRPIdentifier
+	}{arg1, arg2})
+	stub := fake.RestartStub
+	fakeReturns := fake.restartReturns
+	fake.recordInvocation("Restart", []interface{}{arg1, arg2})
+	fake.restartMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLRPBifrost) RestartCallCount() int {
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	return len(fake.restartArgsForCall)
+}
+
+func (fake *FakeLRPBifrost) RestartCalls(stub func(context.Context, opi.LRPIdentifier) error) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = stub
+}
+
+func (fake *FakeLRPBifrost) RestartArgsForCall(i int) (context.Context, opi.LRPIdentifier) {
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	argsForCall := fake.restartArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLRPBifrost) RestartReturns(result1 error) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = nil
+	fake.restartReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLRPBifrost) RestartReturnsOnCall(i int, result1 error) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = nil
+	if fake.restartReturnsOnCall == nil {
+		fake.restartReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.restartReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLRPBifrost) Rollout(arg1 context.Context, arg2 opi.LRPIdentifier, arg3 string) error {
+	fake.rolloutMutex.Lock()
+	ret, specificReturn := fake.rolloutReturnsOnCall[len(fake.rolloutArgsForCall)]
+	fake.rolloutArgsForCall = append(fake.rolloutArgsForCall, struct {
+		arg1 context.Context
+		arg2 opi.LRPIdentifier
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RolloutStub
+	fakeReturns := fake.rolloutReturns
+	fake.recordInvocation("Rollout", []interface{}{arg1, arg2, arg3})
+	fake.rolloutMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLRPBifrost) RolloutCallCount() int {
+	fake.rolloutMutex.RLock()
+	defer fake.rolloutMutex.RUnlock()
+	return len(fake.rolloutArgsForCall)
+}
+
+func (fake *FakeLRPBifrost) RolloutCalls(stub func(context.Context, opi.LRPIdentifier, string) error) {
+	fake.rolloutMutex.Lock()
+	defer fake.rolloutMutex.Unlock()
+	fake.RolloutStub = stub
+}
+
+func (fake *FakeLRPBifrost) RolloutArgsForCall(i int) (context.Context, opi.LRPIdentifier, string) {
+	fake.rolloutMutex.RLock()
+	defer fake.rolloutMutex.RUnlock()
+	argsForCall := fake.rolloutArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeLRPBifrost) RolloutReturns(result1 error) {
+	fake.rolloutMutex.Lock()
+	defer fake.rolloutMutex.Unlock()
+	fake.RolloutStub = nil
+	fake.rolloutReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLRPBifrost) RolloutReturnsOnCall(i int, result1 error) {
+	fake.rolloutMutex.Lock()
+	defer fake.rolloutMutex.Unlock()
+	fake.RolloutStub = nil
+	if fake.rolloutReturnsOnCall == nil {
+		fake.rolloutReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.rolloutReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeLRPBifrost) Stop(arg1 context.Context, arg2 opi.LRPIdentifier) error {
 	fake.stopMutex.Lock()
 	ret, specificReturn := fake.stopReturnsOnCall[len(fake.stopArgsForCall)]
@@ -557,6 +707,10 @@ func (fake *FakeLRPBifrost) Invocations() map[string][][]interface{} {
 	defer fake.getInstancesMutex.RUnlock()
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	fake.rolloutMutex.RLock()
+	defer fake.rolloutMutex.RUnlock()
 	fake.stopMutex.RLock()
 	defer fake.stopMutex.RUnlock()
 	fake.stopInstanceMutex.RLock()
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/lager"
+	"github.com/julienschmidt/httprouter"
+)
+
+type NetworkPolicy struct {
+	logger               lager.Logger
+	networkPolicyBifrost NetworkPolicyBifrost
+}
+
+func NewNetworkPolicyHandler(logger lager.Logger, networkPolicyBifrost NetworkPolicyBifrost) *NetworkPolicy {
+	return &NetworkPolicy{
+		logger:               logger,
+		networkPolicyBifrost: networkPolicyBifrost,
+	}
+}
+
+func (n *NetworkPolicy) Create(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	logger := n.logger.Session("create-network-policies")
+
+	var request cf.NetworkPoliciesRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		logger.Error("request-body-decoding-failed", err)
+		writeErrorResponse(logger, resp, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if err := n.networkPolicyBifrost.CreatePolicies(req.Context(), request); err != nil {
+		logger.Error("create-network-policies-failed", err)
+		writeErrorResponse(logger, resp, networkPolicyErrorStatus(err), err)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+}
+
+func (n *NetworkPolicy) List(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	logger := n.logger.Session("list-network-policies")
+
+	policies, err := n.networkPolicyBifrost.ListPolicies(req.Context())
+	if err != nil {
+		logger.Error("list-network-policies-failed", err)
+		writeErrorResponse(logger, resp, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if err = json.NewEncoder(resp).Encode(policies); err != nil {
+		logger.Error("encode-json-failed", err)
+		resp.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (n *NetworkPolicy) Delete(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	logger := n.logger.Session("delete-network-policies")
+
+	var request cf.NetworkPoliciesRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		logger.Error("request-body-decoding-failed", err)
+		writeErrorResponse(logger, resp, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if err := n.networkPolicyBifrost.DeletePolicies(req.Context(), request); err != nil {
+		logger.Error("delete-network-policies-failed", err)
+		writeErrorResponse(logger, resp, networkPolicyErrorStatus(err), err)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func networkPolicyErrorStatus(err error) int {
+	if errors.Is(err, eirini.ErrInvalidNetworkPolicy) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,179 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/eirini"
+	. "code.cloudfoundry.org/eirini/handler"
+	"code.cloudfoundry.org/eirini/handler/handlerfakes"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("NetworkPolicyHandler", func() {
+	var (
+		ts                   *httptest.Server
+		networkPolicyBifrost *handlerfakes.FakeNetworkPolicyBifrost
+
+		response *http.Response
+		body     string
+		path     string
+		method   string
+	)
+
+	BeforeEach(func() {
+		networkPolicyBifrost = new(handlerfakes.FakeNetworkPolicyBifrost)
+		body = `{
+			"namespace": "the-namespace",
+			"policies": [
+				{"source": {"id": "app-a"}, "destination": {"id": "app-b", "protocol": "tcp", "ports": {"start": 8080, "end": 8081}}}
+			]
+		}`
+	})
+
+	JustBeforeEach(func() {
+		handler := New(nil, nil, nil, nil, networkPolicyBifrost, lagertest.NewTestLogger("test"))
+		ts = httptest.NewServer(handler)
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader([]byte(body)))
+		Expect(err).NotTo(HaveOccurred())
+
+		client := &http.Client{}
+		response, err = client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	expectedRequest := cf.NetworkPoliciesRequest{
+		Namespace: "the-namespace",
+		Policies: []cf.NetworkPolicy{
+			{
+				Source: cf.NetworkPolicySource{ID: "app-a"},
+				Destination: cf.NetworkPolicyDestination{
+					ID:       "app-b",
+					Protocol: "tcp",
+					Ports:    cf.PortRange{Start: 8080, End: 8081},
+				},
+			},
+		},
+	}
+
+	Describe("Create", func() {
+		BeforeEach(func() {
+			method = "POST"
+			path = "/network_policies"
+		})
+
+		It("creates the policies", func() {
+			Expect(response.StatusCode).To(Equal(http.StatusCreated))
+			Expect(networkPolicyBifrost.CreatePoliciesCallCount()).To(Equal(1))
+			_, request := networkPolicyBifrost.CreatePoliciesArgsForCall(0)
+			Expect(request).To(Equal(expectedRequest))
+		})
+
+		When("the request body is invalid", func() {
+			BeforeEach(func() {
+				body = "{invalid"
+			})
+
+			It("returns a bad request status", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(networkPolicyBifrost.CreatePoliciesCallCount()).To(BeZero())
+			})
+		})
+
+		When("a policy is invalid", func() {
+			BeforeEach(func() {
+				networkPolicyBifrost.CreatePoliciesReturns(errors.Wrap(eirini.ErrInvalidNetworkPolicy, "unsupported protocol"))
+			})
+
+			It("returns a bad request status", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		When("creating the policies fails", func() {
+			BeforeEach(func() {
+				networkPolicyBifrost.CreatePoliciesReturns(errors.New("boom"))
+			})
+
+			It("returns an internal server error", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+
+				var errorResponse cf.Error
+				Expect(json.NewDecoder(response.Body).Decode(&errorResponse)).To(Succeed())
+				Expect(errorResponse.Message).To(Equal("boom"))
+			})
+		})
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			method = "GET"
+			path = "/network_policies"
+			body = ""
+
+			networkPolicyBifrost.ListPoliciesReturns(cf.NetworkPoliciesResponse{Policies: expectedRequest.Policies}, nil)
+		})
+
+		It("returns the policies", func() {
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			var policies cf.NetworkPoliciesResponse
+			Expect(json.NewDecoder(response.Body).Decode(&policies)).To(Succeed())
+			Expect(policies.Policies).To(Equal(expectedRequest.Policies))
+		})
+
+		When("listing the policies fails", func() {
+			BeforeEach(func() {
+				networkPolicyBifrost.ListPoliciesReturns(cf.NetworkPoliciesResponse{}, errors.New("boom"))
+			})
+
+			It("returns an internal server error", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		BeforeEach(func() {
+			method = "POST"
+			path = "/network_policies/delete"
+		})
+
+		It("deletes the policies", func() {
+			Expect(response.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(networkPolicyBifrost.DeletePoliciesCallCount()).To(Equal(1))
+			_, request := networkPolicyBifrost.DeletePoliciesArgsForCall(0)
+			Expect(request).To(Equal(expectedRequest))
+		})
+
+		When("the request body is invalid", func() {
+			BeforeEach(func() {
+				body = "{invalid"
+			})
+
+			It("returns a bad request status", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		When("deleting the policies fails", func() {
+			BeforeEach(func() {
+				networkPolicyBifrost.DeletePoliciesReturns(errors.New("boom"))
+			})
+
+			It("returns an internal server error", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+})
@@ -38,7 +38,7 @@ var _ = Describe("StageHandler", func() {
 	})
 
 	JustBeforeEach(func() {
-		handler := New(nil, buildpackStagingClient, dockerStagingClient, bifrostTaskClient, logger)
+		handler := New(nil, buildpackStagingClient, dockerStagingClient, bifrostTaskClient, nil, logger)
 		ts = httptest.NewServer(handler)
 		req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader([]byte(body)))
 		Expect(err).NotTo(HaveOccurred())
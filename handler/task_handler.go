@@ -62,7 +62,13 @@ func (t *Task) Run(resp http.ResponseWriter, req *http.Request, ps httprouter.Pa
 
 	if err := t.taskBifrost.TransferTask(req.Context(), taskGUID, taskRequest); err != nil {
 		logger.Error("task-request-task-create-failed", err)
-		writeErrorResponse(logger, resp, http.StatusInternalServerError, err)
+
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, eirini.ErrImageRejected) {
+			statusCode = http.StatusUnprocessableEntity
+		}
+
+		writeErrorResponse(logger, resp, statusCode, err)
 
 		return
 	}
@@ -39,7 +39,7 @@ var _ = Describe("TaskHandler", func() {
 
 	JustBeforeEach(func() {
 		logger = lagertest.NewTestLogger("test")
-		handler := New(nil, nil, nil, taskBifrost, logger)
+		handler := New(nil, nil, nil, taskBifrost, nil, logger)
 		ts = httptest.NewServer(handler)
 		req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader([]byte(body)))
 		Expect(err).NotTo(HaveOccurred())
@@ -117,6 +117,22 @@ var _ = Describe("TaskHandler", func() {
 			})
 		})
 
+		When("the task image is rejected by the admission policy", func() {
+			BeforeEach(func() {
+				taskBifrost.TransferTaskReturns(errors.Wrap(eirini.ErrImageRejected, `image "some/image" must be referenced by digest`))
+			})
+
+			It("should return 422 Unprocessable Entity code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			})
+
+			It("should return the reason", func() {
+				var cfErr cf.Error
+				Expect(json.NewDecoder(response.Body).Decode(&cfErr)).To(Succeed())
+				Expect(cfErr.Message).To(Equal(`image "some/image" must be referenced by digest: image rejected by admission policy`))
+			})
+		})
+
 		Context("when the request body cannot be unmarshalled", func() {
 			BeforeEach(func() {
 				body = "random stuff"
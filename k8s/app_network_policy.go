@@ -0,0 +1,366 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const networkPolicySourceType = "NETWORK_POLICY"
+
+//counterfeiter:generate . AppNetworkPolicyClient
+//counterfeiter:generate . AppStatefulSetLister
+
+type AppNetworkPolicyClient interface {
+	Create(namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error)
+	Update(namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error)
+	Delete(namespace string, name string) error
+	List(namespace, labelSelector string) ([]networkingv1.NetworkPolicy, error)
+}
+
+type AppStatefulSetLister interface {
+	GetByAppGUID(appGUID string) ([]appsv1.StatefulSet, error)
+}
+
+// AppNetworkPolicyDesirer maintains an ingress NetworkPolicy per destination
+// app, in the namespace of the app. The policies are stored in an annotation
+// of the NetworkPolicy and its spec is rendered from them. Once an app is the
+// destination of a policy, only the allowed apps and the routers selected by
+// RouterSelector can connect to it.
+type AppNetworkPolicyDesirer struct {
+	NetworkPolicies AppNetworkPolicyClient
+	StatefulSets    AppStatefulSetLister
+	// WorkloadsNamespace is where network policies are looked up. Empty
+	// means all namespaces.
+	WorkloadsNamespace string
+	RouterSelector     eirini.RouterSelector
+	Logger             lager.Logger
+}
+
+func (d *AppNetworkPolicyDesirer) Create(namespace string, policies []opi.NetworkPolicy) error {
+	logger := d.Logger.Session("create-network-policies", lager.Data{"namespace": namespace})
+
+	for _, destination := range destinations(policies) {
+		destination := destination
+
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			existing, current, err := d.get(destination)
+			if err != nil {
+				return err
+			}
+
+			policyNamespace := namespace
+			if existing != nil {
+				policyNamespace = existing.Namespace
+			} else if policyNamespace, err = d.appNamespace(destination, namespace); err != nil {
+				return err
+			}
+
+			return d.apply(logger, policyNamespace, existing, destination, addPolicies(current, policies, destination))
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create network policies for app %q", destination)
+		}
+	}
+
+	return nil
+}
+
+func (d *AppNetworkPolicyDesirer) Delete(policies []opi.NetworkPolicy) error {
+	logger := d.Logger.Session("delete-network-policies")
+
+	for _, destination := range destinations(policies) {
+		destination := destination
+
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			existing, current, err := d.get(destination)
+			if err != nil || existing == nil {
+				return err
+			}
+
+			return d.apply(logger, existing.Namespace, existing, destination, removePolicies(current, policies))
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete network policies for app %q", destination)
+		}
+	}
+
+	return nil
+}
+
+func (d *AppNetworkPolicyDesirer) List() ([]opi.NetworkPolicy, error) {
+	networkPolicies, err := d.NetworkPolicies.List(d.WorkloadsNamespace, fmt.Sprintf("%s=%s", LabelSourceType, networkPolicySourceType))
+	if err != nil {
+		return nil, err
+	}
+
+	policies := []opi.NetworkPolicy{}
+
+	for i := range networkPolicies {
+		current, readErr := storedPolicies(&networkPolicies[i])
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		policies = append(policies, current...)
+	}
+
+	return policies, nil
+}
+
+// Resync renders all network policies again from the policies stored in
+// their annotations, reverting any drift.
+func (d *AppNetworkPolicyDesirer) Resync() error {
+	logger := d.Logger.Session("resync-network-policies")
+
+	networkPolicies, err := d.NetworkPolicies.List(d.WorkloadsNamespace, fmt.Sprintf("%s=%s", LabelSourceType, networkPolicySourceType))
+	if err != nil {
+		logger.Error("failed-to-list-network-policies", err)
+
+		return err
+	}
+
+	var errs *multierror.Error
+
+	for i := range networkPolicies {
+		existing := &networkPolicies[i]
+
+		current, readErr := storedPolicies(existing)
+		if readErr != nil {
+			logger.Error("failed-to-read-network-policies", readErr, lager.Data{"name": existing.Name, "namespace": existing.Namespace})
+			errs = multierror.Append(errs, readErr)
+
+			continue
+		}
+
+		if err = d.apply(logger, existing.Namespace, existing, existing.Labels[LabelAppGUID], current); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// appNamespace is where the instances of the app run, as a NetworkPolicy only
+// selects pods of its own namespace. Apps that aren't running yet default to
+// the requested namespace.
+func (d *AppNetworkPolicyDesirer) appNamespace(appGUID, requestedNamespace string) (string, error) {
+	statefulSets, err := d.StatefulSets.GetByAppGUID(appGUID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list statefulsets of the destination app")
+	}
+
+	if len(statefulSets) == 0 {
+		return requestedNamespace, nil
+	}
+
+	return statefulSets[0].Namespace, nil
+}
+
+func (d *AppNetworkPolicyDesirer) get(destination string) (*networkingv1.NetworkPolicy, []opi.NetworkPolicy, error) {
+	networkPolicies, err := d.NetworkPolicies.List(d.WorkloadsNamespace, fmt.Sprintf(
+		"%s=%s,%s=%s",
+		LabelSourceType, networkPolicySourceType,
+		LabelAppGUID, destination,
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(networkPolicies) == 0 {
+		return nil, []opi.NetworkPolicy{}, nil
+	}
+
+	existing := &networkPolicies[0]
+
+	current, err := storedPolicies(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return existing, current, nil
+}
+
+func (d *AppNetworkPolicyDesirer) apply(logger lager.Logger, namespace string, existing *networkingv1.NetworkPolicy, destination string, policies []opi.NetworkPolicy) error {
+	logger = logger.WithData(lager.Data{"destination": destination, "namespace": namespace})
+
+	if len(policies) == 0 {
+		if existing == nil {
+			return nil
+		}
+
+		err := d.NetworkPolicies.Delete(existing.Namespace, existing.Name)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error("failed-to-delete-network-policy", err)
+
+			return errors.Wrap(err, "failed to delete network policy")
+		}
+
+		return nil
+	}
+
+	networkPolicy, err := d.toAppNetworkPolicy(logger, destination, policies)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		_, err = d.NetworkPolicies.Create(namespace, networkPolicy)
+	} else {
+		networkPolicy.ResourceVersion = existing.ResourceVersion
+		_, err = d.NetworkPolicies.Update(namespace, networkPolicy)
+	}
+
+	if err != nil {
+		logger.Error("failed-to-apply-network-policy", err)
+
+		return errors.Wrap(err, "failed to apply network policy")
+	}
+
+	return nil
+}
+
+func (d *AppNetworkPolicyDesirer) toAppNetworkPolicy(logger lager.Logger, destination string, policies []opi.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	annotation, err := json.Marshal(policies)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal network policies")
+	}
+
+	ingressRules := []networkingv1.NetworkPolicyIngressRule{}
+	if rule, ok := routerIngressRule(d.RouterSelector); ok {
+		ingressRules = append(ingressRules, rule)
+	}
+
+	for _, policy := range policies {
+		ports, portsErr := toNetworkPolicyPorts(logger, opi.EgressRule{
+			Protocol:  policy.Protocol,
+			PortRange: &opi.PortRange{Start: policy.Ports.Start, End: policy.Ports.End},
+		})
+		if portsErr != nil {
+			return nil, portsErr
+		}
+
+		ingressRules = append(ingressRules, networkingv1.NetworkPolicyIngressRule{
+			From: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{},
+					PodSelector:       appPodSelector(policy.SourceAppGUID),
+				},
+			},
+			Ports: ports,
+		})
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("ingress-%s", destination),
+			Labels: map[string]string{
+				LabelAppGUID:    destination,
+				LabelSourceType: networkPolicySourceType,
+			},
+			Annotations: map[string]string{
+				AnnotationNetworkPolicies: string(annotation),
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: *appPodSelector(destination),
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     ingressRules,
+		},
+	}, nil
+}
+
+func appPodSelector(appGUID string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			LabelAppGUID:    appGUID,
+			LabelSourceType: appSourceType,
+		},
+	}
+}
+
+// routerIngressRule lets the routers reach the app on any port. Without a
+// router selector no rule is added, so that an app with network policies is
+// only reachable by the allowed apps.
+func routerIngressRule(selector eirini.RouterSelector) (networkingv1.NetworkPolicyIngressRule, bool) {
+	if len(selector.NamespaceLabels) == 0 && len(selector.PodLabels) == 0 {
+		return networkingv1.NetworkPolicyIngressRule{}, false
+	}
+
+	return networkingv1.NetworkPolicyIngressRule{
+		From: []networkingv1.NetworkPolicyPeer{
+			{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: selector.NamespaceLabels},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: selector.PodLabels},
+			},
+		},
+	}, true
+}
+
+func storedPolicies(networkPolicy *networkingv1.NetworkPolicy) ([]opi.NetworkPolicy, error) {
+	policies := []opi.NetworkPolicy{}
+
+	if err := json.Unmarshal([]byte(networkPolicy.Annotations[AnnotationNetworkPolicies]), &policies); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal policies of network policy %q", networkPolicy.Name)
+	}
+
+	return policies, nil
+}
+
+func destinations(policies []opi.NetworkPolicy) []string {
+	seen := map[string]bool{}
+	result := []string{}
+
+	for _, policy := range policies {
+		if !seen[policy.DestinationAppGUID] {
+			seen[policy.DestinationAppGUID] = true
+			result = append(result, policy.DestinationAppGUID)
+		}
+	}
+
+	return result
+}
+
+func addPolicies(current, added []opi.NetworkPolicy, destination string) []opi.NetworkPolicy {
+	result := append([]opi.NetworkPolicy{}, current...)
+
+	for _, policy := range added {
+		if policy.DestinationAppGUID == destination && !containsPolicy(result, policy) {
+			result = append(result, policy)
+		}
+	}
+
+	return result
+}
+
+func removePolicies(current, removed []opi.NetworkPolicy) []opi.NetworkPolicy {
+	result := []opi.NetworkPolicy{}
+
+	for _, policy := range current {
+		if !containsPolicy(removed, policy) {
+			result = append(result, policy)
+		}
+	}
+
+	return result
+}
+
+func containsPolicy(policies []opi.NetworkPolicy, policy opi.NetworkPolicy) bool {
+	for _, p := range policies {
+		if p == policy {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,425 @@
+package k8s_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("AppNetworkPolicyDesirer", func() {
+	var (
+		networkPolicyClient *k8sfakes.FakeAppNetworkPolicyClient
+		statefulSetLister   *k8sfakes.FakeAppStatefulSetLister
+		desirer             *k8s.AppNetworkPolicyDesirer
+		policy              opi.NetworkPolicy
+	)
+
+	storedNetworkPolicy := func(namespace, destination, annotation string) networkingv1.NetworkPolicy {
+		return networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "ingress-" + destination,
+				Namespace:       namespace,
+				ResourceVersion: "42",
+				Labels: map[string]string{
+					k8s.LabelAppGUID:    destination,
+					k8s.LabelSourceType: "NETWORK_POLICY",
+				},
+				Annotations: map[string]string{k8s.AnnotationNetworkPolicies: annotation},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		networkPolicyClient = new(k8sfakes.FakeAppNetworkPolicyClient)
+		networkPolicyClient.ListReturns([]networkingv1.NetworkPolicy{}, nil)
+		statefulSetLister = new(k8sfakes.FakeAppStatefulSetLister)
+		statefulSetLister.GetByAppGUIDReturns([]appsv1.StatefulSet{
+			{ObjectMeta: metav1.ObjectMeta{Name: "app-b-statefulset", Namespace: "app-b-namespace"}},
+		}, nil)
+
+		desirer = &k8s.AppNetworkPolicyDesirer{
+			NetworkPolicies:    networkPolicyClient,
+			StatefulSets:       statefulSetLister,
+			WorkloadsNamespace: "workloads",
+			RouterSelector: eirini.RouterSelector{
+				NamespaceLabels: map[string]string{"name": "routing"},
+				PodLabels:       map[string]string{"app": "gorouter"},
+			},
+			Logger: lagertest.NewTestLogger("app-network-policy-test"),
+		}
+
+		policy = opi.NetworkPolicy{
+			SourceAppGUID:      "app-a",
+			DestinationAppGUID: "app-b",
+			Protocol:           "tcp",
+			Ports:              opi.PortRange{Start: 8080, End: 8081},
+		}
+	})
+
+	Describe("Create", func() {
+		var createErr error
+
+		JustBeforeEach(func() {
+			createErr = desirer.Create("the-namespace", []opi.NetworkPolicy{policy})
+		})
+
+		It("looks up the network policy of the destination app", func() {
+			Expect(networkPolicyClient.ListCallCount()).To(Equal(1))
+			namespace, labelSelector := networkPolicyClient.ListArgsForCall(0)
+			Expect(namespace).To(Equal("workloads"))
+			Expect(labelSelector).To(Equal("cloudfoundry.org/source_type=NETWORK_POLICY,cloudfoundry.org/app_guid=app-b"))
+		})
+
+		It("looks up the statefulsets of the destination app", func() {
+			Expect(statefulSetLister.GetByAppGUIDCallCount()).To(Equal(1))
+			Expect(statefulSetLister.GetByAppGUIDArgsForCall(0)).To(Equal("app-b"))
+		})
+
+		It("creates an ingress network policy for the destination app in its namespace", func() {
+			Expect(createErr).NotTo(HaveOccurred())
+			Expect(networkPolicyClient.CreateCallCount()).To(Equal(1))
+
+			namespace, networkPolicy := networkPolicyClient.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("app-b-namespace"))
+			Expect(networkPolicy.Name).To(Equal("ingress-app-b"))
+			Expect(networkPolicy.Labels).To(Equal(map[string]string{
+				k8s.LabelAppGUID:    "app-b",
+				k8s.LabelSourceType: "NETWORK_POLICY",
+			}))
+			Expect(networkPolicy.Annotations).To(HaveKeyWithValue(k8s.AnnotationNetworkPolicies,
+				`[{"sourceAppGUID":"app-a","destinationAppGUID":"app-b","protocol":"tcp","ports":{"start":8080,"end":8081}}]`))
+			Expect(networkPolicy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{
+				k8s.LabelAppGUID:    "app-b",
+				k8s.LabelSourceType: "APP",
+			}))
+			Expect(networkPolicy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress))
+		})
+
+		It("allows ingress from the source app instances on the policy ports", func() {
+			_, networkPolicy := networkPolicyClient.CreateArgsForCall(0)
+			Expect(networkPolicy.Spec.Ingress).To(HaveLen(2))
+
+			tcp := corev1.ProtocolTCP
+			port8080, port8081 := intstr.FromInt(8080), intstr.FromInt(8081)
+			Expect(networkPolicy.Spec.Ingress[1]).To(Equal(networkingv1.NetworkPolicyIngressRule{
+				From: []networkingv1.NetworkPolicyPeer{
+					{
+						NamespaceSelector: &metav1.LabelSelector{},
+						PodSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{k8s.LabelAppGUID: "app-a", k8s.LabelSourceType: "APP"},
+						},
+					},
+				},
+				Ports: []networkingv1.NetworkPolicyPort{
+					{Protocol: &tcp, Port: &port8080},
+					{Protocol: &tcp, Port: &port8081},
+				},
+			}))
+		})
+
+		It("keeps allowing ingress from the routers", func() {
+			_, networkPolicy := networkPolicyClient.CreateArgsForCall(0)
+			Expect(networkPolicy.Spec.Ingress[0].Ports).To(BeEmpty())
+			Expect(networkPolicy.Spec.Ingress[0].From).To(ConsistOf(networkingv1.NetworkPolicyPeer{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "routing"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "gorouter"}},
+			}))
+		})
+
+		When("no router selector is configured", func() {
+			BeforeEach(func() {
+				desirer.RouterSelector = eirini.RouterSelector{}
+			})
+
+			It("only allows ingress from the source app", func() {
+				_, networkPolicy := networkPolicyClient.CreateArgsForCall(0)
+				Expect(networkPolicy.Spec.Ingress).To(HaveLen(1))
+				Expect(networkPolicy.Spec.Ingress[0].From[0].PodSelector.MatchLabels).To(HaveKeyWithValue(k8s.LabelAppGUID, "app-a"))
+			})
+		})
+
+		When("the destination app is not running", func() {
+			BeforeEach(func() {
+				statefulSetLister.GetByAppGUIDReturns([]appsv1.StatefulSet{}, nil)
+			})
+
+			It("creates the network policy in the requested namespace", func() {
+				Expect(createErr).NotTo(HaveOccurred())
+				namespace, _ := networkPolicyClient.CreateArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+			})
+		})
+
+		When("listing the statefulsets of the destination app fails", func() {
+			BeforeEach(func() {
+				statefulSetLister.GetByAppGUIDReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(createErr).To(MatchError(ContainSubstring("failed to list statefulsets of the destination app")))
+				Expect(networkPolicyClient.CreateCallCount()).To(BeZero())
+			})
+		})
+
+		When("the destination app already has a network policy", func() {
+			BeforeEach(func() {
+				networkPolicyClient.ListReturns([]networkingv1.NetworkPolicy{
+					storedNetworkPolicy("other-namespace", "app-b",
+						`[{"sourceAppGUID":"app-c","destinationAppGUID":"app-b","protocol":"udp","ports":{"start":53,"end":53}}]`),
+				}, nil)
+			})
+
+			It("adds the policy to it", func() {
+				Expect(createErr).NotTo(HaveOccurred())
+				Expect(statefulSetLister.GetByAppGUIDCallCount()).To(BeZero())
+				Expect(networkPolicyClient.CreateCallCount()).To(BeZero())
+				Expect(networkPolicyClient.UpdateCallCount()).To(Equal(1))
+
+				namespace, networkPolicy := networkPolicyClient.UpdateArgsForCall(0)
+				Expect(namespace).To(Equal("other-namespace"))
+				Expect(networkPolicy.ResourceVersion).To(Equal("42"))
+				Expect(networkPolicy.Spec.Ingress).To(HaveLen(3))
+				Expect(networkPolicy.Annotations[k8s.AnnotationNetworkPolicies]).To(ContainSubstring(`"sourceAppGUID":"app-c"`))
+				Expect(networkPolicy.Annotations[k8s.AnnotationNetworkPolicies]).To(ContainSubstring(`"sourceAppGUID":"app-a"`))
+			})
+
+			When("the update conflicts", func() {
+				BeforeEach(func() {
+					networkPolicyClient.UpdateReturnsOnCall(0, nil, k8serrors.NewConflict(schema.GroupResource{}, "ingress-app-b", errors.New("conflict")))
+				})
+
+				It("retries", func() {
+					Expect(createErr).NotTo(HaveOccurred())
+					Expect(networkPolicyClient.UpdateCallCount()).To(Equal(2))
+				})
+			})
+		})
+
+		When("the policy already exists", func() {
+			BeforeEach(func() {
+				networkPolicyClient.ListReturns([]networkingv1.NetworkPolicy{
+					storedNetworkPolicy("the-namespace", "app-b",
+						`[{"sourceAppGUID":"app-a","destinationAppGUID":"app-b","protocol":"tcp","ports":{"start":8080,"end":8081}}]`),
+				}, nil)
+			})
+
+			It("does not duplicate it", func() {
+				_, networkPolicy := networkPolicyClient.UpdateArgsForCall(0)
+				Expect(networkPolicy.Spec.Ingress).To(HaveLen(2))
+			})
+		})
+
+		When("the stored policies cannot be parsed", func() {
+			BeforeEach(func() {
+				networkPolicyClient.ListReturns([]networkingv1.NetworkPolicy{
+					storedNetworkPolicy("the-namespace", "app-b", "{"),
+				}, nil)
+			})
+
+			It("returns an error", func() {
+				Expect(createErr).To(MatchError(ContainSubstring("failed to unmarshal policies")))
+				Expect(networkPolicyClient.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		When("creating the network policy fails", func() {
+			BeforeEach(func() {
+				networkPolicyClient.CreateReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(createErr).To(MatchError(ContainSubstring(`failed to create network policies for app "app-b"`)))
+			})
+		})
+
+		When("listing the network policies fails", func() {
+			BeforeEach(func() {
+				networkPolicyClient.ListReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(createErr).To(MatchError(ContainSubstring("boom")))
+				Expect(networkPolicyClient.CreateCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		var deleteErr error
+
+		JustBeforeEach(func() {
+			deleteErr = desirer.Delete([]opi.NetworkPolicy{policy})
+		})
+
+		When("the destination app has no network policy", func() {
+			It("does nothing", func() {
+				Expect(deleteErr).NotTo(HaveOccurred())
+				Expect(networkPolicyClient.DeleteCallCount()).To(BeZero())
+				Expect(networkPolicyClient.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		When("other policies remain", func() {
+			BeforeEach(func() {
+				networkPolicyClient.ListReturns([]networkingv1.NetworkPolicy{
+					storedNetworkPolicy("the-namespace", "app-b", `[`+
+						`{"sourceAppGUID":"app-a","destinationAppGUID":"app-b","protocol":"tcp","ports":{"start":8080,"end":8081}},`+
+						`{"sourceAppGUID":"app-c","destinationAppGUID":"app-b","protocol":"udp","ports":{"start":53,"end":53}}]`),
+				}, nil)
+			})
+
+			It("removes the policy from the network policy", func() {
+				Expect(deleteErr).NotTo(HaveOccurred())
+				Expect(networkPolicyClient.UpdateCallCount()).To(Equal(1))
+
+				namespace, networkPolicy := networkPolicyClient.UpdateArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(networkPolicy.Annotations).To(HaveKeyWithValue(k8s.AnnotationNetworkPolicies,
+					`[{"sourceAppGUID":"app-c","destinationAppGUID":"app-b","protocol":"udp","ports":{"start":53,"end":53}}]`))
+				Expect(networkPolicy.Spec.Ingress).To(HaveLen(2))
+			})
+		})
+
+		When("it is the last policy of the destination app", func() {
+			BeforeEach(func() {
+				networkPolicyClient.ListReturns([]networkingv1.NetworkPolicy{
+					storedNetworkPolicy("the-namespace", "app-b",
+						`[{"sourceAppGUID":"app-a","destinationAppGUID":"app-b","protocol":"tcp","ports":{"start":8080,"end":8081}}]`),
+				}, nil)
+			})
+
+			It("deletes the network policy", func() {
+				Expect(deleteErr).NotTo(HaveOccurred())
+				Expect(networkPolicyClient.DeleteCallCount()).To(Equal(1))
+				namespace, name := networkPolicyClient.DeleteArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(name).To(Equal("ingress-app-b"))
+			})
+
+			When("the network policy is already gone", func() {
+				BeforeEach(func() {
+					networkPolicyClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "ingress-app-b"))
+				})
+
+				It("succeeds", func() {
+					Expect(deleteErr).NotTo(HaveOccurred())
+				})
+			})
+
+			When("deleting the network policy fails", func() {
+				BeforeEach(func() {
+					networkPolicyClient.DeleteReturns(errors.New("boom"))
+				})
+
+				It("returns an error", func() {
+					Expect(deleteErr).To(MatchError(ContainSubstring("failed to delete network policy")))
+				})
+			})
+		})
+	})
+
+	Describe("List", func() {
+		var (
+			policies []opi.NetworkPolicy
+			listErr  error
+		)
+
+		BeforeEach(func() {
+			networkPolicyClient.ListReturns([]networkingv1.NetworkPolicy{
+				storedNetworkPolicy("the-namespace", "app-b",
+					`[{"sourceAppGUID":"app-a","destinationAppGUID":"app-b","protocol":"tcp","ports":{"start":8080,"end":8081}}]`),
+				storedNetworkPolicy("the-namespace", "app-c",
+					`[{"sourceAppGUID":"app-b","destinationAppGUID":"app-c","protocol":"udp","ports":{"start":53,"end":53}}]`),
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			policies, listErr = desirer.List()
+		})
+
+		It("lists the network policies created by eirini", func() {
+			namespace, labelSelector := networkPolicyClient.ListArgsForCall(0)
+			Expect(namespace).To(Equal("workloads"))
+			Expect(labelSelector).To(Equal("cloudfoundry.org/source_type=NETWORK_POLICY"))
+		})
+
+		It("returns the stored policies", func() {
+			Expect(listErr).NotTo(HaveOccurred())
+			Expect(policies).To(ConsistOf(
+				policy,
+				opi.NetworkPolicy{SourceAppGUID: "app-b", DestinationAppGUID: "app-c", Protocol: "udp", Ports: opi.PortRange{Start: 53, End: 53}},
+			))
+		})
+
+		When("listing fails", func() {
+			BeforeEach(func() {
+				networkPolicyClient.ListReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(listErr).To(MatchError(ContainSubstring("boom")))
+			})
+		})
+	})
+
+	Describe("Resync", func() {
+		var resyncErr error
+
+		BeforeEach(func() {
+			networkPolicyClient.ListReturns([]networkingv1.NetworkPolicy{
+				storedNetworkPolicy("ns-1", "app-b",
+					`[{"sourceAppGUID":"app-a","destinationAppGUID":"app-b","protocol":"tcp","ports":{"start":8080,"end":8081}}]`),
+				storedNetworkPolicy("ns-2", "app-c", `not-json`),
+				storedNetworkPolicy("ns-3", "app-d", `[]`),
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			resyncErr = desirer.Resync()
+		})
+
+		It("renders the network policies from their stored policies", func() {
+			Expect(networkPolicyClient.UpdateCallCount()).To(Equal(1))
+
+			namespace, networkPolicy := networkPolicyClient.UpdateArgsForCall(0)
+			Expect(namespace).To(Equal("ns-1"))
+			Expect(networkPolicy.Name).To(Equal("ingress-app-b"))
+			Expect(networkPolicy.ResourceVersion).To(Equal("42"))
+			Expect(networkPolicy.Spec.Ingress).To(HaveLen(2))
+		})
+
+		It("deletes network policies without policies", func() {
+			Expect(networkPolicyClient.DeleteCallCount()).To(Equal(1))
+			namespace, name := networkPolicyClient.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("ns-3"))
+			Expect(name).To(Equal("ingress-app-d"))
+		})
+
+		It("reports the network policies that cannot be read", func() {
+			Expect(resyncErr).To(MatchError(ContainSubstring(`failed to unmarshal policies of network policy "ingress-app-c"`)))
+		})
+
+		When("listing fails", func() {
+			BeforeEach(func() {
+				networkPolicyClient.ListReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(resyncErr).To(MatchError(ContainSubstring("boom")))
+			})
+		})
+	})
+})
@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (m *StatefulSetDesirer) handleAutoscaler(logger lager.Logger, namespace, name string, lrp *opi.LRP) error {
+	if lrp.Autoscaling == nil {
+		return m.deleteAutoscaler(logger, namespace, name)
+	}
+
+	autoscaler := toHorizontalPodAutoscaler(name, lrp)
+
+	_, err := m.HorizontalPodAutoscalers.Create(namespace, autoscaler)
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = m.HorizontalPodAutoscalers.Update(namespace, autoscaler)
+	}
+
+	if err != nil {
+		logger.Error("failed-to-create-horizontal-pod-autoscaler", err, lager.Data{"namespace": namespace})
+
+		return errors.Wrap(err, "failed to create horizontal pod autoscaler")
+	}
+
+	return nil
+}
+
+func (m *StatefulSetDesirer) deleteAutoscaler(logger lager.Logger, namespace, name string) error {
+	err := m.HorizontalPodAutoscalers.Delete(namespace, name)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		logger.Error("failed-to-delete-horizontal-pod-autoscaler", err, lager.Data{"namespace": namespace})
+
+		return errors.Wrap(err, "failed to delete horizontal pod autoscaler")
+	}
+
+	return nil
+}
+
+func toHorizontalPodAutoscaler(name string, lrp *opi.LRP) *autoscalingv2beta2.HorizontalPodAutoscaler {
+	policy := lrp.Autoscaling
+	minReplicas := policy.MinInstances
+	if minReplicas < 1 {
+		minReplicas = 1
+	}
+
+	metrics := []autoscalingv2beta2.MetricSpec{}
+
+	if policy.TargetCPUPercent > 0 {
+		metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceCPU, policy.TargetCPUPercent))
+	}
+
+	if policy.TargetMemoryPercent > 0 {
+		metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceMemory, policy.TargetMemoryPercent))
+	}
+
+	return &autoscalingv2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				LabelGUID:       lrp.GUID,
+				LabelVersion:    lrp.Version,
+				LabelAppGUID:    lrp.AppGUID,
+				LabelSourceType: appSourceType,
+			},
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "StatefulSet",
+				Name:       name,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: policy.MaxInstances,
+			Metrics:     metrics,
+		},
+	}
+}
+
+func resourceUtilizationMetric(name corev1.ResourceName, targetPercent int32) autoscalingv2beta2.MetricSpec {
+	return autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ResourceMetricSourceType,
+		Resource: &autoscalingv2beta2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2beta2.MetricTarget{
+				Type:               autoscalingv2beta2.UtilizationMetricType,
+				AverageUtilization: &targetPercent,
+			},
+		},
+	}
+}
+
+func autoscalingAnnotation(lrp *opi.LRP) (string, error) {
+	policy, err := json.Marshal(lrp.Autoscaling)
+
+	return string(policy), errors.Wrap(err, "failed to marshal autoscaling policy")
+}
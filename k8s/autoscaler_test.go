@@ -0,0 +1,244 @@
+package k8s_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("Autoscaling", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		hpaClient         *k8sfakes.FakeHorizontalPodAutoscalerClient
+		desirer           *k8s.StatefulSetDesirer
+		lrp               *opi.LRP
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+		statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+		hpaClient = new(k8sfakes.FakeHorizontalPodAutoscalerClient)
+		probeCreator := new(k8sfakes.FakeProbeCreator)
+
+		desirer = &k8s.StatefulSetDesirer{
+			Pods:                     new(k8sfakes.FakePodClient),
+			Secrets:                  new(k8sfakes.FakeSecretsClient),
+			StatefulSets:             statefulSetClient,
+			PodDisruptionBudgets:     new(k8sfakes.FakePodDisruptionBudgetClient),
+			NetworkPolicies:          new(k8sfakes.FakeNetworkPolicyClient),
+			HorizontalPodAutoscalers: hpaClient,
+			Services:                 new(k8sfakes.FakeServiceClient),
+			EventsClient:             new(k8sfakes.FakeEventsClient),
+			StatefulSetToLRPMapper:   new(k8sfakes.FakeLRPMapper).Spy,
+			LivenessProbeCreator:     probeCreator.Spy,
+			ReadinessProbeCreator:    probeCreator.Spy,
+			StartupProbeCreator:      probeCreator.Spy,
+			Logger:                   lagertest.NewTestLogger("autoscaling-test"),
+		}
+
+		lrp = createLRP("Baldur", []opi.Route{})
+		lrp.Autoscaling = &opi.AutoscalingPolicy{MinInstances: 2, MaxInstances: 10, TargetCPUPercent: 70}
+	})
+
+	Describe("Desire", func() {
+		var desireErr error
+
+		JustBeforeEach(func() {
+			desireErr = desirer.Desire("the-namespace", lrp)
+		})
+
+		It("creates a horizontal pod autoscaler targeting the statefulset", func() {
+			Expect(desireErr).NotTo(HaveOccurred())
+			Expect(hpaClient.CreateCallCount()).To(Equal(1))
+
+			namespace, hpa := hpaClient.CreateArgsForCall(0)
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(hpa.Name).To(Equal(statefulSet.Name))
+			Expect(hpa.Labels).To(HaveKeyWithValue(k8s.LabelGUID, lrp.GUID))
+			Expect(hpa.Spec.ScaleTargetRef).To(Equal(autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "StatefulSet",
+				Name:       statefulSet.Name,
+			}))
+			Expect(*hpa.Spec.MinReplicas).To(Equal(int32(2)))
+			Expect(hpa.Spec.MaxReplicas).To(Equal(int32(10)))
+			Expect(hpa.Spec.Metrics).To(HaveLen(1))
+			Expect(hpa.Spec.Metrics[0].Resource.Name).To(Equal(corev1.ResourceCPU))
+			Expect(*hpa.Spec.Metrics[0].Resource.Target.AverageUtilization).To(Equal(int32(70)))
+		})
+
+		It("stores the autoscaling policy in an annotation", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationAutoscaling, `{"minInstances":2,"maxInstances":10,"targetCPUPercent":70}`))
+		})
+
+		When("a memory target is set", func() {
+			BeforeEach(func() {
+				lrp.Autoscaling.TargetMemoryPercent = 80
+			})
+
+			It("scales on memory utilization as well", func() {
+				_, hpa := hpaClient.CreateArgsForCall(0)
+				Expect(hpa.Spec.Metrics).To(HaveLen(2))
+				Expect(hpa.Spec.Metrics[1].Resource.Name).To(Equal(corev1.ResourceMemory))
+				Expect(*hpa.Spec.Metrics[1].Resource.Target.AverageUtilization).To(Equal(int32(80)))
+			})
+		})
+
+		When("the autoscaler already exists", func() {
+			BeforeEach(func() {
+				hpaClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "baldur"))
+			})
+
+			It("updates it", func() {
+				Expect(desireErr).NotTo(HaveOccurred())
+				Expect(hpaClient.UpdateCallCount()).To(Equal(1))
+			})
+		})
+
+		When("creating the autoscaler fails", func() {
+			BeforeEach(func() {
+				hpaClient.CreateReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("failed to create horizontal pod autoscaler")))
+			})
+		})
+
+		When("the app is not autoscaled", func() {
+			BeforeEach(func() {
+				lrp.Autoscaling = nil
+			})
+
+			It("does not touch autoscalers", func() {
+				Expect(hpaClient.CreateCallCount()).To(BeZero())
+				Expect(hpaClient.DeleteCallCount()).To(BeZero())
+			})
+		})
+
+		When("the statefulset has drifted", func() {
+			BeforeEach(func() {
+				statefulSetClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "baldur"))
+				statefulSetClient.GetReturns(&appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{k8s.LabelGUID: lrp.GUID, k8s.LabelVersion: lrp.Version},
+					},
+					Spec: appsv1.StatefulSetSpec{Replicas: int32ptr(7)},
+				}, nil)
+			})
+
+			It("keeps the replicas chosen by the autoscaler", func() {
+				Expect(desireErr).NotTo(HaveOccurred())
+				_, patched := statefulSetClient.UpdateArgsForCall(0)
+				Expect(*patched.Spec.Replicas).To(Equal(int32(7)))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		var updateErr error
+
+		BeforeEach(func() {
+			lrp.TargetInstances = 5
+			statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "baldur",
+						Namespace:   "the-namespace",
+						Annotations: map[string]string{},
+					},
+					Spec: appsv1.StatefulSetSpec{
+						Replicas: int32ptr(3),
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: k8s.OPIContainerName}}},
+						},
+					},
+				},
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			updateErr = desirer.Update(lrp)
+		})
+
+		It("does not overwrite the replicas managed by the autoscaler", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			_, updated := statefulSetClient.UpdateArgsForCall(0)
+			Expect(*updated.Spec.Replicas).To(Equal(int32(3)))
+			Expect(updated.Annotations).To(HaveKey(k8s.AnnotationAutoscaling))
+		})
+
+		It("applies the autoscaling policy", func() {
+			Expect(hpaClient.CreateCallCount()).To(Equal(1))
+			namespace, hpa := hpaClient.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(hpa.Name).To(Equal("baldur"))
+		})
+
+		When("autoscaling is turned off", func() {
+			BeforeEach(func() {
+				lrp.Autoscaling = nil
+			})
+
+			It("sets the requested replicas", func() {
+				_, updated := statefulSetClient.UpdateArgsForCall(0)
+				Expect(*updated.Spec.Replicas).To(Equal(int32(5)))
+				Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationAutoscaling))
+			})
+
+			It("deletes the autoscaler", func() {
+				Expect(hpaClient.DeleteCallCount()).To(Equal(1))
+				namespace, name := hpaClient.DeleteArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(name).To(Equal("baldur"))
+			})
+
+			When("the autoscaler does not exist", func() {
+				BeforeEach(func() {
+					hpaClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+				})
+
+				It("succeeds", func() {
+					Expect(updateErr).NotTo(HaveOccurred())
+				})
+			})
+
+			When("deleting the autoscaler fails", func() {
+				BeforeEach(func() {
+					hpaClient.DeleteReturns(errors.New("boom"))
+				})
+
+				It("returns an error", func() {
+					Expect(updateErr).To(MatchError(ContainSubstring("failed to delete horizontal pod autoscaler")))
+				})
+			})
+		})
+	})
+
+	Describe("Stop", func() {
+		It("deletes the autoscaler", func() {
+			statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{
+				{ObjectMeta: metav1.ObjectMeta{Name: "baldur", Namespace: "the-namespace"}},
+			}, nil)
+
+			Expect(desirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			Expect(hpaClient.DeleteCallCount()).To(Equal(1))
+			namespace, name := hpaClient.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(name).To(Equal("baldur"))
+		})
+	})
+})
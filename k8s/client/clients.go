@@ -9,8 +9,10 @@ import (
 	"code.cloudfoundry.org/eirini/opi"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -57,6 +59,15 @@ func (c *Pod) Delete(namespace, name string) error {
 	return c.clientSet.CoreV1().Pods(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
 }
 
+func (c *Pod) Evict(namespace, name string) error {
+	return c.clientSet.CoreV1().Pods(namespace).Evict(context.Background(), &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	})
+}
+
 func (c *Pod) SetAnnotation(pod *corev1.Pod, key, value string) (*corev1.Pod, error) {
 	patchBytes := patching.NewAnnotation(key, value).GetJSONPatchBytes()
 
@@ -85,6 +96,107 @@ func (c *PodDisruptionBudget) Delete(namespace string, name string) error {
 	return c.clientSet.PolicyV1beta1().PodDisruptionBudgets(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
 }
 
+type NetworkPolicy struct {
+	clientSet kubernetes.Interface
+}
+
+func NewNetworkPolicy(clientSet kubernetes.Interface) *NetworkPolicy {
+	return &NetworkPolicy{clientSet: clientSet}
+}
+
+func (c *NetworkPolicy) Create(namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	return c.clientSet.NetworkingV1().NetworkPolicies(namespace).Create(context.Background(), networkPolicy, metav1.CreateOptions{})
+}
+
+func (c *NetworkPolicy) Update(namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	return c.clientSet.NetworkingV1().NetworkPolicies(namespace).Update(context.Background(), networkPolicy, metav1.UpdateOptions{})
+}
+
+func (c *NetworkPolicy) Delete(namespace string, name string) error {
+	return c.clientSet.NetworkingV1().NetworkPolicies(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+func (c *NetworkPolicy) List(namespace, labelSelector string) ([]networkingv1.NetworkPolicy, error) {
+	policyList, err := c.clientSet.NetworkingV1().NetworkPolicies(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list network policies")
+	}
+
+	return policyList.Items, nil
+}
+
+type HorizontalPodAutoscaler struct {
+	clientSet kubernetes.Interface
+}
+
+func NewHorizontalPodAutoscaler(clientSet kubernetes.Interface) *HorizontalPodAutoscaler {
+	return &HorizontalPodAutoscaler{clientSet: clientSet}
+}
+
+func (c *HorizontalPodAutoscaler) Create(namespace string, autoscaler *autoscalingv2beta2.HorizontalPodAutoscaler) (*autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+	return c.clientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Create(context.Background(), autoscaler, metav1.CreateOptions{})
+}
+
+func (c *HorizontalPodAutoscaler) Update(namespace string, autoscaler *autoscalingv2beta2.HorizontalPodAutoscaler) (*autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+	return c.clientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Update(context.Background(), autoscaler, metav1.UpdateOptions{})
+}
+
+func (c *HorizontalPodAutoscaler) Delete(namespace string, name string) error {
+	return c.clientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+type Service struct {
+	clientSet kubernetes.Interface
+}
+
+func NewService(clientSet kubernetes.Interface) *Service {
+	return &Service{clientSet: clientSet}
+}
+
+func (c *Service) Create(namespace string, service *corev1.Service) (*corev1.Service, error) {
+	return c.clientSet.CoreV1().Services(namespace).Create(context.Background(), service, metav1.CreateOptions{})
+}
+
+// Update replaces the service. Services cannot be updated unconditionally,
+// so the resource version of the existing service is used.
+func (c *Service) Update(namespace string, service *corev1.Service) (*corev1.Service, error) {
+	existing, err := c.clientSet.CoreV1().Services(namespace).Get(context.Background(), service.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get existing service")
+	}
+
+	updated := service.DeepCopy()
+	updated.ResourceVersion = existing.ResourceVersion
+
+	return c.clientSet.CoreV1().Services(namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+}
+
+func (c *Service) Delete(namespace string, name string) error {
+	return c.clientSet.CoreV1().Services(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+type ConfigMap struct {
+	clientSet kubernetes.Interface
+}
+
+func NewConfigMap(clientSet kubernetes.Interface) *ConfigMap {
+	return &ConfigMap{clientSet: clientSet}
+}
+
+func (c *ConfigMap) Get(namespace, name string) (*corev1.ConfigMap, error) {
+	return c.clientSet.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+func (c *ConfigMap) Create(namespace string, configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	return c.clientSet.CoreV1().ConfigMaps(namespace).Create(context.Background(), configMap, metav1.CreateOptions{})
+}
+
+func (c *ConfigMap) Update(namespace string, configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	return c.clientSet.CoreV1().ConfigMaps(namespace).Update(context.Background(), configMap, metav1.UpdateOptions{})
+}
+
 type StatefulSet struct {
 	clientSet          kubernetes.Interface
 	workloadsNamespace string
@@ -131,6 +243,32 @@ func (c *StatefulSet) GetByLRPIdentifier(id opi.LRPIdentifier) ([]appsv1.Statefu
 	return statefulSetList.Items, nil
 }
 
+func (c *StatefulSet) GetByAppGUID(appGUID string) ([]appsv1.StatefulSet, error) {
+	statefulSetList, err := c.clientSet.AppsV1().StatefulSets(c.workloadsNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf(
+			"%s=%s,%s=%s",
+			k8s.LabelAppGUID, appGUID,
+			k8s.LabelSourceType, "APP",
+		),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list statefulsets by app guid")
+	}
+
+	return statefulSetList.Items, nil
+}
+
+func (c *StatefulSet) GetByGUID(guid string) ([]appsv1.StatefulSet, error) {
+	statefulSetList, err := c.clientSet.AppsV1().StatefulSets(c.workloadsNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", k8s.LabelGUID, guid),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list statefulsets by guid")
+	}
+
+	return statefulSetList.Items, nil
+}
+
 func (c *StatefulSet) Update(namespace string, statefulSet *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
 	return c.clientSet.AppsV1().StatefulSets(namespace).Update(context.Background(), statefulSet, metav1.UpdateOptions{})
 }
@@ -249,6 +387,17 @@ func (c *Secret) Delete(namespace string, name string) error {
 	return c.clientSet.CoreV1().Secrets(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
 }
 
+func (c *Secret) List(namespace, labelSelector string) ([]corev1.Secret, error) {
+	secretList, err := c.clientSet.CoreV1().Secrets(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list secrets")
+	}
+
+	return secretList.Items, nil
+}
+
 type Event struct {
 	clientSet          kubernetes.Interface
 	workloadsNamespace string
@@ -27,13 +27,13 @@ var _ = Describe("TaskDeleter", func() {
 		task          *opi.Task
 		deleter       *TaskDeleter
 		jobClient     *k8sfakes.FakeJobDeletingClient
-		secretDeleter *k8sfakes.FakeSecretsCreatorDeleter
+		secretDeleter *k8sfakes.FakeSecretsClient
 		job           batchv1.Job
 	)
 
 	BeforeEach(func() {
 		jobClient = new(k8sfakes.FakeJobDeletingClient)
-		secretDeleter = new(k8sfakes.FakeSecretsCreatorDeleter)
+		secretDeleter = new(k8sfakes.FakeSecretsClient)
 		task = &opi.Task{
 			Image: Image,
 			Name:  "task-name",
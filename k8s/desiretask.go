@@ -55,6 +55,8 @@ type TaskDesirer struct {
 	stagingServiceAccountName         string
 	registrySecretName                string
 	allowAutomountServiceAccountToken bool
+	schedulingPolicies                []eirini.SchedulingPolicy
+	securityProfile                   eirini.SecurityProfile
 }
 
 func NewTaskDesirer(
@@ -67,6 +69,8 @@ func NewTaskDesirer(
 	stagingServiceAccountName string,
 	registrySecretName string,
 	allowAutomountServiceAccountToken bool,
+	schedulingPolicies []eirini.SchedulingPolicy,
+	securityProfile eirini.SecurityProfile,
 ) *TaskDesirer {
 	return &TaskDesirer{
 		logger:                            logger.Session("task-desirer"),
@@ -78,6 +82,8 @@ func NewTaskDesirer(
 		stagingServiceAccountName:         stagingServiceAccountName,
 		registrySecretName:                registrySecretName,
 		allowAutomountServiceAccountToken: allowAutomountServiceAccountToken,
+		schedulingPolicies:                schedulingPolicies,
+		securityProfile:                   securityProfile,
 	}
 }
 
@@ -91,6 +97,8 @@ func NewTaskDesirerWithEiriniInstance(
 	stagingServiceAccountName string,
 	registrySecretName string,
 	allowAutomountServiceAccountToken bool,
+	schedulingPolicies []eirini.SchedulingPolicy,
+	securityProfile eirini.SecurityProfile,
 ) *TaskDesirer {
 	desirer := NewTaskDesirer(
 		logger,
@@ -102,6 +110,8 @@ func NewTaskDesirerWithEiriniInstance(
 		stagingServiceAccountName,
 		registrySecretName,
 		allowAutomountServiceAccountToken,
+		schedulingPolicies,
+		securityProfile,
 	)
 
 	return desirer
@@ -110,7 +120,12 @@ func NewTaskDesirerWithEiriniInstance(
 func (d *TaskDesirer) Desire(namespace string, task *opi.Task, opts ...DesireOption) error {
 	logger := d.logger.Session("desire", lager.Data{"guid": task.GUID, "name": task.Name, "namespace": namespace})
 
-	job := d.toTaskJob(task)
+	job, err := d.toTaskJob(task)
+	if err != nil {
+		logger.Error("failed-to-convert-task", err)
+
+		return errors.Wrap(err, "failed to convert task")
+	}
 
 	if imageInPrivateRegistry(task) {
 		if err := d.addImagePullSecret(namespace, task, job); err != nil {
@@ -131,7 +146,7 @@ func (d *TaskDesirer) Desire(namespace string, task *opi.Task, opts ...DesireOpt
 		}
 	}
 
-	_, err := d.jobClient.Create(namespace, job)
+	_, err = d.jobClient.Create(namespace, job)
 	if err != nil {
 		logger.Error("failed-to-create-job", err)
 
@@ -144,7 +159,14 @@ func (d *TaskDesirer) Desire(namespace string, task *opi.Task, opts ...DesireOpt
 func (d *TaskDesirer) DesireStaging(task *opi.StagingTask) error {
 	logger := d.logger.Session("desire-staging", lager.Data{"guid": task.GUID, "name": task.Name})
 
-	_, err := d.jobClient.Create(d.defaultStagingNamespace, d.toStagingJob(task))
+	job, err := d.toStagingJob(task)
+	if err != nil {
+		logger.Error("failed-to-convert-staging-task", err)
+
+		return errors.Wrap(err, "failed to convert staging task")
+	}
+
+	_, err = d.jobClient.Create(d.defaultStagingNamespace, job)
 	if err != nil {
 		logger.Error("failed-to-create-job", err)
 
@@ -184,7 +206,7 @@ func (d *TaskDesirer) List() ([]*opi.Task, error) {
 	return tasks, nil
 }
 
-func (d *TaskDesirer) toTaskJob(task *opi.Task) *batch.Job {
+func (d *TaskDesirer) toTaskJob(task *opi.Task) (*batch.Job, error) {
 	job := d.toJob(task)
 	job.Spec.Template.Spec.ServiceAccountName = d.serviceAccountName
 	job.Labels[LabelSourceType] = taskSourceType
@@ -213,7 +235,15 @@ func (d *TaskDesirer) toTaskJob(task *opi.Task) *batch.Job {
 
 	job.Spec.Template.Spec.Containers = containers
 
-	return job
+	if err := d.applySchedulingPolicy(task, job); err != nil {
+		return nil, err
+	}
+
+	if err := applySecurityProfile(d.securityProfile, &job.Spec.Template); err != nil {
+		return nil, err
+	}
+
+	return job, nil
 }
 
 func (d *TaskDesirer) createTaskSecret(namespace string, task *opi.Task) (*corev1.Secret, error) {
@@ -240,7 +270,7 @@ func (d *TaskDesirer) createTaskSecret(namespace string, task *opi.Task) (*corev
 	return d.secretsCreator.Create(namespace, secret)
 }
 
-func (d *TaskDesirer) toStagingJob(task *opi.StagingTask) *batch.Job { //nolint:funlen // Boilerplate function, size is ok.
+func (d *TaskDesirer) toStagingJob(task *opi.StagingTask) (*batch.Job, error) { //nolint:funlen // Boilerplate function, size is ok.
 	job := d.toJob(task.Task)
 
 	job.Spec.Template.Spec.ServiceAccountName = d.stagingServiceAccountName
@@ -318,7 +348,21 @@ func (d *TaskDesirer) toStagingJob(task *opi.StagingTask) *batch.Job { //nolint:
 	job.Labels[LabelStagingGUID] = task.GUID
 	job.Spec.Template.Labels[LabelStagingGUID] = task.GUID
 
-	return job
+	if err := d.applySchedulingPolicy(task.Task, job); err != nil {
+		return nil, err
+	}
+
+	if err := applySecurityProfile(stagingSecurityProfile(d.securityProfile), &job.Spec.Template); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (d *TaskDesirer) applySchedulingPolicy(task *opi.Task, job *batch.Job) error {
+	policy := matchSchedulingPolicy(d.schedulingPolicies, task.OrgGUID, task.SpaceGUID, nil)
+
+	return applySchedulingPolicy(policy, &job.Spec.Template.Spec, task.CPUWeight)
 }
 
 func (d *TaskDesirer) getVolumeSources() []corev1.VolumeProjection {
@@ -414,7 +458,7 @@ func (d *TaskDesirer) toJob(task *opi.Task) *batch.Job {
 					RestartPolicy: corev1.RestartPolicyNever,
 					SecurityContext: &corev1.PodSecurityContext{
 						RunAsNonRoot: &runAsNonRoot,
-						RunAsUser:    int64ptr(VcapUID),
+						RunAsUser:    runAsUser(d.securityProfile),
 					},
 				},
 			},
@@ -441,13 +485,12 @@ func (d *TaskDesirer) toJob(task *opi.Task) *batch.Job {
 	}
 
 	job.Annotations = map[string]string{
-		AnnotationAppName:              task.AppName,
-		AnnotationAppID:                task.AppGUID,
-		AnnotationOrgName:              task.OrgName,
-		AnnotationOrgGUID:              task.OrgGUID,
-		AnnotationSpaceName:            task.SpaceName,
-		AnnotationSpaceGUID:            task.SpaceGUID,
-		corev1.SeccompPodAnnotationKey: corev1.SeccompProfileRuntimeDefault,
+		AnnotationAppName:   task.AppName,
+		AnnotationAppID:     task.AppGUID,
+		AnnotationOrgName:   task.OrgName,
+		AnnotationOrgGUID:   task.OrgGUID,
+		AnnotationSpaceName: task.SpaceName,
+		AnnotationSpaceGUID: task.SpaceGUID,
 	}
 
 	job.Spec.Template.Labels = job.Labels
@@ -44,6 +44,8 @@ var _ = Describe("TaskDesirer", func() {
 		Expect(job.Spec.Template.Spec.AutomountServiceAccountToken).To(Equal(&automountServiceAccountToken))
 		Expect(job.Spec.Template.Spec.SecurityContext.RunAsNonRoot).To(PointTo(Equal(true)))
 		Expect(job.Spec.Template.Spec.SecurityContext.RunAsUser).To(PointTo(Equal(int64(2000))))
+		Expect(job.Spec.Template.Spec.SecurityContext.SeccompProfile).To(Equal(&corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}))
+		Expect(job.Annotations).NotTo(HaveKey(corev1.SeccompPodAnnotationKey))
 	}
 
 	assertContainer := func(container corev1.Container, name string) {
@@ -141,6 +143,8 @@ var _ = Describe("TaskDesirer", func() {
 			"staging-service-account",
 			"registry-secret",
 			false,
+			nil,
+			eirini.SecurityProfile{},
 		)
 	})
 
@@ -185,7 +189,6 @@ var _ = Describe("TaskDesirer", func() {
 					HaveKeyWithValue(AnnotationSpaceName, "my-space"),
 					HaveKeyWithValue(AnnotationSpaceGUID, "space-id"),
 					HaveKeyWithValue(AnnotationCompletionCallback, "cloud-countroller.io/task/completed"),
-					HaveKeyWithValue(corev1.SeccompPodAnnotationKey, corev1.SeccompProfileRuntimeDefault),
 				))
 			})
 
@@ -209,7 +212,6 @@ var _ = Describe("TaskDesirer", func() {
 					HaveKeyWithValue(AnnotationOpiTaskContainerName, "opi-task"),
 					HaveKeyWithValue(AnnotationGUID, "task-123"),
 					HaveKeyWithValue(AnnotationCompletionCallback, "cloud-countroller.io/task/completed"),
-					HaveKeyWithValue(corev1.SeccompPodAnnotationKey, corev1.SeccompProfileRuntimeDefault),
 				))
 			})
 
@@ -239,6 +241,8 @@ var _ = Describe("TaskDesirer", func() {
 					"staging-service-account",
 					"registry-secret",
 					true,
+					nil,
+					eirini.SecurityProfile{},
 				)
 			})
 
@@ -316,6 +320,63 @@ var _ = Describe("TaskDesirer", func() {
 			})
 		})
 
+		When("a scheduling policy matches the task", func() {
+			BeforeEach(func() {
+				desirer = NewTaskDesirer(
+					lagertest.NewTestLogger("desiretask"),
+					fakeJobClient,
+					fakeSecretsCreator,
+					defaultNamespace,
+					nil,
+					"service-account",
+					"staging-service-account",
+					"registry-secret",
+					false,
+					[]eirini.SchedulingPolicy{
+						{OrgGUID: "another-org", PriorityClassName: "another-priority"},
+						{SpaceGUID: "space-id", PriorityClassName: "production", CPULimit: CPULimitBurst, CPUBurstMultiplier: 2.5},
+					},
+					eirini.SecurityProfile{},
+				)
+			})
+
+			It("sets the priority class of the pod", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, job = fakeJobClient.CreateArgsForCall(0)
+				Expect(job.Spec.Template.Spec.PriorityClassName).To(Equal("production"))
+			})
+
+			It("sets the cpu request and limit of the task container", func() {
+				_, job = fakeJobClient.CreateArgsForCall(0)
+				resources := job.Spec.Template.Spec.Containers[0].Resources
+				Expect(resources.Requests.Cpu().String()).To(Equal("20m"))
+				Expect(resources.Limits.Cpu().String()).To(Equal("50m"))
+			})
+		})
+
+		When("the matching scheduling policy is invalid", func() {
+			BeforeEach(func() {
+				desirer = NewTaskDesirer(
+					lagertest.NewTestLogger("desiretask"),
+					fakeJobClient,
+					fakeSecretsCreator,
+					defaultNamespace,
+					nil,
+					"service-account",
+					"staging-service-account",
+					"registry-secret",
+					false,
+					[]eirini.SchedulingPolicy{{CPULimit: "unlimited-power"}},
+					eirini.SecurityProfile{},
+				)
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring(`unknown cpu limit policy "unlimited-power"`)))
+				Expect(fakeJobClient.CreateCallCount()).To(BeZero())
+			})
+		})
+
 		Context("and the job already exists", func() {
 			BeforeEach(func() {
 				fakeJobClient.CreateReturns(nil, errors.New("job already exists"))
@@ -583,9 +644,41 @@ var _ = Describe("TaskDesirer", func() {
 			Entry("AnnotationOrgGUID", AnnotationOrgGUID, "org-id"),
 			Entry("AnnotationSpaceName", AnnotationSpaceName, "my-space"),
 			Entry("AnnotationSpaceGUID", AnnotationSpaceGUID, "space-id"),
-			Entry("SeccompPodAnnotationKey", corev1.SeccompPodAnnotationKey, corev1.SeccompProfileRuntimeDefault),
 		)
 
+		When("a scheduling policy matches the staging task", func() {
+			BeforeEach(func() {
+				desirer = NewTaskDesirer(
+					lagertest.NewTestLogger("desiretask"),
+					fakeJobClient,
+					fakeSecretsCreator,
+					defaultNamespace,
+					nil,
+					"service-account",
+					"staging-service-account",
+					"registry-secret",
+					false,
+					[]eirini.SchedulingPolicy{{OrgGUID: "org-id", PriorityClassName: "staging", CPULimit: CPULimitRequest}},
+					eirini.SecurityProfile{},
+				)
+			})
+
+			It("applies it to the pod and every staging container", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(job.Spec.Template.Spec.PriorityClassName).To(Equal("staging"))
+
+				podSpec := job.Spec.Template.Spec
+				containers := append([]corev1.Container{}, podSpec.InitContainers...)
+				containers = append(containers, podSpec.Containers...)
+				Expect(containers).To(HaveLen(3))
+
+				for _, container := range containers {
+					Expect(container.Resources.Limits.Cpu().IsZero()).To(BeFalse(), container.Name)
+					Expect(container.Resources.Limits.Cpu()).To(Equal(container.Resources.Requests.Cpu()), container.Name)
+				}
+			})
+		})
+
 		Context("When the staging task already exists", func() {
 			BeforeEach(func() {
 				fakeJobClient.CreateReturns(nil, errors.New("job already exists"))
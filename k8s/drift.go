@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"encoding/json"
+	"sort"
+
+	"code.cloudfoundry.org/eirini/util"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// liveState holds the statefulset fields that users can change directly,
+// e.g. via kubectl edit or kubectl scale. It only contains fields that the
+// API server does not default, so that the desired and the live object
+// produce the same fingerprint unless the live one has been changed.
+type liveState struct {
+	Replicas   *int32
+	Containers []liveContainerState
+}
+
+type liveContainerState struct {
+	Name      string
+	Image     string
+	Command   []string
+	Args      []string
+	Env       []string
+	EnvFrom   []string
+	Ports     []int32
+	Resources map[string]int64
+}
+
+func liveStateFingerprint(st *appsv1.StatefulSet, includeReplicas bool) (string, error) {
+	state := liveState{}
+
+	if includeReplicas {
+		state.Replicas = st.Spec.Replicas
+	}
+
+	for _, container := range st.Spec.Template.Spec.Containers {
+		state.Containers = append(state.Containers, toLiveContainerState(container))
+	}
+
+	sort.Slice(state.Containers, func(i, j int) bool {
+		return state.Containers[i].Name < state.Containers[j].Name
+	})
+
+	bytes, err := json.Marshal(state)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal statefulset state")
+	}
+
+	return util.Hash(string(bytes))
+}
+
+func toLiveContainerState(container corev1.Container) liveContainerState {
+	state := liveContainerState{
+		Name:      container.Name,
+		Image:     container.Image,
+		Command:   container.Command,
+		Args:      container.Args,
+		Resources: map[string]int64{},
+	}
+
+	for _, env := range container.Env {
+		state.Env = append(state.Env, env.Name+"="+envVarSource(env))
+	}
+
+	for _, envFrom := range container.EnvFrom {
+		switch {
+		case envFrom.SecretRef != nil:
+			state.EnvFrom = append(state.EnvFrom, "secret/"+envFrom.SecretRef.Name)
+		case envFrom.ConfigMapRef != nil:
+			state.EnvFrom = append(state.EnvFrom, "configmap/"+envFrom.ConfigMapRef.Name)
+		}
+	}
+
+	for _, port := range container.Ports {
+		state.Ports = append(state.Ports, port.ContainerPort)
+	}
+
+	for name, quantity := range container.Resources.Requests {
+		state.Resources["requests."+string(name)] = quantity.MilliValue()
+	}
+
+	for name, quantity := range container.Resources.Limits {
+		state.Resources["limits."+string(name)] = quantity.MilliValue()
+	}
+
+	return state
+}
+
+func envVarSource(env corev1.EnvVar) string {
+	switch {
+	case env.ValueFrom == nil:
+		return env.Value
+	case env.ValueFrom.FieldRef != nil:
+		return "field/" + env.ValueFrom.FieldRef.FieldPath
+	case env.ValueFrom.SecretKeyRef != nil:
+		return "secret/" + env.ValueFrom.SecretKeyRef.Name + "/" + env.ValueFrom.SecretKeyRef.Key
+	case env.ValueFrom.ConfigMapKeyRef != nil:
+		return "configmap/" + env.ValueFrom.ConfigMapKeyRef.Name + "/" + env.ValueFrom.ConfigMapKeyRef.Key
+	case env.ValueFrom.ResourceFieldRef != nil:
+		return "resource/" + env.ValueFrom.ResourceFieldRef.Resource
+	default:
+		return ""
+	}
+}
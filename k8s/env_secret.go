@@ -0,0 +1,445 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/eirini/util"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const sidecarEnvSecretSuffix = "-sidecars"
+
+// envSecretName is derived from the env fingerprint, so that every revision
+// of the pod template refers to its own env secret and canaries or rollbacks
+// never see the env of another revision.
+func envSecretName(statefulSetName, fingerprint string) string {
+	return fmt.Sprintf("%s-env-%s", statefulSetName, fingerprint)
+}
+
+func sidecarEnvSecretName(envSecret string) string {
+	return envSecret + sidecarEnvSecretSuffix
+}
+
+func isEnvSecretOf(statefulSetName, secretName string) bool {
+	return secretName == statefulSetName+"-env" || strings.HasPrefix(secretName, statefulSetName+"-env-")
+}
+
+func envFromSecret(envSecret string) []corev1.EnvFromSource {
+	return []corev1.EnvFromSource{
+		{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: envSecret},
+			},
+		},
+	}
+}
+
+func sidecarEnvVars(envSecret, containerName string, env map[string]string) []corev1.EnvVar {
+	envVars := []corev1.EnvVar{}
+
+	for _, envVar := range MapToEnvVar(env) {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: envVar.Name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: sidecarEnvSecretName(envSecret)},
+					Key:                  sidecarEnvKey(containerName, envVar.Name),
+				},
+			},
+		})
+	}
+
+	return envVars
+}
+
+func sidecarEnvKey(containerName, envName string) string {
+	return containerName + "." + envName
+}
+
+func sidecarEnv(lrp *opi.LRP) map[string]string {
+	env := map[string]string{}
+
+	for i, sidecar := range lrp.Sidecars {
+		for k, v := range sidecar.Env {
+			env[sidecarEnvKey(sidecarContainerName(i, sidecar), k)] = v
+		}
+	}
+
+	return env
+}
+
+// createOrUpdateEnvSecret makes the env secrets owned by the statefulset once
+// it exists, so that they are garbage collected together with it.
+func (m *StatefulSetDesirer) createOrUpdateEnvSecret(namespace string, statefulSet *appsv1.StatefulSet, lrp *opi.LRP) error {
+	fingerprint, err := envFingerprint(lrp)
+	if err != nil {
+		return err
+	}
+
+	envSecret := envSecretName(statefulSet.Name, fingerprint)
+	owners := statefulSetOwnerReferences(statefulSet)
+
+	if err = m.createOrUpdateSecret(namespace, envSecret, owners, lrp, lrp.Env); err != nil {
+		return errors.Wrap(err, "failed to create env secret for statefulset")
+	}
+
+	if env := sidecarEnv(lrp); len(env) > 0 {
+		if err = m.createOrUpdateSecret(namespace, sidecarEnvSecretName(envSecret), owners, lrp, env); err != nil {
+			return errors.Wrap(err, "failed to create sidecar env secret for statefulset")
+		}
+	}
+
+	return nil
+}
+
+// setEnvSecretOwner sets the owner of the env secrets created before the
+// statefulset.
+func (m *StatefulSetDesirer) setEnvSecretOwner(logger lager.Logger, namespace string, statefulSet *appsv1.StatefulSet, lrp *opi.LRP) error {
+	if statefulSet == nil || statefulSet.UID == "" {
+		return nil
+	}
+
+	if err := m.createOrUpdateEnvSecret(namespace, statefulSet, lrp); err != nil {
+		logger.Error("failed-to-set-env-secret-owner", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func statefulSetOwnerReferences(statefulSet *appsv1.StatefulSet) []metav1.OwnerReference {
+	if statefulSet.UID == "" {
+		return nil
+	}
+
+	return []metav1.OwnerReference{{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       "StatefulSet",
+		Name:       statefulSet.Name,
+		UID:        statefulSet.UID,
+	}}
+}
+
+func (m *StatefulSetDesirer) createOrUpdateSecret(namespace, name string, owners []metav1.OwnerReference, lrp *opi.LRP, env map[string]string) error {
+	data := map[string][]byte{}
+	for k, v := range env {
+		data[k] = []byte(v)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				LabelGUID:    lrp.GUID,
+				LabelVersion: lrp.Version,
+			},
+			OwnerReferences: owners,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+
+	_, err := m.Secrets.Create(namespace, secret)
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = m.Secrets.Update(namespace, secret)
+	}
+
+	return err
+}
+
+func (m *StatefulSetDesirer) loadEnv(statefulSet *appsv1.StatefulSet, lrp *opi.LRP) error {
+	if len(statefulSet.Spec.Template.Spec.Containers) == 0 {
+		return nil
+	}
+
+	appContainer, sidecarContainers := splitContainers(statefulSet.Spec.Template.Spec.Containers)
+
+	envSecret, ok := envSecretOf(statefulSet.Name, appContainer)
+	if !ok {
+		return nil
+	}
+
+	secret, err := m.Secrets.Get(statefulSet.Namespace, envSecret)
+	if err != nil {
+		return errors.Wrap(err, "failed to get env secret")
+	}
+
+	if lrp.Env == nil {
+		lrp.Env = map[string]string{}
+	}
+
+	for k, v := range secret.Data {
+		lrp.Env[k] = string(v)
+	}
+
+	for i, container := range sidecarContainers {
+		if i >= len(lrp.Sidecars) {
+			break
+		}
+
+		if err = m.loadSidecarEnv(statefulSet.Namespace, container, &lrp.Sidecars[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *StatefulSetDesirer) loadSidecarEnv(namespace string, container corev1.Container, sidecar *opi.Sidecar) error {
+	var secret *corev1.Secret
+
+	for _, env := range container.Env {
+		if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil {
+			continue
+		}
+
+		ref := env.ValueFrom.SecretKeyRef
+		if !strings.HasSuffix(ref.Name, sidecarEnvSecretSuffix) {
+			continue
+		}
+
+		if secret == nil {
+			var err error
+			if secret, err = m.Secrets.Get(namespace, ref.Name); err != nil {
+				return errors.Wrap(err, "failed to get sidecar env secret")
+			}
+		}
+
+		if sidecar.Env == nil {
+			sidecar.Env = map[string]string{}
+		}
+
+		sidecar.Env[env.Name] = string(secret.Data[ref.Key])
+	}
+
+	return nil
+}
+
+func envSecretOf(statefulSetName string, container corev1.Container) (string, bool) {
+	if container.Name != OPIContainerName {
+		return "", false
+	}
+
+	for _, source := range container.EnvFrom {
+		if source.SecretRef != nil && isEnvSecretOf(statefulSetName, source.SecretRef.Name) {
+			return source.SecretRef.Name, true
+		}
+	}
+
+	return "", false
+}
+
+func (m *StatefulSetDesirer) deleteEnvSecrets(statefulSet *appsv1.StatefulSet) error {
+	secrets, err := m.Secrets.List(statefulSet.Namespace, lrpLabelSelector(statefulSet))
+	if err != nil {
+		return errors.Wrap(err, "failed to list env secrets")
+	}
+
+	for _, secret := range secrets {
+		if !isEnvSecretOf(statefulSet.Name, secret.Name) {
+			continue
+		}
+
+		if err = m.Secrets.Delete(statefulSet.Namespace, secret.Name); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func lrpLabelSelector(statefulSet *appsv1.StatefulSet) string {
+	return fmt.Sprintf("%s=%s,%s=%s", LabelGUID, statefulSet.Labels[LabelGUID], LabelVersion, statefulSet.Labels[LabelVersion])
+}
+
+// envFingerprint changes whenever the environment of the app or of one of
+// its sidecars does, so that pods get restarted although their env is not
+// part of the pod template.
+func envFingerprint(lrp *opi.LRP) (string, error) {
+	envJSON, err := json.Marshal(struct {
+		Env        map[string]string
+		SidecarEnv map[string]string
+	}{
+		Env:        lrp.Env,
+		SidecarEnv: sidecarEnv(lrp),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal env")
+	}
+
+	return util.Hash(string(envJSON))
+}
+
+// EnvSecretCollector deletes the env secrets of a statefulset that are
+// neither used by its pod template, by the template kept for rolling back a
+// canary, nor by any of its pods.
+type EnvSecretCollector struct {
+	StatefulSets StatefulSetClient
+	Pods         PodClient
+	Secrets      SecretsClient
+	Logger       lager.Logger
+}
+
+func (c *EnvSecretCollector) Collect() error {
+	logger := c.Logger.Session("collect")
+
+	statefulSets, err := c.StatefulSets.GetBySourceType(appSourceType)
+	if err != nil {
+		return errors.Wrap(err, "failed to list statefulsets")
+	}
+
+	failures := 0
+
+	for i := range statefulSets {
+		if collectErr := c.collect(logger, &statefulSets[i]); collectErr != nil {
+			logger.Error("failed-to-collect-env-secrets", collectErr, lager.Data{"name": statefulSets[i].Name})
+
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("failed to collect the env secrets of %d statefulsets", failures)
+	}
+
+	return nil
+}
+
+func (c *EnvSecretCollector) collect(logger lager.Logger, statefulSet *appsv1.StatefulSet) error {
+	secrets, err := c.Secrets.List(statefulSet.Namespace, lrpLabelSelector(statefulSet))
+	if err != nil {
+		return errors.Wrap(err, "failed to list env secrets")
+	}
+
+	used, err := c.usedSecrets(statefulSet)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range secrets {
+		if !isEnvSecretOf(statefulSet.Name, secret.Name) || used[secret.Name] {
+			continue
+		}
+
+		logger.Debug("deleting-unused-env-secret", lager.Data{"secret": secret.Name})
+
+		if err = c.Secrets.Delete(statefulSet.Namespace, secret.Name); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to delete env secret")
+		}
+	}
+
+	return nil
+}
+
+func (c *EnvSecretCollector) usedSecrets(statefulSet *appsv1.StatefulSet) (map[string]bool, error) {
+	used := map[string]bool{}
+	addSecretRefs(used, statefulSet.Spec.Template.Spec)
+
+	if previous, ok := statefulSet.Annotations[AnnotationRolloutPreviousTemplate]; ok {
+		var template corev1.PodTemplateSpec
+		if err := json.Unmarshal([]byte(previous), &template); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal previous pod template")
+		}
+
+		addSecretRefs(used, template.Spec)
+	}
+
+	pods, err := c.Pods.GetByLRPIdentifier(opi.LRPIdentifier{
+		GUID:    statefulSet.Labels[LabelGUID],
+		Version: statefulSet.Labels[LabelVersion],
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods")
+	}
+
+	for _, pod := range pods {
+		addSecretRefs(used, pod.Spec)
+	}
+
+	return used, nil
+}
+
+func addSecretRefs(refs map[string]bool, podSpec corev1.PodSpec) {
+	for _, container := range podSpec.Containers {
+		for _, source := range container.EnvFrom {
+			if source.SecretRef != nil {
+				refs[source.SecretRef.Name] = true
+			}
+		}
+
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				refs[env.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+	}
+}
+
+// redactOriginalRequest removes the environment of the app and of its
+// sidecars and the registry credentials from the CC desire request. Requests
+// that cannot be parsed are dropped rather than risking to leak them.
+func redactOriginalRequest(request string) string {
+	if request == "" {
+		return ""
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(request), &fields); err != nil {
+		return ""
+	}
+
+	delete(fields, "environment")
+
+	if rawSidecars, ok := fields["sidecars"]; ok {
+		var sidecars []map[string]json.RawMessage
+		if err := json.Unmarshal(rawSidecars, &sidecars); err != nil {
+			return ""
+		}
+
+		for _, sidecar := range sidecars {
+			delete(sidecar, "environment")
+		}
+
+		redactedSidecars, err := json.Marshal(sidecars)
+		if err != nil {
+			return ""
+		}
+
+		fields["sidecars"] = redactedSidecars
+	}
+
+	if rawLifecycle, ok := fields["lifecycle"]; ok {
+		var lifecycle map[string]map[string]json.RawMessage
+		if err := json.Unmarshal(rawLifecycle, &lifecycle); err != nil {
+			return ""
+		}
+
+		if docker := lifecycle["docker_lifecycle"]; docker != nil {
+			delete(docker, "registry_username")
+			delete(docker, "registry_password")
+		}
+
+		redactedLifecycle, err := json.Marshal(lifecycle)
+		if err != nil {
+			return ""
+		}
+
+		fields["lifecycle"] = redactedLifecycle
+	}
+
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+
+	return string(redacted)
+}
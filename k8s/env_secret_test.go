@@ -0,0 +1,178 @@
+package k8s_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("EnvSecretCollector", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		podClient         *k8sfakes.FakePodClient
+		secretsClient     *k8sfakes.FakeSecretsClient
+		collector         *k8s.EnvSecretCollector
+		statefulSet       appsv1.StatefulSet
+		pods              []corev1.Pod
+		collectErr        error
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+		podClient = new(k8sfakes.FakePodClient)
+		secretsClient = new(k8sfakes.FakeSecretsClient)
+
+		statefulSet = appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "baldur",
+				Namespace:   "the-namespace",
+				Labels:      map[string]string{k8s.LabelGUID: "guid_1234", k8s.LabelVersion: "version_1234"},
+				Annotations: map[string]string{},
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: envSecretPodSpec("baldur-env-current"),
+				},
+			},
+		}
+		pods = []corev1.Pod{}
+
+		statefulSetClient.GetBySourceTypeStub = func(string) ([]appsv1.StatefulSet, error) {
+			return []appsv1.StatefulSet{statefulSet}, nil
+		}
+		podClient.GetByLRPIdentifierStub = func(opi.LRPIdentifier) ([]corev1.Pod, error) {
+			return pods, nil
+		}
+		secretsClient.ListReturns([]corev1.Secret{
+			{ObjectMeta: metav1.ObjectMeta{Name: "baldur-env"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "baldur-env-current"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "baldur-env-current-sidecars"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "baldur-env-previous"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "baldur-registry-credentials"}},
+		}, nil)
+
+		collector = &k8s.EnvSecretCollector{
+			StatefulSets: statefulSetClient,
+			Pods:         podClient,
+			Secrets:      secretsClient,
+			Logger:       lagertest.NewTestLogger("env-secret-collector"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		collectErr = collector.Collect()
+	})
+
+	deletedSecrets := func() []string {
+		deleted := []string{}
+		for i := 0; i < secretsClient.DeleteCallCount(); i++ {
+			_, name := secretsClient.DeleteArgsForCall(i)
+			deleted = append(deleted, name)
+		}
+
+		return deleted
+	}
+
+	It("lists the secrets of each LRP", func() {
+		Expect(collectErr).NotTo(HaveOccurred())
+		Expect(secretsClient.ListCallCount()).To(Equal(1))
+		namespace, selector := secretsClient.ListArgsForCall(0)
+		Expect(namespace).To(Equal("the-namespace"))
+		Expect(selector).To(Equal(k8s.LabelGUID + "=guid_1234," + k8s.LabelVersion + "=version_1234"))
+	})
+
+	It("deletes the env secrets that are no longer referenced", func() {
+		Expect(deletedSecrets()).To(ConsistOf("baldur-env", "baldur-env-previous"))
+	})
+
+	When("a pod still runs with an older env secret", func() {
+		BeforeEach(func() {
+			pods = []corev1.Pod{{Spec: envSecretPodSpec("baldur-env-previous")}}
+		})
+
+		It("keeps that secret", func() {
+			Expect(deletedSecrets()).To(ConsistOf("baldur-env"))
+		})
+	})
+
+	When("a canary can still be rolled back", func() {
+		BeforeEach(func() {
+			previous, err := json.Marshal(corev1.PodTemplateSpec{Spec: envSecretPodSpec("baldur-env-previous")})
+			Expect(err).NotTo(HaveOccurred())
+			statefulSet.Annotations[k8s.AnnotationRolloutPreviousTemplate] = string(previous)
+		})
+
+		It("keeps the env secret of the previous template", func() {
+			Expect(deletedSecrets()).To(ConsistOf("baldur-env"))
+		})
+	})
+
+	When("a secret has already been deleted", func() {
+		BeforeEach(func() {
+			secretsClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "baldur-env"))
+		})
+
+		It("succeeds", func() {
+			Expect(collectErr).NotTo(HaveOccurred())
+		})
+	})
+
+	When("listing the pods fails", func() {
+		BeforeEach(func() {
+			podClient.GetByLRPIdentifierStub = nil
+			podClient.GetByLRPIdentifierReturns(nil, errors.New("boom"))
+		})
+
+		It("does not delete any secret", func() {
+			Expect(collectErr).To(MatchError(ContainSubstring("failed to collect the env secrets of 1 statefulsets")))
+			Expect(secretsClient.DeleteCallCount()).To(BeZero())
+		})
+	})
+
+	When("listing the statefulsets fails", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetBySourceTypeStub = nil
+			statefulSetClient.GetBySourceTypeReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(collectErr).To(MatchError(ContainSubstring("failed to list statefulsets")))
+		})
+	})
+})
+
+func envSecretPodSpec(envSecret string) corev1.PodSpec {
+	return corev1.PodSpec{
+		Containers: []corev1.Container{
+			{
+				Name: k8s.OPIContainerName,
+				EnvFrom: []corev1.EnvFromSource{{
+					SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: envSecret}},
+				}},
+			},
+			{
+				Name: k8s.SidecarContainerNamePrefix + "agent",
+				Env: []corev1.EnvVar{{
+					Name: "TOKEN",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: envSecret + "-sidecars"},
+							Key:                  "sidecar-agent.TOKEN",
+						},
+					},
+				}},
+			},
+		},
+	}
+}
@@ -1,6 +1,8 @@
 package k8s
 
 import (
+	"sort"
+
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
@@ -13,6 +15,10 @@ func MapToEnvVar(env map[string]string) []v1.EnvVar {
 		envVars = append(envVars, envVar)
 	}
 
+	sort.Slice(envVars, func(i, j int) bool {
+		return envVars[i].Name < envVars[j].Name
+	})
+
 	return envVars
 }
 
@@ -29,6 +29,10 @@ var _ = Describe("Helpers", func() {
 			Expect(envVars).To(ConsistOf(v1.EnvVar{Name: "foo", Value: "bar"}, v1.EnvVar{Name: "dora", Value: "fedora"}))
 		})
 
+		It("sorts the EnvVars by name, so that the pod template is stable", func() {
+			Expect(envVars).To(Equal([]v1.EnvVar{{Name: "dora", Value: "fedora"}, {Name: "foo", Value: "bar"}}))
+		})
+
 		Context("when env map is empty", func() {
 			BeforeEach(func() {
 				env = map[string]string{}
@@ -1,6 +1,9 @@
 package event
 
 import (
+	"fmt"
+	"strings"
+
 	"code.cloudfoundry.org/eirini/events"
 	"code.cloudfoundry.org/eirini/k8s"
 	"code.cloudfoundry.org/eirini/util"
@@ -37,14 +40,14 @@ func (g DefaultCrashEventGenerator) Generate(pod *v1.Pod, logger lager.Logger) (
 	}
 
 	if container := getMisconfiguredContainerStatusIfAny(pod.Status.ContainerStatuses); container != nil {
-		exitDescription := container.State.Waiting.Message
+		exitDescription := describeExit(container, container.State.Waiting.Message)
 
 		return generateReport(pod, container.State.Waiting.Reason, 0, exitDescription, 0, int(container.RestartCount)), true
 	}
 
 	if container := getCrashedContainerStatusIfAny(pod.Status.ContainerStatuses); container != nil {
 		exitStatus := int(container.LastTerminationState.Terminated.ExitCode)
-		exitDescription := container.LastTerminationState.Terminated.Reason
+		exitDescription := describeExit(container, container.LastTerminationState.Terminated.Reason)
 		crashTimestamp := container.LastTerminationState.Terminated.StartedAt.Unix()
 
 		return generateReport(pod, container.State.Waiting.Reason, exitStatus, exitDescription, crashTimestamp, int(container.RestartCount)), true
@@ -67,7 +70,15 @@ func (g DefaultCrashEventGenerator) generateReportForTerminatedPod(pod *v1.Pod,
 
 	terminated := status.State.Terminated
 
-	return generateReport(pod, terminated.Reason, int(terminated.ExitCode), terminated.Reason, terminated.StartedAt.Unix(), int(status.RestartCount)), true
+	return generateReport(pod, terminated.Reason, int(terminated.ExitCode), describeExit(status, terminated.Reason), terminated.StartedAt.Unix(), int(status.RestartCount)), true
+}
+
+func describeExit(status *v1.ContainerStatus, description string) string {
+	if !strings.HasPrefix(status.Name, k8s.SidecarContainerNamePrefix) {
+		return description
+	}
+
+	return fmt.Sprintf("sidecar %s: %s", strings.TrimPrefix(status.Name, k8s.SidecarContainerNamePrefix), description)
 }
 
 func generateReport(
@@ -84,6 +84,20 @@ var _ = Describe("CrashEventGenerator", func() {
 				}))
 			})
 		})
+
+		Context("When a sidecar container crashes", func() {
+			BeforeEach(func() {
+				pod = newMultiContainerCrashedPod()
+				pod.Status.ContainerStatuses[0].Name = k8s.OPIContainerName
+				pod.Status.ContainerStatuses[1].Name = "sidecar-metrics-agent"
+			})
+
+			It("should mention the sidecar in the exit description", func() {
+				report, returned := generator.Generate(pod, logger)
+				Expect(returned).To(BeTrue())
+				Expect(report.ExitDescription).To(Equal("sidecar metrics-agent: better luck next time"))
+			})
+		})
 	})
 
 	Context("When app has been terminated", func() {
@@ -71,18 +71,32 @@ func (h URIAnnotationUpdateHandler) createRoutesOnUpdate(loggerSession lager.Log
 	resultRoutes := []*eiriniroute.Message{}
 
 	for _, pod := range pods {
+		podGroups := grouped
+
 		if markedForDeletion(pod) {
-			loggerSession.Debug("skipping pod marked for deletion")
+			loggerSession.Debug("only unregistering routes of pod marked for deletion")
 
-			continue
+			podGroups = unregisteredOnly(grouped)
 		}
 
-		resultRoutes = append(resultRoutes, createRouteMessages(loggerSession, pod, grouped)...)
+		resultRoutes = append(resultRoutes, createRouteMessages(loggerSession, pod, podGroups)...)
 	}
 
 	return resultRoutes
 }
 
+func unregisteredOnly(grouped portGroup) portGroup {
+	group := make(portGroup)
+
+	for port, routes := range grouped {
+		if len(routes.UnregisteredRoutes) > 0 {
+			group[port] = eiriniroute.Routes{UnregisteredRoutes: routes.UnregisteredRoutes}
+		}
+	}
+
+	return group
+}
+
 func createRouteMessages(loggerSession lager.Logger, pod corev1.Pod, grouped portGroup) []*eiriniroute.Message {
 	resultRoutes := []*eiriniroute.Message{}
 
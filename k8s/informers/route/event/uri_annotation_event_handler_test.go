@@ -382,6 +382,44 @@ var _ = Describe("StatefulsetAnnotationEventHandler", func() {
 		})
 	})
 
+	Context("When all routes are removed while the pods are being deleted", func() {
+		BeforeEach(func() {
+			oldStatefulSet = createStatefulSetWithRoutes(`[
+						{
+							"hostname": "mr-stateful.cf.domain",
+							"port": 8080
+						}
+					]`)
+			updatedStatefulSet = createStatefulSetWithRoutes(`[]`)
+
+			now := metav1.Time{Time: time.Now()}
+			pod0 := createPod("mr-stateful-0", "10.20.30.40")
+			pod0.DeletionTimestamp = &now
+
+			podClient.ListReturns(&corev1.PodList{Items: []corev1.Pod{pod0}}, nil)
+
+			handler.Handle(oldStatefulSet, updatedStatefulSet)
+
+			for i := 0; i < routeEmitter.EmitCallCount(); i++ {
+				allEmitArgs = append(allEmitArgs, routeEmitter.EmitArgsForCall(i))
+			}
+		})
+
+		It("should still unregister the routes of the pod", func() {
+			Expect(allEmitArgs).To(ConsistOf(
+				eiriniroute.Message{
+					Name: "mr-stateful-0-guid",
+					Routes: eiriniroute.Routes{
+						UnregisteredRoutes: []string{"mr-stateful.cf.domain"},
+					},
+					InstanceID: "mr-stateful-0",
+					Address:    "10.20.30.40",
+					Port:       8080,
+				},
+			))
+		})
+	})
+
 	Context("when the port of a route is changed", func() {
 		BeforeEach(func() {
 			oldStatefulSet = createStatefulSetWithRoutes(`[
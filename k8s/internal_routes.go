@@ -0,0 +1,200 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+)
+
+const internalRoutesDNSKeySuffix = ".override"
+
+// handleInternalRoutes maintains a headless service named after the
+// statefulset. It resolves to the IPs of the ready instances and gives every
+// instance a stable name (<statefulset>-<index>.<statefulset>.<namespace>.svc).
+// The internal hostnames are aliased to the service by CoreDNS rewrite rules,
+// kept under a key of their own in the configured ConfigMap.
+func (m *StatefulSetDesirer) handleInternalRoutes(logger lager.Logger, statefulSet *appsv1.StatefulSet, lrp *opi.LRP) error {
+	if len(lrp.InternalRoutes) == 0 {
+		return m.deleteInternalRoutes(logger, statefulSet)
+	}
+
+	namespace, name := statefulSet.Namespace, statefulSet.Name
+
+	rewrites, err := m.internalRoutesRewrites(namespace, name, lrp)
+	if err != nil {
+		return err
+	}
+
+	service, err := m.toHeadlessService(name, lrp)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.Services.Create(namespace, service)
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = m.Services.Update(namespace, service)
+	}
+
+	if err != nil {
+		logger.Error("failed-to-create-internal-routes-service", err, lager.Data{"namespace": namespace})
+
+		return errors.Wrap(err, "failed to create internal routes service")
+	}
+
+	return m.setInternalRoutesDNS(logger, internalRoutesDNSKey(namespace, name), rewrites)
+}
+
+// deleteInternalRoutes only looks up the DNS rules of statefulsets that have
+// internal routes, so that stopping other LRPs doesn't depend on the ConfigMap.
+func (m *StatefulSetDesirer) deleteInternalRoutes(logger lager.Logger, statefulSet *appsv1.StatefulSet) error {
+	namespace, name := statefulSet.Namespace, statefulSet.Name
+
+	if _, ok := statefulSet.Annotations[AnnotationInternalRoutes]; ok {
+		if err := m.setInternalRoutesDNS(logger, internalRoutesDNSKey(namespace, name), ""); err != nil {
+			return err
+		}
+	}
+
+	err := m.Services.Delete(namespace, name)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		logger.Error("failed-to-delete-internal-routes-service", err, lager.Data{"namespace": namespace})
+
+		return errors.Wrap(err, "failed to delete internal routes service")
+	}
+
+	return nil
+}
+
+// internalRoutesRewrites returns the CoreDNS rules resolving the internal
+// hostnames of the LRP to its service. The answers are rewritten back, so
+// that clients get records for the name they asked for.
+func (m *StatefulSetDesirer) internalRoutesRewrites(namespace, name string, lrp *opi.LRP) (string, error) {
+	service := fmt.Sprintf("%s.%s.svc.%s", name, namespace, m.InternalRoutesDNS.ClusterDomain)
+	rules := []string{}
+
+	for _, route := range lrp.InternalRoutes {
+		hostname := strings.ToLower(route.Hostname)
+		if errs := validation.IsDNS1123Subdomain(hostname); len(errs) > 0 {
+			return "", fmt.Errorf("invalid internal route hostname %q: %s", route.Hostname, strings.Join(errs, ", "))
+		}
+
+		rules = append(rules, fmt.Sprintf("rewrite name exact %s %s answer auto", hostname, service))
+	}
+
+	return strings.Join(rules, "\n") + "\n", nil
+}
+
+// setInternalRoutesDNS sets the rewrite rules of a single LRP, or removes them
+// when there are none, retrying when other LRPs change theirs concurrently.
+func (m *StatefulSetDesirer) setInternalRoutesDNS(logger lager.Logger, key, rewrites string) error {
+	namespace, name := m.InternalRoutesDNS.ConfigMapNamespace, m.InternalRoutesDNS.ConfigMapName
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := m.ConfigMaps.Get(namespace, name)
+		if k8serrors.IsNotFound(err) {
+			if rewrites == "" {
+				return nil
+			}
+
+			_, err = m.ConfigMaps.Create(namespace, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Data:       map[string]string{key: rewrites},
+			})
+			if k8serrors.IsAlreadyExists(err) {
+				return k8serrors.NewConflict(corev1.Resource("configmaps"), name, err)
+			}
+
+			return err
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if current, ok := configMap.Data[key]; current == rewrites && (ok || rewrites == "") {
+			return nil
+		}
+
+		updated := configMap.DeepCopy()
+		if updated.Data == nil {
+			updated.Data = map[string]string{}
+		}
+
+		if rewrites == "" {
+			delete(updated.Data, key)
+		} else {
+			updated.Data[key] = rewrites
+		}
+
+		_, err = m.ConfigMaps.Update(namespace, updated)
+
+		return err
+	})
+	if err != nil {
+		logger.Error("failed-to-set-internal-routes-dns", err, lager.Data{"config-map": namespace + "/" + name})
+
+		return errors.Wrap(err, "failed to set internal routes dns")
+	}
+
+	return nil
+}
+
+// internalRoutesDNSKey has the CoreDNS override suffix, as CoreDNS imports
+// the ConfigMap keys matching it into its server block.
+func internalRoutesDNSKey(namespace, name string) string {
+	return namespace + "." + name + internalRoutesDNSKeySuffix
+}
+
+func (m *StatefulSetDesirer) toHeadlessService(name string, lrp *opi.LRP) (*corev1.Service, error) {
+	internalRoutes, err := internalRoutesAnnotation(lrp)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := []corev1.ServicePort{}
+	for _, port := range lrp.Ports {
+		ports = append(ports, corev1.ServicePort{
+			Name:       fmt.Sprintf("port-%d", port),
+			Protocol:   corev1.ProtocolTCP,
+			Port:       port,
+			TargetPort: intstr.FromInt(int(port)),
+		})
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				LabelGUID:       lrp.GUID,
+				LabelVersion:    lrp.Version,
+				LabelAppGUID:    lrp.AppGUID,
+				LabelSourceType: appSourceType,
+			},
+			Annotations: map[string]string{
+				AnnotationInternalRoutes: internalRoutes,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  m.labelSelector(lrp).MatchLabels,
+			Ports:     ports,
+		},
+	}, nil
+}
+
+func internalRoutesAnnotation(lrp *opi.LRP) (string, error) {
+	routes, err := json.Marshal(lrp.InternalRoutes)
+
+	return string(routes), errors.Wrap(err, "failed to marshal internal routes")
+}
@@ -0,0 +1,409 @@
+package k8s_test
+
+import (
+	"errors"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("Internal routes", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		serviceClient     *k8sfakes.FakeServiceClient
+		configMapClient   *k8sfakes.FakeConfigMapClient
+		desirer           *k8s.StatefulSetDesirer
+		lrp               *opi.LRP
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+		statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+		serviceClient = new(k8sfakes.FakeServiceClient)
+		configMapClient = new(k8sfakes.FakeConfigMapClient)
+		configMapClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "coredns-custom"))
+		probeCreator := new(k8sfakes.FakeProbeCreator)
+
+		desirer = &k8s.StatefulSetDesirer{
+			Pods:                     new(k8sfakes.FakePodClient),
+			Secrets:                  new(k8sfakes.FakeSecretsClient),
+			StatefulSets:             statefulSetClient,
+			PodDisruptionBudgets:     new(k8sfakes.FakePodDisruptionBudgetClient),
+			NetworkPolicies:          new(k8sfakes.FakeNetworkPolicyClient),
+			HorizontalPodAutoscalers: new(k8sfakes.FakeHorizontalPodAutoscalerClient),
+			Services:                 serviceClient,
+			ConfigMaps:               configMapClient,
+			EventsClient:             new(k8sfakes.FakeEventsClient),
+			StatefulSetToLRPMapper:   new(k8sfakes.FakeLRPMapper).Spy,
+			LivenessProbeCreator:     probeCreator.Spy,
+			ReadinessProbeCreator:    probeCreator.Spy,
+			StartupProbeCreator:      probeCreator.Spy,
+			Logger:                   lagertest.NewTestLogger("internal-routes-test"),
+			InternalRoutesDNS: eirini.InternalRoutesDNSConfig{
+				ConfigMapNamespace: "kube-system",
+				ConfigMapName:      "coredns-custom",
+				ClusterDomain:      "cluster.local",
+			},
+		}
+
+		lrp = createLRP("Baldur", []opi.Route{})
+		lrp.InternalRoutes = []opi.InternalRoute{{Hostname: "baldur.apps.internal"}}
+	})
+
+	Describe("Desire", func() {
+		var desireErr error
+
+		JustBeforeEach(func() {
+			desireErr = desirer.Desire("the-namespace", lrp)
+		})
+
+		It("creates a headless service selecting the app instances", func() {
+			Expect(desireErr).NotTo(HaveOccurred())
+			Expect(serviceClient.CreateCallCount()).To(Equal(1))
+
+			namespace, service := serviceClient.CreateArgsForCall(0)
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(service.Name).To(Equal(statefulSet.Name))
+			Expect(service.Labels).To(HaveKeyWithValue(k8s.LabelGUID, lrp.GUID))
+			Expect(service.Labels).To(HaveKeyWithValue(k8s.LabelAppGUID, lrp.AppGUID))
+			Expect(service.Annotations).To(HaveKeyWithValue(k8s.AnnotationInternalRoutes, `[{"hostname":"baldur.apps.internal"}]`))
+			Expect(service.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+			Expect(service.Spec.Selector).To(Equal(statefulSet.Spec.Selector.MatchLabels))
+			Expect(service.Spec.Ports).To(ConsistOf(
+				corev1.ServicePort{Name: "port-8888", Protocol: corev1.ProtocolTCP, Port: 8888, TargetPort: intstr.FromInt(8888)},
+				corev1.ServicePort{Name: "port-9999", Protocol: corev1.ProtocolTCP, Port: 9999, TargetPort: intstr.FromInt(9999)},
+			))
+		})
+
+		It("makes the instances addressable through the service", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Spec.ServiceName).To(Equal(statefulSet.Name))
+		})
+
+		It("stores the internal routes in an annotation", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationInternalRoutes, `[{"hostname":"baldur.apps.internal"}]`))
+		})
+
+		It("resolves the internal hostname to the service", func() {
+			Expect(configMapClient.CreateCallCount()).To(Equal(1))
+
+			namespace, configMap := configMapClient.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("kube-system"))
+			Expect(configMap.Name).To(Equal("coredns-custom"))
+
+			serviceNamespace, service := serviceClient.CreateArgsForCall(0)
+			Expect(resolveInternalRoute(configMap, "baldur.apps.internal")).To(Equal(service.Name + "." + serviceNamespace + ".svc.cluster.local"))
+		})
+
+		It("keeps the DNS rules of the LRP under a key of their own", func() {
+			_, configMap := configMapClient.CreateArgsForCall(0)
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(configMap.Data).To(HaveKeyWithValue(
+				"the-namespace."+statefulSet.Name+".override",
+				"rewrite name exact baldur.apps.internal "+statefulSet.Name+".the-namespace.svc.cluster.local answer auto\n",
+			))
+		})
+
+		When("the DNS ConfigMap already exists", func() {
+			BeforeEach(func() {
+				configMapClient.GetReturns(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "coredns-custom", ResourceVersion: "7"},
+					Data:       map[string]string{"other.override": "rewrite name exact other.apps.internal other.ns.svc.cluster.local answer auto\n"},
+				}, nil)
+			})
+
+			It("adds the DNS rules of the LRP", func() {
+				Expect(configMapClient.UpdateCallCount()).To(Equal(1))
+
+				namespace, configMap := configMapClient.UpdateArgsForCall(0)
+				Expect(namespace).To(Equal("kube-system"))
+				Expect(configMap.ResourceVersion).To(Equal("7"))
+				Expect(configMap.Data).To(HaveKey("other.override"))
+				Expect(resolveInternalRoute(configMap, "baldur.apps.internal")).To(HaveSuffix(".the-namespace.svc.cluster.local"))
+			})
+
+			When("the ConfigMap is changed concurrently", func() {
+				BeforeEach(func() {
+					configMapClient.UpdateReturnsOnCall(0, nil, k8serrors.NewConflict(schema.GroupResource{}, "coredns-custom", errors.New("conflict")))
+				})
+
+				It("retries", func() {
+					Expect(desireErr).NotTo(HaveOccurred())
+					Expect(configMapClient.UpdateCallCount()).To(Equal(2))
+				})
+			})
+		})
+
+		When("the DNS rules are up to date", func() {
+			BeforeEach(func() {
+				configMapClient.GetStub = func(_, _ string) (*corev1.ConfigMap, error) {
+					_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+					key := "the-namespace." + statefulSet.Name + ".override"
+
+					return &corev1.ConfigMap{
+						Data: map[string]string{key: "rewrite name exact baldur.apps.internal " + statefulSet.Name + ".the-namespace.svc.cluster.local answer auto\n"},
+					}, nil
+				}
+			})
+
+			It("does not update the ConfigMap", func() {
+				Expect(desireErr).NotTo(HaveOccurred())
+				Expect(configMapClient.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		When("updating the DNS ConfigMap fails", func() {
+			BeforeEach(func() {
+				configMapClient.CreateReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("failed to set internal routes dns")))
+			})
+		})
+
+		When("an internal hostname is not a valid DNS name", func() {
+			BeforeEach(func() {
+				lrp.InternalRoutes = []opi.InternalRoute{{Hostname: "baldur.apps.internal answer auto\nrewrite"}}
+			})
+
+			It("returns an error", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("invalid internal route hostname")))
+				Expect(configMapClient.CreateCallCount()).To(BeZero())
+			})
+		})
+
+		When("the service already exists", func() {
+			BeforeEach(func() {
+				serviceClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "baldur"))
+			})
+
+			It("updates it", func() {
+				Expect(desireErr).NotTo(HaveOccurred())
+				Expect(serviceClient.UpdateCallCount()).To(Equal(1))
+			})
+		})
+
+		When("creating the service fails", func() {
+			BeforeEach(func() {
+				serviceClient.CreateReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("failed to create internal routes service")))
+			})
+		})
+
+		When("the app has no internal routes", func() {
+			BeforeEach(func() {
+				lrp.InternalRoutes = nil
+			})
+
+			It("does not touch services", func() {
+				Expect(serviceClient.CreateCallCount()).To(BeZero())
+				Expect(serviceClient.DeleteCallCount()).To(BeZero())
+			})
+
+			It("does not touch the DNS ConfigMap", func() {
+				Expect(configMapClient.GetCallCount()).To(BeZero())
+			})
+
+			It("does not set the internal routes annotation", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Annotations).NotTo(HaveKey(k8s.AnnotationInternalRoutes))
+			})
+		})
+
+		When("the statefulset has drifted", func() {
+			BeforeEach(func() {
+				statefulSetClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "baldur"))
+				statefulSetClient.GetReturns(&appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{k8s.LabelGUID: lrp.GUID, k8s.LabelVersion: lrp.Version},
+					},
+				}, nil)
+			})
+
+			It("keeps the immutable service name", func() {
+				Expect(desireErr).NotTo(HaveOccurred())
+				_, patched := statefulSetClient.UpdateArgsForCall(0)
+				Expect(patched.Spec.ServiceName).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		var updateErr error
+
+		BeforeEach(func() {
+			statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "baldur",
+						Namespace:   "the-namespace",
+						Annotations: map[string]string{k8s.AnnotationInternalRoutes: `[{"hostname":"old.apps.internal"}]`},
+					},
+					Spec: appsv1.StatefulSetSpec{
+						Replicas: int32ptr(3),
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: k8s.OPIContainerName}}},
+						},
+					},
+				},
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			updateErr = desirer.Update(lrp)
+		})
+
+		It("applies the internal routes", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			Expect(serviceClient.CreateCallCount()).To(Equal(1))
+			namespace, service := serviceClient.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(service.Name).To(Equal("baldur"))
+			Expect(service.Annotations).To(HaveKeyWithValue(k8s.AnnotationInternalRoutes, `[{"hostname":"baldur.apps.internal"}]`))
+		})
+
+		It("updates the internal routes annotation", func() {
+			_, updated := statefulSetClient.UpdateArgsForCall(0)
+			Expect(updated.Annotations).To(HaveKeyWithValue(k8s.AnnotationInternalRoutes, `[{"hostname":"baldur.apps.internal"}]`))
+		})
+
+		When("the internal routes are removed", func() {
+			BeforeEach(func() {
+				lrp.InternalRoutes = []opi.InternalRoute{}
+			})
+
+			It("removes the internal routes annotation", func() {
+				_, updated := statefulSetClient.UpdateArgsForCall(0)
+				Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationInternalRoutes))
+			})
+
+			It("deletes the service", func() {
+				Expect(serviceClient.DeleteCallCount()).To(Equal(1))
+				namespace, name := serviceClient.DeleteArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(name).To(Equal("baldur"))
+			})
+
+			When("the DNS ConfigMap has rules for the LRP", func() {
+				BeforeEach(func() {
+					configMapClient.GetReturns(&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "coredns-custom"},
+						Data: map[string]string{
+							"the-namespace.baldur.override": "rewrite name exact old.apps.internal baldur.the-namespace.svc.cluster.local answer auto\n",
+							"other.override":                "rewrite name exact other.apps.internal other.ns.svc.cluster.local answer auto\n",
+						},
+					}, nil)
+				})
+
+				It("removes them", func() {
+					Expect(configMapClient.UpdateCallCount()).To(Equal(1))
+
+					_, configMap := configMapClient.UpdateArgsForCall(0)
+					Expect(configMap.Data).NotTo(HaveKey("the-namespace.baldur.override"))
+					Expect(configMap.Data).To(HaveKey("other.override"))
+				})
+			})
+
+			When("the DNS ConfigMap does not exist", func() {
+				It("succeeds without creating it", func() {
+					Expect(updateErr).NotTo(HaveOccurred())
+					Expect(configMapClient.CreateCallCount()).To(BeZero())
+				})
+			})
+
+			When("the service does not exist", func() {
+				BeforeEach(func() {
+					serviceClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+				})
+
+				It("succeeds", func() {
+					Expect(updateErr).NotTo(HaveOccurred())
+				})
+			})
+
+			When("deleting the service fails", func() {
+				BeforeEach(func() {
+					serviceClient.DeleteReturns(errors.New("boom"))
+				})
+
+				It("returns an error", func() {
+					Expect(updateErr).To(MatchError(ContainSubstring("failed to delete internal routes service")))
+				})
+			})
+		})
+	})
+
+	Describe("Stop", func() {
+		It("deletes the service", func() {
+			statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{
+				{ObjectMeta: metav1.ObjectMeta{Name: "baldur", Namespace: "the-namespace"}},
+			}, nil)
+
+			Expect(desirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			Expect(serviceClient.DeleteCallCount()).To(Equal(1))
+			namespace, name := serviceClient.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(name).To(Equal("baldur"))
+		})
+
+		It("only looks up the DNS rules of LRPs with internal routes", func() {
+			statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{
+				{ObjectMeta: metav1.ObjectMeta{Name: "baldur", Namespace: "the-namespace"}},
+			}, nil)
+
+			Expect(desirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			Expect(configMapClient.GetCallCount()).To(BeZero())
+		})
+
+		It("removes the DNS rules", func() {
+			statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{
+				{ObjectMeta: metav1.ObjectMeta{
+					Name:        "baldur",
+					Namespace:   "the-namespace",
+					Annotations: map[string]string{k8s.AnnotationInternalRoutes: `[{"hostname":"baldur.apps.internal"}]`},
+				}},
+			}, nil)
+			configMapClient.GetReturns(&corev1.ConfigMap{
+				Data: map[string]string{"the-namespace.baldur.override": "rewrite name exact baldur.apps.internal baldur.the-namespace.svc.cluster.local answer auto\n"},
+			}, nil)
+
+			Expect(desirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			Expect(configMapClient.UpdateCallCount()).To(Equal(1))
+			_, configMap := configMapClient.UpdateArgsForCall(0)
+			Expect(configMap.Data).To(BeEmpty())
+		})
+	})
+})
+
+// resolveInternalRoute applies the CoreDNS exact name rewrites of the
+// ConfigMap to the hostname, the way CoreDNS rewrites the query.
+func resolveInternalRoute(configMap *corev1.ConfigMap, hostname string) string {
+	for _, rules := range configMap.Data {
+		for _, rule := range strings.Split(strings.TrimSpace(rules), "\n") {
+			fields := strings.Fields(rule)
+			if len(fields) >= 5 && fields[0] == "rewrite" && fields[1] == "name" && fields[2] == "exact" && fields[3] == hostname {
+				return fields[4]
+			}
+		}
+	}
+
+	return ""
+}
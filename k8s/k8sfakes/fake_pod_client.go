@@ -22,6 +22,18 @@ type FakePodClient struct {
 	deleteReturnsOnCall map[int]struct {
 		result1 error
 	}
+	EvictStub        func(string, string) error
+	evictMutex       sync.RWMutex
+	evictArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	evictReturns struct {
+		result1 error
+	}
+	evictReturnsOnCall map[int]struct {
+		result1 error
+	}
 	GetAllStub        func() ([]v1.Pod, error)
 	getAllMutex       sync.RWMutex
 	getAllArgsForCall []struct {
@@ -113,6 +125,68 @@ func (fake *FakePodClient) DeleteReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakePodClient) Evict(arg1 string, arg2 string) error {
+	fake.evictMutex.Lock()
+	ret, specificReturn := fake.evictReturnsOnCall[len(fake.evictArgsForCall)]
+	fake.evictArgsForCall = append(fake.evictArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.EvictStub
+	fakeReturns := fake.evictReturns
+	fake.recordInvocation("Evict", []interface{}{arg1, arg2})
+	fake.evictMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePodClient) EvictCallCount() int {
+	fake.evictMutex.RLock()
+	defer fake.evictMutex.RUnlock()
+	return len(fake.evictArgsForCall)
+}
+
+func (fake *FakePodClient) EvictCalls(stub func(string, string) error) {
+	fake.evictMutex.Lock()
+	defer fake.evictMutex.Unlock()
+	fake.EvictStub = stub
+}
+
+func (fake *FakePodClient) EvictArgsForCall(i int) (string, string) {
+	fake.evictMutex.RLock()
+	defer fake.evictMutex.RUnlock()
+	argsForCall := fake.evictArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePodClient) EvictReturns(result1 error) {
+	fake.evictMutex.Lock()
+	defer fake.evictMutex.Unlock()
+	fake.EvictStub = nil
+	fake.evictReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePodClient) EvictReturnsOnCall(i int, result1 error) {
+	fake.evictMutex.Lock()
+	defer fake.evictMutex.Unlock()
+	fake.EvictStub = nil
+	if fake.evictReturnsOnCall == nil {
+		fake.evictReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.evictReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakePodClient) GetAll() ([]v1.Pod, error) {
 	fake.getAllMutex.Lock()
 	ret, specificReturn := fake.getAllReturnsOnCall[len(fake.getAllArgsForCall)]
@@ -238,6 +312,8 @@ func (fake *FakePodClient) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
+	fake.evictMutex.RLock()
+	defer fake.evictMutex.RUnlock()
 	fake.getAllMutex.RLock()
 	defer fake.getAllMutex.RUnlock()
 	fake.getByLRPIdentifierMutex.RLock()
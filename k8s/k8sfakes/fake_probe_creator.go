@@ -10,22 +10,24 @@ import (
 )
 
 type FakeProbeCreator struct {
-	Stub        func(*opi.LRP) *v1.Probe
+	Stub        func(*opi.LRP) (*v1.Probe, error)
 	mutex       sync.RWMutex
 	argsForCall []struct {
 		arg1 *opi.LRP
 	}
 	returns struct {
 		result1 *v1.Probe
+		result2 error
 	}
 	returnsOnCall map[int]struct {
 		result1 *v1.Probe
+		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeProbeCreator) Spy(arg1 *opi.LRP) *v1.Probe {
+func (fake *FakeProbeCreator) Spy(arg1 *opi.LRP) (*v1.Probe, error) {
 	fake.mutex.Lock()
 	ret, specificReturn := fake.returnsOnCall[len(fake.argsForCall)]
 	fake.argsForCall = append(fake.argsForCall, struct {
@@ -39,9 +41,9 @@ func (fake *FakeProbeCreator) Spy(arg1 *opi.LRP) *v1.Probe {
 		return stub(arg1)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	return returns.result1
+	return returns.result1, returns.result2
 }
 
 func (fake *FakeProbeCreator) CallCount() int {
@@ -50,7 +52,7 @@ func (fake *FakeProbeCreator) CallCount() int {
 	return len(fake.argsForCall)
 }
 
-func (fake *FakeProbeCreator) Calls(stub func(*opi.LRP) *v1.Probe) {
+func (fake *FakeProbeCreator) Calls(stub func(*opi.LRP) (*v1.Probe, error)) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = stub
@@ -62,27 +64,30 @@ func (fake *FakeProbeCreator) ArgsForCall(i int) *opi.LRP {
 	return fake.argsForCall[i].arg1
 }
 
-func (fake *FakeProbeCreator) Returns(result1 *v1.Probe) {
+func (fake *FakeProbeCreator) Returns(result1 *v1.Probe, result2 error) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = nil
 	fake.returns = struct {
 		result1 *v1.Probe
-	}{result1}
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeProbeCreator) ReturnsOnCall(i int, result1 *v1.Probe) {
+func (fake *FakeProbeCreator) ReturnsOnCall(i int, result1 *v1.Probe, result2 error) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = nil
 	if fake.returnsOnCall == nil {
 		fake.returnsOnCall = make(map[int]struct {
 			result1 *v1.Probe
+			result2 error
 		})
 	}
 	fake.returnsOnCall[i] = struct {
 		result1 *v1.Probe
-	}{result1}
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeProbeCreator) Invocations() map[string][][]interface{} {
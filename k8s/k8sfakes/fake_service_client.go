@@ -8,19 +8,19 @@ import (
 	v1 "k8s.io/api/core/v1"
 )
 
-type FakeSecretsCreatorDeleter struct {
-	CreateStub        func(string, *v1.Secret) (*v1.Secret, error)
+type FakeServiceClient struct {
+	CreateStub        func(string, *v1.Service) (*v1.Service, error)
 	createMutex       sync.RWMutex
 	createArgsForCall []struct {
 		arg1 string
-		arg2 *v1.Secret
+		arg2 *v1.Service
 	}
 	createReturns struct {
-		result1 *v1.Secret
+		result1 *v1.Service
 		result2 error
 	}
 	createReturnsOnCall map[int]struct {
-		result1 *v1.Secret
+		result1 *v1.Service
 		result2 error
 	}
 	DeleteStub        func(string, string) error
@@ -35,16 +35,30 @@ type FakeSecretsCreatorDeleter struct {
 	deleteReturnsOnCall map[int]struct {
 		result1 error
 	}
+	UpdateStub        func(string, *v1.Service) (*v1.Service, error)
+	updateMutex       sync.RWMutex
+	updateArgsForCall []struct {
+		arg1 string
+		arg2 *v1.Service
+	}
+	updateReturns struct {
+		result1 *v1.Service
+		result2 error
+	}
+	updateReturnsOnCall map[int]struct {
+		result1 *v1.Service
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeSecretsCreatorDeleter) Create(arg1 string, arg2 *v1.Secret) (*v1.Secret, error) {
+func (fake *FakeServiceClient) Create(arg1 string, arg2 *v1.Service) (*v1.Service, error) {
 	fake.createMutex.Lock()
 	ret, specificReturn := fake.createReturnsOnCall[len(fake.createArgsForCall)]
 	fake.createArgsForCall = append(fake.createArgsForCall, struct {
 		arg1 string
-		arg2 *v1.Secret
+		arg2 *v1.Service
 	}{arg1, arg2})
 	stub := fake.CreateStub
 	fakeReturns := fake.createReturns
@@ -59,52 +73,52 @@ func (fake *FakeSecretsCreatorDeleter) Create(arg1 string, arg2 *v1.Secret) (*v1
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeSecretsCreatorDeleter) CreateCallCount() int {
+func (fake *FakeServiceClient) CreateCallCount() int {
 	fake.createMutex.RLock()
 	defer fake.createMutex.RUnlock()
 	return len(fake.createArgsForCall)
 }
 
-func (fake *FakeSecretsCreatorDeleter) CreateCalls(stub func(string, *v1.Secret) (*v1.Secret, error)) {
+func (fake *FakeServiceClient) CreateCalls(stub func(string, *v1.Service) (*v1.Service, error)) {
 	fake.createMutex.Lock()
 	defer fake.createMutex.Unlock()
 	fake.CreateStub = stub
 }
 
-func (fake *FakeSecretsCreatorDeleter) CreateArgsForCall(i int) (string, *v1.Secret) {
+func (fake *FakeServiceClient) CreateArgsForCall(i int) (string, *v1.Service) {
 	fake.createMutex.RLock()
 	defer fake.createMutex.RUnlock()
 	argsForCall := fake.createArgsForCall[i]
 	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeSecretsCreatorDeleter) CreateReturns(result1 *v1.Secret, result2 error) {
+func (fake *FakeServiceClient) CreateReturns(result1 *v1.Service, result2 error) {
 	fake.createMutex.Lock()
 	defer fake.createMutex.Unlock()
 	fake.CreateStub = nil
 	fake.createReturns = struct {
-		result1 *v1.Secret
+		result1 *v1.Service
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeSecretsCreatorDeleter) CreateReturnsOnCall(i int, result1 *v1.Secret, result2 error) {
+func (fake *FakeServiceClient) CreateReturnsOnCall(i int, result1 *v1.Service, result2 error) {
 	fake.createMutex.Lock()
 	defer fake.createMutex.Unlock()
 	fake.CreateStub = nil
 	if fake.createReturnsOnCall == nil {
 		fake.createReturnsOnCall = make(map[int]struct {
-			result1 *v1.Secret
+			result1 *v1.Service
 			result2 error
 		})
 	}
 	fake.createReturnsOnCall[i] = struct {
-		result1 *v1.Secret
+		result1 *v1.Service
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeSecretsCreatorDeleter) Delete(arg1 string, arg2 string) error {
+func (fake *FakeServiceClient) Delete(arg1 string, arg2 string) error {
 	fake.deleteMutex.Lock()
 	ret, specificReturn := fake.deleteReturnsOnCall[len(fake.deleteArgsForCall)]
 	fake.deleteArgsForCall = append(fake.deleteArgsForCall, struct {
@@ -124,26 +138,26 @@ func (fake *FakeSecretsCreatorDeleter) Delete(arg1 string, arg2 string) error {
 	return fakeReturns.result1
 }
 
-func (fake *FakeSecretsCreatorDeleter) DeleteCallCount() int {
+func (fake *FakeServiceClient) DeleteCallCount() int {
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
 	return len(fake.deleteArgsForCall)
 }
 
-func (fake *FakeSecretsCreatorDeleter) DeleteCalls(stub func(string, string) error) {
+func (fake *FakeServiceClient) DeleteCalls(stub func(string, string) error) {
 	fake.deleteMutex.Lock()
 	defer fake.deleteMutex.Unlock()
 	fake.DeleteStub = stub
 }
 
-func (fake *FakeSecretsCreatorDeleter) DeleteArgsForCall(i int) (string, string) {
+func (fake *FakeServiceClient) DeleteArgsForCall(i int) (string, string) {
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
 	argsForCall := fake.deleteArgsForCall[i]
 	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeSecretsCreatorDeleter) DeleteReturns(result1 error) {
+func (fake *FakeServiceClient) DeleteReturns(result1 error) {
 	fake.deleteMutex.Lock()
 	defer fake.deleteMutex.Unlock()
 	fake.DeleteStub = nil
@@ -152,7 +166,7 @@ func (fake *FakeSecretsCreatorDeleter) DeleteReturns(result1 error) {
 	}{result1}
 }
 
-func (fake *FakeSecretsCreatorDeleter) DeleteReturnsOnCall(i int, result1 error) {
+func (fake *FakeServiceClient) DeleteReturnsOnCall(i int, result1 error) {
 	fake.deleteMutex.Lock()
 	defer fake.deleteMutex.Unlock()
 	fake.DeleteStub = nil
@@ -166,13 +180,80 @@ func (fake *FakeSecretsCreatorDeleter) DeleteReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
-func (fake *FakeSecretsCreatorDeleter) Invocations() map[string][][]interface{} {
+func (fake *FakeServiceClient) Update(arg1 string, arg2 *v1.Service) (*v1.Service, error) {
+	fake.updateMutex.Lock()
+	ret, specificReturn := fake.updateReturnsOnCall[len(fake.updateArgsForCall)]
+	fake.updateArgsForCall = append(fake.updateArgsForCall, struct {
+		arg1 string
+		arg2 *v1.Service
+	}{arg1, arg2})
+	stub := fake.UpdateStub
+	fakeReturns := fake.updateReturns
+	fake.recordInvocation("Update", []interface{}{arg1, arg2})
+	fake.updateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeServiceClient) UpdateCallCount() int {
+	fake.updateMutex.RLock()
+	defer fake.updateMutex.RUnlock()
+	return len(fake.updateArgsForCall)
+}
+
+func (fake *FakeServiceClient) UpdateCalls(stub func(string, *v1.Service) (*v1.Service, error)) {
+	fake.updateMutex.Lock()
+	defer fake.updateMutex.Unlock()
+	fake.UpdateStub = stub
+}
+
+func (fake *FakeServiceClient) UpdateArgsForCall(i int) (string, *v1.Service) {
+	fake.updateMutex.RLock()
+	defer fake.updateMutex.RUnlock()
+	argsForCall := fake.updateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeServiceClient) UpdateReturns(result1 *v1.Service, result2 error) {
+	fake.updateMutex.Lock()
+	defer fake.updateMutex.Unlock()
+	fake.UpdateStub = nil
+	fake.updateReturns = struct {
+		result1 *v1.Service
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeServiceClient) UpdateReturnsOnCall(i int, result1 *v1.Service, result2 error) {
+	fake.updateMutex.Lock()
+	defer fake.updateMutex.Unlock()
+	fake.UpdateStub = nil
+	if fake.updateReturnsOnCall == nil {
+		fake.updateReturnsOnCall = make(map[int]struct {
+			result1 *v1.Service
+			result2 error
+		})
+	}
+	fake.updateReturnsOnCall[i] = struct {
+		result1 *v1.Service
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeServiceClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.createMutex.RLock()
 	defer fake.createMutex.RUnlock()
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
+	fake.updateMutex.RLock()
+	defer fake.updateMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -180,7 +261,7 @@ func (fake *FakeSecretsCreatorDeleter) Invocations() map[string][][]interface{}
 	return copiedInvocations
 }
 
-func (fake *FakeSecretsCreatorDeleter) recordInvocation(key string, args []interface{}) {
+func (fake *FakeServiceClient) recordInvocation(key string, args []interface{}) {
 	fake.invocationsMutex.Lock()
 	defer fake.invocationsMutex.Unlock()
 	if fake.invocations == nil {
@@ -192,4 +273,4 @@ func (fake *FakeSecretsCreatorDeleter) recordInvocation(key string, args []inter
 	fake.invocations[key] = append(fake.invocations[key], args)
 }
 
-var _ k8s.SecretsCreatorDeleter = new(FakeSecretsCreatorDeleter)
+var _ k8s.ServiceClient = new(FakeServiceClient)
@@ -36,6 +36,33 @@ type FakeStatefulSetClient struct {
 	deleteReturnsOnCall map[int]struct {
 		result1 error
 	}
+	GetStub        func(string, string) (*v1.StatefulSet, error)
+	getMutex       sync.RWMutex
+	getArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	getReturns struct {
+		result1 *v1.StatefulSet
+		result2 error
+	}
+	getReturnsOnCall map[int]struct {
+		result1 *v1.StatefulSet
+		result2 error
+	}
+	GetByGUIDStub        func(string) ([]v1.StatefulSet, error)
+	getByGUIDMutex       sync.RWMutex
+	getByGUIDArgsForCall []struct {
+		arg1 string
+	}
+	getByGUIDReturns struct {
+		result1 []v1.StatefulSet
+		result2 error
+	}
+	getByGUIDReturnsOnCall map[int]struct {
+		result1 []v1.StatefulSet
+		result2 error
+	}
 	GetByLRPIdentifierStub        func(opi.LRPIdentifier) ([]v1.StatefulSet, error)
 	getByLRPIdentifierMutex       sync.RWMutex
 	getByLRPIdentifierArgsForCall []struct {
@@ -207,6 +234,135 @@ func (fake *FakeStatefulSetClient) DeleteReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeStatefulSetClient) Get(arg1 string, arg2 string) (*v1.StatefulSet, error) {
+	fake.getMutex.Lock()
+	ret, specificReturn := fake.getReturnsOnCall[len(fake.getArgsForCall)]
+	fake.getArgsForCall = append(fake.getArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetStub
+	fakeReturns := fake.getReturns
+	fake.recordInvocation("Get", []interface{}{arg1, arg2})
+	fake.getMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeStatefulSetClient) GetCallCount() int {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	return len(fake.getArgsForCall)
+}
+
+func (fake *FakeStatefulSetClient) GetCalls(stub func(string, string) (*v1.StatefulSet, error)) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = stub
+}
+
+func (fake *FakeStatefulSetClient) GetArgsForCall(i int) (string, string) {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	argsForCall := fake.getArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeStatefulSetClient) GetReturns(result1 *v1.StatefulSet, result2 error) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = nil
+	fake.getReturns = struct {
+		result1 *v1.StatefulSet
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStatefulSetClient) GetReturnsOnCall(i int, result1 *v1.StatefulSet, result2 error) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = nil
+	if fake.getReturnsOnCall == nil {
+		fake.getReturnsOnCall = make(map[int]struct {
+			result1 *v1.StatefulSet
+			result2 error
+		})
+	}
+	fake.getReturnsOnCall[i] = struct {
+		result1 *v1.StatefulSet
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStatefulSetClient) GetByGUID(arg1 string) ([]v1.StatefulSet, error) {
+	fake.getByGUIDMutex.Lock()
+	ret, specificReturn := fake.getByGUIDReturnsOnCall[len(fake.getByGUIDArgsForCall)]
+	fake.getByGUIDArgsForCall = append(fake.getByGUIDArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.GetByGUIDStub
+	fakeReturns := fake.getByGUIDReturns
+	fake.recordInvocation("GetByGUID", []interface{}{arg1})
+	fake.getByGUIDMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeStatefulSetClient) GetByGUIDCallCount() int {
+	fake.getByGUIDMutex.RLock()
+	defer fake.getByGUIDMutex.RUnlock()
+	return len(fake.getByGUIDArgsForCall)
+}
+
+func (fake *FakeStatefulSetClient) GetByGUIDCalls(stub func(string) ([]v1.StatefulSet, error)) {
+	fake.getByGUIDMutex.Lock()
+	defer fake.getByGUIDMutex.Unlock()
+	fake.GetByGUIDStub = stub
+}
+
+func (fake *FakeStatefulSetClient) GetByGUIDArgsForCall(i int) string {
+	fake.getByGUIDMutex.RLock()
+	defer fake.getByGUIDMutex.RUnlock()
+	argsForCall := fake.getByGUIDArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeStatefulSetClient) GetByGUIDReturns(result1 []v1.StatefulSet, result2 error) {
+	fake.getByGUIDMutex.Lock()
+	defer fake.getByGUIDMutex.Unlock()
+	fake.GetByGUIDStub = nil
+	fake.getByGUIDReturns = struct {
+		result1 []v1.StatefulSet
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStatefulSetClient) GetByGUIDReturnsOnCall(i int, result1 []v1.StatefulSet, result2 error) {
+	fake.getByGUIDMutex.Lock()
+	defer fake.getByGUIDMutex.Unlock()
+	fake.GetByGUIDStub = nil
+	if fake.getByGUIDReturnsOnCall == nil {
+		fake.getByGUIDReturnsOnCall = make(map[int]struct {
+			result1 []v1.StatefulSet
+			result2 error
+		})
+	}
+	fake.getByGUIDReturnsOnCall[i] = struct {
+		result1 []v1.StatefulSet
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeStatefulSetClient) GetByLRPIdentifier(arg1 opi.LRPIdentifier) ([]v1.StatefulSet, error) {
 	fake.getByLRPIdentifierMutex.Lock()
 	ret, specificReturn := fake.getByLRPIdentifierReturnsOnCall[len(fake.getByLRPIdentifierArgsForCall)]
@@ -407,6 +563,10 @@ func (fake *FakeStatefulSetClient) Invocations() map[string][][]interface{} {
 	defer fake.createMutex.RUnlock()
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	fake.getByGUIDMutex.RLock()
+	defer fake.getByGUIDMutex.RUnlock()
 	fake.getByLRPIdentifierMutex.RLock()
 	defer fake.getByLRPIdentifierMutex.RUnlock()
 	fake.getBySourceTypeMutex.RLock()
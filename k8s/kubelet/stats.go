@@ -43,11 +43,16 @@ func (d DiskMetricsClient) GetPodMetrics() (map[string]float64, error) {
 	}
 
 	for _, p := range pods {
-		if p.PodRef.Namespace == d.namespace && len(p.Containers) != 0 {
-			logsBytes := getUsedBytes(p.Containers[0].Logs)
-			rootfsBytes := getUsedBytes(p.Containers[0].Rootfs)
-			metrics[p.PodRef.Name] = logsBytes + rootfsBytes
+		if p.PodRef.Namespace != d.namespace || len(p.Containers) == 0 {
+			continue
 		}
+
+		var usedBytes float64
+		for _, c := range p.Containers {
+			usedBytes += getUsedBytes(c.Logs) + getUsedBytes(c.Rootfs)
+		}
+
+		metrics[p.PodRef.Name] = usedBytes
 	}
 
 	return metrics, nil
@@ -127,6 +127,26 @@ var _ = Describe("Stats", func() {
 		})
 	})
 
+	When("there are multiple containers in the pod stats", func() {
+		It("should add up the used bytes of all containers", func() {
+			nodeClient.ListReturns(&corev1.NodeList{
+				Items: []corev1.Node{
+					{ObjectMeta: metav1.ObjectMeta{
+						Name: "node1",
+					}},
+				},
+			}, nil)
+
+			stats := createStatsSummary("pod-1", "ns-1", 300, 700)
+			sidecarStats := createStatsSummary("pod-1", "ns-1", 50, 25)
+			stats.Pods[0].Containers = append(stats.Pods[0].Containers, sidecarStats.Pods[0].Containers...)
+			kubeletClient.StatsSummaryReturnsOnCall(0, stats, nil)
+
+			metrics, _ := diskMetricsClient.GetPodMetrics()
+			Expect(metrics).To(HaveKeyWithValue("pod-1", float64(1075)))
+		})
+	})
+
 	When("the kubeletClient returns an error for a node", func() {
 		It("should ignore that node", func() {
 			nodeClient.ListReturns(&corev1.NodeList{
@@ -2,13 +2,19 @@ package k8s
 
 import (
 	"encoding/json"
+	"strconv"
+	"strings"
 
 	"code.cloudfoundry.org/eirini/opi"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const eiriniAnnotationPrefix = "cloudfoundry.org/"
+
 func StatefulSetToLRP(s appsv1.StatefulSet) (*opi.LRP, error) {
 	stRoutes := s.Annotations[AnnotationRegisteredRoutes]
 
@@ -19,18 +25,79 @@ func StatefulSetToLRP(s appsv1.StatefulSet) (*opi.LRP, error) {
 		return nil, errors.Wrap(err, "failed to unmarshal uris")
 	}
 
+	var placementTags []string
+
+	if stPlacementTags, ok := s.Annotations[AnnotationPlacementTags]; ok {
+		if err := json.Unmarshal([]byte(stPlacementTags), &placementTags); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal placement tags")
+		}
+	}
+
+	var egressRules []opi.EgressRule
+
+	if stEgressRules, ok := s.Annotations[AnnotationEgressRules]; ok {
+		if err := json.Unmarshal([]byte(stEgressRules), &egressRules); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal egress rules")
+		}
+	}
+
+	var updateStrategy opi.UpdateStrategy
+
+	if stUpdateStrategy, ok := s.Annotations[AnnotationUpdateStrategy]; ok {
+		if err := json.Unmarshal([]byte(stUpdateStrategy), &updateStrategy); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal update strategy")
+		}
+	}
+
+	var autoscaling *opi.AutoscalingPolicy
+
+	if stAutoscaling, ok := s.Annotations[AnnotationAutoscaling]; ok {
+		if err := json.Unmarshal([]byte(stAutoscaling), &autoscaling); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal autoscaling policy")
+		}
+	}
+
+	var internalRoutes []opi.InternalRoute
+
+	if stInternalRoutes, ok := s.Annotations[AnnotationInternalRoutes]; ok {
+		if err := json.Unmarshal([]byte(stInternalRoutes), &internalRoutes); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal internal routes")
+		}
+	}
+
+	var terminationGracePeriod int64
+
+	if stGracePeriod, ok := s.Annotations[AnnotationTerminationGracePeriod]; ok {
+		if terminationGracePeriod, err = strconv.ParseInt(stGracePeriod, 10, 64); err != nil {
+			return nil, errors.Wrap(err, "failed to parse termination grace period")
+		}
+	}
+
 	ports := []int32{}
-	container := s.Spec.Template.Spec.Containers[0]
+	container, sidecarContainers := splitContainers(s.Spec.Template.Spec.Containers)
 
 	for _, port := range container.Ports {
 		ports = append(ports, port.ContainerPort)
 	}
 
-	memory := container.Resources.Requests.Memory().ScaledValue(resource.Mega)
+	env := envVarsToMap(container.Env)
+	sidecars := toSidecars(sidecarContainers, env)
+
+	health, err := getHealthCheck(s, container)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := container.Resources.Requests.Memory().ScaledValue(resource.Mega) + sidecarsMemoryMB(sidecars)
 	disk := container.Resources.Limits.StorageEphemeral().ScaledValue(resource.Mega)
+	cpu := container.Resources.Requests.Cpu().MilliValue()
 	volMounts := []opi.VolumeMount{}
 
 	for _, vol := range container.VolumeMounts {
+		if strings.HasPrefix(vol.Name, writableVolumeNamePrefix) {
+			continue
+		}
+
 		volMounts = append(volMounts, opi.VolumeMount{
 			ClaimName: vol.Name,
 			MountPath: vol.MountPath,
@@ -42,18 +109,145 @@ func StatefulSetToLRP(s appsv1.StatefulSet) (*opi.LRP, error) {
 			GUID:    s.Labels[LabelGUID],
 			Version: s.Annotations[AnnotationVersion],
 		},
-		AppName:          s.Annotations[AnnotationAppName],
-		SpaceName:        s.Annotations[AnnotationSpaceName],
-		Image:            container.Image,
-		Command:          container.Command,
-		RunningInstances: int(s.Status.ReadyReplicas),
-		TargetInstances:  int(*s.Spec.Replicas),
-		Ports:            ports,
-		LastUpdated:      s.Annotations[AnnotationLastUpdated],
-		AppURIs:          uris,
-		AppGUID:          s.Annotations[AnnotationAppID],
-		MemoryMB:         memory,
-		DiskMB:           disk,
-		VolumeMounts:     volMounts,
+		ProcessType:                   s.Labels[LabelProcessType],
+		AppName:                       s.Annotations[AnnotationAppName],
+		AppGUID:                       s.Annotations[AnnotationAppID],
+		OrgName:                       s.Annotations[AnnotationOrgName],
+		OrgGUID:                       s.Annotations[AnnotationOrgGUID],
+		SpaceName:                     s.Annotations[AnnotationSpaceName],
+		SpaceGUID:                     s.Annotations[AnnotationSpaceGUID],
+		Image:                         container.Image,
+		Command:                       container.Command,
+		Env:                           env,
+		Health:                        health,
+		RunningInstances:              int(s.Status.ReadyReplicas),
+		TargetInstances:               int(*s.Spec.Replicas),
+		Ports:                         ports,
+		LastUpdated:                   s.Annotations[AnnotationLastUpdated],
+		AppURIs:                       uris,
+		InternalRoutes:                internalRoutes,
+		MemoryMB:                      memory,
+		DiskMB:                        disk,
+		RunsAsRoot:                    runsAsRoot(s.Spec.Template.Spec.SecurityContext),
+		CPUWeight:                     uint8(cpu / 10), //nolint:gomnd
+		VolumeMounts:                  volMounts,
+		PlacementTags:                 placementTags,
+		EgressRules:                   egressRules,
+		Sidecars:                      sidecars,
+		LRP:                           s.Annotations[AnnotationOriginalRequest],
+		UserDefinedAnnotations:        userDefinedAnnotations(s.Annotations),
+		UpdateStrategy:                updateStrategy,
+		Autoscaling:                   autoscaling,
+		TerminationGracePeriodSeconds: terminationGracePeriod,
+		OwnedByCRD:                    metav1.GetControllerOf(&s) != nil,
+		OriginalImage:                 s.Annotations[AnnotationOriginalImage],
 	}, nil
 }
+
+func splitContainers(containers []corev1.Container) (corev1.Container, []corev1.Container) {
+	appContainer := containers[0]
+	sidecars := []corev1.Container{}
+
+	for _, c := range containers {
+		if c.Name == OPIContainerName {
+			appContainer = c
+
+			continue
+		}
+
+		if strings.HasPrefix(c.Name, SidecarContainerNamePrefix) {
+			sidecars = append(sidecars, c)
+		}
+	}
+
+	return appContainer, sidecars
+}
+
+func toSidecars(containers []corev1.Container, appEnv map[string]string) []opi.Sidecar {
+	sidecars := []opi.Sidecar{}
+
+	for _, c := range containers {
+		env := map[string]string{}
+
+		for k, v := range envVarsToMap(c.Env) {
+			if appValue, ok := appEnv[k]; !ok || appValue != v {
+				env[k] = v
+			}
+		}
+
+		sidecars = append(sidecars, opi.Sidecar{
+			Name:     strings.TrimPrefix(c.Name, SidecarContainerNamePrefix),
+			Command:  c.Command,
+			MemoryMB: c.Resources.Limits.Memory().ScaledValue(resource.Mega),
+			Env:      env,
+		})
+	}
+
+	return sidecars
+}
+
+func envVarsToMap(envVars []corev1.EnvVar) map[string]string {
+	env := map[string]string{}
+
+	for _, e := range envVars {
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+		}
+	}
+
+	return env
+}
+
+func getHealthCheck(s appsv1.StatefulSet, container corev1.Container) (opi.Healtcheck, error) {
+	stHealthCheck, ok := s.Annotations[AnnotationHealthCheck]
+	if !ok {
+		return healthCheckFromProbe(container.LivenessProbe), nil
+	}
+
+	var health opi.Healtcheck
+	if err := json.Unmarshal([]byte(stHealthCheck), &health); err != nil {
+		return opi.Healtcheck{}, errors.Wrap(err, "failed to unmarshal health check")
+	}
+
+	return health, nil
+}
+
+func healthCheckFromProbe(probe *corev1.Probe) opi.Healtcheck {
+	switch {
+	case probe == nil:
+		return opi.Healtcheck{}
+	case probe.HTTPGet != nil:
+		return opi.Healtcheck{
+			Type:     opi.HealthCheckTypeHTTP,
+			Port:     probe.HTTPGet.Port.IntVal,
+			Endpoint: probe.HTTPGet.Path,
+		}
+	case probe.TCPSocket != nil:
+		return opi.Healtcheck{
+			Type: opi.HealthCheckTypePort,
+			Port: probe.TCPSocket.Port.IntVal,
+		}
+	default:
+		return opi.Healtcheck{}
+	}
+}
+
+func runsAsRoot(securityContext *corev1.PodSecurityContext) bool {
+	return securityContext == nil || securityContext.RunAsNonRoot == nil || !*securityContext.RunAsNonRoot
+}
+
+func userDefinedAnnotations(annotations map[string]string) map[string]string {
+	userAnnotations := map[string]string{}
+
+	for k, v := range annotations {
+		if strings.HasPrefix(k, eiriniAnnotationPrefix) ||
+			strings.HasPrefix(k, corev1.AppArmorBetaContainerAnnotationKeyPrefix) ||
+			k == corev1.SeccompPodAnnotationKey {
+			continue
+		}
+
+		userAnnotations[k] = v
+	}
+
+	return userAnnotations
+}
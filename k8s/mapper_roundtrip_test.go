@@ -0,0 +1,350 @@
+package k8s_test
+
+import (
+	"fmt"
+	"math/rand"
+
+	"code.cloudfoundry.org/eirini"
+	. "code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	roundTripIterations   = 100
+	roundTripPlacementTag = "tag-%d"
+	roundTripMaxItems     = 4
+)
+
+var _ = Describe("StatefulSetToLRP round trip", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		secretsClient     *k8sfakes.FakeSecretsClient
+		desirer           *StatefulSetDesirer
+		random            *rand.Rand
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+		statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+		secretsClient = new(k8sfakes.FakeSecretsClient)
+		configMapClient := new(k8sfakes.FakeConfigMapClient)
+		configMapClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "coredns-custom"))
+		random = rand.New(rand.NewSource(GinkgoRandomSeed())) //nolint:gosec
+
+		nodePools := map[string]eirini.NodePool{}
+		for i := 0; i < roundTripMaxItems; i++ {
+			nodePools[fmt.Sprintf(roundTripPlacementTag, i)] = eirini.NodePool{
+				NodeSelector: map[string]string{fmt.Sprintf("pool-%d", i): "true"},
+			}
+		}
+
+		desirer = &StatefulSetDesirer{
+			Pods:                      new(k8sfakes.FakePodClient),
+			Secrets:                   secretsClient,
+			StatefulSets:              statefulSetClient,
+			PodDisruptionBudgets:      new(k8sfakes.FakePodDisruptionBudgetClient),
+			NetworkPolicies:           new(k8sfakes.FakeNetworkPolicyClient),
+			HorizontalPodAutoscalers:  new(k8sfakes.FakeHorizontalPodAutoscalerClient),
+			Services:                  new(k8sfakes.FakeServiceClient),
+			ConfigMaps:                configMapClient,
+			EventsClient:              new(k8sfakes.FakeEventsClient),
+			LivenessProbeCreator:      CreateLivenessProbe,
+			ReadinessProbeCreator:     CreateReadinessProbe,
+			StartupProbeCreator:       CreateStartupProbe,
+			Logger:                    lagertest.NewTestLogger("round-trip"),
+			StatefulSetToLRPMapper:    StatefulSetToLRP,
+			ApplicationServiceAccount: "eirini",
+			PlacementTagNodePools:     nodePools,
+		}
+	})
+
+	It("maps every desired LRP field back from the statefulset", func() {
+		for i := 0; i < roundTripIterations; i++ {
+			lrp := randomLRP(random)
+
+			Expect(desirer.Desire("the-namespace", lrp)).To(Succeed())
+			Expect(statefulSetClient.CreateCallCount()).To(Equal(i + 1))
+
+			namespace, statefulSet := statefulSetClient.CreateArgsForCall(i)
+			statefulSet.Namespace = namespace
+			statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{*statefulSet}, nil)
+
+			secretsClient.GetStub = func(_, name string) (*corev1.Secret, error) {
+				for j := 0; j < secretsClient.CreateCallCount(); j++ {
+					if _, secret := secretsClient.CreateArgsForCall(j); secret.Name == name {
+						return secret, nil
+					}
+				}
+
+				return nil, k8serrors.NewNotFound(schema.GroupResource{}, name)
+			}
+
+			mappedLRP, err := desirer.Get(lrp.LRPIdentifier)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(normalizeLRP(mappedLRP)).To(Equal(normalizeLRP(lrp)), "iteration %d, seed %d", i, GinkgoRandomSeed())
+		}
+	})
+})
+
+func randomLRP(random *rand.Rand) *opi.LRP {
+	env := randomEnv(random, "APP_")
+	sidecars := []opi.Sidecar{}
+	sidecarsMemoryMB := int64(0)
+
+	for i := 0; i < random.Intn(roundTripMaxItems); i++ {
+		memoryMB := int64(random.Intn(256) + 1)
+		sidecarsMemoryMB += memoryMB
+
+		sidecars = append(sidecars, opi.Sidecar{
+			Name:     fmt.Sprintf("%s-%d", randomName(random), i),
+			Command:  randomStrings(random),
+			MemoryMB: memoryMB,
+			Env:      randomEnv(random, "SIDECAR_"),
+		})
+	}
+
+	placementTags := []string{}
+	for _, i := range random.Perm(roundTripMaxItems)[:random.Intn(roundTripMaxItems)] {
+		placementTags = append(placementTags, fmt.Sprintf(roundTripPlacementTag, i))
+	}
+
+	return &opi.LRP{
+		LRPIdentifier: opi.LRPIdentifier{
+			GUID:    randomName(random),
+			Version: randomName(random),
+		},
+		ProcessType:     randomName(random),
+		AppName:         randomName(random),
+		AppGUID:         randomName(random),
+		OrgName:         randomName(random),
+		OrgGUID:         randomName(random),
+		SpaceName:       randomName(random),
+		SpaceGUID:       randomName(random),
+		Image:           randomName(random) + "/" + randomName(random),
+		Command:         randomStrings(random),
+		Env:             env,
+		Health:          randomHealthCheck(random),
+		Ports:           randomPorts(random),
+		TargetInstances: random.Intn(10),
+		MemoryMB:        sidecarsMemoryMB + int64(random.Intn(4096)+1),
+		DiskMB:          int64(random.Intn(4096)),
+		RunsAsRoot:      random.Intn(2) == 0,
+		CPUWeight:       uint8(random.Intn(101)),
+		VolumeMounts:    randomVolumeMounts(random),
+		PlacementTags:   placementTags,
+		EgressRules:     randomEgressRules(random),
+		UpdateStrategy: opi.UpdateStrategy{
+			MaxUnavailable:  int32(random.Intn(3)),
+			CanaryInstances: int32(random.Intn(3)),
+		},
+		Autoscaling:                   randomAutoscalingPolicy(random),
+		TerminationGracePeriodSeconds: int64(random.Intn(120)),
+		OriginalImage:                 randomName(random),
+		Sidecars:                      sidecars,
+		LRP:                           fmt.Sprintf(`{"process_guid":%q}`, randomName(random)),
+		AppURIs:                       randomRoutes(random),
+		InternalRoutes:                randomInternalRoutes(random),
+		LastUpdated:                   fmt.Sprintf("%d.0", random.Int63()),
+		UserDefinedAnnotations: map[string]string{
+			"prometheus.io/" + randomName(random): randomName(random),
+		},
+	}
+}
+
+func randomHealthCheck(random *rand.Rand) opi.Healtcheck {
+	types := []string{"", opi.HealthCheckTypeHTTP, opi.HealthCheckTypePort, opi.HealthCheckTypeProcess}
+	schemes := []string{"", "http", "https"}
+
+	return opi.Healtcheck{
+		Type:                types[random.Intn(len(types))],
+		Port:                int32(random.Intn(65535) + 1),
+		Endpoint:            "/" + randomName(random),
+		TimeoutMs:           uint(random.Intn(60000)),
+		StartTimeoutMs:      uint(random.Intn(600000)),
+		InvocationTimeoutMs: uint(random.Intn(10000)),
+		IntervalMs:          uint(random.Intn(60000)),
+		Scheme:              schemes[random.Intn(len(schemes))],
+		Host:                randomName(random),
+	}
+}
+
+func randomEgressRules(random *rand.Rand) []opi.EgressRule {
+	rules := []opi.EgressRule{}
+
+	for i := 0; i < random.Intn(roundTripMaxItems); i++ {
+		rules = append(rules, opi.EgressRule{
+			Protocol:     "tcp",
+			Destinations: []string{fmt.Sprintf("10.%d.%d.0/24", random.Intn(256), random.Intn(256))},
+			Ports:        randomPorts(random),
+		})
+	}
+
+	return rules
+}
+
+func randomVolumeMounts(random *rand.Rand) []opi.VolumeMount {
+	mounts := []opi.VolumeMount{}
+
+	for i := 0; i < random.Intn(roundTripMaxItems); i++ {
+		mounts = append(mounts, opi.VolumeMount{
+			ClaimName: fmt.Sprintf("%s-%d", randomName(random), i),
+			MountPath: "/" + randomName(random),
+		})
+	}
+
+	return mounts
+}
+
+func randomRoutes(random *rand.Rand) []opi.Route {
+	routes := []opi.Route{}
+
+	for i := 0; i < random.Intn(roundTripMaxItems); i++ {
+		routes = append(routes, opi.Route{
+			Hostname: randomName(random) + ".example.com",
+			Port:     int32(random.Intn(65535) + 1),
+		})
+	}
+
+	return routes
+}
+
+func randomInternalRoutes(random *rand.Rand) []opi.InternalRoute {
+	routes := []opi.InternalRoute{}
+
+	for i := 0; i < random.Intn(roundTripMaxItems); i++ {
+		routes = append(routes, opi.InternalRoute{Hostname: randomName(random) + ".apps.internal"})
+	}
+
+	return routes
+}
+
+func randomPorts(random *rand.Rand) []int32 {
+	ports := []int32{}
+
+	for i := 0; i < random.Intn(roundTripMaxItems); i++ {
+		ports = append(ports, int32(random.Intn(65535)+1))
+	}
+
+	return ports
+}
+
+func randomEnv(random *rand.Rand, prefix string) map[string]string {
+	env := map[string]string{}
+
+	for i := 0; i < random.Intn(roundTripMaxItems); i++ {
+		env[fmt.Sprintf("%s%d", prefix, i)] = randomName(random)
+	}
+
+	return env
+}
+
+func randomStrings(random *rand.Rand) []string {
+	strs := []string{}
+
+	for i := 0; i < random.Intn(roundTripMaxItems); i++ {
+		strs = append(strs, randomName(random))
+	}
+
+	return strs
+}
+
+func randomName(random *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	name := make([]byte, random.Intn(10)+1)
+	name[0] = letters[random.Intn(26)]
+
+	for i := 1; i < len(name); i++ {
+		name[i] = letters[random.Intn(len(letters))]
+	}
+
+	return string(name)
+}
+
+func normalizeLRP(lrp *opi.LRP) *opi.LRP {
+	normalized := *lrp
+	normalized.Command = nilIfEmptyStrings(lrp.Command)
+	normalized.Env = nilIfEmptyMap(lrp.Env)
+	normalized.UserDefinedAnnotations = nilIfEmptyMap(lrp.UserDefinedAnnotations)
+
+	if len(lrp.Ports) == 0 {
+		normalized.Ports = nil
+	}
+
+	if len(lrp.VolumeMounts) == 0 {
+		normalized.VolumeMounts = nil
+	}
+
+	if len(lrp.PlacementTags) == 0 {
+		normalized.PlacementTags = nil
+	}
+
+	normalized.EgressRules = nil
+	for _, rule := range lrp.EgressRules {
+		if len(rule.Ports) == 0 {
+			rule.Ports = nil
+		}
+
+		normalized.EgressRules = append(normalized.EgressRules, rule)
+	}
+
+	if len(lrp.AppURIs) == 0 {
+		normalized.AppURIs = nil
+	}
+
+	if len(lrp.InternalRoutes) == 0 {
+		normalized.InternalRoutes = nil
+	}
+
+	normalized.Sidecars = nil
+	for _, sidecar := range lrp.Sidecars {
+		normalized.Sidecars = append(normalized.Sidecars, opi.Sidecar{
+			Name:     sidecar.Name,
+			Command:  nilIfEmptyStrings(sidecar.Command),
+			MemoryMB: sidecar.MemoryMB,
+			Env:      nilIfEmptyMap(sidecar.Env),
+		})
+	}
+
+	return &normalized
+}
+
+func nilIfEmptyStrings(strs []string) []string {
+	if len(strs) == 0 {
+		return nil
+	}
+
+	return strs
+}
+
+func nilIfEmptyMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+func randomAutoscalingPolicy(random *rand.Rand) *opi.AutoscalingPolicy {
+	if random.Intn(2) == 0 {
+		return nil
+	}
+
+	minInstances := int32(random.Intn(5) + 1)
+
+	return &opi.AutoscalingPolicy{
+		MinInstances:        minInstances,
+		MaxInstances:        minInstances + int32(random.Intn(5)),
+		TargetCPUPercent:    int32(random.Intn(100)),
+		TargetMemoryPercent: int32(random.Intn(100)),
+	}
+}
@@ -9,36 +9,63 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 var _ = Describe("Mapper", func() {
-	var lrp *opi.LRP
+	var (
+		lrp         *opi.LRP
+		statefulset appsv1.StatefulSet
+	)
 
 	BeforeEach(func() {
-		statefulset := appsv1.StatefulSet{
+		statefulset = appsv1.StatefulSet{
 			ObjectMeta: meta.ObjectMeta{
 				Name:      "baldur",
 				Namespace: "baldur-ns",
 				Labels: map[string]string{
-					LabelGUID: "Bald-guid",
+					LabelGUID:        "Bald-guid",
+					LabelProcessType: "worker",
 				},
 				Annotations: map[string]string{
-					AnnotationProcessGUID:      "Baldur-guid",
-					AnnotationLastUpdated:      "last-updated-some-time-ago",
-					AnnotationRegisteredRoutes: `[{"hostname":"my.example.route","port":8080}]`,
-					AnnotationAppID:            "guid_1234",
-					AnnotationVersion:          "version_1234",
-					AnnotationAppName:          "Baldur",
-					AnnotationSpaceName:        "space-foo",
+					AnnotationProcessGUID:            "Baldur-guid",
+					AnnotationLastUpdated:            "last-updated-some-time-ago",
+					AnnotationRegisteredRoutes:       `[{"hostname":"my.example.route","port":8080}]`,
+					AnnotationAppID:                  "guid_1234",
+					AnnotationVersion:                "version_1234",
+					AnnotationAppName:                "Baldur",
+					AnnotationSpaceName:              "space-foo",
+					AnnotationSpaceGUID:              "space-guid",
+					AnnotationOrgName:                "org-foo",
+					AnnotationOrgGUID:                "org-guid",
+					AnnotationOriginalRequest:        "original request",
+					AnnotationHealthCheck:            `{"type":"http","port":8080,"endpoint":"/healthz","timeoutMs":3000,"startTimeoutMs":60000}`,
+					corev1.SeccompPodAnnotationKey:   corev1.SeccompProfileRuntimeDefault,
+					"prometheus.io/scrape":           "secret-value",
+					AnnotationPlacementTags:          `["isolated"]`,
+					AnnotationEgressRules:            `[{"protocol":"tcp","destinations":["10.0.0.0/8"],"portRange":{"start":8000,"end":8080}}]`,
+					AnnotationUpdateStrategy:         `{"maxUnavailable":2,"canaryInstances":1}`,
+					AnnotationAutoscaling:            `{"minInstances":2,"maxInstances":6,"targetCPUPercent":80}`,
+					AnnotationTerminationGracePeriod: "45",
+					AnnotationOriginalImage:          "busybox:latest",
 				},
 			},
 			Spec: appsv1.StatefulSetSpec{
 				Replicas: int32ptr(3),
 				Template: corev1.PodTemplateSpec{
 					Spec: corev1.PodSpec{
+						SecurityContext: &corev1.PodSecurityContext{
+							RunAsNonRoot: boolptr(true),
+						},
 						Containers: []corev1.Container{
 							{
 								Image: "busybox",
+								Env: []corev1.EnvVar{
+									{Name: "FOO", Value: "foo"},
+									{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+									}},
+								},
 								Command: []string{
 									"/bin/sh",
 									"-c",
@@ -55,6 +82,7 @@ var _ = Describe("Mapper", func() {
 								Resources: corev1.ResourceRequirements{
 									Requests: corev1.ResourceList{
 										corev1.ResourceMemory: *resource.NewScaledQuantity(1024, resource.Mega),
+										corev1.ResourceCPU:    *resource.NewScaledQuantity(500, resource.Milli),
 									},
 									Limits: corev1.ResourceList{
 										corev1.ResourceEphemeralStorage: *resource.NewScaledQuantity(2048, resource.Mega),
@@ -75,6 +103,9 @@ var _ = Describe("Mapper", func() {
 				ReadyReplicas: 2,
 			},
 		}
+	})
+
+	JustBeforeEach(func() {
 		lrp, _ = StatefulSetToLRP(statefulset)
 	})
 
@@ -137,6 +168,173 @@ var _ = Describe("Mapper", func() {
 		}))
 	})
 
+	It("should set the correct LRP placement tags", func() {
+		Expect(lrp.PlacementTags).To(Equal([]string{"isolated"}))
+	})
+
+	It("should set the correct LRP egress rules", func() {
+		Expect(lrp.EgressRules).To(Equal([]opi.EgressRule{
+			{
+				Protocol:     "tcp",
+				Destinations: []string{"10.0.0.0/8"},
+				PortRange:    &opi.PortRange{Start: 8000, End: 8080},
+			},
+		}))
+	})
+
+	It("should set the correct LRP process type", func() {
+		Expect(lrp.ProcessType).To(Equal("worker"))
+	})
+
+	It("should set the correct LRP org and space", func() {
+		Expect(lrp.OrgName).To(Equal("org-foo"))
+		Expect(lrp.OrgGUID).To(Equal("org-guid"))
+		Expect(lrp.SpaceGUID).To(Equal("space-guid"))
+	})
+
+	It("should set the LRP env, ignoring env vars that are computed at runtime", func() {
+		Expect(lrp.Env).To(Equal(map[string]string{"FOO": "foo"}))
+	})
+
+	It("should set the correct LRP health check", func() {
+		Expect(lrp.Health).To(Equal(opi.Healtcheck{
+			Type:           "http",
+			Port:           8080,
+			Endpoint:       "/healthz",
+			TimeoutMs:      3000,
+			StartTimeoutMs: 60000,
+		}))
+	})
+
+	It("should set the LRP update strategy", func() {
+		Expect(lrp.UpdateStrategy).To(Equal(opi.UpdateStrategy{MaxUnavailable: 2, CanaryInstances: 1}))
+	})
+
+	It("should set the LRP autoscaling policy", func() {
+		Expect(lrp.Autoscaling).To(Equal(&opi.AutoscalingPolicy{MinInstances: 2, MaxInstances: 6, TargetCPUPercent: 80}))
+	})
+
+	When("the statefulset is not autoscaled", func() {
+		BeforeEach(func() {
+			delete(statefulset.Annotations, AnnotationAutoscaling)
+		})
+
+		It("should not set an autoscaling policy", func() {
+			Expect(lrp.Autoscaling).To(BeNil())
+		})
+	})
+
+	It("should set the LRP termination grace period", func() {
+		Expect(lrp.TerminationGracePeriodSeconds).To(Equal(int64(45)))
+	})
+
+	It("should set the original image", func() {
+		Expect(lrp.OriginalImage).To(Equal("busybox:latest"))
+	})
+
+	It("should set the correct LRP CPU weight", func() {
+		Expect(lrp.CPUWeight).To(Equal(uint8(50)))
+	})
+
+	It("should set whether the LRP runs as root", func() {
+		Expect(lrp.RunsAsRoot).To(BeFalse())
+	})
+
+	It("should set the LRP original request", func() {
+		Expect(lrp.LRP).To(Equal("original request"))
+	})
+
+	It("should set the user defined annotations", func() {
+		Expect(lrp.UserDefinedAnnotations).To(Equal(map[string]string{"prometheus.io/scrape": "secret-value"}))
+	})
+
+	It("should not mark the LRP as owned by the CRD", func() {
+		Expect(lrp.OwnedByCRD).To(BeFalse())
+	})
+
+	When("the statefulset is controlled by an LRP custom resource", func() {
+		BeforeEach(func() {
+			controller := true
+			statefulset.OwnerReferences = []meta.OwnerReference{{
+				APIVersion: "eirini.cloudfoundry.org/v1",
+				Kind:       "LRP",
+				Name:       "baldur",
+				Controller: &controller,
+			}}
+		})
+
+		It("should mark the LRP as owned by the CRD", func() {
+			Expect(lrp.OwnedByCRD).To(BeTrue())
+		})
+	})
+
+	When("the statefulset has no security context", func() {
+		BeforeEach(func() {
+			statefulset.Spec.Template.Spec.SecurityContext = nil
+		})
+
+		It("should run the LRP as root", func() {
+			Expect(lrp.RunsAsRoot).To(BeTrue())
+		})
+	})
+
+	When("the statefulset has no health check annotation", func() {
+		BeforeEach(func() {
+			delete(statefulset.Annotations, AnnotationHealthCheck)
+			statefulset.Spec.Template.Spec.Containers[0].LivenessProbe = &corev1.Probe{
+				Handler: corev1.Handler{
+					HTTPGet: &corev1.HTTPGetAction{
+						Path: "/health",
+						Port: intstr.FromInt(9000),
+					},
+				},
+			}
+		})
+
+		It("should infer the health check from the liveness probe", func() {
+			Expect(lrp.Health).To(Equal(opi.Healtcheck{Type: "http", Port: 9000, Endpoint: "/health"}))
+		})
+	})
+
+	It("should not set any sidecars", func() {
+		Expect(lrp.Sidecars).To(BeEmpty())
+	})
+
+	When("the statefulset has sidecar containers", func() {
+		BeforeEach(func() {
+			containers := statefulset.Spec.Template.Spec.Containers
+			containers[0].Name = OPIContainerName
+			containers[0].Resources.Requests[corev1.ResourceMemory] = *resource.NewScaledQuantity(900, resource.Mega)
+			statefulset.Spec.Template.Spec.Containers = append([]corev1.Container{
+				{
+					Name:    "sidecar-metrics-agent",
+					Command: []string{"./metrics-agent"},
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: *resource.NewScaledQuantity(124, resource.Mega),
+						},
+					},
+				},
+				{Name: "istio-proxy"},
+			}, containers...)
+		})
+
+		It("should map the app container regardless of its position", func() {
+			Expect(lrp.Image).To(Equal("busybox"))
+			Expect(lrp.Ports).To(Equal([]int32{8888, 9999}))
+		})
+
+		It("should set the sidecars", func() {
+			Expect(lrp.Sidecars).To(Equal([]opi.Sidecar{
+				{Name: "metrics-agent", Command: []string{"./metrics-agent"}, MemoryMB: 124, Env: map[string]string{}},
+			}))
+		})
+
+		It("should add the sidecars memory to the LRP memory", func() {
+			Expect(lrp.MemoryMB).To(Equal(int64(1024)))
+		})
+	})
+
 	When("route marshalling fails", func() {
 		It("should return the error", func() {
 			statefulset := appsv1.StatefulSet{
@@ -150,4 +348,118 @@ var _ = Describe("Mapper", func() {
 			Expect(err).To(MatchError(ContainSubstring("failed to unmarshal uris")))
 		})
 	})
+
+	When("placement tags unmarshalling fails", func() {
+		It("should return the error", func() {
+			statefulset := appsv1.StatefulSet{
+				ObjectMeta: meta.ObjectMeta{
+					Annotations: map[string]string{
+						AnnotationRegisteredRoutes: `[]`,
+						AnnotationPlacementTags:    `[`,
+					},
+				},
+			}
+			_, err := StatefulSetToLRP(statefulset)
+			Expect(err).To(MatchError(ContainSubstring("failed to unmarshal placement tags")))
+		})
+	})
+
+	When("health check unmarshalling fails", func() {
+		It("should return the error", func() {
+			statefulset := appsv1.StatefulSet{
+				ObjectMeta: meta.ObjectMeta{
+					Annotations: map[string]string{
+						AnnotationRegisteredRoutes: `[]`,
+						AnnotationHealthCheck:      `{`,
+					},
+				},
+				Spec: appsv1.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{}}},
+					},
+				},
+			}
+			_, err := StatefulSetToLRP(statefulset)
+			Expect(err).To(MatchError(ContainSubstring("failed to unmarshal health check")))
+		})
+	})
+
+	When("update strategy unmarshalling fails", func() {
+		It("should return the error", func() {
+			statefulset := appsv1.StatefulSet{
+				ObjectMeta: meta.ObjectMeta{
+					Annotations: map[string]string{
+						AnnotationRegisteredRoutes: `[]`,
+						AnnotationUpdateStrategy:   `{`,
+					},
+				},
+				Spec: appsv1.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{}}},
+					},
+				},
+			}
+			_, err := StatefulSetToLRP(statefulset)
+			Expect(err).To(MatchError(ContainSubstring("failed to unmarshal update strategy")))
+		})
+	})
+
+	When("autoscaling policy unmarshalling fails", func() {
+		It("should return the error", func() {
+			statefulset := appsv1.StatefulSet{
+				ObjectMeta: meta.ObjectMeta{
+					Annotations: map[string]string{
+						AnnotationRegisteredRoutes: `[]`,
+						AnnotationAutoscaling:      `{`,
+					},
+				},
+				Spec: appsv1.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{}}},
+					},
+				},
+			}
+			_, err := StatefulSetToLRP(statefulset)
+			Expect(err).To(MatchError(ContainSubstring("failed to unmarshal autoscaling policy")))
+		})
+	})
+
+	When("the termination grace period is invalid", func() {
+		It("should return the error", func() {
+			statefulset := appsv1.StatefulSet{
+				ObjectMeta: meta.ObjectMeta{
+					Annotations: map[string]string{
+						AnnotationRegisteredRoutes:       `[]`,
+						AnnotationTerminationGracePeriod: "forever",
+					},
+				},
+				Spec: appsv1.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{}}},
+					},
+				},
+			}
+			_, err := StatefulSetToLRP(statefulset)
+			Expect(err).To(MatchError(ContainSubstring("failed to parse termination grace period")))
+		})
+	})
+
+	When("egress rules unmarshalling fails", func() {
+		It("should return the error", func() {
+			statefulset := appsv1.StatefulSet{
+				ObjectMeta: meta.ObjectMeta{
+					Annotations: map[string]string{
+						AnnotationRegisteredRoutes: `[]`,
+						AnnotationEgressRules:      `[`,
+					},
+				},
+			}
+			_, err := StatefulSetToLRP(statefulset)
+			Expect(err).To(MatchError(ContainSubstring("failed to unmarshal egress rules")))
+		})
+	})
 })
+
+func boolptr(b bool) *bool {
+	return &b
+}
@@ -95,10 +95,7 @@ func (c *metricsCollector) collectMetrics(pods []apiv1.Pod) []metrics.Message {
 
 		cpuPercentage, memoryValue := parseMetrics(podMetrics[pod.Name])
 
-		appContainer := pod.Spec.Containers[0]
-		memoryLimit := appContainer.Resources.Limits.Memory()
-		diskLimit := appContainer.Resources.Limits.StorageEphemeral()
-
+		memoryLimit, diskLimit := parseLimits(pod)
 		diskUsage := diskMetrics[pod.Name]
 
 		messages = append(messages, metrics.Message{
@@ -106,9 +103,9 @@ func (c *metricsCollector) collectMetrics(pods []apiv1.Pod) []metrics.Message {
 			IndexID:     strconv.Itoa(indexID),
 			CPU:         cpuPercentage,
 			Memory:      memoryValue,
-			MemoryQuota: float64(memoryLimit.Value()),
+			MemoryQuota: float64(memoryLimit),
 			Disk:        diskUsage,
-			DiskQuota:   float64(diskLimit.Value()),
+			DiskQuota:   float64(diskLimit),
 		})
 	}
 
@@ -116,16 +113,22 @@ func (c *metricsCollector) collectMetrics(pods []apiv1.Pod) []metrics.Message {
 }
 
 func parseMetrics(metric v1beta1.PodMetrics) (cpu float64, memory float64) {
-	if len(metric.Containers) == 0 {
-		return
+	for _, container := range metric.Containers {
+		usage := container.Usage
+		res := usage[apiv1.ResourceCPU]
+		cpu += toCPUPercentage(res.MilliValue())
+		res = usage[apiv1.ResourceMemory]
+		memory += float64(res.Value())
 	}
 
-	container := metric.Containers[0]
-	usage := container.Usage
-	res := usage[apiv1.ResourceCPU]
-	cpu = toCPUPercentage(res.MilliValue())
-	res = usage[apiv1.ResourceMemory]
-	memory = float64(res.Value())
+	return
+}
+
+func parseLimits(pod apiv1.Pod) (memory int64, disk int64) {
+	for _, container := range pod.Spec.Containers {
+		memory += container.Resources.Limits.Memory().Value()
+		disk += container.Resources.Limits.StorageEphemeral().Value()
+	}
 
 	return
 }
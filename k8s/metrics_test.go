@@ -82,6 +82,47 @@ var _ = Describe("Metrics", func() {
 			})
 		})
 
+		When("the pods have sidecar containers", func() {
+			It("should add up the usage and limits of all containers", func() {
+				podMetric := createMetrics(podName1)
+				podMetric.Containers = append(podMetric.Containers, metricsv1beta1api.ContainerMetrics{
+					Usage: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("1000m"),
+						v1.ResourceMemory: resource.MustParse("100Ki"),
+					},
+				})
+				podMetricsClient.ListReturns(&metricsv1beta1api.PodMetricsList{
+					Items: []metricsv1beta1api.PodMetrics{podMetric},
+				}, nil)
+
+				pod := createPod(podName1)
+				pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							v1.ResourceMemory: *resource.NewScaledQuantity(200, resource.Kilo),
+						},
+					},
+				})
+				podClient.GetAllReturns([]v1.Pod{*pod}, nil)
+
+				diskClient.GetPodMetricsReturns(map[string]float64{podName1: 50}, nil)
+
+				collected, err := collector.Collect()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(collected).To(ConsistOf(
+					metrics.Message{
+						AppID:       podName1,
+						IndexID:     "9000",
+						CPU:         520.5,
+						Memory:      532480,
+						MemoryQuota: 1000000,
+						Disk:        50,
+						DiskQuota:   10000000,
+					},
+				))
+			})
+		})
+
 		When("there are no pods", func() {
 			It("should return empty list", func() {
 				podClient.GetAllReturns([]v1.Pod{}, nil)
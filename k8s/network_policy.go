@@ -0,0 +1,253 @@
+package k8s
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	egressProtocolTCP  = "tcp"
+	egressProtocolUDP  = "udp"
+	egressProtocolICMP = "icmp"
+	egressProtocolAll  = "all"
+
+	dnsPort  = 53
+	minPort  = 1
+	maxPort  = 65535
+	ipv4Bits = 32
+
+	// Port ranges are expanded into single ports, as NetworkPolicies
+	// cannot express ranges. Wider ranges allow all the ports of their
+	// protocol instead, in order to keep the resulting object within the
+	// etcd size limits.
+	maxExpandedPortRange = 1024
+
+	// DNS traffic is only allowed to the cluster DNS pods, which both
+	// kube-dns and CoreDNS label this way.
+	clusterDNSLabel      = "k8s-app"
+	clusterDNSLabelValue = "kube-dns"
+)
+
+// toNetworkPolicy translates the egress rules of an LRP. NetworkPolicies
+// cannot express ICMP, so ICMP rules are skipped: as soon as the LRP has a
+// network policy its instances cannot send any ICMP traffic, whatever its
+// security groups allow.
+func (m *StatefulSetDesirer) toNetworkPolicy(logger lager.Logger, name string, lrp *opi.LRP) (*networkingv1.NetworkPolicy, error) {
+	egressRules := []networkingv1.NetworkPolicyEgressRule{dnsEgressRule()}
+
+	for _, rule := range lrp.EgressRules {
+		if rule.Protocol == egressProtocolICMP {
+			logger.Info("skipping-unsupported-icmp-egress-rule", lager.Data{"destinations": rule.Destinations})
+
+			continue
+		}
+
+		egressRule, err := toNetworkPolicyEgressRule(logger, rule)
+		if err != nil {
+			return nil, err
+		}
+
+		egressRules = append(egressRules, egressRule)
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				LabelGUID:       lrp.GUID,
+				LabelVersion:    lrp.Version,
+				LabelAppGUID:    lrp.AppGUID,
+				LabelSourceType: appSourceType,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: *m.labelSelector(lrp),
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      egressRules,
+		},
+	}, nil
+}
+
+func dnsEgressRule() networkingv1.NetworkPolicyEgressRule {
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+	port := intstr.FromInt(dnsPort)
+
+	return networkingv1.NetworkPolicyEgressRule{
+		To: []networkingv1.NetworkPolicyPeer{{
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{clusterDNSLabel: clusterDNSLabelValue},
+			},
+		}},
+		Ports: []networkingv1.NetworkPolicyPort{
+			{Protocol: &udp, Port: &port},
+			{Protocol: &tcp, Port: &port},
+		},
+	}
+}
+
+func toNetworkPolicyEgressRule(logger lager.Logger, rule opi.EgressRule) (networkingv1.NetworkPolicyEgressRule, error) {
+	peers, err := toNetworkPolicyPeers(rule.Destinations)
+	if err != nil {
+		return networkingv1.NetworkPolicyEgressRule{}, err
+	}
+
+	ports, err := toNetworkPolicyPorts(logger, rule)
+	if err != nil {
+		return networkingv1.NetworkPolicyEgressRule{}, err
+	}
+
+	return networkingv1.NetworkPolicyEgressRule{
+		To:    peers,
+		Ports: ports,
+	}, nil
+}
+
+func toNetworkPolicyPorts(logger lager.Logger, rule opi.EgressRule) ([]networkingv1.NetworkPolicyPort, error) {
+	var protocol corev1.Protocol
+
+	switch rule.Protocol {
+	case egressProtocolAll:
+		return nil, nil
+	case egressProtocolTCP:
+		protocol = corev1.ProtocolTCP
+	case egressProtocolUDP:
+		protocol = corev1.ProtocolUDP
+	default:
+		return nil, fmt.Errorf("unsupported egress rule protocol %q", rule.Protocol)
+	}
+
+	ports := []networkingv1.NetworkPolicyPort{}
+
+	for _, p := range rule.Ports {
+		port := intstr.FromInt(int(p))
+		ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+	}
+
+	if rule.PortRange != nil {
+		start, end := rule.PortRange.Start, rule.PortRange.End
+		if start > end {
+			return nil, fmt.Errorf("invalid egress rule port range %d-%d", start, end)
+		}
+
+		if start <= minPort && end >= maxPort {
+			return []networkingv1.NetworkPolicyPort{{Protocol: &protocol}}, nil
+		}
+
+		if end-start+1 > maxExpandedPortRange {
+			logger.Info("allowing-all-ports-of-wide-port-range", lager.Data{
+				"protocol":     rule.Protocol,
+				"destinations": rule.Destinations,
+				"port-range":   fmt.Sprintf("%d-%d", start, end),
+			})
+
+			return []networkingv1.NetworkPolicyPort{{Protocol: &protocol}}, nil
+		}
+
+		for p := start; p <= end; p++ {
+			port := intstr.FromInt(int(p))
+			ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+		}
+	}
+
+	if len(ports) == 0 {
+		return []networkingv1.NetworkPolicyPort{{Protocol: &protocol}}, nil
+	}
+
+	return ports, nil
+}
+
+func toNetworkPolicyPeers(destinations []string) ([]networkingv1.NetworkPolicyPeer, error) {
+	peers := []networkingv1.NetworkPolicyPeer{}
+
+	for _, destination := range destinations {
+		cidrs, err := destinationToCIDRs(destination)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cidr := range cidrs {
+			peers = append(peers, networkingv1.NetworkPolicyPeer{
+				IPBlock: &networkingv1.IPBlock{CIDR: cidr},
+			})
+		}
+	}
+
+	return peers, nil
+}
+
+func destinationToCIDRs(destination string) ([]string, error) {
+	switch {
+	case strings.Contains(destination, "/"):
+		_, ipNet, err := net.ParseCIDR(destination)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid egress rule destination %q", destination)
+		}
+
+		return []string{ipNet.String()}, nil
+
+	case strings.Contains(destination, "-"):
+		bounds := strings.SplitN(destination, "-", 2) //nolint:gomnd
+		start := net.ParseIP(strings.TrimSpace(bounds[0])).To4()
+		end := net.ParseIP(strings.TrimSpace(bounds[1])).To4()
+
+		if start == nil || end == nil {
+			return nil, fmt.Errorf("invalid egress rule destination range %q", destination)
+		}
+
+		startInt, endInt := binary.BigEndian.Uint32(start), binary.BigEndian.Uint32(end)
+		if startInt > endInt {
+			return nil, fmt.Errorf("invalid egress rule destination range %q", destination)
+		}
+
+		return ipv4RangeToCIDRs(startInt, endInt), nil
+
+	default:
+		ip := net.ParseIP(destination)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid egress rule destination %q", destination)
+		}
+
+		if ip.To4() != nil {
+			return []string{fmt.Sprintf("%s/32", ip)}, nil
+		}
+
+		return []string{fmt.Sprintf("%s/128", ip)}, nil
+	}
+}
+
+func ipv4RangeToCIDRs(start, end uint32) []string {
+	cidrs := []string{}
+
+	for current := uint64(start); current <= uint64(end); {
+		prefix := ipv4Bits
+
+		for prefix > 0 {
+			size := uint64(1) << (ipv4Bits - prefix + 1)
+			if current%size != 0 || current+size-1 > uint64(end) {
+				break
+			}
+
+			prefix--
+		}
+
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(current))
+		cidrs = append(cidrs, fmt.Sprintf("%s/%d", ip, prefix))
+
+		current += uint64(1) << (ipv4Bits - prefix)
+	}
+
+	return cidrs
+}
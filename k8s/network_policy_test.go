@@ -0,0 +1,281 @@
+package k8s_test
+
+import (
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("Network Policy", func() {
+	var (
+		statefulSetDesirer  *k8s.StatefulSetDesirer
+		networkPolicyClient *k8sfakes.FakeNetworkPolicyClient
+		lrp                 *opi.LRP
+		desireErr           error
+		policy              *networkingv1.NetworkPolicy
+	)
+
+	BeforeEach(func() {
+		networkPolicyClient = new(k8sfakes.FakeNetworkPolicyClient)
+		statefulSetClient := new(k8sfakes.FakeStatefulSetClient)
+		statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+		livenessProbeCreator := new(k8sfakes.FakeProbeCreator)
+		readinessProbeCreator := new(k8sfakes.FakeProbeCreator)
+
+		statefulSetDesirer = &k8s.StatefulSetDesirer{
+			Pods:                   new(k8sfakes.FakePodClient),
+			Secrets:                new(k8sfakes.FakeSecretsClient),
+			StatefulSets:           statefulSetClient,
+			PodDisruptionBudgets:   new(k8sfakes.FakePodDisruptionBudgetClient),
+			NetworkPolicies:        networkPolicyClient,
+			EventsClient:           new(k8sfakes.FakeEventsClient),
+			StatefulSetToLRPMapper: new(k8sfakes.FakeLRPMapper).Spy,
+			LivenessProbeCreator:   livenessProbeCreator.Spy,
+			ReadinessProbeCreator:  readinessProbeCreator.Spy,
+			StartupProbeCreator:    new(k8sfakes.FakeProbeCreator).Spy,
+			Logger:                 lagertest.NewTestLogger("network-policy-test"),
+		}
+
+		lrp = createLRP("Baldur", []opi.Route{})
+		policy = nil
+	})
+
+	JustBeforeEach(func() {
+		desireErr = statefulSetDesirer.Desire("the-namespace", lrp)
+
+		if networkPolicyClient.CreateCallCount() > 0 {
+			_, policy = networkPolicyClient.CreateArgsForCall(0)
+		}
+	})
+
+	egressRules := func() []networkingv1.NetworkPolicyEgressRule {
+		Expect(policy).NotTo(BeNil())
+		Expect(policy.Spec.Egress).NotTo(BeEmpty())
+
+		return policy.Spec.Egress[1:]
+	}
+
+	tcpPort := func(port int) networkingv1.NetworkPolicyPort {
+		protocol := corev1.ProtocolTCP
+		p := intstr.FromInt(port)
+
+		return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &p}
+	}
+
+	ipBlock := func(cidr string) networkingv1.NetworkPolicyPeer {
+		return networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}
+	}
+
+	When("the lrp has egress rules", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{
+				{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}, Ports: []int32{80, 443}},
+			}
+		})
+
+		It("succeeds", func() {
+			Expect(desireErr).NotTo(HaveOccurred())
+		})
+
+		It("selects the lrp pods", func() {
+			Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{
+				k8s.LabelGUID:       "guid_1234",
+				k8s.LabelVersion:    "version_1234",
+				k8s.LabelSourceType: "APP",
+			}))
+		})
+
+		It("labels the policy", func() {
+			Expect(policy.Labels).To(HaveKeyWithValue(k8s.LabelGUID, "guid_1234"))
+			Expect(policy.Labels).To(HaveKeyWithValue(k8s.LabelVersion, "version_1234"))
+			Expect(policy.Labels).To(HaveKeyWithValue(k8s.LabelAppGUID, "premium_app_guid_1234"))
+			Expect(policy.Labels).To(HaveKeyWithValue(k8s.LabelSourceType, "APP"))
+		})
+
+		When("the network policy already exists", func() {
+			BeforeEach(func() {
+				networkPolicyClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "baldur"))
+			})
+
+			It("updates it", func() {
+				Expect(desireErr).NotTo(HaveOccurred())
+				Expect(networkPolicyClient.UpdateCallCount()).To(Equal(1))
+				namespace, updatedPolicy := networkPolicyClient.UpdateArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(updatedPolicy).To(Equal(policy))
+			})
+		})
+
+		It("only restricts egress traffic", func() {
+			Expect(policy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeEgress))
+			Expect(policy.Spec.Ingress).To(BeEmpty())
+		})
+
+		It("always allows DNS to the cluster DNS pods", func() {
+			udp := corev1.ProtocolUDP
+			dnsPort := intstr.FromInt(53)
+
+			Expect(policy.Spec.Egress[0].To).To(ConsistOf(networkingv1.NetworkPolicyPeer{
+				NamespaceSelector: &metav1.LabelSelector{},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+			}))
+			Expect(policy.Spec.Egress[0].Ports).To(ConsistOf(
+				networkingv1.NetworkPolicyPort{Protocol: &udp, Port: &dnsPort},
+				tcpPort(53),
+			))
+		})
+
+		It("translates the rule destinations and ports", func() {
+			Expect(egressRules()).To(ConsistOf(networkingv1.NetworkPolicyEgressRule{
+				To:    []networkingv1.NetworkPolicyPeer{ipBlock("10.0.0.0/8")},
+				Ports: []networkingv1.NetworkPolicyPort{tcpPort(80), tcpPort(443)},
+			}))
+		})
+	})
+
+	When("the destination is a single IP", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{{Protocol: "all", Destinations: []string{"192.168.1.1"}}}
+		})
+
+		It("uses a single host CIDR", func() {
+			Expect(egressRules()[0].To).To(ConsistOf(ipBlock("192.168.1.1/32")))
+		})
+
+		It("allows all protocols and ports", func() {
+			Expect(egressRules()[0].Ports).To(BeEmpty())
+		})
+	})
+
+	When("the destination is an IP range", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{{Protocol: "all", Destinations: []string{"10.0.0.1-10.0.0.6"}}}
+		})
+
+		It("converts the range into the minimal set of CIDRs", func() {
+			Expect(egressRules()[0].To).To(Equal([]networkingv1.NetworkPolicyPeer{
+				ipBlock("10.0.0.1/32"),
+				ipBlock("10.0.0.2/31"),
+				ipBlock("10.0.0.4/31"),
+				ipBlock("10.0.0.6/32"),
+			}))
+		})
+	})
+
+	When("the destination is the whole IPv4 range", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{{Protocol: "all", Destinations: []string{"0.0.0.0-255.255.255.255"}}}
+		})
+
+		It("uses a single CIDR", func() {
+			Expect(egressRules()[0].To).To(ConsistOf(ipBlock("0.0.0.0/0")))
+		})
+	})
+
+	When("the rule has a port range", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{
+				{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}, PortRange: &opi.PortRange{Start: 8080, End: 8082}},
+			}
+		})
+
+		It("expands the range", func() {
+			Expect(egressRules()[0].Ports).To(Equal([]networkingv1.NetworkPolicyPort{tcpPort(8080), tcpPort(8081), tcpPort(8082)}))
+		})
+	})
+
+	When("the rule has a port range covering all ports", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{
+				{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}, PortRange: &opi.PortRange{Start: 1, End: 65535}},
+			}
+		})
+
+		It("allows all ports of the protocol", func() {
+			protocol := corev1.ProtocolTCP
+			Expect(egressRules()[0].Ports).To(Equal([]networkingv1.NetworkPolicyPort{{Protocol: &protocol}}))
+		})
+	})
+
+	When("the rule has a port range that is too wide to expand", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{
+				{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}, PortRange: &opi.PortRange{Start: 1000, End: 5000}},
+			}
+		})
+
+		It("allows all ports of the protocol", func() {
+			Expect(desireErr).NotTo(HaveOccurred())
+
+			protocol := corev1.ProtocolTCP
+			Expect(egressRules()[0].Ports).To(Equal([]networkingv1.NetworkPolicyPort{{Protocol: &protocol}}))
+		})
+	})
+
+	When("the rule has no ports", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{{Protocol: "udp", Destinations: []string{"10.0.0.0/8"}}}
+		})
+
+		It("allows all ports of the protocol", func() {
+			protocol := corev1.ProtocolUDP
+			Expect(egressRules()[0].Ports).To(Equal([]networkingv1.NetworkPolicyPort{{Protocol: &protocol}}))
+		})
+	})
+
+	When("the rule is an ICMP rule", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{
+				{Protocol: "icmp", Destinations: []string{"10.0.0.0/8"}, ICMPInfo: &opi.ICMPInfo{Type: 0, Code: 0}},
+				{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}, Ports: []int32{443}},
+			}
+		})
+
+		It("skips it, as network policies do not support ICMP", func() {
+			Expect(desireErr).NotTo(HaveOccurred())
+			Expect(egressRules()).To(HaveLen(1))
+			Expect(egressRules()[0].Ports).To(ConsistOf(tcpPort(443)))
+		})
+	})
+
+	When("the rule protocol is not supported", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{{Protocol: "sctp", Destinations: []string{"10.0.0.0/8"}}}
+		})
+
+		It("fails", func() {
+			Expect(desireErr).To(MatchError(ContainSubstring(`unsupported egress rule protocol "sctp"`)))
+		})
+	})
+
+	When("the port range is inverted", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{
+				{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}, PortRange: &opi.PortRange{Start: 90, End: 80}},
+			}
+		})
+
+		It("fails", func() {
+			Expect(desireErr).To(MatchError(ContainSubstring("invalid egress rule port range 90-80")))
+		})
+	})
+
+	When("the destination range is invalid", func() {
+		BeforeEach(func() {
+			lrp.EgressRules = []opi.EgressRule{{Protocol: "all", Destinations: []string{"10.0.0.9-10.0.0.1"}}}
+		})
+
+		It("fails", func() {
+			Expect(desireErr).To(MatchError(ContainSubstring(`invalid egress rule destination range "10.0.0.9-10.0.0.1"`)))
+		})
+	})
+})
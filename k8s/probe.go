@@ -1,60 +1,113 @@
 package k8s
 
 import (
+	"fmt"
+	"strings"
+
 	"code.cloudfoundry.org/eirini/opi"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func CreateLivenessProbe(lrp *opi.LRP) *v1.Probe {
-	checkType := lrp.Health.Type
+const (
+	livenessFailureThreshold  = 4
+	readinessFailureThreshold = 1
+	startupProbePeriodSeconds = 1
+)
+
+func CreateLivenessProbe(lrp *opi.LRP) (*v1.Probe, error) {
 	initialDelay := toSeconds(lrp.Health.TimeoutMs)
 
-	if checkType == "http" {
-		return createHTTPProbe(lrp, initialDelay, 4)
-	} else if checkType == "port" {
-		return createPortProbe(lrp, initialDelay, 4)
+	if lrp.Health.StartTimeoutMs > 0 {
+		initialDelay = 0
 	}
 
-	return nil
+	probe, err := createProbe(lrp, initialDelay, failureThreshold(lrp.Health.LivenessFailureThreshold, livenessFailureThreshold))
+	if probe == nil || err != nil {
+		return nil, err
+	}
+
+	probe.PeriodSeconds = toSeconds(lrp.Health.IntervalMs)
+
+	return probe, nil
 }
 
-func CreateReadinessProbe(lrp *opi.LRP) *v1.Probe {
-	checkType := lrp.Health.Type
-	if checkType == "http" {
-		return createHTTPProbe(lrp, 0, 1)
-	} else if checkType == "port" {
-		return createPortProbe(lrp, 0, 1)
+func CreateReadinessProbe(lrp *opi.LRP) (*v1.Probe, error) {
+	probe, err := createProbe(lrp, 0, failureThreshold(lrp.Health.ReadinessFailureThreshold, readinessFailureThreshold))
+	if probe == nil || err != nil {
+		return nil, err
 	}
 
-	return nil
+	probe.PeriodSeconds = toSeconds(lrp.Health.IntervalMs)
+
+	return probe, nil
 }
 
-func createPortProbe(lrp *opi.LRP, initialDelay, failureThreshold int32) *v1.Probe {
-	return &v1.Probe{
-		Handler: v1.Handler{
-			TCPSocket: tcpSocketAction(lrp),
-		},
-		InitialDelaySeconds: initialDelay,
-		FailureThreshold:    failureThreshold,
+func CreateStartupProbe(lrp *opi.LRP) (*v1.Probe, error) {
+	if lrp.Health.StartTimeoutMs == 0 {
+		return nil, nil
+	}
+
+	probe, err := createProbe(lrp, 0, startupFailureThreshold(lrp.Health.StartTimeoutMs))
+	if probe == nil || err != nil {
+		return nil, err
+	}
+
+	probe.PeriodSeconds = startupProbePeriodSeconds
+
+	return probe, nil
+}
+
+func failureThreshold(requested, defaultThreshold int32) int32 {
+	if requested > 0 {
+		return requested
 	}
+
+	return defaultThreshold
 }
 
-func createHTTPProbe(lrp *opi.LRP, initialDelay, failureThreshold int32) *v1.Probe {
+func startupFailureThreshold(startTimeoutMs uint) int32 {
+	return toSeconds(startTimeoutMs+999) / startupProbePeriodSeconds //nolint:gomnd
+}
+
+func createProbe(lrp *opi.LRP, initialDelay, failureThreshold int32) (*v1.Probe, error) {
+	var handler v1.Handler
+
+	switch lrp.Health.Type {
+	case opi.HealthCheckTypeHTTP:
+		handler.HTTPGet = httpGetAction(lrp)
+	case opi.HealthCheckTypePort:
+		handler.TCPSocket = tcpSocketAction(lrp)
+	case opi.HealthCheckTypeProcess:
+		// Kubernetes restarts the container once its main process exits,
+		// so the process health check needs no probe.
+		return nil, nil
+	case opi.HealthCheckTypeNone, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported health check type %q", lrp.Health.Type)
+	}
+
 	return &v1.Probe{
-		Handler: v1.Handler{
-			HTTPGet: httpGetAction(lrp),
-		},
+		Handler:             handler,
 		InitialDelaySeconds: initialDelay,
+		TimeoutSeconds:      toSeconds(lrp.Health.InvocationTimeoutMs),
 		FailureThreshold:    failureThreshold,
-	}
+	}, nil
 }
 
 func httpGetAction(lrp *opi.LRP) *v1.HTTPGetAction {
-	return &v1.HTTPGetAction{
-		Path: lrp.Health.Endpoint,
-		Port: intstr.IntOrString{Type: intstr.Int, IntVal: lrp.Health.Port},
+	action := &v1.HTTPGetAction{
+		Path:   lrp.Health.Endpoint,
+		Port:   intstr.IntOrString{Type: intstr.Int, IntVal: lrp.Health.Port},
+		Scheme: v1.URIScheme(strings.ToUpper(lrp.Health.Scheme)),
 	}
+
+	if lrp.Health.Host != "" {
+		action.HTTPHeaders = []v1.HTTPHeader{{Name: "Host", Value: lrp.Health.Host}}
+	}
+
+	return action
 }
 
 func tcpSocketAction(lrp *opi.LRP) *v1.TCPSocketAction {
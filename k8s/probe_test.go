@@ -12,8 +12,9 @@ import (
 
 var _ = Describe("PrrobeCreator", func() {
 	var (
-		probe *v1.Probe
-		lrp   *opi.LRP
+		probe    *v1.Probe
+		probeErr error
+		lrp      *opi.LRP
 	)
 
 	BeforeEach(func() {
@@ -28,7 +29,7 @@ var _ = Describe("PrrobeCreator", func() {
 
 	Context("LivenessProbeCreator", func() {
 		JustBeforeEach(func() {
-			probe = CreateLivenessProbe(lrp)
+			probe, probeErr = CreateLivenessProbe(lrp)
 		})
 
 		Context("When healthcheck type is HTTP", func() {
@@ -79,6 +80,80 @@ var _ = Describe("PrrobeCreator", func() {
 			})
 		})
 
+		Context("When healthcheck type is process", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "process"
+			})
+
+			It("returns nil, as the container restarts when the process exits", func() {
+				Expect(probe).To(BeNil())
+			})
+		})
+
+		Context("When healthcheck type is not supported", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "htpp"
+			})
+
+			It("fails", func() {
+				Expect(probeErr).To(MatchError(`unsupported health check type "htpp"`))
+			})
+		})
+
+		Context("When a liveness failure threshold is set", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "port"
+				lrp.Health.LivenessFailureThreshold = 7
+			})
+
+			It("uses it instead of the default", func() {
+				Expect(probe.FailureThreshold).To(Equal(int32(7)))
+			})
+		})
+
+		Context("When the interval and invocation timeout are set", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "port"
+				lrp.Health.IntervalMs = 10000
+				lrp.Health.InvocationTimeoutMs = 2500
+			})
+
+			It("sets the probe period and timeout", func() {
+				Expect(probe.PeriodSeconds).To(Equal(int32(10)))
+				Expect(probe.TimeoutSeconds).To(Equal(int32(2)))
+			})
+		})
+
+		Context("When the HTTP scheme and host are set", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "http"
+				lrp.Health.Scheme = "https"
+				lrp.Health.Host = "my.app.example.com"
+			})
+
+			It("configures the HTTPGet action accordingly", func() {
+				Expect(probe.HTTPGet).To(Equal(&v1.HTTPGetAction{
+					Path:   "/healthz",
+					Port:   intstr.IntOrString{Type: intstr.Int, IntVal: 8080},
+					Scheme: v1.URISchemeHTTPS,
+					HTTPHeaders: []v1.HTTPHeader{
+						{Name: "Host", Value: "my.app.example.com"},
+					},
+				}))
+			})
+		})
+
+		Context("When a start timeout is set", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "http"
+				lrp.Health.StartTimeoutMs = 60000
+			})
+
+			It("does not delay the probe, as the startup probe gates it", func() {
+				Expect(probe.InitialDelaySeconds).To(BeZero())
+			})
+		})
+
 		Context("When healthcheck information is missing", func() {
 			BeforeEach(func() {
 				lrp = &opi.LRP{}
@@ -90,9 +165,111 @@ var _ = Describe("PrrobeCreator", func() {
 		})
 	})
 
+	Context("StartupProbeCreator", func() {
+		BeforeEach(func() {
+			lrp.Health.StartTimeoutMs = 60000
+		})
+
+		JustBeforeEach(func() {
+			probe, probeErr = CreateStartupProbe(lrp)
+		})
+
+		Context("When healthcheck type is HTTP", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "http"
+			})
+
+			It("creates a probe with HTTPGet action that allows the app the whole start timeout", func() {
+				Expect(probe).To(Equal(&v1.Probe{
+					Handler: v1.Handler{
+						HTTPGet: &v1.HTTPGetAction{
+							Path: "/healthz",
+							Port: intstr.IntOrString{Type: intstr.Int, IntVal: 8080},
+						},
+					},
+					PeriodSeconds:    1,
+					FailureThreshold: 60,
+				}))
+			})
+		})
+
+		Context("When healthcheck type is Port", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "port"
+			})
+
+			It("creates a probe with TCPSocket action that allows the app the whole start timeout", func() {
+				Expect(probe).To(Equal(&v1.Probe{
+					Handler: v1.Handler{
+						TCPSocket: &v1.TCPSocketAction{
+							Port: intstr.IntOrString{Type: intstr.Int, IntVal: 8080},
+						},
+					},
+					PeriodSeconds:    1,
+					FailureThreshold: 60,
+				}))
+			})
+		})
+
+		Context("When the interval and invocation timeout are set", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "port"
+				lrp.Health.IntervalMs = 10000
+				lrp.Health.InvocationTimeoutMs = 2000
+			})
+
+			It("keeps checking every second, but honours the invocation timeout", func() {
+				Expect(probe.PeriodSeconds).To(Equal(int32(1)))
+				Expect(probe.TimeoutSeconds).To(Equal(int32(2)))
+			})
+		})
+
+		Context("When healthcheck type is process", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "process"
+			})
+
+			It("returns nil", func() {
+				Expect(probe).To(BeNil())
+			})
+		})
+
+		Context("When the start timeout is not a whole number", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "port"
+				lrp.Health.StartTimeoutMs = 500
+			})
+
+			It("rounds it up", func() {
+				Expect(probe.FailureThreshold).To(Equal(int32(1)))
+			})
+		})
+
+		Context("When the start timeout is not set", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "http"
+				lrp.Health.StartTimeoutMs = 0
+			})
+
+			It("returns nil", func() {
+				Expect(probe).To(BeNil())
+			})
+		})
+
+		Context("When healthcheck information is missing", func() {
+			BeforeEach(func() {
+				lrp = &opi.LRP{Health: opi.Healtcheck{StartTimeoutMs: 60000}}
+			})
+
+			It("returns nil", func() {
+				Expect(probe).To(BeNil())
+			})
+		})
+	})
+
 	Context("ReadinessProbeCreator", func() {
 		JustBeforeEach(func() {
-			probe = CreateReadinessProbe(lrp)
+			probe, probeErr = CreateReadinessProbe(lrp)
 		})
 
 		Context("When Healtcheck type is HTTP", func() {
@@ -132,6 +309,50 @@ var _ = Describe("PrrobeCreator", func() {
 			})
 		})
 
+		Context("When the interval and invocation timeout are set", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "http"
+				lrp.Health.IntervalMs = 3000
+				lrp.Health.InvocationTimeoutMs = 1000
+			})
+
+			It("sets the probe period and timeout", func() {
+				Expect(probe.PeriodSeconds).To(Equal(int32(3)))
+				Expect(probe.TimeoutSeconds).To(Equal(int32(1)))
+			})
+		})
+
+		Context("When healthcheck type is process", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "process"
+			})
+
+			It("returns nil", func() {
+				Expect(probe).To(BeNil())
+			})
+		})
+
+		Context("When healthcheck type is not supported", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "tcp"
+			})
+
+			It("fails", func() {
+				Expect(probeErr).To(MatchError(`unsupported health check type "tcp"`))
+			})
+		})
+
+		Context("When a readiness failure threshold is set", func() {
+			BeforeEach(func() {
+				lrp.Health.Type = "http"
+				lrp.Health.ReadinessFailureThreshold = 3
+			})
+
+			It("uses it instead of the default", func() {
+				Expect(probe.FailureThreshold).To(Equal(int32(3)))
+			})
+		})
+
 		Context("When healthcheck information is missing", func() {
 			BeforeEach(func() {
 				lrp = &opi.LRP{}
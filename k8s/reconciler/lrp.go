@@ -88,7 +88,7 @@ func (r *LRP) do(lrp *eiriniv1.LRP) error {
 		Version: lrp.Spec.Version,
 	})
 	if errors.Is(err, eirini.ErrNotFound) {
-		appLRP, parseErr := toOpiLrp(lrp)
+		appLRP, parseErr := r.toOpiLrp(lrp)
 		if parseErr != nil {
 			return errors.Wrap(parseErr, "failed to parse the crd spec to the lrp model")
 		}
@@ -100,7 +100,7 @@ func (r *LRP) do(lrp *eiriniv1.LRP) error {
 		return errors.Wrap(err, "failed to get lrp")
 	}
 
-	appLRP, err := toOpiLrp(lrp)
+	appLRP, err := r.toOpiLrp(lrp)
 	if err != nil {
 		return errors.Wrap(err, "failed to parse the crd spec to the lrp model")
 	}
@@ -143,7 +143,7 @@ func (r *LRP) setOwnerFn(lrp *eiriniv1.LRP) func(interface{}) error {
 	}
 }
 
-func toOpiLrp(lrp *eiriniv1.LRP) (*opi.LRP, error) {
+func (r *LRP) toOpiLrp(lrp *eiriniv1.LRP) (*opi.LRP, error) {
 	opiLrp := &opi.LRP{}
 	if err := copier.Copy(opiLrp, lrp.Spec); err != nil {
 		return nil, errors.Wrap(err, "failed to copy lrp spec")
@@ -155,5 +155,12 @@ func toOpiLrp(lrp *eiriniv1.LRP) (*opi.LRP, error) {
 		return nil, errors.Wrap(err, "failed to copy app routes")
 	}
 
+	privateRegistry, err := resolvePrivateRegistry(r.lrps, lrp.Namespace, lrp.Spec.PrivateRegistry)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve private registry credentials")
+	}
+
+	opiLrp.PrivateRegistry = privateRegistry
+
 	return opiLrp, nil
 }
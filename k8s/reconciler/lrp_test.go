@@ -14,6 +14,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -31,6 +32,7 @@ var _ = Describe("reconciler.LRP", func() {
 		desirer           *reconcilerfakes.FakeLRPDesirer
 		scheme            *runtime.Scheme
 		lrpreconciler     *reconciler.LRP
+		privateRegistry   *eiriniv1.PrivateRegistry
 		resultErr         error
 	)
 
@@ -42,8 +44,16 @@ var _ = Describe("reconciler.LRP", func() {
 		logger = lagertest.NewTestLogger("lrp-reconciler")
 		scheme = eiriniv1scheme.Scheme
 		lrpreconciler = reconciler.NewLRP(logger, controllerClient, desirer, statefulsetGetter, scheme)
+		privateRegistry = nil
 
 		controllerClient.GetStub = func(c context.Context, nn types.NamespacedName, o runtime.Object) error {
+			if secret, ok := o.(*corev1.Secret); ok {
+				secret.Name = nn.Name
+				secret.Data = map[string][]byte{"password": []byte("s3cret")}
+
+				return nil
+			}
+
 			lrp := o.(*eiriniv1.LRP)
 			lrp.Name = "some-lrp"
 			lrp.Namespace = "some-ns"
@@ -51,9 +61,19 @@ var _ = Describe("reconciler.LRP", func() {
 			lrp.Spec.Version = "the-lrp-version"
 			lrp.Spec.Command = []string{"ls", "-la"}
 			lrp.Spec.Instances = 10
+			lrp.Spec.Health = eiriniv1.Healtcheck{Type: "http", Port: 8080, TimeoutMs: 1000, StartTimeoutMs: 60000}
+			lrp.Spec.Sidecars = []eiriniv1.Sidecar{
+				{Name: "agent", Command: []string{"./agent"}, MemoryMB: 24, Env: map[string]string{"FOO": "bar"}},
+			}
 			lrp.Spec.AppRoutes = []eiriniv1.Route{
 				{Hostname: "foo.io", Port: 8080}, {Hostname: "bar.io", Port: 9090},
 			}
+			lrp.Spec.InternalRoutes = []eiriniv1.InternalRoute{{Hostname: "app.apps.internal"}}
+			lrp.Spec.UpdateStrategy = eiriniv1.UpdateStrategy{MaxUnavailable: 2, CanaryInstances: 1}
+			lrp.Spec.Autoscaling = &eiriniv1.AutoscalingPolicy{MinInstances: 1, MaxInstances: 4, TargetCPUPercent: 60}
+			lrp.Spec.TerminationGracePeriodSeconds = 45
+			lrp.Spec.OriginalImage = "busybox:latest"
+			lrp.Spec.PrivateRegistry = privateRegistry
 
 			return nil
 		}
@@ -83,10 +103,19 @@ var _ = Describe("reconciler.LRP", func() {
 		Expect(lrp.Version).To(Equal("the-lrp-version"))
 		Expect(lrp.Command).To(ConsistOf("ls", "-la"))
 		Expect(lrp.TargetInstances).To(Equal(10))
+		Expect(lrp.Health).To(Equal(opi.Healtcheck{Type: "http", Port: 8080, TimeoutMs: 1000, StartTimeoutMs: 60000}))
+		Expect(lrp.Sidecars).To(Equal([]opi.Sidecar{
+			{Name: "agent", Command: []string{"./agent"}, MemoryMB: 24, Env: map[string]string{"FOO": "bar"}},
+		}))
 		Expect(lrp.AppURIs).To(ConsistOf(
 			opi.Route{Hostname: "foo.io", Port: 8080},
 			opi.Route{Hostname: "bar.io", Port: 9090},
 		))
+		Expect(lrp.InternalRoutes).To(ConsistOf(opi.InternalRoute{Hostname: "app.apps.internal"}))
+		Expect(lrp.UpdateStrategy).To(Equal(opi.UpdateStrategy{MaxUnavailable: 2, CanaryInstances: 1}))
+		Expect(lrp.Autoscaling).To(Equal(&opi.AutoscalingPolicy{MinInstances: 1, MaxInstances: 4, TargetCPUPercent: 60}))
+		Expect(lrp.TerminationGracePeriodSeconds).To(Equal(int64(45)))
+		Expect(lrp.OriginalImage).To(Equal("busybox:latest"))
 	})
 
 	It("sets an owner reference in the statefulset", func() {
@@ -104,6 +133,64 @@ var _ = Describe("reconciler.LRP", func() {
 		Expect(st.ObjectMeta.OwnerReferences[0].Name).To(Equal("some-lrp"))
 	})
 
+	When("the private registry password is stored in a secret", func() {
+		BeforeEach(func() {
+			privateRegistry = &eiriniv1.PrivateRegistry{
+				Server:   "registry.example.com",
+				Username: "user",
+				PasswordSecretRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "registry-password"},
+					Key:                  "password",
+				},
+			}
+		})
+
+		It("resolves the password from the secret", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+
+			_, name, _ := controllerClient.GetArgsForCall(1)
+			Expect(name).To(Equal(types.NamespacedName{Namespace: "some-ns", Name: "registry-password"}))
+
+			_, lrp, _ := desirer.DesireArgsForCall(0)
+			Expect(lrp.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+				Server:   "registry.example.com",
+				Username: "user",
+				Password: "s3cret",
+			}))
+		})
+
+		When("a CRD for this app already exists", func() {
+			BeforeEach(func() {
+				desirer.GetReturns(nil, nil)
+			})
+
+			It("updates it with the current credentials", func() {
+				Expect(resultErr).NotTo(HaveOccurred())
+
+				lrp := desirer.UpdateArgsForCall(0)
+				Expect(lrp.PrivateRegistry.Password).To(Equal("s3cret"))
+			})
+		})
+
+		When("the secret cannot be read", func() {
+			BeforeEach(func() {
+				getStub := controllerClient.GetStub
+				controllerClient.GetStub = func(c context.Context, nn types.NamespacedName, o runtime.Object) error {
+					if _, ok := o.(*corev1.Secret); ok {
+						return errors.New("boom")
+					}
+
+					return getStub(c, nn, o)
+				}
+			})
+
+			It("fails", func() {
+				Expect(resultErr).To(MatchError(ContainSubstring("failed to resolve private registry credentials")))
+				Expect(desirer.DesireCallCount()).To(BeZero())
+			})
+		})
+	})
+
 	When("a CRD for this app already exists", func() {
 		BeforeEach(func() {
 			desirer.GetReturns(nil, nil)
@@ -0,0 +1,154 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/opi"
+	eiriniv1 "code.cloudfoundry.org/eirini/pkg/apis/eirini/v1"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type dockerConfig struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+func resolvePrivateRegistry(secrets client.Client, namespace string, registry *eiriniv1.PrivateRegistry) (*opi.PrivateRegistry, error) {
+	if registry == nil {
+		return nil, nil
+	}
+
+	resolved := &opi.PrivateRegistry{
+		Server:   registry.Server,
+		Username: registry.Username,
+		Password: registry.Password,
+	}
+
+	switch {
+	case registry.DockerConfigSecretRef != nil:
+		secret, err := getSecret(secrets, namespace, registry.DockerConfigSecretRef.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		return fromDockerConfig(secret, registry.Server)
+	case registry.PasswordSecretRef != nil:
+		secret, err := getSecret(secrets, namespace, registry.PasswordSecretRef.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		password, ok := secret.Data[registry.PasswordSecretRef.Key]
+		if !ok {
+			return nil, errors.Errorf("secret %q has no key %q", secret.Name, registry.PasswordSecretRef.Key)
+		}
+
+		resolved.Password = string(password)
+	}
+
+	return resolved, nil
+}
+
+func getSecret(secrets client.Client, namespace, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := secrets.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get private registry secret %q", name)
+	}
+
+	return secret, nil
+}
+
+func fromDockerConfig(secret *corev1.Secret, server string) (*opi.PrivateRegistry, error) {
+	configJSON, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return nil, errors.Errorf("secret %q has no key %q", secret.Name, corev1.DockerConfigJsonKey)
+	}
+
+	var config dockerConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse docker config of secret %q", secret.Name)
+	}
+
+	if server == "" && len(config.Auths) == 1 {
+		for s := range config.Auths {
+			server = s
+		}
+	}
+
+	entry, ok := config.Auths[server]
+	if !ok {
+		return nil, errors.Errorf("docker config of secret %q has no credentials for server %q", secret.Name, server)
+	}
+
+	if entry.Username == "" && entry.Auth != "" {
+		auth, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode auth of secret %q", secret.Name)
+		}
+
+		credentials := strings.SplitN(string(auth), ":", 2) //nolint:gomnd
+		if len(credentials) != 2 {                          //nolint:gomnd
+			return nil, errors.Errorf("invalid auth in docker config of secret %q", secret.Name)
+		}
+
+		entry.Username, entry.Password = credentials[0], credentials[1]
+	}
+
+	return &opi.PrivateRegistry{
+		Server:   server,
+		Username: entry.Username,
+		Password: entry.Password,
+	}, nil
+}
+
+// LRPsReferencingSecret maps a Secret to the LRPs in its namespace that take
+// their private registry credentials from it, so that they get reconciled
+// when the credentials are rotated.
+func LRPsReferencingSecret(logger lager.Logger, lrps client.Client) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		lrpList := &eiriniv1.LRPList{}
+		if err := lrps.List(context.Background(), lrpList, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+			logger.Error("failed-to-list-lrps", err, lager.Data{"secret": obj.Meta.GetName(), "namespace": obj.Meta.GetNamespace()})
+
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+
+		for _, lrp := range lrpList.Items {
+			if referencesSecret(lrp.Spec.PrivateRegistry, obj.Meta.GetName()) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: lrp.Namespace, Name: lrp.Name},
+				})
+			}
+		}
+
+		return requests
+	}
+}
+
+func referencesSecret(registry *eiriniv1.PrivateRegistry, secretName string) bool {
+	if registry == nil {
+		return false
+	}
+
+	if registry.PasswordSecretRef != nil && registry.PasswordSecretRef.Name == secretName {
+		return true
+	}
+
+	return registry.DockerConfigSecretRef != nil && registry.DockerConfigSecretRef.Name == secretName
+}
@@ -0,0 +1,91 @@
+package reconciler_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini/k8s/reconciler"
+	"code.cloudfoundry.org/eirini/k8s/reconciler/reconcilerfakes"
+	eiriniv1 "code.cloudfoundry.org/eirini/pkg/apis/eirini/v1"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("LRPsReferencingSecret", func() {
+	var (
+		controllerClient *reconcilerfakes.FakeClient
+		secret           *corev1.Secret
+		requests         []reconcile.Request
+	)
+
+	BeforeEach(func() {
+		controllerClient = new(reconcilerfakes.FakeClient)
+		controllerClient.ListStub = func(_ context.Context, list runtime.Object, _ ...client.ListOption) error {
+			lrpList, ok := list.(*eiriniv1.LRPList)
+			Expect(ok).To(BeTrue())
+
+			lrpList.Items = []eiriniv1.LRP{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "public", Namespace: "the-namespace"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "password-ref", Namespace: "the-namespace"},
+					Spec: eiriniv1.LRPSpec{PrivateRegistry: &eiriniv1.PrivateRegistry{
+						PasswordSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}},
+					}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "docker-config-ref", Namespace: "the-namespace"},
+					Spec: eiriniv1.LRPSpec{PrivateRegistry: &eiriniv1.PrivateRegistry{
+						DockerConfigSecretRef: &corev1.LocalObjectReference{Name: "creds"},
+					}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-ref", Namespace: "the-namespace"},
+					Spec: eiriniv1.LRPSpec{PrivateRegistry: &eiriniv1.PrivateRegistry{
+						DockerConfigSecretRef: &corev1.LocalObjectReference{Name: "other-creds"},
+					}},
+				},
+			}
+
+			return nil
+		}
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "the-namespace"}}
+	})
+
+	JustBeforeEach(func() {
+		toRequests := reconciler.LRPsReferencingSecret(lagertest.NewTestLogger("secret-mapper"), controllerClient)
+		requests = toRequests(handler.MapObject{Meta: secret, Object: secret})
+	})
+
+	It("lists the LRPs in the namespace of the secret", func() {
+		Expect(controllerClient.ListCallCount()).To(Equal(1))
+		_, _, opts := controllerClient.ListArgsForCall(0)
+		Expect(opts).To(ConsistOf(client.InNamespace("the-namespace")))
+	})
+
+	It("requests the reconciliation of the LRPs referencing the secret", func() {
+		Expect(requests).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "the-namespace", Name: "password-ref"}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "the-namespace", Name: "docker-config-ref"}},
+		))
+	})
+
+	When("listing the LRPs fails", func() {
+		BeforeEach(func() {
+			controllerClient.ListReturns(errors.New("boom"))
+		})
+
+		It("does not request any reconciliation", func() {
+			Expect(requests).To(BeEmpty())
+		})
+	})
+})
@@ -57,7 +57,14 @@ func (t *Task) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 		return reconcile.Result{}, fmt.Errorf("could not fetch task: %w", err)
 	}
 
-	err = t.taskDesirer.Desire(task.Namespace, toOpiTask(task), t.setOwnerFn(task))
+	opiTask, err := t.toOpiTask(task)
+	if err != nil {
+		logger.Error("failed-to-parse-task", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to parse the crd spec to the task model")
+	}
+
+	err = t.taskDesirer.Desire(task.Namespace, opiTask, t.setOwnerFn(task))
 	if errors.IsAlreadyExists(err) {
 		logger.Info("task-already-exists")
 
@@ -86,7 +93,7 @@ func (t *Task) setOwnerFn(task *eiriniv1.Task) func(interface{}) error {
 	}
 }
 
-func toOpiTask(task *eiriniv1.Task) *opi.Task {
+func (t *Task) toOpiTask(task *eiriniv1.Task) (*opi.Task, error) {
 	opiTask := &opi.Task{
 		GUID:               task.Spec.GUID,
 		Name:               task.Spec.Name,
@@ -105,13 +112,12 @@ func toOpiTask(task *eiriniv1.Task) *opi.Task {
 		CPUWeight:          task.Spec.CPUWeight,
 	}
 
-	if task.Spec.PrivateRegistry != nil {
-		opiTask.PrivateRegistry = &opi.PrivateRegistry{
-			Server:   task.Spec.PrivateRegistry.Server,
-			Username: task.Spec.PrivateRegistry.Username,
-			Password: task.Spec.PrivateRegistry.Password,
-		}
+	privateRegistry, err := resolvePrivateRegistry(t.client, task.Namespace, task.Spec.PrivateRegistry)
+	if err != nil {
+		return nil, exterrors.Wrap(err, "failed to resolve private registry credentials")
 	}
 
-	return opiTask
+	opiTask.PrivateRegistry = privateRegistry
+
+	return opiTask, nil
 }
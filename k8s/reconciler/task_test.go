@@ -13,6 +13,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -135,6 +136,119 @@ var _ = Describe("Task", func() {
 		})
 	})
 
+	Context("resolving private registry credentials", func() {
+		var (
+			privateRegistry *eiriniv1.PrivateRegistry
+			secretData      map[string][]byte
+		)
+
+		BeforeEach(func() {
+			secretData = map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"auth":"dXNlcjpzM2NyZXQ="}}}`),
+			}
+			privateRegistry = &eiriniv1.PrivateRegistry{
+				DockerConfigSecretRef: &corev1.LocalObjectReference{Name: "registry-creds"},
+			}
+
+			controllerClient.GetStub = func(ctx context.Context, namespacedName types.NamespacedName, obj runtime.Object) error {
+				switch o := obj.(type) {
+				case *eiriniv1.Task:
+					o.Namespace = namespacedName.Namespace
+					o.Spec.PrivateRegistry = privateRegistry
+				case *corev1.Secret:
+					o.Name = namespacedName.Name
+					o.Data = secretData
+				}
+
+				return nil
+			}
+		})
+
+		It("reads the credentials from the docker config secret", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+
+			_, name, _ := controllerClient.GetArgsForCall(1)
+			Expect(name).To(Equal(types.NamespacedName{Namespace: "my-namespace", Name: "registry-creds"}))
+
+			_, opiTask, _ := taskDesirer.DesireArgsForCall(0)
+			Expect(opiTask.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+				Server:   "registry.example.com",
+				Username: "user",
+				Password: "s3cret",
+			}))
+		})
+
+		When("the docker config has credentials for several servers", func() {
+			BeforeEach(func() {
+				secretData[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"other.io":{"username":"other","password":"pass"},"registry.example.com":{"username":"user","password":"s3cret"}}}`)
+			})
+
+			It("fails when no server is specified", func() {
+				Expect(reconcileErr).To(MatchError(ContainSubstring(`docker config of secret "registry-creds" has no credentials for server ""`)))
+				Expect(taskDesirer.DesireCallCount()).To(BeZero())
+			})
+
+			When("the server is specified", func() {
+				BeforeEach(func() {
+					privateRegistry.Server = "registry.example.com"
+				})
+
+				It("uses the credentials for that server", func() {
+					_, opiTask, _ := taskDesirer.DesireArgsForCall(0)
+					Expect(opiTask.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+						Server:   "registry.example.com",
+						Username: "user",
+						Password: "s3cret",
+					}))
+				})
+			})
+		})
+
+		When("the secret is not a docker config secret", func() {
+			BeforeEach(func() {
+				secretData = map[string][]byte{"password": []byte("s3cret")}
+			})
+
+			It("fails", func() {
+				Expect(reconcileErr).To(MatchError(ContainSubstring(`secret "registry-creds" has no key ".dockerconfigjson"`)))
+			})
+		})
+
+		When("the password is referenced by key", func() {
+			BeforeEach(func() {
+				secretData = map[string][]byte{"password": []byte("s3cret")}
+				privateRegistry = &eiriniv1.PrivateRegistry{
+					Server:   "registry.example.com",
+					Username: "user",
+					PasswordSecretRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "registry-password"},
+						Key:                  "password",
+					},
+				}
+			})
+
+			It("reads the password from the secret", func() {
+				_, opiTask, _ := taskDesirer.DesireArgsForCall(0)
+				Expect(opiTask.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+					Server:   "registry.example.com",
+					Username: "user",
+					Password: "s3cret",
+				}))
+			})
+
+			When("the key does not exist", func() {
+				BeforeEach(func() {
+					privateRegistry.PasswordSecretRef.Key = "pass"
+				})
+
+				It("fails", func() {
+					Expect(reconcileErr).To(MatchError(ContainSubstring(`secret "registry-password" has no key "pass"`)))
+					Expect(taskDesirer.DesireCallCount()).To(BeZero())
+				})
+			})
+		})
+	})
+
 	When("the task cannot be found", func() {
 		BeforeEach(func() {
 			controllerClient.GetReturns(errors.NewNotFound(schema.GroupResource{}, "foo"))
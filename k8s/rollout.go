@@ -0,0 +1,357 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	RolloutStateCanary    = "canary"
+	RolloutActionPromote  = "promote"
+	RolloutActionRollback = "rollback"
+)
+
+// rolloutAnnotations describe the LRP together with the pod template, so a
+// rollback restores them as well.
+var rolloutAnnotations = []string{
+	AnnotationHealthCheck,
+	AnnotationRegisteredRoutes,
+	AnnotationUpdateStrategy,
+	AnnotationLastUpdated,
+	AnnotationOriginalImage,
+	AnnotationEnvFingerprint,
+}
+
+func (m *StatefulSetDesirer) Rollout(identifier opi.LRPIdentifier, action string) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return m.rollout(identifier, action)
+	})
+
+	return errors.Wrap(err, "failed to roll out statefulset")
+}
+
+func (m *StatefulSetDesirer) rollout(identifier opi.LRPIdentifier, action string) error {
+	logger := m.Logger.Session("rollout", lager.Data{"guid": identifier.GUID, "version": identifier.Version, "action": action})
+
+	statefulSet, err := m.getStatefulSet(identifier)
+	if err != nil {
+		logger.Error("failed-to-get-statefulset", err)
+
+		return err
+	}
+
+	if statefulSet.Annotations[AnnotationRolloutState] != RolloutStateCanary {
+		return eirini.ErrNoRolloutInProgress
+	}
+
+	updated := statefulSet.DeepCopy()
+
+	switch action {
+	case RolloutActionPromote:
+	case RolloutActionRollback:
+		var previous corev1.PodTemplateSpec
+		if err = json.Unmarshal([]byte(statefulSet.Annotations[AnnotationRolloutPreviousTemplate]), &previous); err != nil {
+			return errors.Wrap(err, "failed to unmarshal previous pod template")
+		}
+
+		updated.Spec.Template = previous
+
+		if err = restoreRolloutAnnotations(updated, statefulSet.Annotations); err != nil {
+			return err
+		}
+	default:
+		return errors.Wrap(eirini.ErrInvalidRolloutAction, action)
+	}
+
+	delete(updated.Annotations, AnnotationRolloutState)
+	delete(updated.Annotations, AnnotationRolloutPreviousTemplate)
+	delete(updated.Annotations, AnnotationRolloutPreviousAnnotations)
+	updated.Spec.UpdateStrategy = rollingUpdateStrategy(0)
+
+	if _, err = m.StatefulSets.Update(updated.Namespace, updated); err != nil {
+		logger.Error("failed-to-update-statefulset", err)
+
+		return errors.Wrap(err, "failed to update statefulset")
+	}
+
+	logger.Info("rollout-finished")
+
+	return nil
+}
+
+// Restart replaces all instances of an LRP by bumping an annotation of its
+// pod template. Instances are replaced like in any other rolling update, so
+// the pod disruption budget is honoured: the StatefulSet controller replaces
+// one pod at a time and the RolloutProgressor only evicts pods.
+func (m *StatefulSetDesirer) Restart(identifier opi.LRPIdentifier) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return m.restart(identifier)
+	})
+
+	return errors.Wrap(err, "failed to restart statefulset")
+}
+
+func (m *StatefulSetDesirer) restart(identifier opi.LRPIdentifier) error {
+	logger := m.Logger.Session("restart", lager.Data{"guid": identifier.GUID, "version": identifier.Version})
+
+	statefulSet, err := m.getStatefulSet(identifier)
+	if err != nil {
+		logger.Error("failed-to-get-statefulset", err)
+
+		return err
+	}
+
+	restarted := statefulSet.DeepCopy()
+	if restarted.Spec.Template.Annotations == nil {
+		restarted.Spec.Template.Annotations = map[string]string{}
+	}
+
+	restarted.Spec.Template.Annotations[AnnotationRestartedAt] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	if _, err = m.StatefulSets.Update(restarted.Namespace, restarted); err != nil {
+		logger.Error("failed-to-update-statefulset", err)
+
+		return errors.Wrap(err, "failed to update statefulset")
+	}
+
+	logger.Info("restart-requested")
+
+	return nil
+}
+
+func (m *StatefulSetDesirer) updateStrategy(lrp *opi.LRP) opi.UpdateStrategy {
+	strategy := m.DefaultUpdateStrategy
+
+	if lrp.UpdateStrategy.MaxUnavailable > 0 {
+		strategy.MaxUnavailable = lrp.UpdateStrategy.MaxUnavailable
+	}
+
+	if lrp.UpdateStrategy.CanaryInstances > 0 {
+		strategy.CanaryInstances = lrp.UpdateStrategy.CanaryInstances
+	}
+
+	return strategy
+}
+
+func (m *StatefulSetDesirer) applyUpdateStrategy(sts, updatedSts *appsv1.StatefulSet, lrp *opi.LRP) error {
+	strategy := m.updateStrategy(lrp)
+
+	if strategy == (opi.UpdateStrategy{}) {
+		delete(updatedSts.Annotations, AnnotationUpdateStrategy)
+	} else {
+		updateStrategy, err := json.Marshal(strategy)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal update strategy")
+		}
+
+		updatedSts.Annotations[AnnotationUpdateStrategy] = string(updateStrategy)
+	}
+
+	inCanary := updatedSts.Annotations[AnnotationRolloutState] == RolloutStateCanary
+
+	if strategy.CanaryInstances <= 0 {
+		delete(updatedSts.Annotations, AnnotationRolloutState)
+		delete(updatedSts.Annotations, AnnotationRolloutPreviousTemplate)
+		delete(updatedSts.Annotations, AnnotationRolloutPreviousAnnotations)
+
+		if updatedSts.Spec.UpdateStrategy.RollingUpdate != nil {
+			updatedSts.Spec.UpdateStrategy = rollingUpdateStrategy(0)
+		}
+
+		return nil
+	}
+
+	if !inCanary && !apiequality.Semantic.DeepEqual(sts.Spec.Template, updatedSts.Spec.Template) {
+		previous, err := json.Marshal(sts.Spec.Template)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal previous pod template")
+		}
+
+		previousAnnotations, err := json.Marshal(copyAnnotations(map[string]string{}, sts.Annotations, rolloutAnnotations...))
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal previous annotations")
+		}
+
+		updatedSts.Annotations[AnnotationRolloutState] = RolloutStateCanary
+		updatedSts.Annotations[AnnotationRolloutPreviousTemplate] = string(previous)
+		updatedSts.Annotations[AnnotationRolloutPreviousAnnotations] = string(previousAnnotations)
+		inCanary = true
+	}
+
+	var partition int32
+
+	if inCanary && replicas(updatedSts) > strategy.CanaryInstances {
+		partition = replicas(updatedSts) - strategy.CanaryInstances
+	}
+
+	updatedSts.Spec.UpdateStrategy = rollingUpdateStrategy(partition)
+
+	return nil
+}
+
+// restoreRolloutAnnotations restores the annotations saved when the canary
+// rollout started. Rollouts started before they were saved keep the current
+// ones.
+func restoreRolloutAnnotations(statefulSet *appsv1.StatefulSet, annotations map[string]string) error {
+	saved, ok := annotations[AnnotationRolloutPreviousAnnotations]
+	if !ok {
+		return nil
+	}
+
+	var previous map[string]string
+	if err := json.Unmarshal([]byte(saved), &previous); err != nil {
+		return errors.Wrap(err, "failed to unmarshal previous annotations")
+	}
+
+	for _, key := range rolloutAnnotations {
+		if value, found := previous[key]; found {
+			statefulSet.Annotations[key] = value
+		} else {
+			delete(statefulSet.Annotations, key)
+		}
+	}
+
+	return nil
+}
+
+func rollingUpdateStrategy(partition int32) appsv1.StatefulSetUpdateStrategy {
+	return appsv1.StatefulSetUpdateStrategy{
+		Type: appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+			Partition: &partition,
+		},
+	}
+}
+
+// RolloutProgressor speeds up rolling updates of LRPs with a maxUnavailable
+// greater than one: the StatefulSet controller only replaces one pod at a
+// time, so outdated pods are evicted in batches and recreated at the update
+// revision by the controller. Evictions honour the pod disruption budget of
+// the LRP, so a batch stops early once the budget is exhausted.
+type RolloutProgressor struct {
+	StatefulSets StatefulSetClient
+	Pods         PodClient
+	Logger       lager.Logger
+}
+
+func (p *RolloutProgressor) Progress() error {
+	logger := p.Logger.Session("progress")
+
+	statefulSets, err := p.StatefulSets.GetBySourceType(appSourceType)
+	if err != nil {
+		return errors.Wrap(err, "failed to list statefulsets")
+	}
+
+	failures := 0
+
+	for i := range statefulSets {
+		if progressErr := p.progress(logger, &statefulSets[i]); progressErr != nil {
+			logger.Error("failed-to-progress-rollout", progressErr, lager.Data{"name": statefulSets[i].Name})
+
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("failed to progress the rollout of %d statefulsets", failures)
+	}
+
+	return nil
+}
+
+func (p *RolloutProgressor) progress(logger lager.Logger, statefulSet *appsv1.StatefulSet) error {
+	var strategy opi.UpdateStrategy
+
+	stUpdateStrategy, ok := statefulSet.Annotations[AnnotationUpdateStrategy]
+	if !ok {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(stUpdateStrategy), &strategy); err != nil {
+		return errors.Wrap(err, "failed to unmarshal update strategy")
+	}
+
+	updateRevision := statefulSet.Status.UpdateRevision
+	if strategy.MaxUnavailable <= 1 || updateRevision == "" || updateRevision == statefulSet.Status.CurrentRevision {
+		return nil
+	}
+
+	pods, err := p.Pods.GetByLRPIdentifier(opi.LRPIdentifier{
+		GUID:    statefulSet.Labels[LabelGUID],
+		Version: statefulSet.Labels[LabelVersion],
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list pods")
+	}
+
+	minOrdinal := statefulSetPartition(statefulSet)
+	unavailable := replicas(statefulSet) - int32(len(pods))
+	outdated := []corev1.Pod{}
+
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !podReady(pod) {
+			unavailable++
+
+			continue
+		}
+
+		if pod.Labels[appsv1.StatefulSetRevisionLabel] != updateRevision && podOrdinal(pod) >= minOrdinal {
+			outdated = append(outdated, pod)
+		}
+	}
+
+	sort.Slice(outdated, func(i, j int) bool {
+		return podOrdinal(outdated[i]) > podOrdinal(outdated[j])
+	})
+
+	for i := 0; i < len(outdated) && unavailable < strategy.MaxUnavailable; i++ {
+		logger.Debug("replacing-outdated-pod", lager.Data{"pod": outdated[i].Name})
+
+		err = p.Pods.Evict(outdated[i].Namespace, outdated[i].Name)
+		if k8serrors.IsTooManyRequests(err) {
+			logger.Debug("disruption-budget-exhausted", lager.Data{"pod": outdated[i].Name})
+
+			return nil
+		}
+
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to evict outdated pod")
+		}
+
+		unavailable++
+	}
+
+	return nil
+}
+
+func statefulSetPartition(statefulSet *appsv1.StatefulSet) int32 {
+	rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil || rollingUpdate.Partition == nil {
+		return 0
+	}
+
+	return *rollingUpdate.Partition
+}
+
+func podOrdinal(pod corev1.Pod) int32 {
+	ordinal, err := strconv.Atoi(pod.Name[strings.LastIndex(pod.Name, "-")+1:])
+	if err != nil {
+		return -1
+	}
+
+	return int32(ordinal)
+}
@@ -0,0 +1,610 @@
+package k8s_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("Rollout", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		desirer           *k8s.StatefulSetDesirer
+		statefulSet       appsv1.StatefulSet
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+		statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+		probeCreator := new(k8sfakes.FakeProbeCreator)
+		probeCreator.Returns(&corev1.Probe{}, nil)
+
+		desirer = &k8s.StatefulSetDesirer{
+			Pods:                      new(k8sfakes.FakePodClient),
+			Secrets:                   new(k8sfakes.FakeSecretsClient),
+			StatefulSets:              statefulSetClient,
+			PodDisruptionBudgets:      new(k8sfakes.FakePodDisruptionBudgetClient),
+			NetworkPolicies:           new(k8sfakes.FakeNetworkPolicyClient),
+			HorizontalPodAutoscalers:  new(k8sfakes.FakeHorizontalPodAutoscalerClient),
+			Services:                  new(k8sfakes.FakeServiceClient),
+			RegistrySecretName:        registrySecretName,
+			LivenessProbeCreator:      probeCreator.Spy,
+			ReadinessProbeCreator:     probeCreator.Spy,
+			StartupProbeCreator:       probeCreator.Spy,
+			Logger:                    lagertest.NewTestLogger("rollout"),
+			StatefulSetToLRPMapper:    new(k8sfakes.FakeLRPMapper).Spy,
+			EventsClient:              new(k8sfakes.FakeEventsClient),
+			ApplicationServiceAccount: "eirini",
+		}
+
+		statefulSet = appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "baldur",
+				Namespace:   "the-namespace",
+				Annotations: map[string]string{k8s.AnnotationProcessGUID: "guid_1234-version_1234"},
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: int32ptr(4),
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: k8s.OPIContainerName, Image: "old/image"}},
+					},
+				},
+			},
+		}
+
+		statefulSetClient.GetByLRPIdentifierStub = func(opi.LRPIdentifier) ([]appsv1.StatefulSet, error) {
+			return []appsv1.StatefulSet{statefulSet}, nil
+		}
+	})
+
+	Describe("Desire", func() {
+		It("records the update strategy and does not hold back new instances", func() {
+			lrp := createLRP("Baldur", []opi.Route{})
+			lrp.UpdateStrategy = opi.UpdateStrategy{MaxUnavailable: 2, CanaryInstances: 1}
+
+			Expect(desirer.Desire("the-namespace", lrp)).To(Succeed())
+
+			_, created := statefulSetClient.CreateArgsForCall(0)
+			Expect(created.Annotations).To(HaveKeyWithValue(k8s.AnnotationUpdateStrategy, `{"maxUnavailable":2,"canaryInstances":1}`))
+			Expect(created.Spec.UpdateStrategy.Type).To(Equal(appsv1.RollingUpdateStatefulSetStrategyType))
+			Expect(*created.Spec.UpdateStrategy.RollingUpdate.Partition).To(BeZero())
+		})
+
+		It("uses the default update strategy when the lrp does not specify one", func() {
+			desirer.DefaultUpdateStrategy = opi.UpdateStrategy{MaxUnavailable: 3}
+
+			Expect(desirer.Desire("the-namespace", createLRP("Baldur", []opi.Route{}))).To(Succeed())
+
+			_, created := statefulSetClient.CreateArgsForCall(0)
+			Expect(created.Annotations).To(HaveKeyWithValue(k8s.AnnotationUpdateStrategy, `{"maxUnavailable":3}`))
+		})
+	})
+
+	Describe("Update", func() {
+		var (
+			lrp       *opi.LRP
+			updateErr error
+		)
+
+		BeforeEach(func() {
+			lrp = &opi.LRP{
+				LRPIdentifier:   opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"},
+				TargetInstances: 4,
+				Image:           "new/image",
+				UpdateStrategy:  opi.UpdateStrategy{CanaryInstances: 1},
+			}
+		})
+
+		JustBeforeEach(func() {
+			updateErr = desirer.Update(lrp)
+		})
+
+		It("starts a canary rollout of the new template", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+
+			_, updated := statefulSetClient.UpdateArgsForCall(0)
+			Expect(updated.Annotations).To(HaveKeyWithValue(k8s.AnnotationRolloutState, k8s.RolloutStateCanary))
+			Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(3)))
+
+			var previous corev1.PodTemplateSpec
+			Expect(json.Unmarshal([]byte(updated.Annotations[k8s.AnnotationRolloutPreviousTemplate]), &previous)).To(Succeed())
+			Expect(previous.Spec.Containers[0].Image).To(Equal("old/image"))
+		})
+
+		When("only the number of instances changes", func() {
+			BeforeEach(func() {
+				Expect(desirer.Update(lrp)).To(Succeed())
+				_, rolledOut := statefulSetClient.UpdateArgsForCall(0)
+				statefulSet = *rolledOut
+				delete(statefulSet.Annotations, k8s.AnnotationRolloutState)
+
+				lrp.TargetInstances = 6
+			})
+
+			It("does not start a canary rollout", func() {
+				_, updated := statefulSetClient.UpdateArgsForCall(1)
+				Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationRolloutState))
+				Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(BeZero())
+			})
+		})
+
+		When("a canary rollout is already in progress", func() {
+			BeforeEach(func() {
+				statefulSet.Annotations[k8s.AnnotationRolloutState] = k8s.RolloutStateCanary
+				statefulSet.Annotations[k8s.AnnotationRolloutPreviousTemplate] = "the-original-template"
+				lrp.TargetInstances = 6
+			})
+
+			It("keeps the original template and holds back the remaining instances", func() {
+				_, updated := statefulSetClient.UpdateArgsForCall(0)
+				Expect(updated.Annotations).To(HaveKeyWithValue(k8s.AnnotationRolloutPreviousTemplate, "the-original-template"))
+				Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(5)))
+			})
+		})
+
+		When("the lrp no longer asks for canaries", func() {
+			BeforeEach(func() {
+				lrp.UpdateStrategy = opi.UpdateStrategy{}
+				statefulSet.Annotations[k8s.AnnotationUpdateStrategy] = `{"canaryInstances":1}`
+				statefulSet.Annotations[k8s.AnnotationRolloutState] = k8s.RolloutStateCanary
+				statefulSet.Annotations[k8s.AnnotationRolloutPreviousTemplate] = "the-original-template"
+				partition := int32(3)
+				statefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+					Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+				}
+			})
+
+			It("rolls out to all instances", func() {
+				_, updated := statefulSetClient.UpdateArgsForCall(0)
+				Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationUpdateStrategy))
+				Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationRolloutState))
+				Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationRolloutPreviousTemplate))
+				Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(BeZero())
+			})
+		})
+	})
+
+	Describe("Rollout", func() {
+		var (
+			action     string
+			rolloutErr error
+		)
+
+		BeforeEach(func() {
+			action = k8s.RolloutActionPromote
+
+			previous, err := json.Marshal(statefulSet.Spec.Template)
+			Expect(err).NotTo(HaveOccurred())
+
+			partition := int32(3)
+			statefulSet.Annotations[k8s.AnnotationRolloutState] = k8s.RolloutStateCanary
+			statefulSet.Annotations[k8s.AnnotationRolloutPreviousTemplate] = string(previous)
+			statefulSet.Spec.Template.Spec.Containers[0].Image = "new/image"
+			statefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+				Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+			}
+		})
+
+		JustBeforeEach(func() {
+			rolloutErr = desirer.Rollout(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, action)
+		})
+
+		It("promotes the canary to all instances", func() {
+			Expect(rolloutErr).NotTo(HaveOccurred())
+
+			namespace, updated := statefulSetClient.UpdateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(updated.Spec.Template.Spec.Containers[0].Image).To(Equal("new/image"))
+			Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(BeZero())
+			Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationRolloutState))
+			Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationRolloutPreviousTemplate))
+		})
+
+		When("rolling back", func() {
+			BeforeEach(func() {
+				action = k8s.RolloutActionRollback
+			})
+
+			It("restores the previous template on all instances", func() {
+				Expect(rolloutErr).NotTo(HaveOccurred())
+
+				_, updated := statefulSetClient.UpdateArgsForCall(0)
+				Expect(updated.Spec.Template.Spec.Containers[0].Image).To(Equal("old/image"))
+				Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(BeZero())
+				Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationRolloutState))
+			})
+		})
+
+		When("the action is not supported", func() {
+			BeforeEach(func() {
+				action = "jump"
+			})
+
+			It("returns an invalid action error", func() {
+				Expect(errors.Is(rolloutErr, eirini.ErrInvalidRolloutAction)).To(BeTrue())
+				Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		When("there is no rollout in progress", func() {
+			BeforeEach(func() {
+				delete(statefulSet.Annotations, k8s.AnnotationRolloutState)
+			})
+
+			It("returns a no rollout in progress error", func() {
+				Expect(errors.Is(rolloutErr, eirini.ErrNoRolloutInProgress)).To(BeTrue())
+				Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		When("the statefulset does not exist", func() {
+			BeforeEach(func() {
+				statefulSetClient.GetByLRPIdentifierStub = nil
+				statefulSetClient.GetByLRPIdentifierReturns(nil, nil)
+			})
+
+			It("returns a not found error", func() {
+				Expect(errors.Is(rolloutErr, eirini.ErrNotFound)).To(BeTrue())
+			})
+		})
+
+		When("updating the statefulset fails", func() {
+			BeforeEach(func() {
+				statefulSetClient.UpdateReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(rolloutErr).To(MatchError(ContainSubstring("failed to roll out statefulset")))
+			})
+		})
+	})
+
+	Describe("Rolling back an update", func() {
+		var original, rolledBack *opi.LRP
+
+		BeforeEach(func() {
+			statefulSet.Labels = map[string]string{k8s.LabelGUID: "guid_1234", k8s.LabelVersion: "version_1234"}
+			statefulSet.Annotations[k8s.AnnotationVersion] = "version_1234"
+			statefulSet.Annotations[k8s.AnnotationHealthCheck] = `{"type":"port","port":8080}`
+			statefulSet.Annotations[k8s.AnnotationRegisteredRoutes] = `[{"hostname":"old.example.com","port":8080}]`
+			statefulSet.Annotations[k8s.AnnotationLastUpdated] = "old"
+			statefulSet.Annotations[k8s.AnnotationOriginalImage] = "old/image:latest"
+			statefulSet.Annotations[k8s.AnnotationEnvFingerprint] = "old-env"
+
+			var err error
+			original, err = k8s.StatefulSetToLRP(statefulSet)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(desirer.Update(&opi.LRP{
+				LRPIdentifier:   opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"},
+				TargetInstances: 4,
+				Image:           "new/image@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0",
+				OriginalImage:   "new/image:latest",
+				LastUpdated:     "new",
+				AppURIs:         []opi.Route{{Hostname: "new.example.com", Port: 8080}},
+				Health:          opi.Healtcheck{Type: "http", Port: 8080, Endpoint: "/health"},
+				UpdateStrategy:  opi.UpdateStrategy{CanaryInstances: 1},
+			})).To(Succeed())
+
+			_, canary := statefulSetClient.UpdateArgsForCall(0)
+			statefulSet = *canary
+
+			Expect(desirer.Rollout(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, k8s.RolloutActionRollback)).To(Succeed())
+
+			_, updated := statefulSetClient.UpdateArgsForCall(1)
+			rolledBack, err = k8s.StatefulSetToLRP(*updated)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("maps to the LRP from before the update", func() {
+			Expect(rolledBack.Image).To(Equal(original.Image))
+			Expect(rolledBack.OriginalImage).To(Equal("old/image:latest"))
+			Expect(rolledBack.LastUpdated).To(Equal("old"))
+			Expect(rolledBack.AppURIs).To(Equal(original.AppURIs))
+			Expect(rolledBack.Health).To(Equal(original.Health))
+			Expect(rolledBack.UpdateStrategy).To(Equal(original.UpdateStrategy))
+		})
+
+		It("restores the env fingerprint of the previous template", func() {
+			_, updated := statefulSetClient.UpdateArgsForCall(1)
+			Expect(updated.Annotations).To(HaveKeyWithValue(k8s.AnnotationEnvFingerprint, "old-env"))
+			Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationRolloutPreviousAnnotations))
+		})
+	})
+})
+
+var _ = Describe("Restart", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		desirer           *k8s.StatefulSetDesirer
+		restartErr        error
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+		statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "baldur", Namespace: "the-namespace"},
+				Spec: appsv1.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{k8s.AnnotationRestartedAt: "a-while-ago"}},
+					},
+				},
+			},
+		}, nil)
+
+		desirer = &k8s.StatefulSetDesirer{
+			StatefulSets: statefulSetClient,
+			Logger:       lagertest.NewTestLogger("restart"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		restartErr = desirer.Restart(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})
+	})
+
+	It("bumps the restart annotation of the pod template", func() {
+		Expect(restartErr).NotTo(HaveOccurred())
+		Expect(statefulSetClient.GetByLRPIdentifierArgsForCall(0)).To(Equal(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}))
+
+		namespace, updated := statefulSetClient.UpdateArgsForCall(0)
+		Expect(namespace).To(Equal("the-namespace"))
+		Expect(updated.Spec.Template.Annotations).To(HaveKey(k8s.AnnotationRestartedAt))
+		Expect(updated.Spec.Template.Annotations[k8s.AnnotationRestartedAt]).NotTo(Equal("a-while-ago"))
+		Expect(updated.Annotations).NotTo(HaveKey(k8s.AnnotationRestartedAt))
+	})
+
+	When("the restarted instances are replaced in batches", func() {
+		var (
+			podClient   *k8sfakes.FakePodClient
+			progressErr error
+		)
+
+		BeforeEach(func() {
+			podClient = new(k8sfakes.FakePodClient)
+			podClient.GetByLRPIdentifierReturns([]corev1.Pod{
+				revisionPod("baldur-0", "rev-1"),
+				revisionPod("baldur-1", "rev-1"),
+				revisionPod("baldur-2", "rev-1"),
+			}, nil)
+			podClient.EvictReturnsOnCall(1, k8serrors.NewTooManyRequests("disruption budget", 10))
+		})
+
+		JustBeforeEach(func() {
+			_, restarted := statefulSetClient.UpdateArgsForCall(0)
+			restarted.Labels = map[string]string{k8s.LabelGUID: "guid_1234", k8s.LabelVersion: "version_1234"}
+			restarted.Annotations = map[string]string{k8s.AnnotationUpdateStrategy: `{"maxUnavailable":3}`}
+			restarted.Spec.Replicas = int32ptr(3)
+			restarted.Status.CurrentRevision = "rev-1"
+			restarted.Status.UpdateRevision = "rev-2"
+			statefulSetClient.GetBySourceTypeReturns([]appsv1.StatefulSet{*restarted}, nil)
+
+			progressor := &k8s.RolloutProgressor{
+				StatefulSets: statefulSetClient,
+				Pods:         podClient,
+				Logger:       lagertest.NewTestLogger("rollout-progressor"),
+			}
+			progressErr = progressor.Progress()
+		})
+
+		It("stops once the disruption budget refuses an eviction", func() {
+			Expect(progressErr).NotTo(HaveOccurred())
+			Expect(podClient.EvictCallCount()).To(Equal(2))
+			Expect(podClient.DeleteCallCount()).To(BeZero())
+		})
+	})
+
+	When("the statefulset does not exist", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetByLRPIdentifierReturns(nil, nil)
+		})
+
+		It("returns a not found error", func() {
+			Expect(errors.Is(restartErr, eirini.ErrNotFound)).To(BeTrue())
+			Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+		})
+	})
+
+	When("updating the statefulset fails", func() {
+		BeforeEach(func() {
+			statefulSetClient.UpdateReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(restartErr).To(MatchError(ContainSubstring("failed to restart statefulset")))
+		})
+	})
+})
+
+var _ = Describe("RolloutProgressor", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		podClient         *k8sfakes.FakePodClient
+		progressor        *k8s.RolloutProgressor
+		statefulSet       appsv1.StatefulSet
+		pods              []corev1.Pod
+		progressErr       error
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+		podClient = new(k8sfakes.FakePodClient)
+
+		statefulSet = versionedStatefulSet("baldur", "app-guid", "v1", metav1.Now().Time, 5, 5)
+		statefulSet.Annotations = map[string]string{k8s.AnnotationUpdateStrategy: `{"maxUnavailable":3}`}
+		statefulSet.Status.CurrentRevision = "rev-1"
+		statefulSet.Status.UpdateRevision = "rev-2"
+
+		pods = []corev1.Pod{}
+		for i := 0; i < 5; i++ {
+			pods = append(pods, revisionPod(fmt.Sprintf("baldur-%d", i), "rev-1"))
+		}
+
+		statefulSetClient.GetBySourceTypeStub = func(string) ([]appsv1.StatefulSet, error) {
+			return []appsv1.StatefulSet{statefulSet}, nil
+		}
+		podClient.GetByLRPIdentifierStub = func(opi.LRPIdentifier) ([]corev1.Pod, error) {
+			return pods, nil
+		}
+
+		progressor = &k8s.RolloutProgressor{
+			StatefulSets: statefulSetClient,
+			Pods:         podClient,
+			Logger:       lagertest.NewTestLogger("rollout-progressor"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		progressErr = progressor.Progress()
+	})
+
+	evictedPods := func() []string {
+		names := []string{}
+		for i := 0; i < podClient.EvictCallCount(); i++ {
+			_, name := podClient.EvictArgsForCall(i)
+			names = append(names, name)
+		}
+
+		return names
+	}
+
+	It("replaces up to maxUnavailable outdated pods, highest ordinal first", func() {
+		Expect(progressErr).NotTo(HaveOccurred())
+		Expect(podClient.GetByLRPIdentifierArgsForCall(0)).To(Equal(opi.LRPIdentifier{GUID: "app-guid", Version: "v1"}))
+		Expect(evictedPods()).To(Equal([]string{"baldur-4", "baldur-3", "baldur-2"}))
+	})
+
+	It("evicts the pods instead of deleting them, so that the disruption budget is honoured", func() {
+		Expect(podClient.DeleteCallCount()).To(BeZero())
+	})
+
+	When("the disruption budget does not allow another eviction", func() {
+		BeforeEach(func() {
+			podClient.EvictReturnsOnCall(1, k8serrors.NewTooManyRequests("disruption budget", 10))
+		})
+
+		It("stops replacing pods until the next cycle", func() {
+			Expect(progressErr).NotTo(HaveOccurred())
+			Expect(evictedPods()).To(Equal([]string{"baldur-4", "baldur-3"}))
+		})
+	})
+
+	When("an outdated pod has already gone", func() {
+		BeforeEach(func() {
+			podClient.EvictReturnsOnCall(0, k8serrors.NewNotFound(schema.GroupResource{}, "baldur-4"))
+		})
+
+		It("carries on with the next one", func() {
+			Expect(progressErr).NotTo(HaveOccurred())
+			Expect(evictedPods()).To(Equal([]string{"baldur-4", "baldur-3", "baldur-2"}))
+		})
+	})
+
+	When("some pods are already unavailable", func() {
+		BeforeEach(func() {
+			pods[4] = revisionPod("baldur-4", "rev-2")
+			pods[4].Status.Conditions = nil
+			pods = append(pods[:3], pods[4])
+		})
+
+		It("only replaces pods within the budget", func() {
+			Expect(evictedPods()).To(Equal([]string{"baldur-2"}))
+		})
+	})
+
+	When("some pods are already updated", func() {
+		BeforeEach(func() {
+			pods[4] = revisionPod("baldur-4", "rev-2")
+			pods[3] = revisionPod("baldur-3", "rev-2")
+		})
+
+		It("only replaces the outdated pods", func() {
+			Expect(evictedPods()).To(Equal([]string{"baldur-2", "baldur-1", "baldur-0"}))
+		})
+	})
+
+	When("a canary partition is set", func() {
+		BeforeEach(func() {
+			partition := int32(4)
+			statefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+				Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+			}
+		})
+
+		It("does not replace pods below the partition", func() {
+			Expect(evictedPods()).To(Equal([]string{"baldur-4"}))
+		})
+	})
+
+	When("maxUnavailable is one", func() {
+		BeforeEach(func() {
+			statefulSet.Annotations[k8s.AnnotationUpdateStrategy] = `{"maxUnavailable":1}`
+		})
+
+		It("leaves the rollout to the statefulset controller", func() {
+			Expect(podClient.GetByLRPIdentifierCallCount()).To(BeZero())
+			Expect(podClient.EvictCallCount()).To(BeZero())
+		})
+	})
+
+	When("the statefulset is not being rolled out", func() {
+		BeforeEach(func() {
+			statefulSet.Status.UpdateRevision = "rev-1"
+		})
+
+		It("does nothing", func() {
+			Expect(podClient.EvictCallCount()).To(BeZero())
+		})
+	})
+
+	When("evicting a pod fails", func() {
+		BeforeEach(func() {
+			podClient.EvictReturns(errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(progressErr).To(MatchError(ContainSubstring("failed to progress the rollout of 1 statefulsets")))
+		})
+	})
+
+	When("listing the statefulsets fails", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetBySourceTypeStub = nil
+			statefulSetClient.GetBySourceTypeReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(progressErr).To(MatchError(ContainSubstring("failed to list statefulsets")))
+		})
+	})
+})
+
+func revisionPod(name, revision string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "the-namespace",
+			Labels:    map[string]string{appsv1.StatefulSetRevisionLabel: revision},
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
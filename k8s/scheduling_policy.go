@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"fmt"
+	"math"
+
+	"code.cloudfoundry.org/eirini"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	CPULimitNone    = "none"
+	CPULimitRequest = "request"
+	CPULimitBurst   = "burst"
+)
+
+func matchSchedulingPolicy(policies []eirini.SchedulingPolicy, orgGUID, spaceGUID string, annotations map[string]string) *eirini.SchedulingPolicy {
+	for i, policy := range policies {
+		if policy.OrgGUID != "" && policy.OrgGUID != orgGUID {
+			continue
+		}
+
+		if policy.SpaceGUID != "" && policy.SpaceGUID != spaceGUID {
+			continue
+		}
+
+		if !hasAnnotations(annotations, policy.Annotations) {
+			continue
+		}
+
+		return &policies[i]
+	}
+
+	return nil
+}
+
+func hasAnnotations(annotations, expected map[string]string) bool {
+	for k, v := range expected {
+		if actual, ok := annotations[k]; !ok || actual != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applySchedulingPolicy sets the priority class and the cpu limit of the
+// policy on the pod. The limit applies to every container, including
+// sidecars and init containers, so that none of them can escape it. Containers
+// without a cpu request get the one of the app, as kubernetes would default
+// it to the limit otherwise.
+func applySchedulingPolicy(policy *eirini.SchedulingPolicy, podSpec *corev1.PodSpec, cpuWeight uint8) error {
+	if policy == nil {
+		return nil
+	}
+
+	podSpec.PriorityClassName = policy.PriorityClassName
+
+	limit, err := cpuLimit(policy, cpuWeight)
+	if err != nil || limit == nil {
+		return err
+	}
+
+	for i := range podSpec.InitContainers {
+		applyCPULimit(&podSpec.InitContainers[i].Resources, *limit, cpuWeight)
+	}
+
+	for i := range podSpec.Containers {
+		applyCPULimit(&podSpec.Containers[i].Resources, *limit, cpuWeight)
+	}
+
+	return nil
+}
+
+func applyCPULimit(resources *corev1.ResourceRequirements, limit resource.Quantity, cpuWeight uint8) {
+	if resources.Requests == nil {
+		resources.Requests = corev1.ResourceList{}
+	}
+
+	if _, ok := resources.Requests[corev1.ResourceCPU]; !ok {
+		resources.Requests[corev1.ResourceCPU] = toCPUMillicores(cpuWeight)
+	}
+
+	if resources.Limits == nil {
+		resources.Limits = corev1.ResourceList{}
+	}
+
+	resources.Limits[corev1.ResourceCPU] = limit
+}
+
+func cpuLimit(policy *eirini.SchedulingPolicy, cpuWeight uint8) (*resource.Quantity, error) {
+	multiplier := 1.0
+
+	switch policy.CPULimit {
+	case "", CPULimitNone:
+		return nil, nil
+	case CPULimitRequest:
+	case CPULimitBurst:
+		if policy.CPUBurstMultiplier < 1 {
+			return nil, fmt.Errorf("cpu burst multiplier must be at least 1, got %v", policy.CPUBurstMultiplier)
+		}
+
+		multiplier = policy.CPUBurstMultiplier
+	default:
+		return nil, fmt.Errorf("unknown cpu limit policy %q", policy.CPULimit)
+	}
+
+	if cpuWeight == 0 {
+		return nil, nil
+	}
+
+	millicores := int64(math.Ceil(float64(cpuWeight) * 10 * multiplier)) //nolint:gomnd
+
+	return resource.NewScaledQuantity(millicores, resource.Milli), nil
+}
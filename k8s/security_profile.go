@@ -0,0 +1,289 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultWritablePath      = "/tmp"
+	writableVolumeNamePrefix = "eirini-writable-"
+	capabilityAll            = "ALL"
+	capabilityNetBindService = "NET_BIND_SERVICE"
+)
+
+func applySecurityProfile(profile eirini.SecurityProfile, template *corev1.PodTemplateSpec) error {
+	spec := &template.Spec
+
+	seccompProfile, err := toSeccompProfile(profile)
+	if err != nil {
+		return err
+	}
+
+	if err = validateAppArmorProfile(profile.AppArmorProfile); err != nil {
+		return err
+	}
+
+	if spec.SecurityContext == nil {
+		spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+
+	spec.SecurityContext.SeccompProfile = seccompProfile
+
+	var writableMounts []corev1.VolumeMount
+	if profile.ReadOnlyRootFilesystem {
+		writableMounts = addWritableVolumes(profile, spec)
+	}
+
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			hardenContainer(profile, &containers[i], writableMounts)
+
+			if profile.AppArmorProfile != "" {
+				template.Annotations[corev1.AppArmorBetaContainerAnnotationKeyPrefix+containers[i].Name] = profile.AppArmorProfile
+			}
+		}
+	}
+
+	if !profile.EnforceRestricted {
+		return nil
+	}
+
+	if violations := restrictedViolations(template); len(violations) > 0 {
+		return fmt.Errorf("pod violates the restricted pod security standard: %s", strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+func runAsUser(profile eirini.SecurityProfile) *int64 {
+	if profile.RunAsUser > 0 {
+		uid := profile.RunAsUser
+
+		return &uid
+	}
+
+	return int64ptr(VcapUID)
+}
+
+// Buildpacks write all over the root filesystem of staging containers, so it
+// stays writable. Everything else of the profile applies, as the staging
+// workspace, buildpacks and output are emptyDir volumes.
+func stagingSecurityProfile(profile eirini.SecurityProfile) eirini.SecurityProfile {
+	profile.ReadOnlyRootFilesystem = false
+
+	return profile
+}
+
+func toSeccompProfile(profile eirini.SecurityProfile) (*corev1.SeccompProfile, error) {
+	switch corev1.SeccompProfileType(profile.SeccompProfileType) {
+	case "", corev1.SeccompProfileTypeRuntimeDefault:
+		return &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}, nil
+	case corev1.SeccompProfileTypeUnconfined:
+		return &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}, nil
+	case corev1.SeccompProfileTypeLocalhost:
+		if profile.SeccompLocalhostProfile == "" {
+			return nil, errors.New("localhost seccomp profile requires a profile path")
+		}
+
+		localhostProfile := profile.SeccompLocalhostProfile
+
+		return &corev1.SeccompProfile{
+			Type:             corev1.SeccompProfileTypeLocalhost,
+			LocalhostProfile: &localhostProfile,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown seccomp profile type %q", profile.SeccompProfileType)
+	}
+}
+
+func validateAppArmorProfile(profile string) error {
+	if profile == "" || profile == corev1.AppArmorBetaProfileRuntimeDefault || profile == corev1.AppArmorBetaProfileNameUnconfined {
+		return nil
+	}
+
+	if strings.HasPrefix(profile, corev1.AppArmorBetaProfileNamePrefix) && len(profile) > len(corev1.AppArmorBetaProfileNamePrefix) {
+		return nil
+	}
+
+	return fmt.Errorf("invalid apparmor profile %q", profile)
+}
+
+func addWritableVolumes(profile eirini.SecurityProfile, spec *corev1.PodSpec) []corev1.VolumeMount {
+	paths := profile.WritablePaths
+	if len(paths) == 0 {
+		paths = []string{defaultWritablePath}
+	}
+
+	mounts := make([]corev1.VolumeMount, 0, len(paths))
+
+	for i, path := range paths {
+		name := fmt.Sprintf("%s%d", writableVolumeNamePrefix, i)
+
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+
+		mounts = append(mounts, corev1.VolumeMount{Name: name, MountPath: path})
+	}
+
+	return mounts
+}
+
+func hardenContainer(profile eirini.SecurityProfile, container *corev1.Container, writableMounts []corev1.VolumeMount) {
+	if container.SecurityContext == nil {
+		container.SecurityContext = &corev1.SecurityContext{}
+	}
+
+	allowPrivilegeEscalation := false
+	container.SecurityContext.AllowPrivilegeEscalation = &allowPrivilegeEscalation
+
+	if profile.DropAllCapabilities {
+		container.SecurityContext.Capabilities = &corev1.Capabilities{
+			Drop: []corev1.Capability{capabilityAll},
+		}
+	}
+
+	if !profile.ReadOnlyRootFilesystem {
+		return
+	}
+
+	readOnlyRootFilesystem := true
+	container.SecurityContext.ReadOnlyRootFilesystem = &readOnlyRootFilesystem
+
+	for _, mount := range writableMounts {
+		if !hasMountPath(container.VolumeMounts, mount.MountPath) {
+			container.VolumeMounts = append(container.VolumeMounts, mount)
+		}
+	}
+}
+
+func hasMountPath(mounts []corev1.VolumeMount, path string) bool {
+	for _, m := range mounts {
+		if m.MountPath == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+func restrictedViolations(template *corev1.PodTemplateSpec) []string { //nolint:gocyclo // a flat list of checks
+	spec := &template.Spec
+	violations := []string{}
+
+	if spec.HostNetwork || spec.HostPID || spec.HostIPC {
+		violations = append(violations, "host namespaces are not allowed")
+	}
+
+	for _, v := range spec.Volumes {
+		if !isAllowedVolumeType(v.VolumeSource) {
+			violations = append(violations, fmt.Sprintf("volume %q uses a volume type that is not allowed", v.Name))
+		}
+	}
+
+	podContext := spec.SecurityContext
+	if podContext == nil {
+		podContext = &corev1.PodSecurityContext{}
+	}
+
+	if isRoot(podContext.RunAsUser) {
+		violations = append(violations, "pod must not run as user 0")
+	}
+
+	for _, c := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			violations = append(violations, fmt.Sprintf("container %q must set allowPrivilegeEscalation=false", c.Name))
+		}
+
+		if sc.Privileged != nil && *sc.Privileged {
+			violations = append(violations, fmt.Sprintf("container %q must not be privileged", c.Name))
+		}
+
+		if (!isTrue(sc.RunAsNonRoot) && !isTrue(podContext.RunAsNonRoot)) || isRoot(sc.RunAsUser) {
+			violations = append(violations, fmt.Sprintf("container %q must run as non-root", c.Name))
+		}
+
+		seccompProfile := podContext.SeccompProfile
+		if sc.SeccompProfile != nil {
+			seccompProfile = sc.SeccompProfile
+		}
+
+		if seccompProfile == nil || seccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			violations = append(violations, fmt.Sprintf("container %q must use the RuntimeDefault or a Localhost seccomp profile", c.Name))
+		}
+
+		if !dropsAllCapabilities(sc.Capabilities) {
+			violations = append(violations, fmt.Sprintf("container %q must drop all capabilities and may only add NET_BIND_SERVICE", c.Name))
+		}
+
+		for _, p := range c.Ports {
+			if p.HostPort != 0 {
+				violations = append(violations, fmt.Sprintf("container %q must not use host ports", c.Name))
+			}
+		}
+
+		if template.Annotations[corev1.AppArmorBetaContainerAnnotationKeyPrefix+c.Name] == corev1.AppArmorBetaProfileNameUnconfined {
+			violations = append(violations, fmt.Sprintf("container %q must not use an unconfined apparmor profile", c.Name))
+		}
+	}
+
+	return violations
+}
+
+func isAllowedVolumeType(source corev1.VolumeSource) bool {
+	switch {
+	case source.ConfigMap != nil,
+		source.CSI != nil,
+		source.DownwardAPI != nil,
+		source.EmptyDir != nil,
+		source.Ephemeral != nil,
+		source.PersistentVolumeClaim != nil,
+		source.Projected != nil,
+		source.Secret != nil:
+		return true
+	default:
+		// volumes without a source are defaulted to emptyDir
+		return source == corev1.VolumeSource{}
+	}
+}
+
+func dropsAllCapabilities(capabilities *corev1.Capabilities) bool {
+	if capabilities == nil {
+		return false
+	}
+
+	for _, c := range capabilities.Add {
+		if c != capabilityNetBindService {
+			return false
+		}
+	}
+
+	for _, c := range capabilities.Drop {
+		if c == capabilityAll {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+func isRoot(uid *int64) bool {
+	return uid != nil && *uid == 0
+}
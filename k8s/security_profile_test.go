@@ -0,0 +1,317 @@
+package k8s_test
+
+import (
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("SecurityProfile", func() {
+	var profile eirini.SecurityProfile
+
+	BeforeEach(func() {
+		profile = eirini.SecurityProfile{
+			DropAllCapabilities:     true,
+			ReadOnlyRootFilesystem:  true,
+			WritablePaths:           []string{"/tmp", "/home/vcap/tmp"},
+			SeccompProfileType:      "Localhost",
+			SeccompLocalhostProfile: "profiles/eirini.json",
+			AppArmorProfile:         "localhost/eirini",
+			EnforceRestricted:       true,
+		}
+	})
+
+	Describe("LRPs", func() {
+		var (
+			statefulSetClient *k8sfakes.FakeStatefulSetClient
+			lrp               *opi.LRP
+			desireErr         error
+		)
+
+		BeforeEach(func() {
+			statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+			statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+			lrp = createLRP("Baldur", []opi.Route{})
+			lrp.Sidecars = []opi.Sidecar{{Name: "first-sidecar", Command: []string{"echo"}, MemoryMB: 10}}
+		})
+
+		JustBeforeEach(func() {
+			probeCreator := new(k8sfakes.FakeProbeCreator)
+			desirer := &k8s.StatefulSetDesirer{
+				Pods:                     new(k8sfakes.FakePodClient),
+				Secrets:                  new(k8sfakes.FakeSecretsClient),
+				StatefulSets:             statefulSetClient,
+				PodDisruptionBudgets:     new(k8sfakes.FakePodDisruptionBudgetClient),
+				NetworkPolicies:          new(k8sfakes.FakeNetworkPolicyClient),
+				HorizontalPodAutoscalers: new(k8sfakes.FakeHorizontalPodAutoscalerClient),
+				Services:                 new(k8sfakes.FakeServiceClient),
+				EventsClient:             new(k8sfakes.FakeEventsClient),
+				StatefulSetToLRPMapper:   k8s.StatefulSetToLRP,
+				LivenessProbeCreator:     probeCreator.Spy,
+				ReadinessProbeCreator:    probeCreator.Spy,
+				StartupProbeCreator:      probeCreator.Spy,
+				Logger:                   lagertest.NewTestLogger("security-profile-test"),
+				SecurityProfile:          profile,
+			}
+
+			desireErr = desirer.Desire("the-namespace", lrp)
+		})
+
+		It("hardens every container", func() {
+			Expect(desireErr).NotTo(HaveOccurred())
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+
+			containers := statefulSet.Spec.Template.Spec.Containers
+			Expect(containers).To(HaveLen(2))
+
+			for _, c := range containers {
+				Expect(*c.SecurityContext.AllowPrivilegeEscalation).To(BeFalse())
+				Expect(*c.SecurityContext.ReadOnlyRootFilesystem).To(BeTrue())
+				Expect(c.SecurityContext.Capabilities).To(Equal(&corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}))
+				Expect(c.VolumeMounts).To(ContainElements(
+					corev1.VolumeMount{Name: "eirini-writable-0", MountPath: "/tmp"},
+					corev1.VolumeMount{Name: "eirini-writable-1", MountPath: "/home/vcap/tmp"},
+				))
+				Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue(
+					"container.apparmor.security.beta.kubernetes.io/"+c.Name, "localhost/eirini",
+				))
+			}
+		})
+
+		It("runs the app as the default vcap user", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(*statefulSet.Spec.Template.Spec.SecurityContext.RunAsUser).To(Equal(int64(2000)))
+		})
+
+		When("the profile sets the user to run as", func() {
+			BeforeEach(func() {
+				profile.RunAsUser = 3000
+			})
+
+			It("runs the app as that user", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(*statefulSet.Spec.Template.Spec.SecurityContext.RunAsUser).To(Equal(int64(3000)))
+			})
+		})
+
+		It("mounts writable emptyDir volumes", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Spec.Template.Spec.Volumes).To(ContainElements(
+				corev1.Volume{Name: "eirini-writable-0", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				corev1.Volume{Name: "eirini-writable-1", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			))
+		})
+
+		It("sets the localhost seccomp profile", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			localhostProfile := "profiles/eirini.json"
+			Expect(statefulSet.Spec.Template.Spec.SecurityContext.SeccompProfile).To(Equal(&corev1.SeccompProfile{
+				Type:             corev1.SeccompProfileTypeLocalhost,
+				LocalhostProfile: &localhostProfile,
+			}))
+		})
+
+		It("keeps the apparmor annotations off the statefulset", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			for key := range statefulSet.Annotations {
+				Expect(key).NotTo(HavePrefix(corev1.AppArmorBetaContainerAnnotationKeyPrefix))
+			}
+		})
+
+		It("does not report the apparmor annotations as user defined", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			mapped, err := k8s.StatefulSetToLRP(*statefulSet)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mapped.UserDefinedAnnotations).To(Equal(lrp.UserDefinedAnnotations))
+		})
+
+		It("does not report the writable volumes as app volume mounts", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			mapped, err := k8s.StatefulSetToLRP(*statefulSet)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mapped.VolumeMounts).To(Equal(lrp.VolumeMounts))
+		})
+
+		When("the app runs as root", func() {
+			BeforeEach(func() {
+				lrp.RunsAsRoot = true
+			})
+
+			It("is rejected by the restricted standard", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring(`container "opi" must run as non-root`)))
+				Expect(statefulSetClient.CreateCallCount()).To(BeZero())
+			})
+
+			When("the restricted standard is not enforced", func() {
+				BeforeEach(func() {
+					profile.EnforceRestricted = false
+				})
+
+				It("succeeds", func() {
+					Expect(desireErr).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		When("capabilities are not dropped", func() {
+			BeforeEach(func() {
+				profile.DropAllCapabilities = false
+			})
+
+			It("is rejected by the restricted standard", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("must drop all capabilities")))
+			})
+		})
+
+		When("the seccomp profile is unconfined", func() {
+			BeforeEach(func() {
+				profile.SeccompProfileType = "Unconfined"
+			})
+
+			It("is rejected by the restricted standard", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("must use the RuntimeDefault or a Localhost seccomp profile")))
+			})
+		})
+
+		When("the localhost seccomp profile has no path", func() {
+			BeforeEach(func() {
+				profile.SeccompLocalhostProfile = ""
+			})
+
+			It("fails", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("localhost seccomp profile requires a profile path")))
+			})
+		})
+
+		When("the seccomp profile type is unknown", func() {
+			BeforeEach(func() {
+				profile.SeccompProfileType = "Everything"
+			})
+
+			It("fails", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring(`unknown seccomp profile type "Everything"`)))
+			})
+		})
+
+		When("the apparmor profile is invalid", func() {
+			BeforeEach(func() {
+				profile.AppArmorProfile = "eirini"
+			})
+
+			It("fails", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring(`invalid apparmor profile "eirini"`)))
+			})
+		})
+	})
+
+	Describe("tasks and staging", func() {
+		var (
+			jobClient *k8sfakes.FakeJobCreatingClient
+			desirer   *k8s.TaskDesirer
+			task      *opi.Task
+		)
+
+		BeforeEach(func() {
+			jobClient = new(k8sfakes.FakeJobCreatingClient)
+			task = &opi.Task{
+				GUID:      "task-guid",
+				AppName:   "app",
+				SpaceName: "space",
+				Image:     "busybox",
+				Env:       map[string]string{},
+				CPUWeight: 1,
+			}
+		})
+
+		JustBeforeEach(func() {
+			desirer = k8s.NewTaskDesirer(
+				lagertest.NewTestLogger("security-profile-test"),
+				jobClient,
+				new(k8sfakes.FakeSecretsCreator),
+				"staging-namespace",
+				nil,
+				"",
+				"",
+				"",
+				false,
+				nil,
+				profile,
+			)
+		})
+
+		It("hardens task pods so that they satisfy the restricted standard", func() {
+			Expect(desirer.Desire("the-namespace", task)).To(Succeed())
+
+			_, job := jobClient.CreateArgsForCall(0)
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(*container.SecurityContext.ReadOnlyRootFilesystem).To(BeTrue())
+			Expect(container.SecurityContext.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")))
+			Expect(job.Annotations).To(HaveKeyWithValue("container.apparmor.security.beta.kubernetes.io/opi-task", "localhost/eirini"))
+		})
+
+		It("runs task pods as the default vcap user", func() {
+			Expect(desirer.Desire("the-namespace", task)).To(Succeed())
+
+			_, job := jobClient.CreateArgsForCall(0)
+			Expect(*job.Spec.Template.Spec.SecurityContext.RunAsUser).To(Equal(int64(2000)))
+		})
+
+		When("the profile sets the user to run as", func() {
+			BeforeEach(func() {
+				profile.RunAsUser = 3000
+			})
+
+			It("runs task and staging pods as that user", func() {
+				Expect(desirer.Desire("the-namespace", task)).To(Succeed())
+				Expect(desirer.DesireStaging(&opi.StagingTask{Task: task})).To(Succeed())
+
+				_, taskJob := jobClient.CreateArgsForCall(0)
+				_, stagingJob := jobClient.CreateArgsForCall(1)
+				Expect(*taskJob.Spec.Template.Spec.SecurityContext.RunAsUser).To(Equal(int64(3000)))
+				Expect(*stagingJob.Spec.Template.Spec.SecurityContext.RunAsUser).To(Equal(int64(3000)))
+			})
+		})
+
+		It("keeps the root filesystem of staging pods writable", func() {
+			Expect(desirer.DesireStaging(&opi.StagingTask{Task: task})).To(Succeed())
+
+			_, job := jobClient.CreateArgsForCall(0)
+			allContainers := append(job.Spec.Template.Spec.InitContainers, job.Spec.Template.Spec.Containers...)
+			Expect(allContainers).To(HaveLen(3))
+
+			for _, c := range allContainers {
+				Expect(c.SecurityContext.ReadOnlyRootFilesystem).To(BeNil())
+				Expect(c.SecurityContext.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")))
+				Expect(*c.SecurityContext.AllowPrivilegeEscalation).To(BeFalse())
+				Expect(job.Annotations).To(HaveKeyWithValue("container.apparmor.security.beta.kubernetes.io/"+c.Name, "localhost/eirini"))
+			}
+
+			for _, v := range job.Spec.Template.Spec.Volumes {
+				Expect(v.Name).NotTo(HavePrefix("eirini-writable-"))
+			}
+		})
+
+		When("the profile violates the restricted standard", func() {
+			BeforeEach(func() {
+				profile.AppArmorProfile = "unconfined"
+			})
+
+			It("does not create the task job", func() {
+				Expect(desirer.Desire("the-namespace", task)).To(MatchError(ContainSubstring("must not use an unconfined apparmor profile")))
+				Expect(jobClient.CreateCallCount()).To(BeZero())
+			})
+
+			It("does not create the staging job either", func() {
+				Expect(desirer.DesireStaging(&opi.StagingTask{Task: task})).To(MatchError(ContainSubstring("must not use an unconfined apparmor profile")))
+				Expect(jobClient.CreateCallCount()).To(BeZero())
+			})
+		})
+	})
+})
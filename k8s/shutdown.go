@@ -0,0 +1,169 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const noRoutes = "[]"
+
+// terminationGracePeriod includes the pre-stop delay, as kubernetes starts
+// counting the grace period before running the pre-stop hook.
+func (m *StatefulSetDesirer) terminationGracePeriod(lrp *opi.LRP) *int64 {
+	gracePeriod := lrp.TerminationGracePeriodSeconds
+	if gracePeriod == 0 {
+		gracePeriod = m.TerminationGracePeriodSeconds
+	}
+
+	if gracePeriod == 0 && m.PreStopDelaySeconds == 0 {
+		return nil
+	}
+
+	if gracePeriod == 0 {
+		gracePeriod = corev1.DefaultTerminationGracePeriodSeconds
+	}
+
+	gracePeriod += m.PreStopDelaySeconds
+
+	return &gracePeriod
+}
+
+func (m *StatefulSetDesirer) preStopLifecycle() *corev1.Lifecycle {
+	command := m.PreStopCommand
+	if len(command) == 0 {
+		if m.PreStopDelaySeconds == 0 {
+			return nil
+		}
+
+		// Exec sleep without a shell. Images that don't ship a sleep
+		// binary, such as distroless ones, need a PreStopCommand instead.
+		command = []string{"sleep", strconv.FormatInt(m.PreStopDelaySeconds, 10)}
+	}
+
+	return &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{Command: command},
+		},
+	}
+}
+
+// unregisterRoutes reports whether the statefulset has routes left to drain
+// once they are unregistered. The route emitter unregisters routes
+// asynchronously and the gorouter would keep sending requests to instances
+// being deleted, so a statefulset with routes to drain is only marked to be
+// deleted once the drain period is over, and the DrainedStatefulSetCollector
+// deletes it. Should it be desired again in the meantime, the mark is
+// cleared. Stopping the LRP again deletes it straight away, as it has no
+// routes anymore.
+func (m *StatefulSetDesirer) unregisterRoutes(logger lager.Logger, statefulSet *appsv1.StatefulSet) (bool, error) {
+	routes, ok := statefulSet.Annotations[AnnotationRegisteredRoutes]
+	if !ok || routes == noRoutes {
+		return false, nil
+	}
+
+	statefulSet.Annotations[AnnotationRegisteredRoutes] = noRoutes
+
+	drainRoutes := m.RouteDrainPeriod > 0
+	if drainRoutes {
+		statefulSet.Annotations[AnnotationDeleteAfter] = time.Now().Add(m.RouteDrainPeriod).UTC().Format(time.RFC3339)
+
+		logger.Debug("draining-routes", lager.Data{"drain-period": m.RouteDrainPeriod.String()})
+	}
+
+	if _, err := m.StatefulSets.Update(statefulSet.Namespace, statefulSet); err != nil {
+		logger.Error("failed-to-unregister-routes", err)
+
+		return false, errors.Wrap(err, "failed to unregister routes")
+	}
+
+	return drainRoutes, nil
+}
+
+func hasDeleteAfter(statefulSet *appsv1.StatefulSet) bool {
+	_, ok := statefulSet.Annotations[AnnotationDeleteAfter]
+
+	return ok
+}
+
+// DrainedStatefulSetCollector deletes the statefulsets whose routes have
+// drained after they were stopped.
+type DrainedStatefulSetCollector struct {
+	StatefulSets StatefulSetClient
+	Logger       lager.Logger
+}
+
+func (c *DrainedStatefulSetCollector) Collect() error {
+	logger := c.Logger.Session("collect")
+
+	statefulSets, err := c.StatefulSets.GetBySourceType(appSourceType)
+	if err != nil {
+		return errors.Wrap(err, "failed to list statefulsets")
+	}
+
+	failures := 0
+
+	for i := range statefulSets {
+		if !drained(&statefulSets[i]) {
+			continue
+		}
+
+		if collectErr := c.collect(logger, &statefulSets[i]); collectErr != nil {
+			logger.Error("failed-to-delete-drained-statefulset", collectErr, lager.Data{"name": statefulSets[i].Name})
+
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("failed to delete %d drained statefulsets", failures)
+	}
+
+	return nil
+}
+
+// collect checks the statefulset once more right before deleting it, as it
+// may have been desired again since it was listed.
+func (c *DrainedStatefulSetCollector) collect(logger lager.Logger, listed *appsv1.StatefulSet) error {
+	statefulSet, err := c.StatefulSets.Get(listed.Namespace, listed.Name)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to get statefulset")
+	}
+
+	if statefulSet.UID != listed.UID || !drained(statefulSet) {
+		return nil
+	}
+
+	logger.Info("deleting-drained-statefulset", lager.Data{"name": statefulSet.Name, "namespace": statefulSet.Namespace})
+
+	if err = c.StatefulSets.Delete(statefulSet.Namespace, statefulSet.Name); err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete statefulset")
+	}
+
+	return nil
+}
+
+// drained reports whether the statefulset is marked to be deleted and its
+// drain period is over. Marks that cannot be parsed count as drained, so the
+// statefulset doesn't linger.
+func drained(statefulSet *appsv1.StatefulSet) bool {
+	deleteAfter, ok := statefulSet.Annotations[AnnotationDeleteAfter]
+	if !ok {
+		return false
+	}
+
+	deadline, err := time.Parse(time.RFC3339, deleteAfter)
+
+	return err != nil || !time.Now().Before(deadline)
+}
@@ -0,0 +1,145 @@
+package k8s_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("DrainedStatefulSetCollector", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		collector         *k8s.DrainedStatefulSetCollector
+		drained           appsv1.StatefulSet
+		draining          appsv1.StatefulSet
+		running           appsv1.StatefulSet
+		collectErr        error
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+
+		drained = markedStatefulSet("drained", time.Now().Add(-time.Second))
+		draining = markedStatefulSet("draining", time.Now().Add(time.Minute))
+		running = appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "ns", UID: "running-uid"}}
+
+		statefulSetClient.GetBySourceTypeReturns([]appsv1.StatefulSet{drained, draining, running}, nil)
+		statefulSetClient.GetStub = func(_, name string) (*appsv1.StatefulSet, error) {
+			for _, st := range []appsv1.StatefulSet{drained, draining, running} {
+				if st.Name == name {
+					return st.DeepCopy(), nil
+				}
+			}
+
+			return nil, k8serrors.NewNotFound(schema.GroupResource{}, name)
+		}
+
+		collector = &k8s.DrainedStatefulSetCollector{
+			StatefulSets: statefulSetClient,
+			Logger:       lagertest.NewTestLogger("drained-statefulset-collector"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		collectErr = collector.Collect()
+	})
+
+	It("lists the app statefulsets", func() {
+		Expect(collectErr).NotTo(HaveOccurred())
+		Expect(statefulSetClient.GetBySourceTypeArgsForCall(0)).To(Equal("APP"))
+	})
+
+	It("deletes only the statefulsets whose routes have drained", func() {
+		Expect(statefulSetClient.DeleteCallCount()).To(Equal(1))
+
+		namespace, name := statefulSetClient.DeleteArgsForCall(0)
+		Expect(namespace).To(Equal("ns"))
+		Expect(name).To(Equal("drained"))
+	})
+
+	When("the statefulset has been desired again since it was listed", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetStub = func(_, name string) (*appsv1.StatefulSet, error) {
+				desiredAgain := drained.DeepCopy()
+				delete(desiredAgain.Annotations, k8s.AnnotationDeleteAfter)
+
+				return desiredAgain, nil
+			}
+		})
+
+		It("does not delete it", func() {
+			Expect(collectErr).NotTo(HaveOccurred())
+			Expect(statefulSetClient.DeleteCallCount()).To(BeZero())
+		})
+	})
+
+	When("the statefulset has been replaced since it was listed", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetStub = func(_, name string) (*appsv1.StatefulSet, error) {
+				replaced := drained.DeepCopy()
+				replaced.UID = "another-uid"
+
+				return replaced, nil
+			}
+		})
+
+		It("does not delete it", func() {
+			Expect(statefulSetClient.DeleteCallCount()).To(BeZero())
+		})
+	})
+
+	When("the statefulset is already gone", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "drained"))
+			statefulSetClient.GetStub = nil
+		})
+
+		It("does not fail", func() {
+			Expect(collectErr).NotTo(HaveOccurred())
+			Expect(statefulSetClient.DeleteCallCount()).To(BeZero())
+		})
+	})
+
+	When("listing the statefulsets fails", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetBySourceTypeReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(collectErr).To(MatchError(ContainSubstring("failed to list statefulsets")))
+		})
+	})
+
+	When("deleting a statefulset fails", func() {
+		BeforeEach(func() {
+			statefulSetClient.DeleteReturns(errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(collectErr).To(MatchError(ContainSubstring("failed to delete 1 drained statefulsets")))
+		})
+	})
+})
+
+func markedStatefulSet(name string, deleteAfter time.Time) appsv1.StatefulSet {
+	return appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "ns",
+			UID:       types.UID("uid-" + name),
+			Annotations: map[string]string{
+				k8s.AnnotationDeleteAfter: deleteAfter.UTC().Format(time.RFC3339),
+			},
+		},
+	}
+}
@@ -3,7 +3,9 @@ package k8s
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s/utils"
@@ -13,7 +15,9 @@ import (
 	"code.cloudfoundry.org/lager"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/api/policy/v1beta1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -26,6 +30,7 @@ const (
 	eventKilling          = "Killing"
 	eventFailedScheduling = "FailedScheduling"
 	eventFailedScaleUp    = "NotTriggerScaleUp"
+	nodeSelectorMismatch  = "didn't match node selector"
 	appSourceType         = "APP"
 
 	AnnotationAppName                        = "cloudfoundry.org/application_name"
@@ -38,6 +43,24 @@ const (
 	AnnotationLastUpdated                    = "cloudfoundry.org/last_updated"
 	AnnotationProcessGUID                    = "cloudfoundry.org/process_guid"
 	AnnotationRegisteredRoutes               = "cloudfoundry.org/routes"
+	AnnotationPlacementTags                  = "cloudfoundry.org/placement_tags"
+	AnnotationEgressRules                    = "cloudfoundry.org/egress_rules"
+	AnnotationHealthCheck                    = "cloudfoundry.org/health_check"
+	AnnotationSpecFingerprint                = "cloudfoundry.org/spec_fingerprint"
+	AnnotationStopRequested                  = "cloudfoundry.org/stop_requested"
+	AnnotationDeleteAfter                    = "cloudfoundry.org/delete_after"
+	AnnotationUpdateStrategy                 = "cloudfoundry.org/update_strategy"
+	AnnotationRolloutState                   = "cloudfoundry.org/rollout_state"
+	AnnotationRolloutPreviousTemplate        = "cloudfoundry.org/rollout_previous_template"
+	AnnotationRolloutPreviousAnnotations     = "cloudfoundry.org/rollout_previous_annotations"
+	AnnotationRestartedAt                    = "cloudfoundry.org/restarted_at"
+	AnnotationAutoscaling                    = "cloudfoundry.org/autoscaling"
+	AnnotationInternalRoutes                 = "cloudfoundry.org/internal_routes"
+	AnnotationNetworkPolicies                = "cloudfoundry.org/network_policies"
+	AnnotationTerminationGracePeriod         = "cloudfoundry.org/termination_grace_period"
+	AnnotationTopologySpread                 = "cloudfoundry.org/topology_spread"
+	AnnotationOriginalImage                  = "cloudfoundry.org/original_image"
+	AnnotationEnvFingerprint                 = "cloudfoundry.org/env_fingerprint"
 	AnnotationOriginalRequest                = "cloudfoundry.org/original_request"
 	AnnotationCompletionCallback             = "cloudfoundry.org/completion_callback"
 	AnnotationOpiTaskContainerName           = "cloudfoundry.org/opi-task-container-name"
@@ -59,27 +82,31 @@ const (
 	LabelTaskCompleted = "cloudfoundry.org/task_completed"
 	TaskCompletedTrue  = "true"
 
-	OPIContainerName = "opi"
+	OPIContainerName           = "opi"
+	SidecarContainerNamePrefix = "sidecar-"
 
 	VcapUID                  = 2000
 	PdbMinAvailableInstances = 1
-	PodAffinityTermWeight    = 100
 )
 
 //counterfeiter:generate . PodClient
 //counterfeiter:generate . PodDisruptionBudgetClient
+//counterfeiter:generate . NetworkPolicyClient
+//counterfeiter:generate . HorizontalPodAutoscalerClient
+//counterfeiter:generate . ServiceClient
+//counterfeiter:generate . ConfigMapClient
 //counterfeiter:generate . StatefulSetClient
-//counterfeiter:generate . SecretsCreatorDeleter
+//counterfeiter:generate . SecretsClient
 //counterfeiter:generate . EventsClient
 //counterfeiter:generate . LRPMapper
 //counterfeiter:generate . ProbeCreator
 //counterfeiter:generate . DesireOption
-//counterfeiter:generate . StatefulSetClient
 
 type PodClient interface {
 	GetAll() ([]corev1.Pod, error)
 	GetByLRPIdentifier(opi.LRPIdentifier) ([]corev1.Pod, error)
 	Delete(namespace, name string) error
+	Evict(namespace, name string) error
 }
 
 type PodDisruptionBudgetClient interface {
@@ -87,17 +114,46 @@ type PodDisruptionBudgetClient interface {
 	Delete(namespace string, name string) error
 }
 
+type NetworkPolicyClient interface {
+	Create(namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error)
+	Update(namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error)
+	Delete(namespace string, name string) error
+}
+
+type HorizontalPodAutoscalerClient interface {
+	Create(namespace string, autoscaler *autoscalingv2beta2.HorizontalPodAutoscaler) (*autoscalingv2beta2.HorizontalPodAutoscaler, error)
+	Update(namespace string, autoscaler *autoscalingv2beta2.HorizontalPodAutoscaler) (*autoscalingv2beta2.HorizontalPodAutoscaler, error)
+	Delete(namespace string, name string) error
+}
+
+type ServiceClient interface {
+	Create(namespace string, service *corev1.Service) (*corev1.Service, error)
+	Update(namespace string, service *corev1.Service) (*corev1.Service, error)
+	Delete(namespace string, name string) error
+}
+
+type ConfigMapClient interface {
+	Get(namespace, name string) (*corev1.ConfigMap, error)
+	Create(namespace string, configMap *corev1.ConfigMap) (*corev1.ConfigMap, error)
+	Update(namespace string, configMap *corev1.ConfigMap) (*corev1.ConfigMap, error)
+}
+
 type StatefulSetClient interface {
 	Create(namespace string, statefulSet *appsv1.StatefulSet) (*appsv1.StatefulSet, error)
+	Get(namespace, name string) (*appsv1.StatefulSet, error)
 	Update(namespace string, statefulSet *appsv1.StatefulSet) (*appsv1.StatefulSet, error)
 	Delete(namespace string, name string) error
 	GetBySourceType(sourceType string) ([]appsv1.StatefulSet, error)
 	GetByLRPIdentifier(id opi.LRPIdentifier) ([]appsv1.StatefulSet, error)
+	GetByGUID(guid string) ([]appsv1.StatefulSet, error)
 }
 
-type SecretsCreatorDeleter interface {
+type SecretsClient interface {
+	Get(namespace, name string) (*corev1.Secret, error)
 	Create(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	Update(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
 	Delete(namespace string, name string) error
+	List(namespace, labelSelector string) ([]corev1.Secret, error)
 }
 
 type EventsClient interface {
@@ -108,20 +164,37 @@ type LRPMapper func(s appsv1.StatefulSet) (*opi.LRP, error)
 
 type StatefulSetDesirer struct {
 	Pods                              PodClient
-	Secrets                           SecretsCreatorDeleter
+	Secrets                           SecretsClient
 	StatefulSets                      StatefulSetClient
 	PodDisruptionBudgets              PodDisruptionBudgetClient
+	NetworkPolicies                   NetworkPolicyClient
+	HorizontalPodAutoscalers          HorizontalPodAutoscalerClient
+	Services                          ServiceClient
+	ConfigMaps                        ConfigMapClient
 	EventsClient                      EventsClient
 	StatefulSetToLRPMapper            LRPMapper
 	RegistrySecretName                string
 	LivenessProbeCreator              ProbeCreator
 	ReadinessProbeCreator             ProbeCreator
+	StartupProbeCreator               ProbeCreator
 	Logger                            lager.Logger
 	ApplicationServiceAccount         string
 	AllowAutomountServiceAccountToken bool
+	PlacementTagNodePools             map[string]eirini.NodePool
+	TopologySpread                    []eirini.TopologySpreadConstraint
+	SchedulingPolicies                []eirini.SchedulingPolicy
+	SecurityProfile                   eirini.SecurityProfile
+	TerminationGracePeriodSeconds     int64
+	PreStopDelaySeconds               int64
+	PreStopCommand                    []string
+	RouteDrainPeriod                  time.Duration
+	ZeroDowntimeVersionSwitch         bool
+	OldVersionGracePeriod             time.Duration
+	DefaultUpdateStrategy             opi.UpdateStrategy
+	InternalRoutesDNS                 eirini.InternalRoutesDNSConfig
 }
 
-type ProbeCreator func(lrp *opi.LRP) *corev1.Probe
+type ProbeCreator func(lrp *opi.LRP) (*corev1.Probe, error)
 
 type DesireOption func(resource interface{}) error
 
@@ -133,6 +206,19 @@ func (m *StatefulSetDesirer) Desire(namespace string, lrp *opi.LRP, opts ...Desi
 		return err
 	}
 
+	st, err := m.toStatefulSet(statefulSetName, lrp)
+	if err != nil {
+		return err
+	}
+
+	var networkPolicy *networkingv1.NetworkPolicy
+	if len(lrp.EgressRules) > 0 {
+		networkPolicy, err = m.toNetworkPolicy(logger, statefulSetName, lrp)
+		if err != nil {
+			return errors.Wrap(err, "failed to convert egress rules")
+		}
+	}
+
 	if lrp.PrivateRegistry != nil {
 		err = m.createRegistryCredsSecret(namespace, statefulSetName, lrp)
 		if err != nil {
@@ -140,11 +226,6 @@ func (m *StatefulSetDesirer) Desire(namespace string, lrp *opi.LRP, opts ...Desi
 		}
 	}
 
-	st, err := m.toStatefulSet(statefulSetName, lrp)
-	if err != nil {
-		return err
-	}
-
 	st.Namespace = namespace
 
 	err = applyOpts(st, opts...)
@@ -152,26 +233,207 @@ func (m *StatefulSetDesirer) Desire(namespace string, lrp *opi.LRP, opts ...Desi
 		return err
 	}
 
-	if _, err := m.StatefulSets.Create(namespace, st); err != nil {
-		var statusErr *k8serrors.StatusError
-		if errors.As(err, &statusErr) && statusErr.Status().Reason == metav1.StatusReasonAlreadyExists {
-			logger.Debug("statefulset-already-exists", lager.Data{"error": err.Error()})
+	fingerprint, err := specFingerprint(st)
+	if err != nil {
+		return err
+	}
 
-			return nil
-		}
+	st.Annotations[AnnotationSpecFingerprint] = fingerprint
 
-		return errors.Wrap(err, "failed to create statefulset")
+	if err = m.createOrPatchStatefulSet(logger, namespace, st, lrp); err != nil {
+		return err
 	}
 
-	if err := m.createPodDisruptionBudget(namespace, statefulSetName, lrp); err != nil {
+	if err = m.createPodDisruptionBudget(namespace, statefulSetName, lrp); err != nil && !k8serrors.IsAlreadyExists(err) {
 		logger.Error("failed-to-create-pod-disruption-budget", err)
 
 		return errors.Wrap(err, "failed to create pod disruption budget")
 	}
 
+	if lrp.Autoscaling != nil {
+		if err = m.handleAutoscaler(logger, namespace, statefulSetName, lrp); err != nil {
+			return err
+		}
+	}
+
+	if len(lrp.InternalRoutes) > 0 {
+		if err = m.handleInternalRoutes(logger, st, lrp); err != nil {
+			return err
+		}
+	}
+
+	if networkPolicy != nil {
+		_, err = m.NetworkPolicies.Create(namespace, networkPolicy)
+		if k8serrors.IsAlreadyExists(err) {
+			_, err = m.NetworkPolicies.Update(namespace, networkPolicy)
+		}
+
+		if err != nil {
+			logger.Error("failed-to-create-network-policy", err)
+
+			return errors.Wrap(err, "failed to create network policy")
+		}
+	}
+
 	return nil
 }
 
+func (m *StatefulSetDesirer) createOrPatchStatefulSet(logger lager.Logger, namespace string, st *appsv1.StatefulSet, lrp *opi.LRP) error {
+	existing, err := m.StatefulSets.Get(namespace, st.Name)
+	if k8serrors.IsNotFound(err) {
+		existing, err = m.createStatefulSet(logger, namespace, st, lrp)
+		if existing == nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to get existing statefulset")
+	}
+
+	if existing.Labels[LabelGUID] != lrp.GUID || existing.Labels[LabelVersion] != lrp.Version {
+		return k8serrors.NewConflict(appsv1.Resource("statefulsets"), st.Name,
+			fmt.Errorf("statefulset belongs to a different LRP (guid %q, version %q)", existing.Labels[LabelGUID], existing.Labels[LabelVersion]))
+	}
+
+	if existing.DeletionTimestamp != nil {
+		return k8serrors.NewConflict(appsv1.Resource("statefulsets"), st.Name, errors.New("statefulset is being deleted"))
+	}
+
+	if err = m.createOrUpdateEnvSecret(namespace, existing, lrp); err != nil {
+		logger.Error("failed-to-create-env-secret", err)
+
+		return err
+	}
+
+	desiredState, err := liveStateFingerprint(st, lrp.Autoscaling == nil)
+	if err != nil {
+		return err
+	}
+
+	liveState, err := liveStateFingerprint(existing, lrp.Autoscaling == nil)
+	if err != nil {
+		return err
+	}
+
+	if existing.Annotations[AnnotationSpecFingerprint] == st.Annotations[AnnotationSpecFingerprint] && liveState == desiredState && !hasDeleteAfter(existing) {
+		logger.Debug("statefulset-already-exists")
+
+		return nil
+	}
+
+	logger.Info("patching-drifted-statefulset", lager.Data{
+		"existing-fingerprint": existing.Annotations[AnnotationSpecFingerprint],
+		"desired-fingerprint":  st.Annotations[AnnotationSpecFingerprint],
+		"live-state-drifted":   liveState != desiredState,
+		"delete-requested":     hasDeleteAfter(existing),
+	})
+
+	patched, err := m.patchManagedFields(existing, st, lrp)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.StatefulSets.Update(namespace, patched)
+
+	return errors.Wrap(err, "failed to patch drifted statefulset")
+}
+
+// patchManagedFields copies the fields derived from the LRP onto the existing
+// statefulset. Annotations maintained by rollouts, restarts and stops are
+// kept, and so are the immutable parts of the spec. The update strategy is
+// applied the way Update applies it, so a patch starts a configured canary.
+func (m *StatefulSetDesirer) patchManagedFields(existing, desired *appsv1.StatefulSet, lrp *opi.LRP) (*appsv1.StatefulSet, error) {
+	patched := existing.DeepCopy()
+
+	if patched.Labels == nil {
+		patched.Labels = map[string]string{}
+	}
+
+	for key, value := range desired.Labels {
+		patched.Labels[key] = value
+	}
+
+	patched.Annotations = copyAnnotations(desired.Annotations, existing.Annotations,
+		AnnotationRolloutState, AnnotationRolloutPreviousTemplate, AnnotationRolloutPreviousAnnotations, AnnotationStopRequested)
+
+	patched.Spec.Template = *desired.Spec.Template.DeepCopy()
+	patched.Spec.Template.Annotations = copyAnnotations(desired.Spec.Template.Annotations, existing.Spec.Template.Annotations,
+		AnnotationRestartedAt)
+
+	if lrp.Autoscaling == nil {
+		patched.Spec.Replicas = desired.Spec.Replicas
+	}
+
+	if err := m.applyUpdateStrategy(existing, patched, lrp); err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
+
+// copyAnnotations returns a copy of annotations with the given keys taken
+// from current, when current has them.
+func copyAnnotations(annotations, current map[string]string, keys ...string) map[string]string {
+	result := map[string]string{}
+
+	for key, value := range annotations {
+		result[key] = value
+	}
+
+	for _, key := range keys {
+		if value, ok := current[key]; ok {
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+// createStatefulSet returns the existing statefulset if it has been created
+// concurrently, so that it is checked before being patched.
+func (m *StatefulSetDesirer) createStatefulSet(logger lager.Logger, namespace string, st *appsv1.StatefulSet, lrp *opi.LRP) (*appsv1.StatefulSet, error) {
+	if err := m.createOrUpdateEnvSecret(namespace, st, lrp); err != nil {
+		logger.Error("failed-to-create-env-secret", err)
+
+		return nil, err
+	}
+
+	created, err := m.StatefulSets.Create(namespace, st)
+	if err == nil {
+		return nil, m.setEnvSecretOwner(logger, namespace, created, lrp)
+	}
+
+	if !k8serrors.IsAlreadyExists(err) {
+		if deleteErr := m.deleteEnvSecrets(st); deleteErr != nil {
+			logger.Error("failed-to-delete-env-secrets", deleteErr)
+		}
+
+		return nil, errors.Wrap(err, "failed to create statefulset")
+	}
+
+	existing, err := m.StatefulSets.Get(namespace, st.Name)
+
+	return existing, errors.Wrap(err, "failed to get existing statefulset")
+}
+
+func specFingerprint(st *appsv1.StatefulSet) (string, error) {
+	spec, err := json.Marshal(struct {
+		Labels      map[string]string
+		Annotations map[string]string
+		Spec        appsv1.StatefulSetSpec
+	}{
+		Labels:      st.Labels,
+		Annotations: st.Annotations,
+		Spec:        st.Spec,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal statefulset spec")
+	}
+
+	return util.Hash(string(spec))
+}
+
 func (m *StatefulSetDesirer) List() ([]*opi.LRP, error) {
 	logger := m.Logger.Session("list")
 
@@ -216,6 +478,18 @@ func (m *StatefulSetDesirer) stop(identifier opi.LRPIdentifier) error {
 		return err
 	}
 
+	if m.ZeroDowntimeVersionSwitch {
+		deferred, deferErr := m.deferStop(logger, statefulSet)
+		if deferErr != nil || deferred {
+			return deferErr
+		}
+	}
+
+	drainRoutes, err := m.unregisterRoutes(logger, statefulSet)
+	if err != nil {
+		return err
+	}
+
 	err = m.PodDisruptionBudgets.Delete(statefulSet.Namespace, statefulSet.Name)
 	if err != nil && !k8serrors.IsNotFound(err) {
 		logger.Error("failed-to-delete-disruption-budget", err)
@@ -223,6 +497,21 @@ func (m *StatefulSetDesirer) stop(identifier opi.LRPIdentifier) error {
 		return errors.Wrap(err, "failed to delete pod disruption budget")
 	}
 
+	err = m.NetworkPolicies.Delete(statefulSet.Namespace, statefulSet.Name)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		logger.Error("failed-to-delete-network-policy", err)
+
+		return errors.Wrap(err, "failed to delete network policy")
+	}
+
+	if err = m.deleteAutoscaler(logger, statefulSet.Namespace, statefulSet.Name); err != nil {
+		return err
+	}
+
+	if err = m.deleteInternalRoutes(logger, statefulSet); err != nil {
+		return err
+	}
+
 	err = m.deletePrivateRegistrySecret(statefulSet)
 	if err != nil && !k8serrors.IsNotFound(err) {
 		logger.Error("failed-to-delete-private-registry-secret", err)
@@ -230,15 +519,63 @@ func (m *StatefulSetDesirer) stop(identifier opi.LRPIdentifier) error {
 		return err
 	}
 
+	// Instances keep restarting until the statefulset is deleted, so its env
+	// secrets go after it. Those of a statefulset draining its routes are
+	// garbage collected with it, as it owns them.
+	if drainRoutes {
+		return nil
+	}
+
 	if err := m.StatefulSets.Delete(statefulSet.Namespace, statefulSet.Name); err != nil {
 		logger.Error("failed-to-delete-statefulset", err)
 
 		return errors.Wrap(err, "failed to delete statefulset")
 	}
 
+	if err = m.deleteEnvSecrets(statefulSet); err != nil {
+		logger.Error("failed-to-delete-env-secrets", err)
+
+		return errors.Wrap(err, "failed to delete env secrets")
+	}
+
 	return nil
 }
 
+func (m *StatefulSetDesirer) deferStop(logger lager.Logger, statefulSet *appsv1.StatefulSet) (bool, error) {
+	versions, err := m.StatefulSets.GetByGUID(statefulSet.Labels[LabelGUID])
+	if err != nil {
+		logger.Error("failed-to-list-versions", err)
+
+		return false, errors.Wrap(err, "failed to list versions")
+	}
+
+	latest := latestVersion(versions)
+	if latest == nil || !supersedes(latest, statefulSet) || isReady(latest) || gracePeriodExpired(latest, m.OldVersionGracePeriod) {
+		return false, nil
+	}
+
+	if hasStopRequested(statefulSet) {
+		return true, nil
+	}
+
+	deferred := statefulSet.DeepCopy()
+	if deferred.Annotations == nil {
+		deferred.Annotations = map[string]string{}
+	}
+
+	deferred.Annotations[AnnotationStopRequested] = time.Now().UTC().Format(time.RFC3339)
+
+	if _, err = m.StatefulSets.Update(deferred.Namespace, deferred); err != nil {
+		logger.Error("failed-to-defer-stop", err)
+
+		return false, errors.Wrap(err, "failed to defer stop")
+	}
+
+	logger.Info("stop-deferred-until-newer-version-is-ready", lager.Data{"newer-version": latest.Labels[LabelVersion]})
+
+	return true, nil
+}
+
 func (m *StatefulSetDesirer) deletePrivateRegistrySecret(statefulSet *appsv1.StatefulSet) error {
 	for _, secret := range statefulSet.Spec.Template.Spec.ImagePullSecrets {
 		if secret.Name == m.privateRegistrySecretName(statefulSet.Name) {
@@ -297,18 +634,27 @@ func (m *StatefulSetDesirer) update(lrp *opi.LRP) error {
 		return err
 	}
 
-	updatedStatefulSet, err := m.getUpdatedStatefulSetObj(statefulSet,
-		lrp.AppURIs,
-		lrp.TargetInstances,
-		lrp.LastUpdated,
-		lrp.Image,
-	)
+	updatedStatefulSet, err := m.getUpdatedStatefulSetObj(statefulSet, lrp)
 	if err != nil {
 		logger.Error("failed-to-get-updated-statefulset", err)
 
 		return err
 	}
 
+	if err = m.createOrUpdateEnvSecret(statefulSet.Namespace, statefulSet, lrp); err != nil {
+		logger.Error("failed-to-update-env-secret", err)
+
+		return err
+	}
+
+	if lrp.PrivateRegistry != nil {
+		if err = m.createRegistryCredsSecret(statefulSet.Namespace, statefulSet.Name, lrp); err != nil {
+			logger.Error("failed-to-update-private-registry-secret", err)
+
+			return err
+		}
+	}
+
 	_, err = m.StatefulSets.Update(updatedStatefulSet.Namespace, updatedStatefulSet)
 	if err != nil {
 		logger.Error("failed-to-update-statefulset", err, lager.Data{"namespace": statefulSet.Namespace})
@@ -316,7 +662,30 @@ func (m *StatefulSetDesirer) update(lrp *opi.LRP) error {
 		return errors.Wrap(err, "failed to update statefulset")
 	}
 
-	return m.handlePodDisruptionBudget(logger,
+	err = m.handlePodDisruptionBudget(logger,
+		statefulSet.Namespace,
+		statefulSet.Name,
+		lrp,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = m.handleAutoscaler(logger,
+		statefulSet.Namespace,
+		statefulSet.Name,
+		lrp,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = m.handleInternalRoutes(logger, statefulSet, lrp)
+	if err != nil {
+		return err
+	}
+
+	return m.handleNetworkPolicy(logger,
 		statefulSet.Namespace,
 		statefulSet.Name,
 		lrp,
@@ -344,6 +713,12 @@ func (m *StatefulSetDesirer) getLRP(logger lager.Logger, identifier opi.LRPIdent
 		return nil, err
 	}
 
+	if err = m.loadEnv(statefulset, lrp); err != nil {
+		logger.Error("failed-to-load-env", err)
+
+		return nil, err
+	}
+
 	return lrp, nil
 }
 
@@ -365,10 +740,27 @@ func (m *StatefulSetDesirer) getStatefulSet(identifier opi.LRPIdentifier) (*apps
 
 func (m *StatefulSetDesirer) GetInstances(identifier opi.LRPIdentifier) ([]*opi.Instance, error) {
 	logger := m.Logger.Session("get-instance", lager.Data{"guid": identifier.GUID, "version": identifier.Version})
-	if _, err := m.getLRP(logger, identifier); errors.Is(err, eirini.ErrNotFound) {
+
+	statefulSet, err := m.getStatefulSet(identifier)
+	if errors.Is(err, eirini.ErrNotFound) {
 		return nil, err
 	}
 
+	var (
+		lrp            *opi.LRP
+		updateRevision string
+	)
+
+	if err != nil {
+		logger.Error("failed-to-get-statefulset", err)
+	} else {
+		updateRevision = statefulSet.Status.UpdateRevision
+
+		if lrp, err = m.StatefulSetToLRPMapper(*statefulSet); err != nil {
+			logger.Error("failed-to-map-statefulset-to-lrp", err)
+		}
+	}
+
 	pods, err := m.Pods.GetByLRPIdentifier(identifier)
 	if err != nil {
 		logger.Error("failed-to-list-pods", err)
@@ -403,9 +795,13 @@ func (m *StatefulSetDesirer) GetInstances(identifier opi.LRPIdentifier) ([]*opi.
 		}
 
 		var state, placementError string
-		if hasInsufficientMemory(events) {
+
+		switch {
+		case hasInsufficientMemory(events):
 			state, placementError = opi.ErrorState, opi.InsufficientMemoryError
-		} else {
+		case lrp != nil && len(lrp.PlacementTags) > 0 && hasNodeSelectorMismatch(events):
+			state, placementError = opi.ErrorState, placementTagMismatchError(lrp.PlacementTags)
+		default:
 			state = utils.GetPodState(pod)
 		}
 
@@ -414,6 +810,7 @@ func (m *StatefulSetDesirer) GetInstances(identifier opi.LRPIdentifier) ([]*opi.
 			Index:          index,
 			State:          state,
 			PlacementError: placementError,
+			UpdatePending:  updateRevision != "" && pod.Labels[appsv1.StatefulSetRevisionLabel] != updateRevision,
 		}
 		instances = append(instances, &instance)
 	}
@@ -451,6 +848,25 @@ func hasInsufficientMemory(events []corev1.Event) bool {
 		strings.Contains(event.Message, "Insufficient memory")
 }
 
+func hasNodeSelectorMismatch(events []corev1.Event) bool {
+	if len(events) == 0 {
+		return false
+	}
+
+	event := events[len(events)-1]
+
+	return event.Reason == eventFailedScheduling && strings.Contains(event.Message, nodeSelectorMismatch)
+}
+
+func placementTagMismatchError(placementTags []string) string {
+	quotedTags := make([]string, 0, len(placementTags))
+	for _, tag := range placementTags {
+		quotedTags = append(quotedTags, fmt.Sprintf("%q", tag))
+	}
+
+	return fmt.Sprintf("%s %s", opi.PlacementTagMismatch, strings.Join(quotedTags, ", "))
+}
+
 func (m *StatefulSetDesirer) statefulSetsToLRPs(statefulSets []appsv1.StatefulSet) ([]*opi.LRP, error) {
 	lrps := []*opi.LRP{}
 
@@ -508,99 +924,67 @@ func (m *StatefulSetDesirer) calculateImagePullSecrets(statefulSetName string, l
 }
 
 func (m *StatefulSetDesirer) toStatefulSet(statefulSetName string, lrp *opi.LRP) (*appsv1.StatefulSet, error) { //nolint:funlen // this is a boilerplate function, its length is fine
-	envs := MapToEnvVar(lrp.Env)
-	fieldEnvs := []corev1.EnvVar{
-		{
-			Name: eirini.EnvPodName,
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{
-					FieldPath: "metadata.name",
-				},
-			},
-		},
-		{
-			Name: eirini.EnvCFInstanceGUID,
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{
-					FieldPath: "metadata.uid",
-				},
-			},
-		},
-		{
-			Name: eirini.EnvCFInstanceIP,
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{
-					FieldPath: "status.hostIP",
-				},
-			},
-		},
-		{
-			Name: eirini.EnvCFInstanceInternalIP,
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{
-					FieldPath: "status.podIP",
-				},
-			},
-		},
+	envHash, err := envFingerprint(lrp)
+	if err != nil {
+		return nil, err
 	}
 
-	envs = append(envs, fieldEnvs...)
-	ports := []corev1.ContainerPort{}
+	fieldEnvs := fieldEnvVars()
+	envSecret := envSecretName(statefulSetName, envHash)
+	ports := containerPorts(lrp)
 
-	for _, port := range lrp.Ports {
-		ports = append(ports, corev1.ContainerPort{ContainerPort: port})
+	livenessProbe, readinessProbe, startupProbe, err := m.createProbes(lrp)
+	if err != nil {
+		return nil, err
 	}
 
-	livenessProbe := m.LivenessProbeCreator(lrp)
-	readinessProbe := m.ReadinessProbeCreator(lrp)
-
-	memory := *resource.NewScaledQuantity(lrp.MemoryMB, resource.Mega)
-	cpu := toCPUMillicores(lrp.CPUWeight)
-	ephemeralStorage := *resource.NewScaledQuantity(lrp.DiskMB, resource.Mega)
-
 	volumes, volumeMounts := getVolumeSpecs(lrp.VolumeMounts)
 	allowPrivilegeEscalation := false
+	sidecars := toSidecarContainers(lrp, envSecret, fieldEnvs, volumeMounts)
 	imagePullSecrets := m.calculateImagePullSecrets(statefulSetName, lrp)
 
+	nodeSelector, tolerations, err := m.getNodePlacement(lrp.PlacementTags)
+	if err != nil {
+		return nil, err
+	}
+
 	statefulSet := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: statefulSetName,
 		},
 		Spec: appsv1.StatefulSetSpec{
+			ServiceName:         statefulSetName,
 			PodManagementPolicy: "Parallel",
 			Replicas:            int32ptr(lrp.TargetInstances),
 			Template: corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
 					ImagePullSecrets: imagePullSecrets,
-					Containers: []corev1.Container{
+					Containers: append([]corev1.Container{
 						{
 							Name:            OPIContainerName,
 							Image:           lrp.Image,
-							ImagePullPolicy: corev1.PullAlways,
+							ImagePullPolicy: imagePullPolicy(lrp.Image),
 							Command:         lrp.Command,
-							Env:             envs,
+							EnvFrom:         envFromSecret(envSecret),
+							Env:             fieldEnvs,
 							Ports:           ports,
 							SecurityContext: &corev1.SecurityContext{
 								AllowPrivilegeEscalation: &allowPrivilegeEscalation,
 							},
-							Resources: corev1.ResourceRequirements{
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory:           memory,
-									corev1.ResourceEphemeralStorage: ephemeralStorage,
-								},
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: memory,
-									corev1.ResourceCPU:    cpu,
-								},
-							},
+							Resources:      appContainerResources(lrp),
 							LivenessProbe:  livenessProbe,
 							ReadinessProbe: readinessProbe,
+							StartupProbe:   startupProbe,
 							VolumeMounts:   volumeMounts,
+							Lifecycle:      m.preStopLifecycle(),
 						},
-					},
-					SecurityContext:    m.getGetSecurityContext(lrp),
-					ServiceAccountName: m.ApplicationServiceAccount,
-					Volumes:            volumes,
+					}, sidecars...),
+					TerminationGracePeriodSeconds: m.terminationGracePeriod(lrp),
+					SecurityContext:               m.getGetSecurityContext(lrp),
+					ServiceAccountName:            m.ApplicationServiceAccount,
+					Volumes:                       volumes,
+					NodeSelector:                  nodeSelector,
+					Tolerations:                   tolerations,
 				},
 			},
 		},
@@ -614,20 +998,18 @@ func (m *StatefulSetDesirer) toStatefulSet(statefulSetName string, lrp *opi.LRP)
 
 	statefulSet.Spec.Selector = m.labelSelector(lrp)
 
-	statefulSet.Spec.Template.Spec.Affinity = &corev1.Affinity{
-		PodAntiAffinity: &corev1.PodAntiAffinity{
-			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
-				{
-					Weight: PodAffinityTermWeight,
-					PodAffinityTerm: corev1.PodAffinityTerm{
-						TopologyKey: corev1.LabelHostname,
-						LabelSelector: &metav1.LabelSelector{
-							MatchExpressions: toLabelSelectorRequirements(statefulSet.Spec.Selector),
-						},
-					},
-				},
-			},
-		},
+	topologySpreadConstraints, topologySpread, err := m.topologySpreadConstraints(statefulSet.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	statefulSet.Spec.Template.Spec.TopologySpreadConstraints = topologySpreadConstraints
+
+	podSpec := &statefulSet.Spec.Template.Spec
+	schedulingPolicy := matchSchedulingPolicy(m.SchedulingPolicies, lrp.OrgGUID, lrp.SpaceGUID, lrp.UserDefinedAnnotations)
+
+	if err = applySchedulingPolicy(schedulingPolicy, podSpec, lrp.CPUWeight); err != nil {
+		return nil, err
 	}
 
 	labels := map[string]string{
@@ -646,10 +1028,15 @@ func (m *StatefulSetDesirer) toStatefulSet(statefulSetName string, lrp *opi.LRP)
 		return nil, errors.Wrap(err, "failed to marshal app uris")
 	}
 
+	healthCheck, err := json.Marshal(lrp.Health)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal health check")
+	}
+
 	annotations := map[string]string{
 		AnnotationSpaceName:        lrp.SpaceName,
 		AnnotationSpaceGUID:        lrp.SpaceGUID,
-		AnnotationOriginalRequest:  lrp.LRP,
+		AnnotationOriginalRequest:  redactOriginalRequest(lrp.LRP),
 		AnnotationRegisteredRoutes: string(uris),
 		AnnotationAppID:            lrp.AppGUID,
 		AnnotationVersion:          lrp.Version,
@@ -658,6 +1045,67 @@ func (m *StatefulSetDesirer) toStatefulSet(statefulSetName string, lrp *opi.LRP)
 		AnnotationAppName:          lrp.AppName,
 		AnnotationOrgName:          lrp.OrgName,
 		AnnotationOrgGUID:          lrp.OrgGUID,
+		AnnotationHealthCheck:      string(healthCheck),
+		AnnotationTopologySpread:   topologySpread,
+		AnnotationEnvFingerprint:   envHash,
+	}
+
+	if len(lrp.PlacementTags) > 0 {
+		placementTags, marshalErr := json.Marshal(lrp.PlacementTags)
+		if marshalErr != nil {
+			return nil, errors.Wrap(marshalErr, "failed to marshal placement tags")
+		}
+
+		annotations[AnnotationPlacementTags] = string(placementTags)
+	}
+
+	if len(lrp.EgressRules) > 0 {
+		egressRules, marshalErr := json.Marshal(lrp.EgressRules)
+		if marshalErr != nil {
+			return nil, errors.Wrap(marshalErr, "failed to marshal egress rules")
+		}
+
+		annotations[AnnotationEgressRules] = string(egressRules)
+	}
+
+	if lrp.OriginalImage != "" {
+		annotations[AnnotationOriginalImage] = lrp.OriginalImage
+	}
+
+	if lrp.TerminationGracePeriodSeconds > 0 {
+		annotations[AnnotationTerminationGracePeriod] = strconv.FormatInt(lrp.TerminationGracePeriodSeconds, 10)
+	}
+
+	if lrp.Autoscaling != nil {
+		autoscaling, marshalErr := autoscalingAnnotation(lrp)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+
+		annotations[AnnotationAutoscaling] = autoscaling
+	}
+
+	if len(lrp.InternalRoutes) > 0 {
+		internalRoutes, marshalErr := internalRoutesAnnotation(lrp)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+
+		annotations[AnnotationInternalRoutes] = internalRoutes
+	}
+
+	strategy := m.updateStrategy(lrp)
+	if strategy != (opi.UpdateStrategy{}) {
+		updateStrategy, marshalErr := json.Marshal(strategy)
+		if marshalErr != nil {
+			return nil, errors.Wrap(marshalErr, "failed to marshal update strategy")
+		}
+
+		annotations[AnnotationUpdateStrategy] = string(updateStrategy)
+	}
+
+	if strategy.CanaryInstances > 0 {
+		statefulSet.Spec.UpdateStrategy = rollingUpdateStrategy(0)
 	}
 
 	for k, v := range lrp.UserDefinedAnnotations {
@@ -665,25 +1113,158 @@ func (m *StatefulSetDesirer) toStatefulSet(statefulSetName string, lrp *opi.LRP)
 	}
 
 	statefulSet.Annotations = annotations
-	statefulSet.Spec.Template.Annotations = annotations
-	statefulSet.Spec.Template.Annotations[corev1.SeccompPodAnnotationKey] = corev1.SeccompProfileRuntimeDefault
+	statefulSet.Spec.Template.Annotations = podAnnotations(annotations)
+
+	if err = applySecurityProfile(m.SecurityProfile, &statefulSet.Spec.Template); err != nil {
+		return nil, err
+	}
 
 	return statefulSet, nil
 }
 
-func toLabelSelectorRequirements(selector *metav1.LabelSelector) []metav1.LabelSelectorRequirement {
-	labels := selector.MatchLabels
-	reqs := make([]metav1.LabelSelectorRequirement, 0, len(labels))
+// statefulSetOnlyAnnotations change without the pods having to, so they are
+// kept out of the pod template: a change to any of them would otherwise
+// restart every instance.
+var statefulSetOnlyAnnotations = []string{
+	AnnotationSpecFingerprint,
+	AnnotationRegisteredRoutes,
+	AnnotationLastUpdated,
+	AnnotationAutoscaling,
+	AnnotationUpdateStrategy,
+	AnnotationInternalRoutes,
+}
+
+func podAnnotations(annotations map[string]string) map[string]string {
+	result := map[string]string{}
+
+	for key, value := range annotations {
+		result[key] = value
+	}
+
+	for _, key := range statefulSetOnlyAnnotations {
+		delete(result, key)
+	}
+
+	return result
+}
+
+// imagePullPolicy avoids pulling images pinned to a digest on every restart,
+// as their content cannot change.
+func imagePullPolicy(image string) corev1.PullPolicy {
+	if strings.Contains(image, "@") {
+		return corev1.PullIfNotPresent
+	}
+
+	return corev1.PullAlways
+}
+
+func fieldEnvVars() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name: eirini.EnvPodName,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.name",
+				},
+			},
+		},
+		{
+			Name: eirini.EnvCFInstanceGUID,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.uid",
+				},
+			},
+		},
+		{
+			Name: eirini.EnvCFInstanceIP,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "status.hostIP",
+				},
+			},
+		},
+		{
+			Name: eirini.EnvCFInstanceInternalIP,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "status.podIP",
+				},
+			},
+		},
+	}
+}
+
+func containerPorts(lrp *opi.LRP) []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{}
+
+	for _, port := range lrp.Ports {
+		ports = append(ports, corev1.ContainerPort{ContainerPort: port})
+	}
+
+	return ports
+}
+
+func appContainerResources(lrp *opi.LRP) corev1.ResourceRequirements {
+	memory := *resource.NewScaledQuantity(lrp.MemoryMB-sidecarsMemoryMB(lrp.Sidecars), resource.Mega)
+	ephemeralStorage := *resource.NewScaledQuantity(lrp.DiskMB, resource.Mega)
+
+	return corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory:           memory,
+			corev1.ResourceEphemeralStorage: ephemeralStorage,
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: memory,
+			corev1.ResourceCPU:    toCPUMillicores(lrp.CPUWeight),
+		},
+	}
+}
+
+func toSidecarContainers(lrp *opi.LRP, envSecret string, fieldEnvs []corev1.EnvVar, volumeMounts []corev1.VolumeMount) []corev1.Container {
+	containers := []corev1.Container{}
+	allowPrivilegeEscalation := false
 
-	for label, value := range labels {
-		reqs = append(reqs, metav1.LabelSelectorRequirement{
-			Key:      label,
-			Operator: metav1.LabelSelectorOpIn,
-			Values:   []string{value},
+	for i, sidecar := range lrp.Sidecars {
+		memory := *resource.NewScaledQuantity(sidecar.MemoryMB, resource.Mega)
+		name := sidecarContainerName(i, sidecar)
+
+		containers = append(containers, corev1.Container{
+			Name:            name,
+			Image:           lrp.Image,
+			ImagePullPolicy: imagePullPolicy(lrp.Image),
+			Command:         sidecar.Command,
+			EnvFrom:         envFromSecret(envSecret),
+			Env:             append(sidecarEnvVars(envSecret, name, sidecar.Env), fieldEnvs...),
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+			},
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: memory,
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: memory,
+				},
+			},
+			VolumeMounts: volumeMounts,
 		})
 	}
 
-	return reqs
+	return containers
+}
+
+func sidecarContainerName(index int, sidecar opi.Sidecar) string {
+	return SidecarContainerNamePrefix + utils.SanitizeName(sidecar.Name, strconv.Itoa(index))
+}
+
+func sidecarsMemoryMB(sidecars []opi.Sidecar) int64 {
+	var total int64
+	for _, s := range sidecars {
+		total += s.MemoryMB
+	}
+
+	return total
 }
 
 func getVolumeSpecs(lrpVolumeMounts []opi.VolumeMount) ([]corev1.Volume, []corev1.VolumeMount) {
@@ -709,6 +1290,41 @@ func getVolumeSpecs(lrpVolumeMounts []opi.VolumeMount) ([]corev1.Volume, []corev
 	return volumes, volumeMounts
 }
 
+func (m *StatefulSetDesirer) getNodePlacement(placementTags []string) (map[string]string, []corev1.Toleration, error) {
+	if len(placementTags) == 0 {
+		return nil, nil, nil
+	}
+
+	nodeSelector := map[string]string{}
+	tolerations := []corev1.Toleration{}
+
+	for _, tag := range placementTags {
+		nodePool, ok := m.PlacementTagNodePools[tag]
+		if !ok {
+			return nil, nil, fmt.Errorf("no node pool configured for placement tag %q", tag)
+		}
+
+		for key, value := range nodePool.NodeSelector {
+			if existing, ok := nodeSelector[key]; ok && existing != value {
+				return nil, nil, fmt.Errorf("placement tags require conflicting values for node label %q", key)
+			}
+
+			nodeSelector[key] = value
+		}
+
+		for _, t := range nodePool.Tolerations {
+			tolerations = append(tolerations, corev1.Toleration{
+				Key:      t.Key,
+				Operator: corev1.TolerationOperator(t.Operator),
+				Value:    t.Value,
+				Effect:   corev1.TaintEffect(t.Effect),
+			})
+		}
+	}
+
+	return nodeSelector, tolerations, nil
+}
+
 func (m *StatefulSetDesirer) labelSelector(lrp *opi.LRP) *metav1.LabelSelector {
 	return &metav1.LabelSelector{
 		MatchLabels: map[string]string{
@@ -728,7 +1344,7 @@ func (m *StatefulSetDesirer) getGetSecurityContext(lrp *opi.LRP) *corev1.PodSecu
 
 	return &corev1.PodSecurityContext{
 		RunAsNonRoot: &runAsNonRoot,
-		RunAsUser:    int64ptr(VcapUID),
+		RunAsUser:    runAsUser(m.SecurityProfile),
 	}
 }
 
@@ -755,30 +1371,215 @@ func (m *StatefulSetDesirer) handlePodDisruptionBudget(logger lager.Logger, name
 	return nil
 }
 
-func (m *StatefulSetDesirer) getUpdatedStatefulSetObj(sts *appsv1.StatefulSet, routes []opi.Route, instances int, lastUpdated, image string) (*appsv1.StatefulSet, error) {
+func (m *StatefulSetDesirer) handleNetworkPolicy(logger lager.Logger, namespace, name string, lrp *opi.LRP) error {
+	if len(lrp.EgressRules) == 0 {
+		err := m.NetworkPolicies.Delete(namespace, name)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error("failed-to-delete-network-policy", err, lager.Data{"namespace": namespace})
+
+			return errors.Wrap(err, "failed to delete network policy")
+		}
+
+		return nil
+	}
+
+	networkPolicy, err := m.toNetworkPolicy(logger, name, lrp)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert egress rules")
+	}
+
+	_, err = m.NetworkPolicies.Create(namespace, networkPolicy)
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = m.NetworkPolicies.Update(namespace, networkPolicy)
+	}
+
+	if err != nil {
+		logger.Error("failed-to-apply-network-policy", err, lager.Data{"namespace": namespace})
+
+		return errors.Wrap(err, "failed to apply network policy")
+	}
+
+	return nil
+}
+
+func (m *StatefulSetDesirer) getUpdatedStatefulSetObj(sts *appsv1.StatefulSet, lrp *opi.LRP) (*appsv1.StatefulSet, error) {
 	updatedSts := sts.DeepCopy()
 
-	uris, err := json.Marshal(routes)
+	uris, err := json.Marshal(lrp.AppURIs)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to marshal routes")
 	}
 
-	count := int32(instances)
-	updatedSts.Spec.Replicas = &count
-	updatedSts.Annotations[AnnotationLastUpdated] = lastUpdated
+	healthCheck, err := json.Marshal(lrp.Health)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal health check")
+	}
+
+	if lrp.Autoscaling == nil {
+		count := int32(lrp.TargetInstances)
+		updatedSts.Spec.Replicas = &count
+
+		delete(updatedSts.Annotations, AnnotationAutoscaling)
+	} else {
+		autoscaling, marshalErr := autoscalingAnnotation(lrp)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+
+		updatedSts.Annotations[AnnotationAutoscaling] = autoscaling
+	}
+
+	if len(lrp.InternalRoutes) > 0 {
+		internalRoutes, marshalErr := internalRoutesAnnotation(lrp)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+
+		updatedSts.Annotations[AnnotationInternalRoutes] = internalRoutes
+	} else {
+		delete(updatedSts.Annotations, AnnotationInternalRoutes)
+	}
+
+	updatedSts.Annotations[AnnotationLastUpdated] = lrp.LastUpdated
 	updatedSts.Annotations[AnnotationRegisteredRoutes] = string(uris)
+	updatedSts.Annotations[AnnotationHealthCheck] = string(healthCheck)
+
+	if len(lrp.EgressRules) > 0 {
+		rules, marshalErr := json.Marshal(lrp.EgressRules)
+		if marshalErr != nil {
+			return nil, errors.Wrap(marshalErr, "failed to marshal egress rules")
+		}
+
+		updatedSts.Annotations[AnnotationEgressRules] = string(rules)
+	} else {
+		delete(updatedSts.Annotations, AnnotationEgressRules)
+	}
+
+	envHash, err := envFingerprint(lrp)
+	if err != nil {
+		return nil, err
+	}
+
+	if updatedSts.Spec.Template.Annotations == nil {
+		updatedSts.Spec.Template.Annotations = map[string]string{}
+	}
+
+	updatedSts.Annotations[AnnotationEnvFingerprint] = envHash
+	updatedSts.Spec.Template.Annotations[AnnotationEnvFingerprint] = envHash
+
+	_, volumeMounts := getVolumeSpecs(lrp.VolumeMounts)
+	envSecret := envSecretName(sts.Name, envHash)
+	envFrom := envFromSecret(envSecret)
+	sidecars := map[string]corev1.Container{}
+
+	for _, sidecar := range toSidecarContainers(lrp, envSecret, fieldEnvVars(), volumeMounts) {
+		sidecars[sidecar.Name] = sidecar
+	}
 
-	if image != "" {
-		for i, container := range updatedSts.Spec.Template.Spec.Containers {
-			if container.Name == OPIContainerName {
-				updatedSts.Spec.Template.Spec.Containers[i].Image = image
+	for i := range updatedSts.Spec.Template.Spec.Containers {
+		container := &updatedSts.Spec.Template.Spec.Containers[i]
+
+		switch {
+		case container.Name == OPIContainerName:
+			if err = m.updateAppContainer(container, envFrom, lrp); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(container.Name, SidecarContainerNamePrefix):
+			if sidecar, ok := sidecars[container.Name]; ok {
+				container.Command = sidecar.Command
+				container.EnvFrom = sidecar.EnvFrom
+				container.Env = sidecar.Env
+				container.Resources = sidecar.Resources
 			}
+		default:
+			continue
+		}
+
+		if lrp.Image != "" {
+			container.Image = lrp.Image
+			container.ImagePullPolicy = imagePullPolicy(lrp.Image)
+		}
+	}
+
+	// the container resources have just been reset, so the cpu limit of the
+	// matching scheduling policy has to be applied again
+	schedulingPolicy := matchSchedulingPolicy(m.SchedulingPolicies, lrp.OrgGUID, lrp.SpaceGUID, lrp.UserDefinedAnnotations)
+	if err = applySchedulingPolicy(schedulingPolicy, &updatedSts.Spec.Template.Spec, lrp.CPUWeight); err != nil {
+		return nil, err
+	}
+
+	if lrp.PrivateRegistry != nil {
+		updatedSts.Spec.Template.Spec.ImagePullSecrets = m.calculateImagePullSecrets(sts.Name, lrp)
+	}
+
+	if lrp.Image != "" {
+		if lrp.OriginalImage != "" {
+			updatedSts.Annotations[AnnotationOriginalImage] = lrp.OriginalImage
+		} else {
+			delete(updatedSts.Annotations, AnnotationOriginalImage)
 		}
 	}
 
+	if err = m.applyUpdateStrategy(sts, updatedSts, lrp); err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := m.desiredSpecFingerprint(sts.Name, lrp)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedSts.Annotations[AnnotationSpecFingerprint] = fingerprint
+
 	return updatedSts, nil
 }
 
+// desiredSpecFingerprint is the spec fingerprint Desire computes for the
+// updated LRP, so that replaying its Desire doesn't patch the statefulset
+// once more.
+func (m *StatefulSetDesirer) desiredSpecFingerprint(statefulSetName string, lrp *opi.LRP) (string, error) {
+	desired, err := m.toStatefulSet(statefulSetName, lrp)
+	if err != nil {
+		return "", err
+	}
+
+	return specFingerprint(desired)
+}
+
+func (m *StatefulSetDesirer) updateAppContainer(container *corev1.Container, envFrom []corev1.EnvFromSource, lrp *opi.LRP) error {
+	livenessProbe, readinessProbe, startupProbe, err := m.createProbes(lrp)
+	if err != nil {
+		return err
+	}
+
+	container.Command = lrp.Command
+	container.EnvFrom = envFrom
+	container.Env = fieldEnvVars()
+	container.Ports = containerPorts(lrp)
+	container.Resources = appContainerResources(lrp)
+	container.LivenessProbe = livenessProbe
+	container.ReadinessProbe = readinessProbe
+	container.StartupProbe = startupProbe
+
+	return nil
+}
+
+func (m *StatefulSetDesirer) createProbes(lrp *opi.LRP) (liveness, readiness, startup *corev1.Probe, err error) {
+	if liveness, err = m.LivenessProbeCreator(lrp); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to create liveness probe")
+	}
+
+	if readiness, err = m.ReadinessProbeCreator(lrp); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to create readiness probe")
+	}
+
+	if startup, err = m.StartupProbeCreator(lrp); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to create startup probe")
+	}
+
+	return liveness, readiness, startup, nil
+}
+
 func (m *StatefulSetDesirer) createRegistryCredsSecret(namespace, statefulSetName string, lrp *opi.LRP) error {
 	secret, err := m.generateRegistryCredsSecret(statefulSetName, lrp)
 	if err != nil {
@@ -786,6 +1587,9 @@ func (m *StatefulSetDesirer) createRegistryCredsSecret(namespace, statefulSetNam
 	}
 
 	_, err = m.Secrets.Create(namespace, secret)
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = m.Secrets.Update(namespace, secret)
+	}
 
 	return errors.Wrap(err, "failed to create private registry secret for statefulset")
 }
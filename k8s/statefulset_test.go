@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
 	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s"
 	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/k8s/utils"
 	"code.cloudfoundry.org/eirini/opi"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
@@ -35,26 +37,32 @@ var _ = Describe("Statefulset Desirer", func() {
 	var (
 		podsClient            *k8sfakes.FakePodClient
 		eventsClient          *k8sfakes.FakeEventsClient
-		secretsClient         *k8sfakes.FakeSecretsCreatorDeleter
+		secretsClient         *k8sfakes.FakeSecretsClient
 		statefulSetClient     *k8sfakes.FakeStatefulSetClient
 		statefulSetDesirer    *k8s.StatefulSetDesirer
 		livenessProbeCreator  *k8sfakes.FakeProbeCreator
 		readinessProbeCreator *k8sfakes.FakeProbeCreator
+		startupProbeCreator   *k8sfakes.FakeProbeCreator
 		logger                *lagertest.TestLogger
 		mapper                *k8sfakes.FakeLRPMapper
 		pdbClient             *k8sfakes.FakePodDisruptionBudgetClient
+		networkPolicyClient   *k8sfakes.FakeNetworkPolicyClient
+		serviceClient         *k8sfakes.FakeServiceClient
 	)
 
 	BeforeEach(func() {
 		podsClient = new(k8sfakes.FakePodClient)
 		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
-		secretsClient = new(k8sfakes.FakeSecretsCreatorDeleter)
+		secretsClient = new(k8sfakes.FakeSecretsClient)
 		eventsClient = new(k8sfakes.FakeEventsClient)
 
 		livenessProbeCreator = new(k8sfakes.FakeProbeCreator)
 		readinessProbeCreator = new(k8sfakes.FakeProbeCreator)
+		startupProbeCreator = new(k8sfakes.FakeProbeCreator)
 		mapper = new(k8sfakes.FakeLRPMapper)
 		pdbClient = new(k8sfakes.FakePodDisruptionBudgetClient)
+		networkPolicyClient = new(k8sfakes.FakeNetworkPolicyClient)
+		serviceClient = new(k8sfakes.FakeServiceClient)
 
 		logger = lagertest.NewTestLogger("handler-test")
 		statefulSetDesirer = &k8s.StatefulSetDesirer{
@@ -62,9 +70,13 @@ var _ = Describe("Statefulset Desirer", func() {
 			Secrets:                   secretsClient,
 			StatefulSets:              statefulSetClient,
 			PodDisruptionBudgets:      pdbClient,
+			NetworkPolicies:           networkPolicyClient,
+			HorizontalPodAutoscalers:  new(k8sfakes.FakeHorizontalPodAutoscalerClient),
+			Services:                  serviceClient,
 			RegistrySecretName:        registrySecretName,
 			LivenessProbeCreator:      livenessProbeCreator.Spy,
 			ReadinessProbeCreator:     readinessProbeCreator.Spy,
+			StartupProbeCreator:       startupProbeCreator.Spy,
 			Logger:                    logger,
 			StatefulSetToLRPMapper:    mapper.Spy,
 			EventsClient:              eventsClient,
@@ -81,10 +93,12 @@ var _ = Describe("Statefulset Desirer", func() {
 
 		BeforeEach(func() {
 			lrp = createLRP("Baldur", []opi.Route{{Hostname: "my.example.route", Port: 1000}})
-			livenessProbeCreator.Returns(&corev1.Probe{})
-			readinessProbeCreator.Returns(&corev1.Probe{})
+			livenessProbeCreator.Returns(&corev1.Probe{}, nil)
+			readinessProbeCreator.Returns(&corev1.Probe{}, nil)
+			startupProbeCreator.Returns(&corev1.Probe{PeriodSeconds: 1}, nil)
 			desireOptOne = new(k8sfakes.FakeDesireOption)
 			desireOptTwo = new(k8sfakes.FakeDesireOption)
+			statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
 		})
 
 		JustBeforeEach(func() {
@@ -107,6 +121,50 @@ var _ = Describe("Statefulset Desirer", func() {
 			Expect(readinessProbeCreator.CallCount()).To(Equal(1))
 		})
 
+		It("should create a startup probe", func() {
+			Expect(startupProbeCreator.CallCount()).To(Equal(1))
+			Expect(startupProbeCreator.ArgsForCall(0)).To(Equal(lrp))
+
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].StartupProbe).To(Equal(&corev1.Probe{PeriodSeconds: 1}))
+		})
+
+		When("a probe cannot be created", func() {
+			BeforeEach(func() {
+				livenessProbeCreator.Returns(nil, errors.New("unsupported health check type"))
+			})
+
+			It("fails", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("failed to create liveness probe: unsupported health check type")))
+			})
+
+			It("does not create the statefulset", func() {
+				Expect(statefulSetClient.CreateCallCount()).To(BeZero())
+			})
+		})
+
+		It("should store a fingerprint of the desired spec", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationSpecFingerprint, Not(BeEmpty())))
+		})
+
+		It("should compute the same fingerprint when the same LRP is desired again", func() {
+			Expect(statefulSetDesirer.Desire("the-namespace", lrp)).To(Succeed())
+
+			_, first := statefulSetClient.CreateArgsForCall(0)
+			_, second := statefulSetClient.CreateArgsForCall(1)
+			Expect(second.Annotations[k8s.AnnotationSpecFingerprint]).To(Equal(first.Annotations[k8s.AnnotationSpecFingerprint]))
+		})
+
+		It("should compute a different fingerprint when the LRP changes", func() {
+			lrp.MemoryMB++
+			Expect(statefulSetDesirer.Desire("the-namespace", lrp)).To(Succeed())
+
+			_, first := statefulSetClient.CreateArgsForCall(0)
+			_, second := statefulSetClient.CreateArgsForCall(1)
+			Expect(second.Annotations[k8s.AnnotationSpecFingerprint]).NotTo(Equal(first.Annotations[k8s.AnnotationSpecFingerprint]))
+		})
+
 		It("should invoke the opts with the StatefulSet", func() {
 			Expect(desireOptOne.CallCount()).To(Equal(1))
 			Expect(desireOptTwo.CallCount()).To(Equal(1))
@@ -125,7 +183,7 @@ var _ = Describe("Statefulset Desirer", func() {
 			Entry("AppName", k8s.AnnotationAppName, "Baldur"),
 			Entry("AppID", k8s.AnnotationAppID, "premium_app_guid_1234"),
 			Entry("Version", k8s.AnnotationVersion, "version_1234"),
-			Entry("OriginalRequest", k8s.AnnotationOriginalRequest, "original request"),
+			Entry("OriginalRequest", k8s.AnnotationOriginalRequest, `{"lifecycle":{"docker_lifecycle":{"image":"busybox"}},"process_guid":"guid_1234"}`),
 			Entry("RegisteredRoutes", k8s.AnnotationRegisteredRoutes, `[{"hostname":"my.example.route","port":1000}]`),
 			Entry("SpaceName", k8s.AnnotationSpaceName, "space-foo"),
 			Entry("SpaceGUID", k8s.AnnotationSpaceGUID, "space-guid"),
@@ -142,22 +200,35 @@ var _ = Describe("Statefulset Desirer", func() {
 			Entry("AppName", k8s.AnnotationAppName, "Baldur"),
 			Entry("AppID", k8s.AnnotationAppID, "premium_app_guid_1234"),
 			Entry("Version", k8s.AnnotationVersion, "version_1234"),
-			Entry("OriginalRequest", k8s.AnnotationOriginalRequest, "original request"),
-			Entry("RegisteredRoutes", k8s.AnnotationRegisteredRoutes, `[{"hostname":"my.example.route","port":1000}]`),
+			Entry("OriginalRequest", k8s.AnnotationOriginalRequest, `{"lifecycle":{"docker_lifecycle":{"image":"busybox"}},"process_guid":"guid_1234"}`),
 			Entry("SpaceName", k8s.AnnotationSpaceName, "space-foo"),
 			Entry("SpaceGUID", k8s.AnnotationSpaceGUID, "space-guid"),
 			Entry("OrgName", k8s.AnnotationOrgName, "org-foo"),
 			Entry("OrgGUID", k8s.AnnotationOrgGUID, "org-guid"),
 		)
 
+		DescribeTable("Statefulset-only Annotations",
+			func(annotationName string) {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Spec.Template.Annotations).NotTo(HaveKey(annotationName))
+			},
+			Entry("SpecFingerprint", k8s.AnnotationSpecFingerprint),
+			Entry("RegisteredRoutes", k8s.AnnotationRegisteredRoutes),
+			Entry("LastUpdated", k8s.AnnotationLastUpdated),
+			Entry("Autoscaling", k8s.AnnotationAutoscaling),
+			Entry("UpdateStrategy", k8s.AnnotationUpdateStrategy),
+			Entry("InternalRoutes", k8s.AnnotationInternalRoutes),
+		)
+
 		It("should provide last updated to the statefulset annotation", func() {
 			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
 			Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationLastUpdated, lrp.LastUpdated))
 		})
 
-		It("should set seccomp pod annotation", func() {
+		It("should set the runtime default seccomp profile", func() {
 			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
-			Expect(statefulSet.Spec.Template.Annotations[corev1.SeccompPodAnnotationKey]).To(Equal(corev1.SeccompProfileRuntimeDefault))
+			Expect(statefulSet.Spec.Template.Spec.SecurityContext.SeccompProfile).To(Equal(&corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}))
+			Expect(statefulSet.Spec.Template.Annotations).NotTo(HaveKey(corev1.SeccompPodAnnotationKey))
 		})
 
 		It("should set name for the stateful set", func() {
@@ -198,6 +269,31 @@ var _ = Describe("Statefulset Desirer", func() {
 			Expect(string(statefulSet.Spec.Template.Spec.Containers[0].ImagePullPolicy)).To(Equal("Always"))
 		})
 
+		It("should not set the original image annotation", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Annotations).NotTo(HaveKey(k8s.AnnotationOriginalImage))
+		})
+
+		When("the image is pinned to a digest", func() {
+			BeforeEach(func() {
+				lrp.Image = "busybox@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"
+				lrp.OriginalImage = "busybox:latest"
+				lrp.Sidecars = []opi.Sidecar{{Name: "first-sidecar", Command: []string{"echo"}, MemoryMB: 10}}
+			})
+
+			It("should only pull the image if it is not present", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				for _, c := range statefulSet.Spec.Template.Spec.Containers {
+					Expect(c.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+				}
+			})
+
+			It("should keep the original image reference in an annotation", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationOriginalImage, "busybox:latest"))
+			})
+		})
+
 		It("should set app_guid as a label", func() {
 			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
 
@@ -271,32 +367,190 @@ var _ = Describe("Statefulset Desirer", func() {
 			Expect(pdbClient.CreateCallCount()).To(BeZero())
 		})
 
-		It("should set soft inter-pod anti-affinity", func() {
+		It("should spread the instances across hosts by default", func() {
 			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
-			podAntiAffinity := statefulSet.Spec.Template.Spec.Affinity.PodAntiAffinity
-			Expect(podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(BeEmpty())
-			Expect(podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
-
-			weightedTerm := podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0]
-			Expect(weightedTerm.Weight).To(Equal(int32(100)))
-			Expect(weightedTerm.PodAffinityTerm.TopologyKey).To(Equal("kubernetes.io/hostname"))
-			Expect(weightedTerm.PodAffinityTerm.LabelSelector.MatchExpressions).To(ConsistOf(
-				metav1.LabelSelectorRequirement{
-					Key:      k8s.LabelGUID,
-					Operator: metav1.LabelSelectorOpIn,
-					Values:   []string{"guid_1234"},
-				},
-				metav1.LabelSelectorRequirement{
-					Key:      k8s.LabelVersion,
-					Operator: metav1.LabelSelectorOpIn,
-					Values:   []string{"version_1234"},
-				},
-				metav1.LabelSelectorRequirement{
-					Key:      k8s.LabelSourceType,
-					Operator: metav1.LabelSelectorOpIn,
-					Values:   []string{"APP"},
+			Expect(statefulSet.Spec.Template.Spec.Affinity).To(BeNil())
+			Expect(statefulSet.Spec.Template.Spec.TopologySpreadConstraints).To(Equal([]corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           1,
+					TopologyKey:       "kubernetes.io/hostname",
+					WhenUnsatisfiable: corev1.ScheduleAnyway,
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							k8s.LabelGUID:       "guid_1234",
+							k8s.LabelVersion:    "version_1234",
+							k8s.LabelSourceType: "APP",
+						},
+					},
 				},
-			))
+			}))
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationTopologySpread,
+				`[{"topologyKey":"kubernetes.io/hostname","maxSkew":1,"whenUnsatisfiable":"ScheduleAnyway"}]`))
+		})
+
+		When("a topology spread policy is configured", func() {
+			BeforeEach(func() {
+				statefulSetDesirer.TopologySpread = []eirini.TopologySpreadConstraint{
+					{TopologyKey: k8s.TopologyKeyZone, Hard: true},
+					{TopologyKey: k8s.TopologyKeyHostname, MaxSkew: 2},
+					{TopologyKey: "example.com/rack"},
+				}
+			})
+
+			It("should generate the configured topology spread constraints", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				constraints := statefulSet.Spec.Template.Spec.TopologySpreadConstraints
+				Expect(constraints).To(HaveLen(3))
+
+				Expect(constraints[0].TopologyKey).To(Equal("topology.kubernetes.io/zone"))
+				Expect(constraints[0].MaxSkew).To(Equal(int32(1)))
+				Expect(constraints[0].WhenUnsatisfiable).To(Equal(corev1.DoNotSchedule))
+
+				Expect(constraints[1].TopologyKey).To(Equal("kubernetes.io/hostname"))
+				Expect(constraints[1].MaxSkew).To(Equal(int32(2)))
+				Expect(constraints[1].WhenUnsatisfiable).To(Equal(corev1.ScheduleAnyway))
+
+				Expect(constraints[2].TopologyKey).To(Equal("example.com/rack"))
+				Expect(constraints[2].LabelSelector).To(Equal(statefulSet.Spec.Selector))
+			})
+
+			It("should expose the effective policy", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationTopologySpread,
+					`[{"topologyKey":"topology.kubernetes.io/zone","maxSkew":1,"whenUnsatisfiable":"DoNotSchedule"},`+
+						`{"topologyKey":"kubernetes.io/hostname","maxSkew":2,"whenUnsatisfiable":"ScheduleAnyway"},`+
+						`{"topologyKey":"example.com/rack","maxSkew":1,"whenUnsatisfiable":"ScheduleAnyway"}]`))
+			})
+		})
+
+		It("should not configure graceful shutdown by default", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds).To(BeNil())
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].Lifecycle).To(BeNil())
+		})
+
+		When("graceful shutdown is configured", func() {
+			BeforeEach(func() {
+				statefulSetDesirer.TerminationGracePeriodSeconds = 20
+				statefulSetDesirer.PreStopDelaySeconds = 5
+			})
+
+			It("should sleep before the app container is stopped, without needing a shell in the image", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Spec.Template.Spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(Equal([]string{"sleep", "5"}))
+			})
+
+			It("should extend the termination grace period by the pre-stop delay", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(*statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(25)))
+			})
+
+			When("a pre-stop command is configured", func() {
+				BeforeEach(func() {
+					statefulSetDesirer.PreStopCommand = []string{"/usr/bin/drain"}
+				})
+
+				It("should run it instead of sleeping", func() {
+					_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+					Expect(statefulSet.Spec.Template.Spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(Equal([]string{"/usr/bin/drain"}))
+				})
+			})
+
+			When("the LRP requests a termination grace period", func() {
+				BeforeEach(func() {
+					lrp.TerminationGracePeriodSeconds = 60
+				})
+
+				It("should take precedence over the configured one", func() {
+					_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+					Expect(*statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(65)))
+					Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationTerminationGracePeriod, "60"))
+				})
+			})
+
+			When("only a pre-stop delay is configured", func() {
+				BeforeEach(func() {
+					statefulSetDesirer.TerminationGracePeriodSeconds = 0
+				})
+
+				It("should extend the default grace period", func() {
+					_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+					Expect(*statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(35)))
+				})
+			})
+		})
+
+		When("scheduling policies are configured", func() {
+			BeforeEach(func() {
+				lrp.CPUWeight = 50
+				lrp.UserDefinedAnnotations = map[string]string{"example.com/tier": "gold"}
+				statefulSetDesirer.SchedulingPolicies = []eirini.SchedulingPolicy{
+					{OrgGUID: "another-org", PriorityClassName: "other"},
+					{Annotations: map[string]string{"example.com/tier": "gold"}, PriorityClassName: "gold", CPULimit: k8s.CPULimitRequest},
+					{PriorityClassName: "default"},
+				}
+			})
+
+			It("applies the first matching policy", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Spec.Template.Spec.PriorityClassName).To(Equal("gold"))
+
+				resources := statefulSet.Spec.Template.Spec.Containers[0].Resources
+				Expect(resources.Requests.Cpu().String()).To(Equal("500m"))
+				Expect(resources.Limits.Cpu().String()).To(Equal("500m"))
+				Expect(resources.Limits.Memory()).To(Equal(resources.Requests.Memory()))
+			})
+
+			When("the lrp has sidecars", func() {
+				BeforeEach(func() {
+					lrp.Sidecars = []opi.Sidecar{{Name: "the-sidecar", Command: []string{"sleep", "infinity"}, MemoryMB: 10}}
+				})
+
+				It("limits the cpu of the sidecars too", func() {
+					_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+					containers := statefulSet.Spec.Template.Spec.Containers
+					Expect(containers).To(HaveLen(2))
+
+					sidecar := containers[1].Resources
+					Expect(sidecar.Requests.Cpu().String()).To(Equal("500m"))
+					Expect(sidecar.Limits.Cpu().String()).To(Equal("500m"))
+					Expect(sidecar.Limits.Memory().String()).To(Equal("10M"))
+				})
+			})
+
+			When("no selector matches", func() {
+				BeforeEach(func() {
+					lrp.UserDefinedAnnotations = map[string]string{"example.com/tier": "bronze"}
+				})
+
+				It("falls back to the catch-all policy without a cpu limit", func() {
+					_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+					Expect(statefulSet.Spec.Template.Spec.PriorityClassName).To(Equal("default"))
+					Expect(statefulSet.Spec.Template.Spec.Containers[0].Resources.Limits).NotTo(HaveKey(corev1.ResourceCPU))
+				})
+			})
+
+			When("the burst multiplier is invalid", func() {
+				BeforeEach(func() {
+					statefulSetDesirer.SchedulingPolicies[1].CPULimit = k8s.CPULimitBurst
+					statefulSetDesirer.SchedulingPolicies[1].CPUBurstMultiplier = 0.5
+				})
+
+				It("should fail", func() {
+					Expect(desireErr).To(MatchError(ContainSubstring("cpu burst multiplier must be at least 1")))
+				})
+			})
+		})
+
+		When("a topology spread constraint has no topology key", func() {
+			BeforeEach(func() {
+				statefulSetDesirer.TopologySpread = []eirini.TopologySpreadConstraint{{MaxSkew: 1}}
+			})
+
+			It("should fail", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("topology spread constraints require a topology key")))
+				Expect(statefulSetClient.CreateCallCount()).To(BeZero())
+			})
 		})
 
 		It("should set application service account", func() {
@@ -316,6 +570,146 @@ var _ = Describe("Statefulset Desirer", func() {
 			))
 		})
 
+		When("the app has environment variables", func() {
+			BeforeEach(func() {
+				lrp.Env = map[string]string{"DATABASE_URL": "postgres://user:pass@db"}
+			})
+
+			It("should store them in an env secret named after the env fingerprint", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				fingerprint := statefulSet.Spec.Template.Annotations[k8s.AnnotationEnvFingerprint]
+
+				Expect(secretsClient.CreateCallCount()).To(Equal(1))
+				namespace, secret := secretsClient.CreateArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(secret.Name).To(Equal("baldur-space-foo-34f869d015-env-" + fingerprint))
+				Expect(secret.Labels).To(HaveKeyWithValue(k8s.LabelGUID, "guid_1234"))
+				Expect(secret.Labels).To(HaveKeyWithValue(k8s.LabelVersion, "version_1234"))
+				Expect(secret.Data).To(Equal(map[string][]byte{"DATABASE_URL": []byte("postgres://user:pass@db")}))
+			})
+
+			It("should reference the env secret from the app container", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				_, secret := secretsClient.CreateArgsForCall(0)
+				container := statefulSet.Spec.Template.Spec.Containers[0]
+				Expect(container.EnvFrom).To(ConsistOf(corev1.EnvFromSource{
+					SecretRef: &corev1.SecretEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+					},
+				}))
+				Expect(container.Env).NotTo(ContainElement(MatchFields(IgnoreExtras, Fields{"Name": Equal("DATABASE_URL")})))
+			})
+
+			It("should store a fingerprint of the env in the pod template", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue(k8s.AnnotationEnvFingerprint, Not(BeEmpty())))
+			})
+
+			It("should compute a different env fingerprint when the env changes", func() {
+				lrp.Env["DATABASE_URL"] = "postgres://user:other-pass@db"
+				Expect(statefulSetDesirer.Desire("the-namespace", lrp)).To(Succeed())
+
+				_, first := statefulSetClient.CreateArgsForCall(0)
+				_, second := statefulSetClient.CreateArgsForCall(1)
+				Expect(second.Spec.Template.Annotations[k8s.AnnotationEnvFingerprint]).NotTo(Equal(first.Spec.Template.Annotations[k8s.AnnotationEnvFingerprint]))
+			})
+
+			When("the env secret already exists", func() {
+				BeforeEach(func() {
+					secretsClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "secret"))
+				})
+
+				It("should update it", func() {
+					Expect(desireErr).NotTo(HaveOccurred())
+					Expect(secretsClient.UpdateCallCount()).To(Equal(1))
+					_, secret := secretsClient.UpdateArgsForCall(0)
+					Expect(secret.Data).To(HaveKeyWithValue("DATABASE_URL", []byte("postgres://user:pass@db")))
+				})
+			})
+
+			When("the statefulset has been created", func() {
+				BeforeEach(func() {
+					statefulSetClient.CreateStub = func(_ string, st *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+						created := st.DeepCopy()
+						created.UID = "the-uid"
+
+						return created, nil
+					}
+				})
+
+				It("makes the statefulset own the env secret", func() {
+					Expect(desireErr).NotTo(HaveOccurred())
+					Expect(secretsClient.CreateCallCount()).To(Equal(2))
+
+					_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+					_, secret := secretsClient.CreateArgsForCall(1)
+					Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+						APIVersion: "apps/v1",
+						Kind:       "StatefulSet",
+						Name:       statefulSet.Name,
+						UID:        "the-uid",
+					}))
+				})
+			})
+
+			When("creating the statefulset fails", func() {
+				BeforeEach(func() {
+					statefulSetClient.CreateReturns(nil, errors.New("potato"))
+					secretsClient.ListStub = func(_, _ string) ([]corev1.Secret, error) {
+						_, secret := secretsClient.CreateArgsForCall(0)
+
+						return []corev1.Secret{*secret}, nil
+					}
+				})
+
+				It("deletes the env secret", func() {
+					Expect(desireErr).To(MatchError(ContainSubstring("failed to create statefulset")))
+					Expect(secretsClient.DeleteCallCount()).To(Equal(1))
+
+					_, created := secretsClient.CreateArgsForCall(0)
+					namespace, name := secretsClient.DeleteArgsForCall(0)
+					Expect(namespace).To(Equal("the-namespace"))
+					Expect(name).To(Equal(created.Name))
+				})
+			})
+
+			When("creating the env secret fails", func() {
+				BeforeEach(func() {
+					secretsClient.CreateReturns(nil, errors.New("boom"))
+				})
+
+				It("should fail", func() {
+					Expect(desireErr).To(MatchError(ContainSubstring("failed to create env secret for statefulset")))
+				})
+
+				It("should not create the statefulset", func() {
+					Expect(statefulSetClient.CreateCallCount()).To(BeZero())
+				})
+			})
+		})
+
+		When("the original request has sidecars with environment variables", func() {
+			BeforeEach(func() {
+				lrp.LRP = `{"process_guid":"guid_1234","sidecars":[{"name":"agent","command":["./agent"],"environment":[{"name":"TOKEN","value":"s3cret"}]}]}`
+			})
+
+			It("should not store the sidecar environment", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationOriginalRequest, `{"process_guid":"guid_1234","sidecars":[{"command":["./agent"],"name":"agent"}]}`))
+			})
+		})
+
+		When("the original request cannot be parsed", func() {
+			BeforeEach(func() {
+				lrp.LRP = "not json"
+			})
+
+			It("should not store it", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationOriginalRequest, ""))
+			})
+		})
+
 		When("automounting service account token is allowed", func() {
 			BeforeEach(func() {
 				statefulSetDesirer.AllowAutomountServiceAccountToken = true
@@ -334,7 +728,8 @@ var _ = Describe("Statefulset Desirer", func() {
 
 			It("does not set privileged context", func() {
 				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
-				Expect(statefulSet.Spec.Template.Spec.SecurityContext).To(BeNil())
+				Expect(statefulSet.Spec.Template.Spec.SecurityContext.RunAsNonRoot).To(BeNil())
+				Expect(statefulSet.Spec.Template.Spec.SecurityContext.RunAsUser).To(BeNil())
 			})
 		})
 
@@ -378,13 +773,340 @@ var _ = Describe("Statefulset Desirer", func() {
 			})
 
 			When("the statefulset already exists", func() {
+				var existingStatefulSet *appsv1.StatefulSet
+
 				BeforeEach(func() {
-					statefulSetClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "potato"))
+					statefulSetClient.CreateStub = func(_ string, st *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+						if existingStatefulSet == nil {
+							existingStatefulSet = st.DeepCopy()
+							existingStatefulSet.ResourceVersion = "42"
+						}
+
+						return nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "potato")
+					}
+					statefulSetClient.GetStub = func(_, _ string) (*appsv1.StatefulSet, error) {
+						if existingStatefulSet == nil {
+							return nil, k8serrors.NewNotFound(schema.GroupResource{}, "potato")
+						}
+
+						return existingStatefulSet, nil
+					}
+				})
+
+				AfterEach(func() {
+					existingStatefulSet = nil
 				})
 
 				It("does not fail", func() {
 					Expect(desireErr).NotTo(HaveOccurred())
 				})
+
+				It("gets the existing statefulset once it failed to create it", func() {
+					Expect(statefulSetClient.GetCallCount()).To(Equal(2))
+					namespace, name := statefulSetClient.GetArgsForCall(1)
+					_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+					Expect(namespace).To(Equal("the-namespace"))
+					Expect(name).To(Equal(statefulSet.Name))
+				})
+
+				It("does not patch it, as the spec has not changed", func() {
+					Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+				})
+
+				It("still makes sure the pod disruption budget exists", func() {
+					Expect(pdbClient.CreateCallCount()).To(Equal(1))
+				})
+
+				When("the pod disruption budget exists too", func() {
+					BeforeEach(func() {
+						pdbClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "potato"))
+					})
+
+					It("does not fail", func() {
+						Expect(desireErr).NotTo(HaveOccurred())
+					})
+				})
+
+				When("the statefulset was stopped and is draining its routes", func() {
+					BeforeEach(func() {
+						statefulSetClient.CreateStub = func(_ string, st *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+							if existingStatefulSet == nil {
+								existingStatefulSet = st.DeepCopy()
+								existingStatefulSet.Annotations[k8s.AnnotationRegisteredRoutes] = "[]"
+								existingStatefulSet.Annotations[k8s.AnnotationDeleteAfter] = time.Now().Add(time.Minute).Format(time.RFC3339)
+							}
+
+							return nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "potato")
+						}
+					})
+
+					It("keeps it and registers its routes again", func() {
+						Expect(desireErr).NotTo(HaveOccurred())
+						Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+
+						_, patched := statefulSetClient.UpdateArgsForCall(0)
+						Expect(patched.Annotations).NotTo(HaveKey(k8s.AnnotationDeleteAfter))
+						Expect(patched.Annotations).To(HaveKeyWithValue(k8s.AnnotationRegisteredRoutes, `[{"hostname":"my.example.route","port":1000}]`))
+					})
+				})
+
+				When("the existing spec has drifted from the desired one", func() {
+					BeforeEach(func() {
+						statefulSetClient.CreateStub = func(_ string, _ *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+							existingStatefulSet = &appsv1.StatefulSet{
+								ObjectMeta: metav1.ObjectMeta{
+									Name:            "potato",
+									ResourceVersion: "42",
+									Labels: map[string]string{
+										k8s.LabelGUID:    lrp.GUID,
+										k8s.LabelVersion: lrp.Version,
+									},
+									Annotations: map[string]string{
+										k8s.AnnotationSpecFingerprint: "stale",
+									},
+								},
+							}
+
+							return nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "potato")
+						}
+					})
+
+					It("patches the statefulset with the desired spec", func() {
+						Expect(desireErr).NotTo(HaveOccurred())
+						Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+
+						namespace, patched := statefulSetClient.UpdateArgsForCall(0)
+						_, desired := statefulSetClient.CreateArgsForCall(0)
+						Expect(namespace).To(Equal("the-namespace"))
+						Expect(patched.ResourceVersion).To(Equal("42"))
+						Expect(patched.Spec.Template).To(Equal(desired.Spec.Template))
+						Expect(patched.Spec.Replicas).To(Equal(desired.Spec.Replicas))
+						Expect(patched.Spec.UpdateStrategy).To(Equal(desired.Spec.UpdateStrategy))
+						Expect(patched.Annotations).To(HaveKeyWithValue(k8s.AnnotationSpecFingerprint, desired.Annotations[k8s.AnnotationSpecFingerprint]))
+					})
+
+					When("the LRP has a canary update strategy", func() {
+						BeforeEach(func() {
+							lrp.UpdateStrategy = opi.UpdateStrategy{CanaryInstances: 1}
+						})
+
+						It("rolls the patch out to the canary instances first", func() {
+							_, patched := statefulSetClient.UpdateArgsForCall(0)
+							Expect(patched.Annotations).To(HaveKeyWithValue(k8s.AnnotationRolloutState, k8s.RolloutStateCanary))
+							Expect(patched.Spec.UpdateStrategy.RollingUpdate.Partition).To(PointTo(Equal(int32(lrp.TargetInstances - 1))))
+						})
+					})
+
+					When("the statefulset carries operational annotations", func() {
+						BeforeEach(func() {
+							lrp.UpdateStrategy = opi.UpdateStrategy{CanaryInstances: 1}
+
+							createStub := statefulSetClient.CreateStub
+							statefulSetClient.CreateStub = func(namespace string, st *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+								_, err := createStub(namespace, st)
+								existingStatefulSet.Annotations[k8s.AnnotationRolloutState] = k8s.RolloutStateCanary
+								existingStatefulSet.Annotations[k8s.AnnotationRolloutPreviousTemplate] = "{}"
+								existingStatefulSet.Annotations[k8s.AnnotationStopRequested] = "true"
+								existingStatefulSet.Spec.Template.Annotations = map[string]string{k8s.AnnotationRestartedAt: "2020-10-08T10:00:00Z"}
+								existingStatefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+									Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+									RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: int32ptr(1)},
+								}
+
+								return nil, err
+							}
+						})
+
+						It("keeps them", func() {
+							Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+
+							_, patched := statefulSetClient.UpdateArgsForCall(0)
+							Expect(patched.Annotations).To(HaveKeyWithValue(k8s.AnnotationRolloutState, k8s.RolloutStateCanary))
+							Expect(patched.Annotations).To(HaveKeyWithValue(k8s.AnnotationRolloutPreviousTemplate, "{}"))
+							Expect(patched.Annotations).To(HaveKeyWithValue(k8s.AnnotationStopRequested, "true"))
+							Expect(patched.Spec.Template.Annotations).To(HaveKeyWithValue(k8s.AnnotationRestartedAt, "2020-10-08T10:00:00Z"))
+						})
+
+						It("keeps the canary partition", func() {
+							_, patched := statefulSetClient.UpdateArgsForCall(0)
+							Expect(patched.Spec.UpdateStrategy.RollingUpdate.Partition).To(PointTo(Equal(int32(1))))
+						})
+					})
+
+					When("patching fails", func() {
+						BeforeEach(func() {
+							statefulSetClient.UpdateReturns(nil, errors.New("patch-boom"))
+						})
+
+						It("propagates the error", func() {
+							Expect(desireErr).To(MatchError(ContainSubstring("failed to patch drifted statefulset")))
+							Expect(desireErr).To(MatchError(ContainSubstring("patch-boom")))
+						})
+					})
+				})
+
+				When("the live statefulset has been scaled directly", func() {
+					BeforeEach(func() {
+						statefulSetClient.GetStub = func(_, _ string) (*appsv1.StatefulSet, error) {
+							if existingStatefulSet == nil {
+								return nil, k8serrors.NewNotFound(schema.GroupResource{}, "potato")
+							}
+
+							live := existingStatefulSet.DeepCopy()
+							live.Spec.Replicas = int32ptr(7)
+
+							return live, nil
+						}
+					})
+
+					It("restores the desired number of replicas", func() {
+						Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+
+						_, patched := statefulSetClient.UpdateArgsForCall(0)
+						Expect(patched.Spec.Replicas).To(PointTo(Equal(int32(lrp.TargetInstances))))
+					})
+
+					When("the LRP is autoscaled", func() {
+						BeforeEach(func() {
+							lrp.Autoscaling = &opi.AutoscalingPolicy{MinInstances: 1, MaxInstances: 10, TargetCPUPercent: 80}
+						})
+
+						It("does not patch it, as the autoscaler owns the replicas", func() {
+							Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+						})
+					})
+				})
+
+				When("the live statefulset has been edited directly", func() {
+					BeforeEach(func() {
+						statefulSetClient.GetStub = func(_, _ string) (*appsv1.StatefulSet, error) {
+							if existingStatefulSet == nil {
+								return nil, k8serrors.NewNotFound(schema.GroupResource{}, "potato")
+							}
+
+							live := existingStatefulSet.DeepCopy()
+							live.Spec.Template.Spec.Containers[0].Image = "evil/image"
+							live.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("10Gi")
+
+							return live, nil
+						}
+					})
+
+					It("patches it back to the desired spec", func() {
+						Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+
+						_, patched := statefulSetClient.UpdateArgsForCall(0)
+						_, desired := statefulSetClient.CreateArgsForCall(0)
+						Expect(patched.Spec.Template.Spec.Containers).To(Equal(desired.Spec.Template.Spec.Containers))
+					})
+				})
+
+				When("a sidecar has been removed from the live statefulset", func() {
+					BeforeEach(func() {
+						lrp.Sidecars = []opi.Sidecar{{Name: "the-sidecar", Command: []string{"sleep", "infinity"}, MemoryMB: 100}}
+						statefulSetClient.GetStub = func(_, _ string) (*appsv1.StatefulSet, error) {
+							if existingStatefulSet == nil {
+								return nil, k8serrors.NewNotFound(schema.GroupResource{}, "potato")
+							}
+
+							live := existingStatefulSet.DeepCopy()
+							live.Spec.Template.Spec.Containers = live.Spec.Template.Spec.Containers[:1]
+
+							return live, nil
+						}
+					})
+
+					It("restores the sidecar", func() {
+						Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+
+						_, patched := statefulSetClient.UpdateArgsForCall(0)
+						Expect(patched.Spec.Template.Spec.Containers).To(HaveLen(2))
+					})
+				})
+
+				When("the live statefulset only differs in fields defaulted by the API server", func() {
+					BeforeEach(func() {
+						statefulSetClient.GetStub = func(_, _ string) (*appsv1.StatefulSet, error) {
+							if existingStatefulSet == nil {
+								return nil, k8serrors.NewNotFound(schema.GroupResource{}, "potato")
+							}
+
+							live := existingStatefulSet.DeepCopy()
+							live.Spec.Template.Spec.Containers[0].TerminationMessagePath = "/dev/termination-log"
+							live.Spec.Template.Spec.Containers[0].Ports[0].Protocol = corev1.ProtocolTCP
+							live.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+
+							return live, nil
+						}
+					})
+
+					It("does not patch it", func() {
+						Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+					})
+				})
+
+				When("the existing statefulset belongs to a different LRP", func() {
+					BeforeEach(func() {
+						existingStatefulSet = &appsv1.StatefulSet{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "potato",
+								Labels: map[string]string{
+									k8s.LabelGUID:    "another-guid",
+									k8s.LabelVersion: lrp.Version,
+								},
+							},
+						}
+					})
+
+					It("returns a conflict error", func() {
+						Expect(k8serrors.IsConflict(desireErr)).To(BeTrue())
+						Expect(desireErr).To(MatchError(ContainSubstring("statefulset belongs to a different LRP")))
+					})
+
+					It("does not touch the statefulset", func() {
+						Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+					})
+
+					It("does not create a pod disruption budget", func() {
+						Expect(pdbClient.CreateCallCount()).To(BeZero())
+					})
+
+					It("does not write the env secret of the other LRP", func() {
+						Expect(secretsClient.CreateCallCount()).To(BeZero())
+						Expect(secretsClient.UpdateCallCount()).To(BeZero())
+					})
+				})
+
+				When("the existing statefulset is being deleted", func() {
+					BeforeEach(func() {
+						now := metav1.Now()
+						existingStatefulSet = &appsv1.StatefulSet{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:              "potato",
+								DeletionTimestamp: &now,
+								Labels: map[string]string{
+									k8s.LabelGUID:    lrp.GUID,
+									k8s.LabelVersion: lrp.Version,
+								},
+							},
+						}
+					})
+
+					It("returns a conflict error", func() {
+						Expect(k8serrors.IsConflict(desireErr)).To(BeTrue())
+					})
+				})
+
+				When("getting the existing statefulset fails", func() {
+					BeforeEach(func() {
+						statefulSetClient.GetStub = nil
+						statefulSetClient.GetReturns(nil, errors.New("get-boom"))
+					})
+
+					It("propagates the error", func() {
+						Expect(desireErr).To(MatchError(ContainSubstring("get-boom")))
+					})
+				})
 			})
 
 			When("creating the statefulset fails", func() {
@@ -398,6 +1120,209 @@ var _ = Describe("Statefulset Desirer", func() {
 			})
 		})
 
+		It("should not restrict the nodes the app can be scheduled on", func() {
+			_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+			Expect(statefulSet.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+			Expect(statefulSet.Spec.Template.Spec.Tolerations).To(BeEmpty())
+			Expect(statefulSet.Annotations).NotTo(HaveKey(k8s.AnnotationPlacementTags))
+		})
+
+		When("the app has placement tags", func() {
+			BeforeEach(func() {
+				statefulSetDesirer.PlacementTagNodePools = map[string]eirini.NodePool{
+					"isolated": {
+						NodeSelector: map[string]string{"pool": "isolated"},
+						Tolerations: []eirini.Toleration{
+							{Key: "dedicated", Operator: "Equal", Value: "isolated", Effect: "NoSchedule"},
+						},
+					},
+					"gpu": {
+						NodeSelector: map[string]string{"accelerator": "gpu"},
+					},
+					"shared": {
+						NodeSelector: map[string]string{"pool": "shared"},
+					},
+				}
+				lrp.PlacementTags = []string{"isolated", "gpu"}
+			})
+
+			It("should set the node selector of all node pools", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{
+					"pool":        "isolated",
+					"accelerator": "gpu",
+				}))
+			})
+
+			It("should tolerate the node pool taints", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Spec.Template.Spec.Tolerations).To(ConsistOf(corev1.Toleration{
+					Key:      "dedicated",
+					Operator: corev1.TolerationOpEqual,
+					Value:    "isolated",
+					Effect:   corev1.TaintEffectNoSchedule,
+				}))
+			})
+
+			It("should store the placement tags in an annotation", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationPlacementTags, `["isolated","gpu"]`))
+			})
+
+			When("a placement tag has no configured node pool", func() {
+				BeforeEach(func() {
+					lrp.PlacementTags = []string{"isolated", "unknown"}
+				})
+
+				It("should fail", func() {
+					Expect(desireErr).To(MatchError(ContainSubstring(`no node pool configured for placement tag "unknown"`)))
+				})
+
+				It("should not create the statefulset", func() {
+					Expect(statefulSetClient.CreateCallCount()).To(BeZero())
+				})
+			})
+
+			When("the node pools require conflicting node labels", func() {
+				BeforeEach(func() {
+					lrp.PlacementTags = []string{"isolated", "shared"}
+				})
+
+				It("should fail", func() {
+					Expect(desireErr).To(MatchError(ContainSubstring(`conflicting values for node label "pool"`)))
+				})
+			})
+		})
+
+		It("should not create a network policy", func() {
+			Expect(networkPolicyClient.CreateCallCount()).To(BeZero())
+		})
+
+		When("the app has egress rules", func() {
+			BeforeEach(func() {
+				lrp.EgressRules = []opi.EgressRule{
+					{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}, Ports: []int32{443}},
+				}
+			})
+
+			It("should create a network policy for the app", func() {
+				Expect(networkPolicyClient.CreateCallCount()).To(Equal(1))
+
+				policyNamespace, policy := networkPolicyClient.CreateArgsForCall(0)
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(policyNamespace).To(Equal("the-namespace"))
+				Expect(policy.Name).To(Equal(statefulSet.Name))
+				Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(statefulSet.Spec.Selector.MatchLabels))
+			})
+
+			It("should store the egress rules in an annotation", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				Expect(statefulSet.Annotations).To(HaveKeyWithValue(k8s.AnnotationEgressRules, `[{"protocol":"tcp","destinations":["10.0.0.0/8"],"ports":[443]}]`))
+			})
+
+			When("creating the network policy fails", func() {
+				BeforeEach(func() {
+					networkPolicyClient.CreateReturns(nil, errors.New("boom"))
+				})
+
+				It("should propagate the error", func() {
+					Expect(desireErr).To(MatchError(ContainSubstring("failed to create network policy")))
+				})
+			})
+
+			When("the egress rules are invalid", func() {
+				BeforeEach(func() {
+					lrp.EgressRules[0].Destinations = []string{"not-an-ip"}
+				})
+
+				It("should fail", func() {
+					Expect(desireErr).To(MatchError(ContainSubstring("failed to convert egress rules")))
+				})
+
+				It("should not create the statefulset", func() {
+					Expect(statefulSetClient.CreateCallCount()).To(BeZero())
+				})
+			})
+		})
+
+		When("the app has sidecars", func() {
+			BeforeEach(func() {
+				lrp.Env = map[string]string{"FOO": "app-foo", "BAR": "app-bar"}
+				lrp.Sidecars = []opi.Sidecar{
+					{
+						Name:     "metrics_agent",
+						Command:  []string{"/bin/sh", "-c", "./metrics-agent"},
+						MemoryMB: 100,
+						Env:      map[string]string{"BAR": "sidecar-bar"},
+					},
+					{
+						Name:     "Not A Valid Name!",
+						Command:  []string{"./other"},
+						MemoryMB: 24,
+					},
+				}
+			})
+
+			It("should render a container for each sidecar", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				containers := statefulSet.Spec.Template.Spec.Containers
+				Expect(containers).To(HaveLen(3))
+				Expect(containers[0].Name).To(Equal(k8s.OPIContainerName))
+				Expect(containers[1].Name).To(Equal("sidecar-metrics-agent"))
+				Expect(containers[1].Command).To(Equal([]string{"/bin/sh", "-c", "./metrics-agent"}))
+				Expect(containers[2].Name).To(Equal("sidecar-1"))
+			})
+
+			It("should share the droplet image and volumes", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				sidecar := statefulSet.Spec.Template.Spec.Containers[1]
+				Expect(sidecar.Image).To(Equal("busybox"))
+				Expect(sidecar.VolumeMounts).To(Equal(statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts))
+			})
+
+			It("should merge the sidecar env into the app env", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				_, appSecret := secretsClient.CreateArgsForCall(0)
+				sidecar := statefulSet.Spec.Template.Spec.Containers[1]
+				Expect(sidecar.EnvFrom).To(Equal(statefulSet.Spec.Template.Spec.Containers[0].EnvFrom))
+				Expect(sidecar.Env).To(ContainElements(
+					corev1.EnvVar{Name: "BAR", ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: appSecret.Name + "-sidecars"},
+							Key:                  "sidecar-metrics-agent.BAR",
+						},
+					}},
+					corev1.EnvVar{Name: eirini.EnvPodName, ValueFrom: expectedValFrom("metadata.name")},
+				))
+				Expect(sidecar.Env).NotTo(ContainElement(MatchFields(IgnoreExtras, Fields{"Name": Equal("FOO")})))
+			})
+
+			It("should store the sidecar env in a secret rather than in the pod template", func() {
+				Expect(secretsClient.CreateCallCount()).To(Equal(2))
+				_, appSecret := secretsClient.CreateArgsForCall(0)
+				_, sidecarSecret := secretsClient.CreateArgsForCall(1)
+				Expect(sidecarSecret.Name).To(Equal(appSecret.Name + "-sidecars"))
+				Expect(sidecarSecret.Data).To(Equal(map[string][]byte{"sidecar-metrics-agent.BAR": []byte("sidecar-bar")}))
+			})
+
+			It("should carve the sidecar memory out of the app memory", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				containers := statefulSet.Spec.Template.Spec.Containers
+				Expect(containers[0].Resources.Limits.Memory().String()).To(Equal("900M"))
+				Expect(containers[1].Resources.Limits.Memory().String()).To(Equal("100M"))
+				Expect(containers[1].Resources.Requests.Memory().String()).To(Equal("100M"))
+				Expect(containers[2].Resources.Limits.Memory().String()).To(Equal("24M"))
+			})
+
+			It("should not give the sidecars probes or ports", func() {
+				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
+				sidecar := statefulSet.Spec.Template.Spec.Containers[1]
+				Expect(sidecar.LivenessProbe).To(BeNil())
+				Expect(sidecar.ReadinessProbe).To(BeNil())
+				Expect(sidecar.Ports).To(BeEmpty())
+			})
+		})
+
 		When("the app references a private docker image", func() {
 			BeforeEach(func() {
 				lrp.PrivateRegistry = &opi.PrivateRegistry{
@@ -408,7 +1333,7 @@ var _ = Describe("Statefulset Desirer", func() {
 			})
 
 			It("should create a private repo secret containing the private repo credentials", func() {
-				Expect(secretsClient.CreateCallCount()).To(Equal(1))
+				Expect(secretsClient.CreateCallCount()).To(Equal(2))
 				secretNamespace, actualSecret := secretsClient.CreateArgsForCall(0)
 				Expect(secretNamespace).To(Equal("the-namespace"))
 				Expect(actualSecret.Name).To(Equal("baldur-space-foo-34f869d015-registry-credentials"))
@@ -424,6 +1349,32 @@ var _ = Describe("Statefulset Desirer", func() {
 				)
 			})
 
+			When("the private repo secret already exists", func() {
+				BeforeEach(func() {
+					secretsClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "secret"))
+				})
+
+				It("should update it with the current credentials", func() {
+					Expect(desireErr).NotTo(HaveOccurred())
+					Expect(secretsClient.UpdateCallCount()).To(Equal(2))
+
+					secretNamespace, updatedSecret := secretsClient.UpdateArgsForCall(0)
+					_, createdSecret := secretsClient.CreateArgsForCall(0)
+					Expect(secretNamespace).To(Equal("the-namespace"))
+					Expect(updatedSecret).To(Equal(createdSecret))
+				})
+
+				When("updating the secret fails", func() {
+					BeforeEach(func() {
+						secretsClient.UpdateReturns(nil, errors.New("secret-boom"))
+					})
+
+					It("should propagate the error", func() {
+						Expect(desireErr).To(MatchError(ContainSubstring("secret-boom")))
+					})
+				})
+			})
+
 			It("should add the private repo secret to podImagePullSecret", func() {
 				Expect(statefulSetClient.CreateCallCount()).To(Equal(1))
 				_, statefulSet := statefulSetClient.CreateArgsForCall(0)
@@ -452,6 +1403,62 @@ var _ = Describe("Statefulset Desirer", func() {
 			Expect(lrp.AppName).To(Equal("baldur-app"))
 		})
 
+		When("the statefulset references an env secret", func() {
+			var (
+				lrp    *opi.LRP
+				getErr error
+			)
+
+			BeforeEach(func() {
+				st := appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "baldur",
+						Namespace: "the-namespace",
+					},
+					Spec: appsv1.StatefulSetSpec{
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{
+									{
+										Name: k8s.OPIContainerName,
+										EnvFrom: []corev1.EnvFromSource{{
+											SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "baldur-env"}},
+										}},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{st}, nil)
+				secretsClient.GetReturns(&corev1.Secret{Data: map[string][]byte{"FOO": []byte("foo")}}, nil)
+			})
+
+			JustBeforeEach(func() {
+				lrp, getErr = statefulSetDesirer.Get(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})
+			})
+
+			It("loads the env from the secret", func() {
+				Expect(getErr).NotTo(HaveOccurred())
+				Expect(secretsClient.GetCallCount()).To(Equal(1))
+				namespace, name := secretsClient.GetArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(name).To(Equal("baldur-env"))
+				Expect(lrp.Env).To(Equal(map[string]string{"FOO": "foo"}))
+			})
+
+			When("getting the env secret fails", func() {
+				BeforeEach(func() {
+					secretsClient.GetReturns(nil, errors.New("boom"))
+				})
+
+				It("returns an error", func() {
+					Expect(getErr).To(MatchError(ContainSubstring("failed to get env secret")))
+				})
+			})
+		})
+
 		When("the app does not exist", func() {
 			BeforeEach(func() {
 				statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{}, nil)
@@ -489,6 +1496,7 @@ var _ = Describe("Statefulset Desirer", func() {
 	Describe("Update", func() {
 		var (
 			updatedLRP *opi.LRP
+			existing   []appsv1.StatefulSet
 			err        error
 		)
 
@@ -504,15 +1512,23 @@ var _ = Describe("Statefulset Desirer", func() {
 				LastUpdated:     "now",
 				AppURIs:         []opi.Route{{Hostname: "new-route.io", Port: 6666}},
 				Image:           "new/image",
+				Command:         []string{"/bin/app", "--verbose"},
+				Env:             map[string]string{"FOO": "new-foo"},
+				Ports:           []int32{9090},
+				MemoryMB:        1024,
+				DiskMB:          2048,
+				CPUWeight:       50,
+				Health:          opi.Healtcheck{Type: "http", Port: 9090, Endpoint: "/health"},
 			}
 
 			replicas := int32(3)
 
-			st := []appsv1.StatefulSet{
+			existing = []appsv1.StatefulSet{
 				{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "baldur",
 						Namespace: "the-namespace",
+						UID:       "baldur-uid",
 						Annotations: map[string]string{
 							k8s.AnnotationProcessGUID:      "Baldur-guid",
 							k8s.AnnotationLastUpdated:      "never",
@@ -526,6 +1542,7 @@ var _ = Describe("Statefulset Desirer", func() {
 								Containers: []corev1.Container{
 									{Name: "another-container", Image: "another/image"},
 									{Name: k8s.OPIContainerName, Image: "old/image"},
+									{Name: "sidecar-agent", Image: "old/image"},
 								},
 							},
 						},
@@ -533,28 +1550,270 @@ var _ = Describe("Statefulset Desirer", func() {
 				},
 			}
 
-			statefulSetClient.GetByLRPIdentifierReturns(st, nil)
+			statefulSetClient.GetByLRPIdentifierStub = func(opi.LRPIdentifier) ([]appsv1.StatefulSet, error) {
+				return existing, nil
+			}
+		})
+
+		JustBeforeEach(func() {
+			err = statefulSetDesirer.Update(updatedLRP)
+		})
+
+		It("succeeds", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("updates the statefulset", func() {
+			Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+
+			namespace, st := statefulSetClient.UpdateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(st.GetAnnotations()).To(HaveKeyWithValue(k8s.AnnotationLastUpdated, "now"))
+			Expect(st.GetAnnotations()).To(HaveKeyWithValue(k8s.AnnotationRegisteredRoutes, `[{"hostname":"new-route.io","port":6666}]`))
+			Expect(st.GetAnnotations()).NotTo(HaveKey("another"))
+			Expect(*st.Spec.Replicas).To(Equal(int32(5)))
+			Expect(st.Spec.Template.Spec.Containers[0].Image).To(Equal("another/image"))
+			Expect(st.Spec.Template.Spec.Containers[1].Image).To(Equal("new/image"))
+		})
+
+		When("a scheduling policy with a cpu limit matches the LRP", func() {
+			BeforeEach(func() {
+				updatedLRP.UserDefinedAnnotations = map[string]string{"example.com/tier": "gold"}
+				statefulSetDesirer.SchedulingPolicies = []eirini.SchedulingPolicy{
+					{Annotations: map[string]string{"example.com/tier": "gold"}, PriorityClassName: "gold", CPULimit: k8s.CPULimitRequest},
+				}
+			})
+
+			It("keeps the cpu limit of the policy", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				_, st := statefulSetClient.UpdateArgsForCall(0)
+				resources := st.Spec.Template.Spec.Containers[1].Resources
+				Expect(resources.Requests.Cpu().String()).To(Equal("500m"))
+				Expect(resources.Limits.Cpu().String()).To(Equal("500m"))
+				Expect(st.Spec.Template.Spec.PriorityClassName).To(Equal("gold"))
+			})
+
+			When("the lrp has sidecars", func() {
+				BeforeEach(func() {
+					updatedLRP.Sidecars = []opi.Sidecar{{Name: "agent", Command: []string{"/bin/agent"}, MemoryMB: 24}}
+				})
+
+				It("keeps the cpu limit of the sidecars", func() {
+					_, st := statefulSetClient.UpdateArgsForCall(0)
+					resources := st.Spec.Template.Spec.Containers[2].Resources
+					Expect(resources.Limits.Cpu().String()).To(Equal("500m"))
+					Expect(resources.Limits.Memory().String()).To(Equal("24M"))
+				})
+			})
+		})
+
+		It("updates the image of the sidecars", func() {
+			_, st := statefulSetClient.UpdateArgsForCall(0)
+			Expect(st.Spec.Template.Spec.Containers[2].Image).To(Equal("new/image"))
+		})
+
+		When("the new image is pinned to a digest", func() {
+			BeforeEach(func() {
+				updatedLRP.Image = "new/image@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0"
+				updatedLRP.OriginalImage = "new/image:latest"
+			})
+
+			It("only pulls the image if it is not present", func() {
+				_, st := statefulSetClient.UpdateArgsForCall(0)
+				Expect(st.Spec.Template.Spec.Containers[1].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+				Expect(st.Spec.Template.Spec.Containers[2].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+			})
+
+			It("keeps the original image reference in an annotation", func() {
+				_, st := statefulSetClient.UpdateArgsForCall(0)
+				Expect(st.Annotations).To(HaveKeyWithValue(k8s.AnnotationOriginalImage, "new/image:latest"))
+			})
+		})
+
+		It("updates the process settings of the app container", func() {
+			_, st := statefulSetClient.UpdateArgsForCall(0)
+			container := st.Spec.Template.Spec.Containers[1]
+			Expect(container.Command).To(Equal([]string{"/bin/app", "--verbose"}))
+			Expect(container.EnvFrom).To(ConsistOf(corev1.EnvFromSource{
+				SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "baldur-env-" + st.Annotations[k8s.AnnotationEnvFingerprint]}},
+			}))
+			Expect(container.Env).NotTo(ContainElement(corev1.EnvVar{Name: "FOO", Value: "new-foo"}))
+			Expect(container.Env).To(ContainElement(MatchFields(IgnoreExtras, Fields{"Name": Equal(eirini.EnvPodName)})))
+			Expect(container.Ports).To(Equal([]corev1.ContainerPort{{ContainerPort: 9090}}))
+			Expect(container.Resources.Limits.Memory().String()).To(Equal("1024M"))
+			Expect(container.Resources.Requests.Memory().String()).To(Equal("1024M"))
+			Expect(container.Resources.Limits.StorageEphemeral().String()).To(Equal("2048M"))
+			Expect(container.Resources.Requests.Cpu().String()).To(Equal("500m"))
+		})
+
+		It("syncs the env secret of the new revision", func() {
+			_, st := statefulSetClient.UpdateArgsForCall(0)
+			Expect(secretsClient.CreateCallCount()).To(Equal(1))
+			namespace, secret := secretsClient.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(secret.Name).To(Equal("baldur-env-" + st.Annotations[k8s.AnnotationEnvFingerprint]))
+			Expect(secret.Data).To(Equal(map[string][]byte{"FOO": []byte("new-foo")}))
+		})
+
+		It("updates the env fingerprint so that the pods get restarted", func() {
+			_, st := statefulSetClient.UpdateArgsForCall(0)
+			Expect(st.Annotations).To(HaveKeyWithValue(k8s.AnnotationEnvFingerprint, Not(BeEmpty())))
+			Expect(st.Spec.Template.Annotations).To(HaveKeyWithValue(k8s.AnnotationEnvFingerprint, st.Annotations[k8s.AnnotationEnvFingerprint]))
+		})
+
+		When("the env secret already exists", func() {
+			BeforeEach(func() {
+				secretsClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "secret"))
+			})
+
+			It("updates it", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(secretsClient.UpdateCallCount()).To(Equal(1))
+				_, secret := secretsClient.UpdateArgsForCall(0)
+				Expect(secret.Data).To(Equal(map[string][]byte{"FOO": []byte("new-foo")}))
+			})
+		})
+
+		It("makes the statefulset own the env secret", func() {
+			_, secret := secretsClient.CreateArgsForCall(0)
+			Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+				APIVersion: "apps/v1",
+				Kind:       "StatefulSet",
+				Name:       "baldur",
+				UID:        "baldur-uid",
+			}))
+		})
+
+		When("syncing the env secret fails", func() {
+			BeforeEach(func() {
+				secretsClient.CreateReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to create env secret for statefulset")))
+			})
+		})
+
+		When("the lrp references a private registry", func() {
+			BeforeEach(func() {
+				updatedLRP.PrivateRegistry = &opi.PrivateRegistry{
+					Server:   "host",
+					Username: "user",
+					Password: "rotated-password",
+				}
+			})
+
+			It("rotates the private registry secret", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(secretsClient.CreateCallCount()).To(Equal(2))
+				namespace, secret := secretsClient.CreateArgsForCall(1)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(secret.Name).To(Equal("baldur-registry-credentials"))
+				Expect(secret.StringData).To(HaveKeyWithValue(".dockerconfigjson", ContainSubstring(`"password":"rotated-password"`)))
+			})
+
+			It("references the private registry secret from the pods", func() {
+				_, st := statefulSetClient.UpdateArgsForCall(0)
+				Expect(st.Spec.Template.Spec.ImagePullSecrets).To(ConsistOf(
+					corev1.LocalObjectReference{Name: registrySecretName},
+					corev1.LocalObjectReference{Name: "baldur-registry-credentials"},
+				))
+			})
+
+			When("the private registry secret already exists", func() {
+				BeforeEach(func() {
+					secretsClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "secret"))
+				})
+
+				It("updates it", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(secretsClient.UpdateCallCount()).To(Equal(2))
+					_, secret := secretsClient.UpdateArgsForCall(1)
+					Expect(secret.Name).To(Equal("baldur-registry-credentials"))
+				})
+			})
+
+			When("rotating the private registry secret fails", func() {
+				BeforeEach(func() {
+					secretsClient.CreateReturnsOnCall(1, nil, errors.New("boom"))
+				})
+
+				It("does not update the statefulset", func() {
+					Expect(err).To(MatchError(ContainSubstring("failed to create private registry secret for statefulset")))
+					Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+				})
+			})
+		})
+
+		It("does not touch containers it does not manage", func() {
+			_, st := statefulSetClient.UpdateArgsForCall(0)
+			Expect(st.Spec.Template.Spec.Containers[0]).To(Equal(corev1.Container{Name: "another-container", Image: "another/image"}))
 		})
 
-		JustBeforeEach(func() {
-			err = statefulSetDesirer.Update(updatedLRP)
+		It("recreates the probes from the updated health check", func() {
+			Expect(livenessProbeCreator.CallCount()).NotTo(BeZero())
+			for i := 0; i < livenessProbeCreator.CallCount(); i++ {
+				Expect(livenessProbeCreator.ArgsForCall(i)).To(Equal(updatedLRP))
+			}
+			Expect(readinessProbeCreator.CallCount()).To(Equal(livenessProbeCreator.CallCount()))
+			Expect(startupProbeCreator.CallCount()).To(Equal(livenessProbeCreator.CallCount()))
 		})
 
-		It("succeeds", func() {
-			Expect(err).NotTo(HaveOccurred())
+		When("the statefulset has the name desiring the LRP gives it", func() {
+			BeforeEach(func() {
+				name, nameErr := utils.GetStatefulsetName(updatedLRP)
+				Expect(nameErr).NotTo(HaveOccurred())
+
+				existing[0].Name = name
+			})
+
+			It("sets the spec fingerprint that desiring the updated LRP computes", func() {
+				_, updated := statefulSetClient.UpdateArgsForCall(0)
+
+				statefulSetClient.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+				Expect(statefulSetDesirer.Desire("the-namespace", updatedLRP)).To(Succeed())
+
+				_, desired := statefulSetClient.CreateArgsForCall(0)
+				Expect(updated.Annotations).To(HaveKeyWithValue(k8s.AnnotationSpecFingerprint, desired.Annotations[k8s.AnnotationSpecFingerprint]))
+			})
 		})
 
-		It("updates the statefulset", func() {
-			Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+		It("updates the health check annotation", func() {
+			_, st := statefulSetClient.UpdateArgsForCall(0)
+			Expect(st.Annotations).To(HaveKeyWithValue(k8s.AnnotationHealthCheck, `{"type":"http","port":9090,"endpoint":"/health"}`))
+		})
 
-			namespace, st := statefulSetClient.UpdateArgsForCall(0)
-			Expect(namespace).To(Equal("the-namespace"))
-			Expect(st.GetAnnotations()).To(HaveKeyWithValue(k8s.AnnotationLastUpdated, "now"))
-			Expect(st.GetAnnotations()).To(HaveKeyWithValue(k8s.AnnotationRegisteredRoutes, `[{"hostname":"new-route.io","port":6666}]`))
-			Expect(st.GetAnnotations()).NotTo(HaveKey("another"))
-			Expect(*st.Spec.Replicas).To(Equal(int32(5)))
-			Expect(st.Spec.Template.Spec.Containers[0].Image).To(Equal("another/image"))
-			Expect(st.Spec.Template.Spec.Containers[1].Image).To(Equal("new/image"))
+		When("the lrp has sidecars", func() {
+			BeforeEach(func() {
+				updatedLRP.Sidecars = []opi.Sidecar{
+					{Name: "agent", Command: []string{"/bin/agent"}, MemoryMB: 24, Env: map[string]string{"BAR": "bar"}},
+				}
+			})
+
+			It("updates the sidecar containers", func() {
+				_, st := statefulSetClient.UpdateArgsForCall(0)
+				sidecar := st.Spec.Template.Spec.Containers[2]
+				Expect(sidecar.Command).To(Equal([]string{"/bin/agent"}))
+				Expect(sidecar.EnvFrom).To(Equal(st.Spec.Template.Spec.Containers[1].EnvFrom))
+				Expect(sidecar.Env).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Name":      Equal("BAR"),
+					"ValueFrom": PointTo(MatchFields(IgnoreExtras, Fields{"SecretKeyRef": Not(BeNil())})),
+				})))
+				Expect(sidecar.Resources.Limits.Memory().String()).To(Equal("24M"))
+			})
+
+			It("syncs the sidecar env secret", func() {
+				Expect(secretsClient.CreateCallCount()).To(Equal(2))
+				_, secret := secretsClient.CreateArgsForCall(1)
+				Expect(secret.Name).To(HaveSuffix("-sidecars"))
+				Expect(secret.Data).To(Equal(map[string][]byte{"sidecar-agent.BAR": []byte("bar")}))
+			})
+
+			It("carves the sidecar memory out of the app container memory", func() {
+				_, st := statefulSetClient.UpdateArgsForCall(0)
+				Expect(st.Spec.Template.Spec.Containers[1].Resources.Limits.Memory().ScaledValue(resource.Mega)).To(Equal(int64(1000)))
+			})
 		})
 
 		When("the image is missing", func() {
@@ -647,6 +1906,68 @@ var _ = Describe("Statefulset Desirer", func() {
 			})
 		})
 
+		It("should delete the network policy as there are no egress rules", func() {
+			Expect(networkPolicyClient.DeleteCallCount()).To(Equal(1))
+			policyNamespace, policyName := networkPolicyClient.DeleteArgsForCall(0)
+			Expect(policyNamespace).To(Equal("the-namespace"))
+			Expect(policyName).To(Equal("baldur"))
+		})
+
+		When("the network policy does not exist", func() {
+			BeforeEach(func() {
+				networkPolicyClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+			})
+
+			It("should ignore the error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		When("the lrp has egress rules", func() {
+			BeforeEach(func() {
+				updatedLRP.EgressRules = []opi.EgressRule{
+					{Protocol: "udp", Destinations: []string{"10.10.10.10"}, Ports: []int32{8125}},
+				}
+			})
+
+			It("should create the network policy", func() {
+				Expect(networkPolicyClient.CreateCallCount()).To(Equal(1))
+				policyNamespace, policy := networkPolicyClient.CreateArgsForCall(0)
+				Expect(policyNamespace).To(Equal("the-namespace"))
+				Expect(policy.Name).To(Equal("baldur"))
+				Expect(networkPolicyClient.UpdateCallCount()).To(BeZero())
+			})
+
+			It("should update the egress rules annotation", func() {
+				_, st := statefulSetClient.UpdateArgsForCall(0)
+				Expect(st.Annotations).To(HaveKeyWithValue(k8s.AnnotationEgressRules, `[{"protocol":"udp","destinations":["10.10.10.10"],"ports":[8125]}]`))
+			})
+
+			When("the network policy already exists", func() {
+				BeforeEach(func() {
+					networkPolicyClient.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "baldur"))
+				})
+
+				It("should update it", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(networkPolicyClient.UpdateCallCount()).To(Equal(1))
+					policyNamespace, policy := networkPolicyClient.UpdateArgsForCall(0)
+					Expect(policyNamespace).To(Equal("the-namespace"))
+					Expect(policy.Name).To(Equal("baldur"))
+				})
+
+				When("updating the network policy fails", func() {
+					BeforeEach(func() {
+						networkPolicyClient.UpdateReturns(nil, errors.New("boom"))
+					})
+
+					It("should propagate the error", func() {
+						Expect(err).To(MatchError(ContainSubstring("failed to apply network policy")))
+					})
+				})
+			})
+		})
+
 		When("update fails", func() {
 			BeforeEach(func() {
 				statefulSetClient.UpdateReturns(nil, errors.New("boom"))
@@ -765,6 +2086,76 @@ var _ = Describe("Statefulset Desirer", func() {
 			Expect(name).To(Equal("baldur"))
 		})
 
+		When("the statefulset has registered routes", func() {
+			var calls []string
+
+			BeforeEach(func() {
+				calls = []string{}
+				statefulSets[0].Annotations = map[string]string{
+					k8s.AnnotationRegisteredRoutes: `[{"hostname":"baldur.example.com","port":8080}]`,
+				}
+				statefulSetClient.GetByLRPIdentifierReturns(statefulSets, nil)
+				statefulSetClient.UpdateStub = func(_ string, st *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+					calls = append(calls, "update")
+
+					return st, nil
+				}
+				statefulSetClient.DeleteStub = func(_, _ string) error {
+					calls = append(calls, "delete")
+
+					return nil
+				}
+			})
+
+			It("unregisters the routes before deleting the statefulset", func() {
+				Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				Expect(calls).To(Equal([]string{"update", "delete"}))
+
+				_, updated := statefulSetClient.UpdateArgsForCall(0)
+				Expect(updated.Annotations).To(HaveKeyWithValue(k8s.AnnotationRegisteredRoutes, "[]"))
+			})
+
+			When("a route drain period is configured", func() {
+				BeforeEach(func() {
+					statefulSetDesirer.RouteDrainPeriod = time.Minute
+				})
+
+				It("does not delete the statefulset", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+					Expect(calls).To(Equal([]string{"update"}))
+				})
+
+				It("marks the statefulset to be deleted once the routes have drained", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+
+					_, updated := statefulSetClient.UpdateArgsForCall(0)
+					Expect(updated.Annotations).To(HaveKeyWithValue(k8s.AnnotationRegisteredRoutes, "[]"))
+					Expect(updated.Annotations).To(HaveKey(k8s.AnnotationDeleteAfter))
+
+					deleteAfter, err := time.Parse(time.RFC3339, updated.Annotations[k8s.AnnotationDeleteAfter])
+					Expect(err).NotTo(HaveOccurred())
+					Expect(deleteAfter).To(BeTemporally("~", time.Now().Add(time.Minute), 2*time.Second))
+				})
+			})
+
+			When("unregistering the routes fails", func() {
+				BeforeEach(func() {
+					statefulSetClient.UpdateStub = nil
+					statefulSetClient.UpdateReturns(nil, errors.New("boom"))
+				})
+
+				It("does not delete the statefulset", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("failed to unregister routes")))
+					Expect(calls).To(BeEmpty())
+				})
+			})
+		})
+
+		It("does not update a statefulset without routes", func() {
+			Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+		})
+
 		It("should delete any corresponding pod disruption budgets", func() {
 			Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
 			Expect(pdbClient.DeleteCallCount()).To(Equal(1))
@@ -773,6 +2164,34 @@ var _ = Describe("Statefulset Desirer", func() {
 			Expect(pdbName).To(Equal("baldur"))
 		})
 
+		It("should delete the corresponding network policy", func() {
+			Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			Expect(networkPolicyClient.DeleteCallCount()).To(Equal(1))
+			namespace, policyName := networkPolicyClient.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(policyName).To(Equal("baldur"))
+		})
+
+		When("the network policy does not exist", func() {
+			BeforeEach(func() {
+				networkPolicyClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "baldur"))
+			})
+
+			It("succeeds", func() {
+				Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			})
+		})
+
+		When("network policy deletion fails", func() {
+			BeforeEach(func() {
+				networkPolicyClient.DeleteReturns(errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("failed to delete network policy")))
+			})
+		})
+
 		When("the stateful set runs an image from a private registry", func() {
 			BeforeEach(func() {
 				statefulSets[0].Spec = appsv1.StatefulSetSpec{
@@ -818,6 +2237,110 @@ var _ = Describe("Statefulset Desirer", func() {
 			})
 		})
 
+		When("the stateful set has env secrets", func() {
+			BeforeEach(func() {
+				statefulSets[0].Labels = map[string]string{
+					k8s.LabelGUID:    "guid_1234",
+					k8s.LabelVersion: "version_1234",
+				}
+				statefulSetClient.GetByLRPIdentifierReturns(statefulSets, nil)
+				secretsClient.ListReturns([]corev1.Secret{
+					{ObjectMeta: metav1.ObjectMeta{Name: "baldur-env"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "baldur-env-1234"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "baldur-env-1234-sidecars"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "baldur-registry-credentials"}},
+				}, nil)
+			})
+
+			It("lists the secrets of the LRP", func() {
+				Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				Expect(secretsClient.ListCallCount()).To(Equal(1))
+				namespace, selector := secretsClient.ListArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(selector).To(Equal(k8s.LabelGUID + "=guid_1234," + k8s.LabelVersion + "=version_1234"))
+			})
+
+			It("deletes the env secrets of every revision", func() {
+				Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				Expect(secretsClient.DeleteCallCount()).To(Equal(3))
+
+				deleted := []string{}
+				for i := 0; i < secretsClient.DeleteCallCount(); i++ {
+					secretNs, secretName := secretsClient.DeleteArgsForCall(i)
+					Expect(secretNs).To(Equal("the-namespace"))
+					deleted = append(deleted, secretName)
+				}
+				Expect(deleted).To(ConsistOf("baldur-env", "baldur-env-1234", "baldur-env-1234-sidecars"))
+			})
+
+			It("deletes them after the statefulset, so restarting instances still find them", func() {
+				calls := []string{}
+				statefulSetClient.DeleteStub = func(_, _ string) error {
+					calls = append(calls, "delete-statefulset")
+
+					return nil
+				}
+				secretsClient.DeleteStub = func(_, _ string) error {
+					calls = append(calls, "delete-secret")
+
+					return nil
+				}
+
+				Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				Expect(calls).To(Equal([]string{"delete-statefulset", "delete-secret", "delete-secret", "delete-secret"}))
+			})
+
+			When("the statefulset drains its routes", func() {
+				BeforeEach(func() {
+					statefulSets[0].Annotations = map[string]string{
+						k8s.AnnotationRegisteredRoutes: `[{"hostname":"baldur.example.com","port":8080}]`,
+					}
+					statefulSetClient.GetByLRPIdentifierReturns(statefulSets, nil)
+					statefulSetDesirer.RouteDrainPeriod = time.Minute
+				})
+
+				It("leaves them to be garbage collected with the statefulset", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+					Expect(secretsClient.DeleteCallCount()).To(BeZero())
+				})
+			})
+
+			When("an env secret does not exist anymore", func() {
+				BeforeEach(func() {
+					secretsClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "baldur-env"))
+				})
+
+				It("succeeds", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				})
+			})
+
+			When("listing the env secrets fails", func() {
+				BeforeEach(func() {
+					secretsClient.ListReturns(nil, errors.New("boom"))
+				})
+
+				It("returns the error", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("failed to list env secrets")))
+				})
+
+				It("has deleted the statefulset already", func() {
+					_ = statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})
+					Expect(statefulSetClient.DeleteCallCount()).To(Equal(1))
+				})
+			})
+
+			When("deleting an env secret fails", func() {
+				BeforeEach(func() {
+					secretsClient.DeleteReturns(errors.New("boom"))
+				})
+
+				It("returns the error", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("failed to delete env secrets")))
+				})
+			})
+		})
+
 		When("deletion of stateful set fails", func() {
 			BeforeEach(func() {
 				statefulSetClient.DeleteReturns(errors.New("boom"))
@@ -872,6 +2395,138 @@ var _ = Describe("Statefulset Desirer", func() {
 				Expect(logger).To(gbytes.Say("statefulset-does-not-exist.*missing_guid.*some_version"))
 			})
 		})
+
+		When("zero-downtime version switching is enabled", func() {
+			var newerVersion appsv1.StatefulSet
+
+			BeforeEach(func() {
+				statefulSetDesirer.ZeroDowntimeVersionSwitch = true
+				statefulSetDesirer.OldVersionGracePeriod = time.Hour
+
+				statefulSets[0].Labels = map[string]string{k8s.LabelGUID: "guid_1234", k8s.LabelVersion: "version_1234"}
+				statefulSets[0].CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+				newerVersion = appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "baldur-new",
+						Namespace:         "the-namespace",
+						Labels:            map[string]string{k8s.LabelGUID: "guid_1234", k8s.LabelVersion: "version_5678"},
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute)),
+					},
+					Spec:   appsv1.StatefulSetSpec{Replicas: int32ptr(2)},
+					Status: appsv1.StatefulSetStatus{ReadyReplicas: 1},
+				}
+				statefulSetClient.GetByGUIDReturns([]appsv1.StatefulSet{statefulSets[0], newerVersion}, nil)
+			})
+
+			When("a newer version is not ready yet", func() {
+				It("defers the stop by marking the statefulset", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+
+					Expect(statefulSetClient.GetByGUIDArgsForCall(0)).To(Equal("guid_1234"))
+					Expect(statefulSetClient.DeleteCallCount()).To(BeZero())
+					Expect(statefulSetClient.UpdateCallCount()).To(Equal(1))
+					_, updated := statefulSetClient.UpdateArgsForCall(0)
+					Expect(updated.Name).To(Equal("baldur"))
+					Expect(updated.Annotations).To(HaveKey(k8s.AnnotationStopRequested))
+				})
+
+				When("the stop has already been requested", func() {
+					BeforeEach(func() {
+						statefulSets[0].Annotations = map[string]string{k8s.AnnotationStopRequested: "yesterday"}
+						statefulSetClient.GetByLRPIdentifierReturns(statefulSets, nil)
+					})
+
+					It("does nothing", func() {
+						Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+						Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+						Expect(statefulSetClient.DeleteCallCount()).To(BeZero())
+					})
+				})
+
+				When("marking the statefulset fails", func() {
+					BeforeEach(func() {
+						statefulSetClient.UpdateReturns(nil, errors.New("boom"))
+					})
+
+					It("returns an error", func() {
+						Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("failed to defer stop")))
+						Expect(statefulSetClient.DeleteCallCount()).To(BeZero())
+					})
+				})
+
+				When("the grace period has expired", func() {
+					BeforeEach(func() {
+						statefulSetDesirer.OldVersionGracePeriod = time.Second
+					})
+
+					It("deletes the statefulset", func() {
+						Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+						Expect(statefulSetClient.DeleteCallCount()).To(Equal(1))
+					})
+				})
+				When("the newer version is being stopped as well", func() {
+					BeforeEach(func() {
+						newerVersion.Annotations = map[string]string{k8s.AnnotationStopRequested: "now"}
+						statefulSetClient.GetByGUIDReturns([]appsv1.StatefulSet{statefulSets[0], newerVersion}, nil)
+					})
+
+					It("deletes the statefulset, as it has not been superseded", func() {
+						Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+						Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+						Expect(statefulSetClient.DeleteCallCount()).To(Equal(1))
+					})
+				})
+
+				When("the newer version is being deleted", func() {
+					BeforeEach(func() {
+						now := metav1.Now()
+						newerVersion.DeletionTimestamp = &now
+						statefulSetClient.GetByGUIDReturns([]appsv1.StatefulSet{statefulSets[0], newerVersion}, nil)
+					})
+
+					It("deletes the statefulset", func() {
+						Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+						Expect(statefulSetClient.DeleteCallCount()).To(Equal(1))
+					})
+				})
+			})
+
+			When("the newer version is ready", func() {
+				BeforeEach(func() {
+					newerVersion.Status.ReadyReplicas = 2
+					newerVersion.Status.UpdatedReplicas = 2
+					statefulSetClient.GetByGUIDReturns([]appsv1.StatefulSet{statefulSets[0], newerVersion}, nil)
+				})
+
+				It("deletes the statefulset", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+					Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+					Expect(statefulSetClient.DeleteCallCount()).To(Equal(1))
+				})
+			})
+
+			When("the statefulset is the latest version", func() {
+				BeforeEach(func() {
+					statefulSetClient.GetByGUIDReturns([]appsv1.StatefulSet{statefulSets[0]}, nil)
+				})
+
+				It("deletes the statefulset", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+					Expect(statefulSetClient.DeleteCallCount()).To(Equal(1))
+				})
+			})
+
+			When("listing the versions fails", func() {
+				BeforeEach(func() {
+					statefulSetClient.GetByGUIDReturns(nil, errors.New("boom"))
+				})
+
+				It("returns an error", func() {
+					Expect(statefulSetDesirer.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("failed to list versions")))
+					Expect(statefulSetClient.DeleteCallCount()).To(BeZero())
+				})
+			})
+		})
 	})
 
 	Describe("StopInstance", func() {
@@ -1035,6 +2690,26 @@ var _ = Describe("Statefulset Desirer", func() {
 			})
 		})
 
+		When("the statefulset is being rolled out", func() {
+			It("should report the instances that are not updated yet", func() {
+				statefulSetClient.GetByLRPIdentifierReturns([]appsv1.StatefulSet{
+					{Status: appsv1.StatefulSetStatus{CurrentRevision: "rev-1", UpdateRevision: "rev-2"}},
+				}, nil)
+				pods := []corev1.Pod{
+					{ObjectMeta: metav1.ObjectMeta{Name: "odin-0", Labels: map[string]string{appsv1.StatefulSetRevisionLabel: "rev-1"}}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "odin-1", Labels: map[string]string{appsv1.StatefulSetRevisionLabel: "rev-2"}}},
+				}
+				podsClient.GetByLRPIdentifierReturns(pods, nil)
+				eventsClient.GetByPodReturns([]corev1.Event{}, nil)
+
+				instances, err := statefulSetDesirer.GetInstances(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instances).To(HaveLen(2))
+				Expect(instances[0].UpdatePending).To(BeTrue())
+				Expect(instances[1].UpdatePending).To(BeFalse())
+			})
+		})
+
 		When("time since creation is not available yet", func() {
 			It("should return a default value", func() {
 				pods := []corev1.Pod{
@@ -1095,6 +2770,43 @@ var _ = Describe("Statefulset Desirer", func() {
 			})
 		})
 
+		When("no node matches the placement tags", func() {
+			BeforeEach(func() {
+				pods := []corev1.Pod{
+					{ObjectMeta: metav1.ObjectMeta{Name: "odin-0"}},
+				}
+				podsClient.GetByLRPIdentifierReturns(pods, nil)
+				eventsClient.GetByPodReturns([]corev1.Event{
+					{
+						Reason:  "FailedScheduling",
+						Message: "0/3 nodes are available: 3 node(s) didn't match node selector.",
+					},
+				}, nil)
+				mapper.Returns(&opi.LRP{PlacementTags: []string{"isolated", "gpu"}}, nil)
+			})
+
+			It("returns a placement tag mismatch error", func() {
+				instances, err := statefulSetDesirer.GetInstances(opi.LRPIdentifier{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instances).To(HaveLen(1))
+				Expect(instances[0].State).To(Equal(opi.ErrorState))
+				Expect(instances[0].PlacementError).To(Equal(`found no compatible cell with placement tags "isolated", "gpu"`))
+			})
+
+			When("the app has no placement tags", func() {
+				BeforeEach(func() {
+					mapper.Returns(&opi.LRP{}, nil)
+				})
+
+				It("does not report a placement error", func() {
+					instances, err := statefulSetDesirer.GetInstances(opi.LRPIdentifier{})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(instances).To(HaveLen(1))
+					Expect(instances[0].PlacementError).To(BeEmpty())
+				})
+			})
+		})
+
 		When("the StatefulSet was deleted/stopped", func() {
 			It("should return a default value", func() {
 				event1 := corev1.Event{
@@ -1179,7 +2891,7 @@ func createLRP(name string, routes []opi.Route) *opi.LRP {
 				MountPath: "/some/path",
 			},
 		},
-		LRP: "original request",
+		LRP: `{"process_guid":"guid_1234","environment":[{"name":"SECRET","value":"s3cret"}],"lifecycle":{"docker_lifecycle":{"image":"busybox","registry_username":"user","registry_password":"pass"}}}`,
 		UserDefinedAnnotations: map[string]string{
 			"prometheus.io/scrape": "secret-value",
 		},
@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/eirini"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	TopologyKeyHostname = "hostname"
+	TopologyKeyZone     = "zone"
+
+	defaultMaxSkew = 1
+)
+
+type topologySpreadPolicy struct {
+	TopologyKey       string                               `json:"topologyKey"`
+	MaxSkew           int32                                `json:"maxSkew"`
+	WhenUnsatisfiable corev1.UnsatisfiableConstraintAction `json:"whenUnsatisfiable"`
+}
+
+func (m *StatefulSetDesirer) topologySpreadConstraints(selector *metav1.LabelSelector) ([]corev1.TopologySpreadConstraint, string, error) {
+	configured := m.TopologySpread
+	if len(configured) == 0 {
+		configured = []eirini.TopologySpreadConstraint{{TopologyKey: TopologyKeyHostname}}
+	}
+
+	constraints := make([]corev1.TopologySpreadConstraint, 0, len(configured))
+	policies := make([]topologySpreadPolicy, 0, len(configured))
+
+	for _, c := range configured {
+		if c.TopologyKey == "" {
+			return nil, "", errors.New("topology spread constraints require a topology key")
+		}
+
+		policy := topologySpreadPolicy{
+			TopologyKey:       topologyKey(c.TopologyKey),
+			MaxSkew:           c.MaxSkew,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+		}
+
+		if policy.MaxSkew < 1 {
+			policy.MaxSkew = defaultMaxSkew
+		}
+
+		if c.Hard {
+			policy.WhenUnsatisfiable = corev1.DoNotSchedule
+		}
+
+		policies = append(policies, policy)
+		constraints = append(constraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           policy.MaxSkew,
+			TopologyKey:       policy.TopologyKey,
+			WhenUnsatisfiable: policy.WhenUnsatisfiable,
+			LabelSelector:     selector.DeepCopy(),
+		})
+	}
+
+	policy, err := json.Marshal(policies)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to marshal topology spread policy")
+	}
+
+	return constraints, string(policy), nil
+}
+
+func topologyKey(key string) string {
+	switch key {
+	case TopologyKeyHostname:
+		return corev1.LabelHostname
+	case TopologyKeyZone:
+		return corev1.LabelZoneFailureDomainStable
+	default:
+		return key
+	}
+}
@@ -0,0 +1,152 @@
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+//counterfeiter:generate . LRPStopper
+
+type LRPStopper interface {
+	Stop(identifier opi.LRPIdentifier) error
+}
+
+type VersionSwitcher struct {
+	StatefulSets StatefulSetClient
+	Stopper      LRPStopper
+	GracePeriod  time.Duration
+	Logger       lager.Logger
+}
+
+func (s *VersionSwitcher) Switch() error {
+	logger := s.Logger.Session("switch")
+
+	statefulSets, err := s.StatefulSets.GetBySourceType(appSourceType)
+	if err != nil {
+		return errors.Wrap(err, "failed to list statefulsets")
+	}
+
+	failures := 0
+
+	for guid, versions := range groupByGUID(statefulSets) {
+		if len(versions) < 2 && !hasStopRequested(&versions[0]) { //nolint:gomnd
+			continue
+		}
+
+		if switchErr := s.switchVersions(logger, versions); switchErr != nil {
+			logger.Error("failed-to-switch-versions", switchErr, lager.Data{"guid": guid})
+
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("failed to switch versions of %d apps", failures)
+	}
+
+	return nil
+}
+
+func (s *VersionSwitcher) switchVersions(logger lager.Logger, versions []appsv1.StatefulSet) error {
+	latest := latestVersion(versions)
+	ready := isReady(latest)
+	expired := gracePeriodExpired(latest, s.GracePeriod)
+
+	for i := range versions {
+		old := &versions[i]
+		stopRequested := hasStopRequested(old)
+		superseded := supersedes(latest, old)
+
+		// Old versions that nobody asked to stop keep running until the grace
+		// period has expired. Versions asked to stop wait for the newer
+		// version to become ready, unless there is no newer one to wait for.
+		stop := (stopRequested && (!superseded || ready || expired)) || (superseded && ready && expired)
+		if !stop {
+			continue
+		}
+
+		identifier := opi.LRPIdentifier{GUID: old.Labels[LabelGUID], Version: old.Labels[LabelVersion]}
+		logger.Info("stopping-old-version", lager.Data{"guid": identifier.GUID, "version": identifier.Version})
+
+		if err := s.Stopper.Stop(identifier); err != nil {
+			return errors.Wrap(err, "failed to stop old version")
+		}
+	}
+
+	return nil
+}
+
+func groupByGUID(statefulSets []appsv1.StatefulSet) map[string][]appsv1.StatefulSet {
+	groups := map[string][]appsv1.StatefulSet{}
+
+	for _, s := range statefulSets {
+		guid := s.Labels[LabelGUID]
+		groups[guid] = append(groups[guid], s)
+	}
+
+	return groups
+}
+
+func latestVersion(versions []appsv1.StatefulSet) *appsv1.StatefulSet {
+	var latest *appsv1.StatefulSet
+
+	for i := range versions {
+		v := &versions[i]
+
+		if latest == nil ||
+			latest.CreationTimestamp.Before(&v.CreationTimestamp) ||
+			(latest.CreationTimestamp.Equal(&v.CreationTimestamp) && latest.Name < v.Name) {
+			latest = v
+		}
+	}
+
+	return latest
+}
+
+// A version only supersedes another one if it is newer and is still meant
+// to run, i.e. it is not being stopped itself.
+func supersedes(latest, old *appsv1.StatefulSet) bool {
+	return !isSameStatefulSet(latest, old) &&
+		old.CreationTimestamp.Before(&latest.CreationTimestamp) &&
+		latest.DeletionTimestamp == nil &&
+		!hasStopRequested(latest) &&
+		replicas(latest) > 0
+}
+
+func hasStopRequested(statefulSet *appsv1.StatefulSet) bool {
+	_, ok := statefulSet.Annotations[AnnotationStopRequested]
+
+	return ok
+}
+
+// isReady only counts instances running the latest spec: right after an
+// update the status still describes the previous one until the controller
+// observes the new generation, and then the pods are replaced one by one.
+func isReady(statefulSet *appsv1.StatefulSet) bool {
+	status := statefulSet.Status
+
+	return status.ObservedGeneration >= statefulSet.Generation &&
+		status.UpdatedReplicas >= replicas(statefulSet) &&
+		status.ReadyReplicas >= replicas(statefulSet)
+}
+
+func replicas(statefulSet *appsv1.StatefulSet) int32 {
+	if statefulSet.Spec.Replicas == nil {
+		return 1
+	}
+
+	return *statefulSet.Spec.Replicas
+}
+
+func gracePeriodExpired(statefulSet *appsv1.StatefulSet, gracePeriod time.Duration) bool {
+	return time.Since(statefulSet.CreationTimestamp.Time) > gracePeriod
+}
+
+func isSameStatefulSet(a, b *appsv1.StatefulSet) bool {
+	return a.Namespace == b.Namespace && a.Name == b.Name
+}
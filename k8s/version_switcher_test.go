@@ -0,0 +1,222 @@
+package k8s_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("VersionSwitcher", func() {
+	var (
+		statefulSetClient *k8sfakes.FakeStatefulSetClient
+		stopper           *k8sfakes.FakeLRPStopper
+		switcher          *k8s.VersionSwitcher
+		oldVersion        appsv1.StatefulSet
+		newVersion        appsv1.StatefulSet
+		otherApp          appsv1.StatefulSet
+		switchErr         error
+	)
+
+	BeforeEach(func() {
+		statefulSetClient = new(k8sfakes.FakeStatefulSetClient)
+		stopper = new(k8sfakes.FakeLRPStopper)
+
+		oldVersion = versionedStatefulSet("app-old", "app-guid", "v1", time.Now().Add(-time.Hour), 2, 2)
+		newVersion = versionedStatefulSet("app-new", "app-guid", "v2", time.Now().Add(-time.Minute), 2, 1)
+		otherApp = versionedStatefulSet("other", "other-guid", "v1", time.Now().Add(-time.Hour), 1, 1)
+
+		statefulSetClient.GetBySourceTypeStub = func(string) ([]appsv1.StatefulSet, error) {
+			return []appsv1.StatefulSet{newVersion, oldVersion, otherApp}, nil
+		}
+
+		switcher = &k8s.VersionSwitcher{
+			StatefulSets: statefulSetClient,
+			Stopper:      stopper,
+			GracePeriod:  10 * time.Minute,
+			Logger:       lagertest.NewTestLogger("version-switcher"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		switchErr = switcher.Switch()
+	})
+
+	It("lists the app statefulsets", func() {
+		Expect(switchErr).NotTo(HaveOccurred())
+		Expect(statefulSetClient.GetBySourceTypeArgsForCall(0)).To(Equal("APP"))
+	})
+
+	When("the new version is not ready yet", func() {
+		It("keeps the old version running", func() {
+			Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+			Expect(stopper.StopCallCount()).To(BeZero())
+		})
+
+		When("the old version was requested to stop", func() {
+			BeforeEach(func() {
+				oldVersion.Annotations = map[string]string{k8s.AnnotationStopRequested: "now"}
+			})
+
+			It("keeps the old version running", func() {
+				Expect(stopper.StopCallCount()).To(BeZero())
+			})
+
+			When("the grace period has expired", func() {
+				BeforeEach(func() {
+					switcher.GracePeriod = time.Second
+				})
+
+				It("stops the old version", func() {
+					Expect(stopper.StopCallCount()).To(Equal(1))
+					Expect(stopper.StopArgsForCall(0)).To(Equal(opi.LRPIdentifier{GUID: "app-guid", Version: "v1"}))
+				})
+			})
+		})
+
+		When("the grace period has expired", func() {
+			BeforeEach(func() {
+				switcher.GracePeriod = time.Second
+			})
+
+			It("keeps the old version running", func() {
+				Expect(stopper.StopCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	When("the new version is ready", func() {
+		BeforeEach(func() {
+			newVersion.Status.ReadyReplicas = 2
+		})
+
+		It("keeps the old version running, as nobody asked to stop it", func() {
+			Expect(statefulSetClient.UpdateCallCount()).To(BeZero())
+			Expect(stopper.StopCallCount()).To(BeZero())
+		})
+
+		When("the old version was requested to stop", func() {
+			BeforeEach(func() {
+				oldVersion.Annotations = map[string]string{k8s.AnnotationStopRequested: "now"}
+			})
+
+			It("stops the old version", func() {
+				Expect(stopper.StopCallCount()).To(Equal(1))
+				Expect(stopper.StopArgsForCall(0)).To(Equal(opi.LRPIdentifier{GUID: "app-guid", Version: "v1"}))
+			})
+		})
+
+		When("the controller has not observed the latest spec of the new version yet", func() {
+			BeforeEach(func() {
+				oldVersion.Annotations = map[string]string{k8s.AnnotationStopRequested: "now"}
+				newVersion.Generation = 2
+				newVersion.Status.ObservedGeneration = 1
+			})
+
+			It("keeps the old version running", func() {
+				Expect(stopper.StopCallCount()).To(BeZero())
+			})
+		})
+
+		When("some instances of the new version still run a previous revision", func() {
+			BeforeEach(func() {
+				oldVersion.Annotations = map[string]string{k8s.AnnotationStopRequested: "now"}
+				newVersion.Status.UpdatedReplicas = 1
+			})
+
+			It("keeps the old version running", func() {
+				Expect(stopper.StopCallCount()).To(BeZero())
+			})
+		})
+
+		When("the grace period has expired", func() {
+			BeforeEach(func() {
+				switcher.GracePeriod = time.Second
+			})
+
+			It("garbage-collects the old version", func() {
+				Expect(stopper.StopCallCount()).To(Equal(1))
+				Expect(stopper.StopArgsForCall(0)).To(Equal(opi.LRPIdentifier{GUID: "app-guid", Version: "v1"}))
+			})
+		})
+
+		When("stopping fails", func() {
+			BeforeEach(func() {
+				switcher.GracePeriod = time.Second
+				stopper.StopReturns(errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(switchErr).To(MatchError(ContainSubstring("failed to switch versions of 1 apps")))
+			})
+		})
+	})
+
+	When("the newer version is being stopped itself", func() {
+		BeforeEach(func() {
+			oldVersion.Annotations = map[string]string{k8s.AnnotationStopRequested: "now"}
+			newVersion.Annotations = map[string]string{k8s.AnnotationStopRequested: "now"}
+		})
+
+		It("does not wait for it before stopping the old version", func() {
+			Expect(stopper.StopCallCount()).To(Equal(2))
+		})
+	})
+
+	When("only a version that was requested to stop is left", func() {
+		BeforeEach(func() {
+			oldVersion.Annotations = map[string]string{k8s.AnnotationStopRequested: "now"}
+			statefulSetClient.GetBySourceTypeStub = func(string) ([]appsv1.StatefulSet, error) {
+				return []appsv1.StatefulSet{oldVersion, otherApp}, nil
+			}
+		})
+
+		It("stops it", func() {
+			Expect(stopper.StopCallCount()).To(Equal(1))
+			Expect(stopper.StopArgsForCall(0)).To(Equal(opi.LRPIdentifier{GUID: "app-guid", Version: "v1"}))
+		})
+	})
+
+	When("only one version that was not requested to stop exists", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetBySourceTypeStub = func(string) ([]appsv1.StatefulSet, error) {
+				return []appsv1.StatefulSet{oldVersion, otherApp}, nil
+			}
+		})
+
+		It("does nothing", func() {
+			Expect(stopper.StopCallCount()).To(BeZero())
+		})
+	})
+
+	When("listing the statefulsets fails", func() {
+		BeforeEach(func() {
+			statefulSetClient.GetBySourceTypeStub = nil
+			statefulSetClient.GetBySourceTypeReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(switchErr).To(MatchError(ContainSubstring("failed to list statefulsets")))
+		})
+	})
+})
+
+func versionedStatefulSet(name, guid, version string, created time.Time, replicas int, readyReplicas int32) appsv1.StatefulSet {
+	return appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "the-namespace",
+			Labels:            map[string]string{k8s.LabelGUID: guid, k8s.LabelVersion: version},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec:   appsv1.StatefulSetSpec{Replicas: int32ptr(replicas)},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: readyReplicas, UpdatedReplicas: int32(replicas)},
+	}
+}
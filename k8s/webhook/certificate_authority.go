@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	instanceKeyBits    = 2048
+	serialNumberBits   = 128
+	certBackdateMargin = time.Minute
+)
+
+// InstanceSubject identifies the app instance a certificate is minted for.
+type InstanceSubject struct {
+	PodName   string
+	AppGUID   string
+	SpaceGUID string
+	OrgGUID   string
+}
+
+type CertificateAuthority struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     crypto.Signer
+}
+
+func LoadCertificateAuthority(certPath, keyPath string) (*CertificateAuthority, error) {
+	keyPair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load CA key pair")
+	}
+
+	return NewCertificateAuthority(keyPair)
+}
+
+func NewCertificateAuthority(keyPair tls.Certificate) (*CertificateAuthority, error) {
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse CA certificate")
+	}
+
+	key, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("unsupported CA key type %T", keyPair.PrivateKey)
+	}
+
+	return &CertificateAuthority{
+		cert:    cert,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		key:     key,
+	}, nil
+}
+
+// Mint issues a certificate for the instance. The returned certificate PEM
+// contains the chain up to the CA.
+func (ca *CertificateAuthority) Mint(subject InstanceSubject, validity time.Duration) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, instanceKeyBits)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate instance key")
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialNumberBits))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serial number")
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName: subject.PodName,
+			OrganizationalUnit: []string{
+				fmt.Sprintf("organization:%s", subject.OrgGUID),
+				fmt.Sprintf("space:%s", subject.SpaceGUID),
+				fmt.Sprintf("app:%s", subject.AppGUID),
+			},
+		},
+		DNSNames:    []string{subject.PodName},
+		NotBefore:   now.Add(-certBackdateMargin),
+		NotAfter:    now.Add(validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create instance certificate")
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	certPEM = append(certPEM, ca.certPEM...)
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,154 @@
+package webhook_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/webhook"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CertificateAuthority", func() {
+	var (
+		ca        *webhook.CertificateAuthority
+		caKeyPair tls.Certificate
+		caPool    *x509.CertPool
+		certPEM   []byte
+		keyPEM    []byte
+		mintErr   error
+	)
+
+	BeforeEach(func() {
+		caKeyPair, caPool = generateCA()
+
+		var err error
+		ca, err = webhook.NewCertificateAuthority(caKeyPair)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		certPEM, keyPEM, mintErr = ca.Mint(webhook.InstanceSubject{
+			PodName:   "dora-0",
+			AppGUID:   "app-guid",
+			SpaceGUID: "space-guid",
+			OrgGUID:   "org-guid",
+		}, time.Hour)
+	})
+
+	It("mints a certificate signed by the CA", func() {
+		Expect(mintErr).NotTo(HaveOccurred())
+
+		cert := parseLeaf(certPEM)
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:     caPool,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("carries the instance identity in the subject", func() {
+		cert := parseLeaf(certPEM)
+		Expect(cert.Subject.CommonName).To(Equal("dora-0"))
+		Expect(cert.Subject.OrganizationalUnit).To(ConsistOf("organization:org-guid", "space:space-guid", "app:app-guid"))
+		Expect(cert.DNSNames).To(ConsistOf("dora-0"))
+	})
+
+	It("is valid for the requested duration", func() {
+		cert := parseLeaf(certPEM)
+		Expect(cert.NotAfter).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+	})
+
+	It("includes the CA certificate in the chain", func() {
+		_, rest := pem.Decode(certPEM)
+		caBlock, _ := pem.Decode(rest)
+		Expect(caBlock).NotTo(BeNil())
+
+		caCert, err := x509.ParseCertificate(caBlock.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(caCert.IsCA).To(BeTrue())
+	})
+
+	It("returns the matching private key", func() {
+		_, err := tls.X509KeyPair(certPEM, keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("LoadCertificateAuthority", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "instance-identity-ca")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("loads the CA from PEM files", func() {
+			caKeyDER, err := x509.MarshalECPrivateKey(caKeyPair.PrivateKey.(*ecdsa.PrivateKey))
+			Expect(err).NotTo(HaveOccurred())
+
+			certPath := filepath.Join(dir, "ca.crt")
+			keyPath := filepath.Join(dir, "ca.key")
+			Expect(ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caKeyPair.Certificate[0]}), 0o600)).To(Succeed())
+			Expect(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKeyDER}), 0o600)).To(Succeed())
+
+			_, err = webhook.LoadCertificateAuthority(certPath, keyPath)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("fails when the files do not exist", func() {
+			_, err := webhook.LoadCertificateAuthority(filepath.Join(dir, "nope.crt"), filepath.Join(dir, "nope.key"))
+			Expect(err).To(MatchError(ContainSubstring("failed to load CA key pair")))
+		})
+	})
+})
+
+func generateCA() (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "instance-identity-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	Expect(err).NotTo(HaveOccurred())
+
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func parseLeaf(certPEM []byte) *x509.Certificate {
+	block, _ := pem.Decode(certPEM)
+	ExpectWithOffset(1, block).NotTo(BeNil())
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+	return cert
+}
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s"
+	eirinix "code.cloudfoundry.org/eirinix"
+	"code.cloudfoundry.org/lager"
+	exterrors "github.com/pkg/errors"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	LabelInstanceIdentity = "cloudfoundry.org/instance_identity"
+	AnnotationPodName     = "cloudfoundry.org/pod_name"
+
+	InstanceIdentityVolumeName = "cf-instance-identity"
+	InstanceIdentityMountPath  = "/etc/cf-instance-credentials"
+	InstanceCertKey            = "instance.crt"
+	InstanceKeyKey             = "instance.key"
+)
+
+//counterfeiter:generate . IdentitySecretsClient
+
+type IdentitySecretsClient interface {
+	Create(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	Update(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	List(namespace, labelSelector string) ([]corev1.Secret, error)
+	Delete(namespace, name string) error
+}
+
+type InstanceIdentityInjector struct {
+	logger   lager.Logger
+	ca       *CertificateAuthority
+	secrets  IdentitySecretsClient
+	validity time.Duration
+}
+
+func NewInstanceIdentityInjector(logger lager.Logger, ca *CertificateAuthority, secrets IdentitySecretsClient, validity time.Duration) InstanceIdentityInjector {
+	return InstanceIdentityInjector{
+		logger:   logger,
+		ca:       ca,
+		secrets:  secrets,
+		validity: validity,
+	}
+}
+
+func (i InstanceIdentityInjector) Handle(ctx context.Context, eiriniManager eirinix.Manager, pod *corev1.Pod, req admission.Request) admission.Response {
+	logger := i.logger.Session("handle-webhook-request")
+
+	if req.Operation != v1beta1.Create {
+		return admission.Allowed("pod was already created")
+	}
+
+	if pod == nil {
+		err := errors.New("no pod could be decoded from the request")
+		logger.Error("no-pod-in-request", err)
+
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	namespace := pod.Namespace
+	if namespace == "" {
+		namespace = req.Namespace
+	}
+
+	logger = logger.WithData(lager.Data{"pod-name": pod.Name, "pod-namespace": namespace})
+
+	podCopy := pod.DeepCopy()
+
+	if err := injectInstanceIdentity(podCopy, identitySecretName(pod.Name)); err != nil {
+		logger.Error("failed-to-inject-instance-identity", err)
+
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if err := i.createIdentitySecret(namespace, podCopy); err != nil {
+		logger.Error("failed-to-create-identity-secret", err)
+
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	return eiriniManager.PatchFromPod(req, podCopy)
+}
+
+func (i InstanceIdentityInjector) createIdentitySecret(namespace string, pod *corev1.Pod) error {
+	subject := InstanceSubject{
+		PodName:   pod.Name,
+		AppGUID:   pod.Annotations[k8s.AnnotationAppID],
+		SpaceGUID: pod.Annotations[k8s.AnnotationSpaceGUID],
+		OrgGUID:   pod.Annotations[k8s.AnnotationOrgGUID],
+	}
+
+	secret, err := identitySecret(i.ca, subject, i.validity)
+	if err != nil {
+		return err
+	}
+
+	secret.OwnerReferences = controllerOwnerReferences(pod)
+
+	_, err = i.secrets.Create(namespace, secret)
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = i.secrets.Update(namespace, secret)
+	}
+
+	return exterrors.Wrap(err, "failed to create instance identity secret")
+}
+
+func identitySecret(ca *CertificateAuthority, subject InstanceSubject, validity time.Duration) (*corev1.Secret, error) {
+	certPEM, keyPEM, err := ca.Mint(subject, validity)
+	if err != nil {
+		return nil, exterrors.Wrap(err, "failed to mint instance identity certificate")
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: identitySecretName(subject.PodName),
+			Labels: map[string]string{
+				LabelInstanceIdentity: "true",
+			},
+			Annotations: map[string]string{
+				AnnotationPodName:       subject.PodName,
+				k8s.AnnotationAppID:     subject.AppGUID,
+				k8s.AnnotationSpaceGUID: subject.SpaceGUID,
+				k8s.AnnotationOrgGUID:   subject.OrgGUID,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			InstanceCertKey: certPEM,
+			InstanceKeyKey:  keyPEM,
+		},
+	}, nil
+}
+
+func identitySecretName(podName string) string {
+	return fmt.Sprintf("%s-identity", podName)
+}
+
+// controllerOwnerReferences makes the identity secret share the lifecycle of
+// the statefulset, as the pod has no UID to refer to yet.
+func controllerOwnerReferences(pod *corev1.Pod) []metav1.OwnerReference {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller {
+			return []metav1.OwnerReference{ref}
+		}
+	}
+
+	return nil
+}
+
+func injectInstanceIdentity(pod *corev1.Pod, secretName string) error {
+	for c := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[c]
+		if container.Name != k8s.OPIContainerName {
+			continue
+		}
+
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      InstanceIdentityVolumeName,
+			MountPath: InstanceIdentityMountPath,
+			ReadOnly:  true,
+		})
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: eirini.EnvCFInstanceCert, Value: filepath.Join(InstanceIdentityMountPath, InstanceCertKey)},
+			corev1.EnvVar{Name: eirini.EnvCFInstanceKey, Value: filepath.Join(InstanceIdentityMountPath, InstanceKeyKey)},
+		)
+
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: InstanceIdentityVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+			},
+		})
+
+		return nil
+	}
+
+	return errors.New("no opi container found in pod")
+}
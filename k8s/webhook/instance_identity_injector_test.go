@@ -0,0 +1,193 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/k8s/webhook/webhookfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gomodules.xyz/jsonpatch/v2"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("InstanceIdentityInjector", func() {
+	var (
+		injector                 webhook.InstanceIdentityInjector
+		manager                  *webhookfakes.FakeManager
+		secrets                  *webhookfakes.FakeIdentitySecretsClient
+		pod                      *corev1.Pod
+		req                      admission.Request
+		actualResp, expectedResp admission.Response
+	)
+
+	BeforeEach(func() {
+		manager = new(webhookfakes.FakeManager)
+		secrets = new(webhookfakes.FakeIdentitySecretsClient)
+
+		caKeyPair, _ := generateCA()
+		ca, err := webhook.NewCertificateAuthority(caKeyPair)
+		Expect(err).NotTo(HaveOccurred())
+
+		injector = webhook.NewInstanceIdentityInjector(lagertest.NewTestLogger("instance-identity-injector"), ca, secrets, time.Hour)
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dora-0",
+				Annotations: map[string]string{
+					k8s.AnnotationAppID:     "app-guid",
+					k8s.AnnotationSpaceGUID: "space-guid",
+					k8s.AnnotationOrgGUID:   "org-guid",
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "StatefulSet", Name: "dora", Controller: pointer.BoolPtr(true)},
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "opi"},
+					{Name: "sidecar-agent"},
+				},
+			},
+		}
+
+		req = admission.Request{
+			AdmissionRequest: v1beta1.AdmissionRequest{
+				Operation: v1beta1.Create,
+				Namespace: "the-namespace",
+			},
+		}
+
+		expectedResp = admission.Response{
+			Patches: []jsonpatch.JsonPatchOperation{
+				{Operation: "add", Path: "somewhere", Value: "something"},
+			},
+		}
+		manager.PatchFromPodReturns(expectedResp)
+	})
+
+	JustBeforeEach(func() {
+		actualResp = injector.Handle(context.Background(), manager, pod, req)
+	})
+
+	It("creates a secret with the instance identity", func() {
+		Expect(secrets.CreateCallCount()).To(Equal(1))
+
+		namespace, secret := secrets.CreateArgsForCall(0)
+		Expect(namespace).To(Equal("the-namespace"))
+		Expect(secret.Name).To(Equal("dora-0-identity"))
+		Expect(secret.Labels).To(HaveKeyWithValue(webhook.LabelInstanceIdentity, "true"))
+		Expect(secret.Annotations).To(HaveKeyWithValue(webhook.AnnotationPodName, "dora-0"))
+
+		cert := parseLeaf(secret.Data[webhook.InstanceCertKey])
+		Expect(cert.Subject.OrganizationalUnit).To(ConsistOf("organization:org-guid", "space:space-guid", "app:app-guid"))
+		Expect(secret.Data).To(HaveKey(webhook.InstanceKeyKey))
+	})
+
+	It("makes the statefulset own the secret", func() {
+		_, secret := secrets.CreateArgsForCall(0)
+		Expect(secret.OwnerReferences).To(ConsistOf(pod.OwnerReferences[0]))
+	})
+
+	It("mounts the identity into the opi container", func() {
+		Expect(actualResp).To(Equal(expectedResp))
+		Expect(manager.PatchFromPodCallCount()).To(Equal(1))
+
+		actualReq, actualPod := manager.PatchFromPodArgsForCall(0)
+		Expect(actualReq).To(Equal(req))
+		Expect(actualPod.Spec.Volumes).To(ConsistOf(corev1.Volume{
+			Name: webhook.InstanceIdentityVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: "dora-0-identity"},
+			},
+		}))
+		Expect(actualPod.Spec.Containers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{
+			Name:      webhook.InstanceIdentityVolumeName,
+			MountPath: "/etc/cf-instance-credentials",
+			ReadOnly:  true,
+		}))
+		Expect(actualPod.Spec.Containers[0].Env).To(ConsistOf(
+			corev1.EnvVar{Name: "CF_INSTANCE_CERT", Value: "/etc/cf-instance-credentials/instance.crt"},
+			corev1.EnvVar{Name: "CF_INSTANCE_KEY", Value: "/etc/cf-instance-credentials/instance.key"},
+		))
+		Expect(actualPod.Spec.Containers[1]).To(Equal(corev1.Container{Name: "sidecar-agent"}))
+	})
+
+	It("does not mutate the passed pod", func() {
+		Expect(pod.Spec.Volumes).To(BeEmpty())
+		Expect(pod.Spec.Containers[0].Env).To(BeEmpty())
+	})
+
+	When("the secret already exists", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "dora-0-identity"))
+		})
+
+		It("replaces it with a fresh certificate", func() {
+			Expect(secrets.UpdateCallCount()).To(Equal(1))
+
+			namespace, secret := secrets.UpdateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(secret.Name).To(Equal("dora-0-identity"))
+			Expect(actualResp).To(Equal(expectedResp))
+		})
+	})
+
+	When("creating the secret fails", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error response", func() {
+			Expect(actualResp.Allowed).To(BeFalse())
+			Expect(actualResp.Result.Code).To(Equal(int32(http.StatusInternalServerError)))
+			Expect(actualResp.Result.Message).To(ContainSubstring("failed to create instance identity secret"))
+		})
+	})
+
+	When("the pod has no OPI container", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Name = "ipo"
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "no opi container found in pod")
+		})
+
+		It("does not create a secret", func() {
+			Expect(secrets.CreateCallCount()).To(BeZero())
+		})
+	})
+
+	When("no pod is passed to handle", func() {
+		BeforeEach(func() {
+			pod = nil
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "no pod could be decoded from the request")
+		})
+	})
+
+	When("the pod has already been created", func() {
+		BeforeEach(func() {
+			req.AdmissionRequest.Operation = v1beta1.Update
+		})
+
+		It("allows the operation without minting a certificate", func() {
+			Expect(secrets.CreateCallCount()).To(BeZero())
+			ExpectAllowResponse(actualResp)
+		})
+	})
+})
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/lager"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	renewalFraction = 3
+
+	// orphanGracePeriod covers the time between the injector creating the
+	// secret of a pod and the pod being persisted.
+	orphanGracePeriod = time.Minute
+)
+
+//counterfeiter:generate . IdentityPodsClient
+
+type IdentityPodsClient interface {
+	GetAll() ([]corev1.Pod, error)
+}
+
+// InstanceIdentityRotator renews instance identity certificates once less
+// than a third of their validity is left. Kubelet propagates the renewed
+// secret into the mounted volume of the running pods. Secrets of pods that
+// no longer exist are deleted instead.
+type InstanceIdentityRotator struct {
+	logger    lager.Logger
+	ca        *CertificateAuthority
+	secrets   IdentitySecretsClient
+	pods      IdentityPodsClient
+	namespace string
+	validity  time.Duration
+}
+
+func NewInstanceIdentityRotator(logger lager.Logger, ca *CertificateAuthority, secrets IdentitySecretsClient, pods IdentityPodsClient, namespace string, validity time.Duration) *InstanceIdentityRotator {
+	return &InstanceIdentityRotator{
+		logger:    logger,
+		ca:        ca,
+		secrets:   secrets,
+		pods:      pods,
+		namespace: namespace,
+		validity:  validity,
+	}
+}
+
+func (r *InstanceIdentityRotator) Rotate() error {
+	logger := r.logger.Session("rotate")
+
+	secrets, err := r.secrets.List(r.namespace, fmt.Sprintf("%s=true", LabelInstanceIdentity))
+	if err != nil {
+		return errors.Wrap(err, "failed to list instance identity secrets")
+	}
+
+	pods, err := r.pods.GetAll()
+	if err != nil {
+		return errors.Wrap(err, "failed to list pods")
+	}
+
+	existingPods := map[string]bool{}
+	for _, pod := range pods {
+		existingPods[pod.Namespace+"/"+pod.Name] = true
+	}
+
+	var errs *multierror.Error
+
+	for i := range secrets {
+		secret := &secrets[i]
+
+		if !existingPods[secret.Namespace+"/"+secret.Annotations[AnnotationPodName]] {
+			if !isOrphan(secret) {
+				continue
+			}
+
+			logger.Debug("deleting-orphaned-secret", lager.Data{"secret": secret.Name, "namespace": secret.Namespace})
+
+			if deleteErr := r.delete(secret); deleteErr != nil {
+				logger.Error("failed-to-delete-orphaned-secret", deleteErr, lager.Data{"secret": secret.Name, "namespace": secret.Namespace})
+				errs = multierror.Append(errs, deleteErr)
+			}
+
+			continue
+		}
+
+		if !r.needsRenewal(secret) {
+			continue
+		}
+
+		logger.Debug("renewing-certificate", lager.Data{"secret": secret.Name, "namespace": secret.Namespace})
+
+		if renewErr := r.renew(secret); renewErr != nil {
+			logger.Error("failed-to-renew-certificate", renewErr, lager.Data{"secret": secret.Name, "namespace": secret.Namespace})
+			errs = multierror.Append(errs, renewErr)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func isOrphan(secret *corev1.Secret) bool {
+	return time.Since(secret.CreationTimestamp.Time) > orphanGracePeriod
+}
+
+func (r *InstanceIdentityRotator) delete(secret *corev1.Secret) error {
+	err := r.secrets.Delete(secret.Namespace, secret.Name)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "failed to delete instance identity secret %q", secret.Name)
+}
+
+func (r *InstanceIdentityRotator) needsRenewal(secret *corev1.Secret) bool {
+	block, _ := pem.Decode(secret.Data[InstanceCertKey])
+	if block == nil {
+		return true
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+
+	return time.Until(cert.NotAfter) < r.validity/renewalFraction
+}
+
+func (r *InstanceIdentityRotator) renew(secret *corev1.Secret) error {
+	subject := InstanceSubject{
+		PodName:   secret.Annotations[AnnotationPodName],
+		AppGUID:   secret.Annotations[k8s.AnnotationAppID],
+		SpaceGUID: secret.Annotations[k8s.AnnotationSpaceGUID],
+		OrgGUID:   secret.Annotations[k8s.AnnotationOrgGUID],
+	}
+
+	renewed, err := identitySecret(r.ca, subject, r.validity)
+	if err != nil {
+		return err
+	}
+
+	updated := secret.DeepCopy()
+	updated.Data = renewed.Data
+
+	_, err = r.secrets.Update(secret.Namespace, updated)
+
+	return errors.Wrapf(err, "failed to update instance identity secret %q", secret.Name)
+}
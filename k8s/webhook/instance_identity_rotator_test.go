@@ -0,0 +1,231 @@
+package webhook_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/k8s/webhook/webhookfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("InstanceIdentityRotator", func() {
+	var (
+		ca        *webhook.CertificateAuthority
+		secrets   *webhookfakes.FakeIdentitySecretsClient
+		pods      *webhookfakes.FakeIdentityPodsClient
+		rotator   *webhook.InstanceIdentityRotator
+		rotateErr error
+	)
+
+	identitySecret := func(name string, validity time.Duration) corev1.Secret {
+		certPEM, keyPEM, err := ca.Mint(webhook.InstanceSubject{PodName: name}, validity)
+		Expect(err).NotTo(HaveOccurred())
+
+		return corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name + "-identity",
+				Namespace:         "the-namespace",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+				Annotations: map[string]string{
+					webhook.AnnotationPodName: name,
+					k8s.AnnotationAppID:       "app-guid",
+					k8s.AnnotationSpaceGUID:   "space-guid",
+					k8s.AnnotationOrgGUID:     "org-guid",
+				},
+			},
+			Data: map[string][]byte{
+				webhook.InstanceCertKey: certPEM,
+				webhook.InstanceKeyKey:  keyPEM,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		caKeyPair, _ := generateCA()
+
+		var err error
+		ca, err = webhook.NewCertificateAuthority(caKeyPair)
+		Expect(err).NotTo(HaveOccurred())
+
+		secrets = new(webhookfakes.FakeIdentitySecretsClient)
+		secrets.ListReturns([]corev1.Secret{
+			identitySecret("fresh-0", 3*time.Hour),
+			identitySecret("expiring-0", 50*time.Minute),
+		}, nil)
+
+		pods = new(webhookfakes.FakeIdentityPodsClient)
+		pods.GetAllReturns([]corev1.Pod{
+			pod("fresh-0"), pod("expiring-0"), pod("expiring-1"), pod("broken-0"),
+		}, nil)
+
+		rotator = webhook.NewInstanceIdentityRotator(lagertest.NewTestLogger("rotator"), ca, secrets, pods, "the-namespace", 3*time.Hour)
+	})
+
+	JustBeforeEach(func() {
+		rotateErr = rotator.Rotate()
+	})
+
+	It("lists the instance identity secrets", func() {
+		Expect(rotateErr).NotTo(HaveOccurred())
+		Expect(secrets.ListCallCount()).To(Equal(1))
+
+		namespace, selector := secrets.ListArgsForCall(0)
+		Expect(namespace).To(Equal("the-namespace"))
+		Expect(selector).To(Equal("cloudfoundry.org/instance_identity=true"))
+	})
+
+	It("renews the certificates that are about to expire", func() {
+		Expect(secrets.UpdateCallCount()).To(Equal(1))
+
+		namespace, secret := secrets.UpdateArgsForCall(0)
+		Expect(namespace).To(Equal("the-namespace"))
+		Expect(secret.Name).To(Equal("expiring-0-identity"))
+
+		cert := parseLeaf(secret.Data[webhook.InstanceCertKey])
+		Expect(cert.NotAfter).To(BeTemporally("~", time.Now().Add(3*time.Hour), time.Minute))
+		Expect(cert.Subject.CommonName).To(Equal("expiring-0"))
+		Expect(cert.Subject.OrganizationalUnit).To(ConsistOf("organization:org-guid", "space:space-guid", "app:app-guid"))
+	})
+
+	It("does not delete the secrets of existing pods", func() {
+		Expect(pods.GetAllCallCount()).To(Equal(1))
+		Expect(secrets.DeleteCallCount()).To(BeZero())
+	})
+
+	When("the pod of a secret no longer exists", func() {
+		BeforeEach(func() {
+			secrets.ListReturns([]corev1.Secret{
+				identitySecret("expiring-0", time.Minute),
+				identitySecret("gone-0", time.Minute),
+			}, nil)
+		})
+
+		It("deletes the secret", func() {
+			Expect(rotateErr).NotTo(HaveOccurred())
+			Expect(secrets.DeleteCallCount()).To(Equal(1))
+
+			namespace, name := secrets.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(name).To(Equal("gone-0-identity"))
+		})
+
+		It("does not renew it", func() {
+			Expect(secrets.UpdateCallCount()).To(Equal(1))
+			_, secret := secrets.UpdateArgsForCall(0)
+			Expect(secret.Name).To(Equal("expiring-0-identity"))
+		})
+
+		When("the pod of the secret lives in another namespace", func() {
+			BeforeEach(func() {
+				otherNamespacePod := pod("gone-0")
+				otherNamespacePod.Namespace = "other-namespace"
+				pods.GetAllReturns([]corev1.Pod{pod("expiring-0"), otherNamespacePod}, nil)
+			})
+
+			It("still deletes the secret", func() {
+				Expect(secrets.DeleteCallCount()).To(Equal(1))
+			})
+		})
+
+		When("the secret has just been created", func() {
+			BeforeEach(func() {
+				young := identitySecret("gone-0", time.Minute)
+				young.CreationTimestamp = metav1.Now()
+				secrets.ListReturns([]corev1.Secret{young}, nil)
+			})
+
+			It("keeps it for the pod that is being created", func() {
+				Expect(secrets.DeleteCallCount()).To(BeZero())
+				Expect(secrets.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		When("the secret is already gone", func() {
+			BeforeEach(func() {
+				secrets.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "gone-0-identity"))
+			})
+
+			It("does not fail", func() {
+				Expect(rotateErr).NotTo(HaveOccurred())
+			})
+		})
+
+		When("deleting the secret fails", func() {
+			BeforeEach(func() {
+				secrets.DeleteReturns(errors.New("boom"))
+			})
+
+			It("still renews the other secrets", func() {
+				Expect(secrets.UpdateCallCount()).To(Equal(1))
+			})
+
+			It("returns an error", func() {
+				Expect(rotateErr).To(MatchError(ContainSubstring(`failed to delete instance identity secret "gone-0-identity"`)))
+			})
+		})
+	})
+
+	When("listing the pods fails", func() {
+		BeforeEach(func() {
+			pods.GetAllReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(rotateErr).To(MatchError(ContainSubstring("failed to list pods")))
+			Expect(secrets.UpdateCallCount()).To(BeZero())
+			Expect(secrets.DeleteCallCount()).To(BeZero())
+		})
+	})
+
+	When("a secret does not contain a valid certificate", func() {
+		BeforeEach(func() {
+			broken := identitySecret("broken-0", 3*time.Hour)
+			broken.Data[webhook.InstanceCertKey] = []byte("garbage")
+			secrets.ListReturns([]corev1.Secret{broken}, nil)
+		})
+
+		It("renews it", func() {
+			Expect(secrets.UpdateCallCount()).To(Equal(1))
+		})
+	})
+
+	When("listing the secrets fails", func() {
+		BeforeEach(func() {
+			secrets.ListReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(rotateErr).To(MatchError(ContainSubstring("failed to list instance identity secrets")))
+		})
+	})
+
+	When("updating a secret fails", func() {
+		BeforeEach(func() {
+			secrets.ListReturns([]corev1.Secret{
+				identitySecret("expiring-0", time.Minute),
+				identitySecret("expiring-1", time.Minute),
+			}, nil)
+			secrets.UpdateReturnsOnCall(0, nil, errors.New("boom"))
+		})
+
+		It("still renews the other secrets", func() {
+			Expect(secrets.UpdateCallCount()).To(Equal(2))
+		})
+
+		It("returns an error", func() {
+			Expect(rotateErr).To(MatchError(ContainSubstring(`failed to update instance identity secret "expiring-0-identity"`)))
+		})
+	})
+})
+
+func pod(name string) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "the-namespace"}}
+}
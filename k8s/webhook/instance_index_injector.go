@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s"
@@ -63,19 +64,27 @@ func injectInstanceIndex(logger lager.Logger, pod *corev1.Pod) error {
 		return exterrors.Wrap(err, "failed to parse app index")
 	}
 
+	cfInstanceVar := corev1.EnvVar{Name: eirini.EnvCFInstanceIndex, Value: strconv.Itoa(index)}
+	opiContainerFound := false
+
 	for c := range pod.Spec.Containers {
 		container := &pod.Spec.Containers[c]
 		if container.Name == k8s.OPIContainerName {
-			cfInstanceVar := corev1.EnvVar{Name: eirini.EnvCFInstanceIndex, Value: strconv.Itoa(index)}
-			container.Env = append(container.Env, cfInstanceVar)
+			opiContainerFound = true
+		} else if !strings.HasPrefix(container.Name, k8s.SidecarContainerNamePrefix) {
+			continue
+		}
 
-			logger.Debug("patching-instance-index", lager.Data{"env-var": cfInstanceVar})
+		container.Env = append(container.Env, cfInstanceVar)
 
-			return nil
-		}
+		logger.Debug("patching-instance-index", lager.Data{"container": container.Name, "env-var": cfInstanceVar})
 	}
 
-	logger.Info("no-opi-container-found")
+	if !opiContainerFound {
+		logger.Info("no-opi-container-found")
+
+		return errors.New("no opi container found in pod")
+	}
 
-	return errors.New("no opi container found in pod")
+	return nil
 }
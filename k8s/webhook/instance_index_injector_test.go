@@ -86,6 +86,30 @@ var _ = Describe("InstanceIndexInjector", func() {
 		}))
 	})
 
+	When("the pod has sidecar containers", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers = append(pod.Spec.Containers,
+				corev1.Container{Name: "sidecar-metrics-agent"},
+				corev1.Container{Name: "istio-proxy"},
+			)
+		})
+
+		It("injects the app instance in the sidecars too", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers).To(ContainElement(corev1.Container{
+				Name: "sidecar-metrics-agent",
+				Env: []corev1.EnvVar{
+					{Name: "CF_INSTANCE_INDEX", Value: "3"},
+				},
+			}))
+		})
+
+		It("does not inject the app instance in containers that are not managed by eirini", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers).To(ContainElement(corev1.Container{Name: "istio-proxy"}))
+		})
+	})
+
 	Context("the passed pod has already been created", func() {
 		When("operation is Update", func() {
 			BeforeEach(func() {
@@ -27,6 +27,8 @@ const (
 	EnvCFInstanceAddr       = "CF_INSTANCE_ADDR"
 	EnvCFInstancePort       = "CF_INSTANCE_PORT"
 	EnvCFInstancePorts      = "CF_INSTANCE_PORTS"
+	EnvCFInstanceCert       = "CF_INSTANCE_CERT"
+	EnvCFInstanceKey        = "CF_INSTANCE_KEY"
 
 	RecipeBuildPacksDir    = "/var/lib/buildpacks"
 	RecipeBuildPacksName   = "recipe-buildpacks"
@@ -39,6 +41,21 @@ const (
 	BuildpackCacheName     = "buildpack-cache"
 
 	AppMetricsEmissionIntervalInSecs = 15
+	ConvergencePeriodInSecs          = 60
+	ConvergenceMaxOperationsPerCycle = 100
+	VersionSwitchIntervalInSecs      = 10
+	OldVersionGracePeriodInSecs      = 600
+	RolloutProgressIntervalInSecs    = 5
+	InstanceIdentityValidityInHours  = 24
+	IdentityRotationIntervalInSecs   = 300
+	EnvSecretCollectIntervalInSecs   = 300
+
+	DrainedStatefulSetCollectIntervalInSecs = 5
+
+	// Internal routes DNS:
+	DefaultInternalRoutesDNSNamespace     = "kube-system"
+	DefaultInternalRoutesDNSConfigMapName = "coredns-custom"
+	DefaultClusterDomain                  = "cluster.local"
 
 	// Staging TLS:
 	CertsMountPath   = "/etc/config/certs"
@@ -74,6 +91,16 @@ var ErrNotFound = errors.New("not found")
 
 var ErrInvalidInstanceIndex = errors.New("invalid instance index")
 
+var ErrInvalidUpdate = errors.New("invalid update")
+
+var ErrNoRolloutInProgress = errors.New("no rollout in progress")
+
+var ErrInvalidRolloutAction = errors.New("invalid rollout action")
+
+var ErrImageRejected = errors.New("image rejected by admission policy")
+
+var ErrInvalidNetworkPolicy = errors.New("invalid network policy")
+
 type Config struct {
 	Properties Properties `yaml:"opi"`
 }
@@ -112,8 +139,141 @@ type Properties struct { //nolint:maligned
 
 	AllowRunImageAsRoot                     bool `yaml:"allow_run_image_as_root"`
 	UnsafeAllowAutomountServiceAccountToken bool `yaml:"unsafe_allow_automount_service_account_token"`
+	PinImageDigests                         bool `yaml:"pin_image_digests"`
+
+	ImagePolicy            ImagePolicy            `yaml:"image_policy"`
+	SignatureTrustPolicies []SignatureTrustPolicy `yaml:"signature_trust_policies"`
 
 	ServePlaintext bool `yaml:"serve_plaintext"`
+
+	PlacementTagNodePools map[string]NodePool        `yaml:"placement_tag_node_pools"`
+	TopologySpread        []TopologySpreadConstraint `yaml:"topology_spread"`
+	SchedulingPolicies    []SchedulingPolicy         `yaml:"scheduling_policies"`
+
+	SecurityProfile SecurityProfile `yaml:"security_profile"`
+
+	Convergence ConvergenceConfig `yaml:"convergence"`
+
+	ZeroDowntimeVersionSwitch      bool `yaml:"zero_downtime_version_switch"`
+	OldVersionGracePeriodInSeconds int  `yaml:"old_version_grace_period_in_seconds"`
+
+	UpdateStrategy UpdateStrategyConfig `yaml:"update_strategy"`
+
+	GracefulShutdown GracefulShutdownConfig `yaml:"graceful_shutdown"`
+
+	InternalRoutesDNS InternalRoutesDNSConfig `yaml:"internal_routes_dns"`
+
+	NetworkPolicyRouters RouterSelector `yaml:"network_policy_routers"`
+}
+
+// ImagePolicy restricts the docker images apps, tasks and staging can use.
+// Registries are matched against the normalized image name, e.g. "docker.io"
+// or "docker.io/library". Denied registries take precedence over allowed ones
+// and an empty allow list allows every registry. Images are admitted as CC
+// references them, before they get pinned, so RequireDigest is only met by
+// images that CC already references by digest.
+type ImagePolicy struct {
+	AllowedRegistries []string `yaml:"allowed_registries"`
+	DeniedRegistries  []string `yaml:"denied_registries"`
+	RequireDigest     bool     `yaml:"require_digest"`
+	MaxImageSizeMB    int64    `yaml:"max_image_size_mb"`
+}
+
+// SignatureTrustPolicy requires docker images staged in an org to be signed
+// by one of the given PEM encoded ECDSA public keys. A policy without an org
+// GUID applies to every org that has no policy of its own.
+type SignatureTrustPolicy struct {
+	OrgGUID    string   `yaml:"org_guid"`
+	PublicKeys []string `yaml:"public_keys"`
+}
+
+type GracefulShutdownConfig struct {
+	TerminationGracePeriodSeconds int64    `yaml:"termination_grace_period_seconds"`
+	PreStopDelaySeconds           int64    `yaml:"pre_stop_delay_seconds"`
+	PreStopCommand                []string `yaml:"pre_stop_command"`
+	// RouteDrainSeconds delays deleting a stopped LRP once its routes are
+	// unregistered. Draining is disabled unless it is set.
+	RouteDrainSeconds int64 `yaml:"route_drain_seconds"`
+}
+
+// RouterSelector selects the router pods, e.g. the gorouter, that can still
+// reach apps that are the destination of network policies. Empty namespace
+// labels select every namespace and empty pod labels every pod of the
+// selected namespaces. Routers cannot reach such apps unless it is set.
+type RouterSelector struct {
+	NamespaceLabels map[string]string `yaml:"namespace_labels"`
+	PodLabels       map[string]string `yaml:"pod_labels"`
+}
+
+// InternalRoutesDNSConfig names the ConfigMap of CoreDNS server block
+// overrides that aliases the internal route hostnames to the services of
+// their LRPs. CoreDNS has to import its *.override keys, as the
+// coredns-custom ConfigMap of k3s and AKS does.
+type InternalRoutesDNSConfig struct {
+	ConfigMapNamespace string `yaml:"config_map_namespace"`
+	ConfigMapName      string `yaml:"config_map_name"`
+	ClusterDomain      string `yaml:"cluster_domain"`
+}
+
+type UpdateStrategyConfig struct {
+	MaxUnavailable  int32 `yaml:"max_unavailable"`
+	CanaryInstances int32 `yaml:"canary_instances"`
+}
+
+type ConvergenceConfig struct {
+	Enabled               bool    `yaml:"enabled"`
+	DryRun                bool    `yaml:"dry_run"`
+	CcInternalAPI         string  `yaml:"cc_internal_api"`
+	PeriodInSeconds       int     `yaml:"period_in_seconds"`
+	BatchSize             int     `yaml:"batch_size"`
+	OperationsPerSecond   float64 `yaml:"operations_per_second"`
+	MaxOperationsPerCycle int     `yaml:"max_operations_per_cycle"`
+
+	LoggregatorAddress  string `yaml:"loggregator_address"`
+	LoggregatorCertPath string `yaml:"loggregator_cert_path"`
+	LoggregatorKeyPath  string `yaml:"loggregator_key_path"`
+	LoggregatorCAPath   string `yaml:"loggregator_ca_path"`
+}
+
+type NodePool struct {
+	NodeSelector map[string]string `yaml:"node_selector"`
+	Tolerations  []Toleration      `yaml:"tolerations"`
+}
+
+type TopologySpreadConstraint struct {
+	TopologyKey string `yaml:"topology_key"`
+	MaxSkew     int32  `yaml:"max_skew"`
+	Hard        bool   `yaml:"hard"`
+}
+
+// SchedulingPolicy assigns a priority class and a CPU limit policy to the
+// workloads it matches. A policy matches when all of its non-empty selectors
+// match; the first matching policy wins.
+type SchedulingPolicy struct {
+	OrgGUID            string            `yaml:"org_guid"`
+	SpaceGUID          string            `yaml:"space_guid"`
+	Annotations        map[string]string `yaml:"annotations"`
+	PriorityClassName  string            `yaml:"priority_class_name"`
+	CPULimit           string            `yaml:"cpu_limit"`
+	CPUBurstMultiplier float64           `yaml:"cpu_burst_multiplier"`
+}
+
+type SecurityProfile struct {
+	RunAsUser               int64    `yaml:"run_as_user"`
+	DropAllCapabilities     bool     `yaml:"drop_all_capabilities"`
+	ReadOnlyRootFilesystem  bool     `yaml:"read_only_root_filesystem"`
+	WritablePaths           []string `yaml:"writable_paths"`
+	SeccompProfileType      string   `yaml:"seccomp_profile_type"`
+	SeccompLocalhostProfile string   `yaml:"seccomp_localhost_profile"`
+	AppArmorProfile         string   `yaml:"apparmor_profile"`
+	EnforceRestricted       bool     `yaml:"enforce_restricted"`
+}
+
+type Toleration struct {
+	Key      string `yaml:"key"`
+	Operator string `yaml:"operator"`
+	Value    string `yaml:"value"`
+	Effect   string `yaml:"effect"`
 }
 
 type EventReporterConfig struct {
@@ -179,6 +339,16 @@ type InstanceIndexEnvInjectorConfig struct {
 	ServiceNamespace           string `yaml:"service_namespace"`
 	ServicePort                int32  `yaml:"service_port"`
 	EiriniXOperatorFingerprint string
+	InstanceIdentity           InstanceIdentityConfig `yaml:"instance_identity"`
 
 	KubeConfig `yaml:",inline"`
 }
+
+// InstanceIdentityConfig enables per-instance identity certificates when a
+// CA is configured.
+type InstanceIdentityConfig struct {
+	CACertPath                  string `yaml:"ca_cert_path"`
+	CAKeyPath                   string `yaml:"ca_key_path"`
+	CertValidityInHours         int    `yaml:"cert_validity_in_hours"`
+	RotationCheckIntervalInSecs int    `yaml:"rotation_check_interval_in_secs"`
+}
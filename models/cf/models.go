@@ -22,35 +22,87 @@ type DesiredLRPResponse struct {
 }
 
 type DesireLRPRequest struct {
-	GUID                    string                     `json:"guid"`
-	Version                 string                     `json:"version"`
-	ProcessGUID             string                     `json:"process_guid"`
-	ProcessType             string                     `json:"process_type"`
-	AppGUID                 string                     `json:"app_guid"`
-	AppName                 string                     `json:"app_name"`
-	SpaceGUID               string                     `json:"space_guid"`
-	SpaceName               string                     `json:"space_name"`
-	OrganizationGUID        string                     `json:"organization_guid"`
-	OrganizationName        string                     `json:"organization_name"`
-	Namespace               string                     `json:"namespace"`
-	PlacementTags           []string                   `json:"placement_tags"`
-	Ports                   []int32                    `json:"ports"`
-	Routes                  map[string]json.RawMessage `json:"routes"`
-	Environment             map[string]string          `json:"environment"`
-	EgressRules             []json.RawMessage          `json:"egress_rules"`
-	NumInstances            int                        `json:"instances"`
-	LastUpdated             string                     `json:"last_updated"`
-	HealthCheckType         string                     `json:"health_check_type"`
-	HealthCheckHTTPEndpoint string                     `json:"health_check_http_endpoint"`
-	HealthCheckTimeoutMs    uint                       `json:"health_check_timeout_ms"`
-	StartTimeoutMs          uint                       `json:"start_timeout_ms"`
-	MemoryMB                int64                      `json:"memory_mb"`
-	DiskMB                  int64                      `json:"disk_mb"`
-	CPUWeight               uint8                      `json:"cpu_weight"`
-	VolumeMounts            []VolumeMount              `json:"volume_mounts"`
-	Lifecycle               Lifecycle                  `json:"lifecycle"`
-	UserDefinedAnnotations  map[string]string          `json:"user_defined_annotations"`
-	LRP                     string
+	GUID                           string                     `json:"guid"`
+	Version                        string                     `json:"version"`
+	ProcessGUID                    string                     `json:"process_guid"`
+	ProcessType                    string                     `json:"process_type"`
+	AppGUID                        string                     `json:"app_guid"`
+	AppName                        string                     `json:"app_name"`
+	SpaceGUID                      string                     `json:"space_guid"`
+	SpaceName                      string                     `json:"space_name"`
+	OrganizationGUID               string                     `json:"organization_guid"`
+	OrganizationName               string                     `json:"organization_name"`
+	Namespace                      string                     `json:"namespace"`
+	PlacementTags                  []string                   `json:"placement_tags"`
+	Ports                          []int32                    `json:"ports"`
+	Routes                         map[string]json.RawMessage `json:"routes"`
+	Environment                    map[string]string          `json:"environment"`
+	EgressRules                    []json.RawMessage          `json:"egress_rules"`
+	NumInstances                   int                        `json:"instances"`
+	LastUpdated                    string                     `json:"last_updated"`
+	HealthCheckType                string                     `json:"health_check_type"`
+	HealthCheckHTTPEndpoint        string                     `json:"health_check_http_endpoint"`
+	HealthCheckTimeoutMs           uint                       `json:"health_check_timeout_ms"`
+	HealthCheckInvocationTimeoutMs uint                       `json:"health_check_invocation_timeout_ms"`
+	HealthCheckIntervalMs          uint                       `json:"health_check_interval_ms"`
+	HealthCheckHTTPScheme          string                     `json:"health_check_http_scheme"`
+	HealthCheckHTTPHost            string                     `json:"health_check_http_host"`
+	HealthCheckLivenessFailures    int32                      `json:"health_check_liveness_failure_threshold"`
+	HealthCheckReadinessFailures   int32                      `json:"health_check_readiness_failure_threshold"`
+	StartTimeoutMs                 uint                       `json:"start_timeout_ms"`
+	MemoryMB                       int64                      `json:"memory_mb"`
+	DiskMB                         int64                      `json:"disk_mb"`
+	CPUWeight                      uint8                      `json:"cpu_weight"`
+	VolumeMounts                   []VolumeMount              `json:"volume_mounts"`
+	Sidecars                       []Sidecar                  `json:"sidecars"`
+	Lifecycle                      Lifecycle                  `json:"lifecycle"`
+	UserDefinedAnnotations         map[string]string          `json:"user_defined_annotations"`
+	UpdateStrategy                 *UpdateStrategy            `json:"update_strategy,omitempty"`
+	Autoscaling                    *AutoscalingPolicy         `json:"autoscaling,omitempty"`
+	TerminationGracePeriodSeconds  int64                      `json:"termination_grace_period_seconds,omitempty"`
+	LRP                            string
+}
+
+type UpdateStrategy struct {
+	MaxUnavailable  int32 `json:"max_unavailable"`
+	CanaryInstances int32 `json:"canary_instances"`
+}
+
+type AutoscalingPolicy struct {
+	MinInstances        int32 `json:"min_instances"`
+	MaxInstances        int32 `json:"max_instances"`
+	TargetCPUPercent    int32 `json:"target_cpu_percent"`
+	TargetMemoryPercent int32 `json:"target_memory_percent"`
+}
+
+type RolloutRequest struct {
+	Action string `json:"action"`
+}
+
+type Sidecar struct {
+	Name        string            `json:"name"`
+	Command     string            `json:"command"`
+	MemoryMB    int64             `json:"memory_mb"`
+	Environment map[string]string `json:"environment"`
+}
+
+type EgressRule struct {
+	Protocol     string     `json:"protocol"`
+	Destinations []string   `json:"destinations"`
+	Ports        []int32    `json:"ports,omitempty"`
+	PortRange    *PortRange `json:"port_range,omitempty"`
+	IcmpInfo     *ICMPInfo  `json:"icmp_info,omitempty"`
+	Log          bool       `json:"log"`
+}
+
+type PortRange struct {
+	Start int32 `json:"start"`
+	End   int32 `json:"end"`
+}
+
+type ICMPInfo struct {
+	Type int32 `json:"type"`
+	Code int32 `json:"code"`
 }
 
 type DesiredLRPSchedulingInfo struct {
@@ -58,6 +110,9 @@ type DesiredLRPSchedulingInfo struct {
 	GUID          string `json:"guid"`
 	Version       string `json:"version"`
 	Annotation    string `json:"annotation"`
+	// OwnedByCRD is not part of the Diego API: it keeps the convergence
+	// loop away from LRPs that Cloud Controller doesn't know about.
+	OwnedByCRD bool `json:"-"`
 }
 
 type DesiredLRPKey struct {
@@ -181,10 +236,31 @@ type UpdateDesiredLRPRequest struct {
 }
 
 type DesiredLRPUpdate struct {
-	Instances  int                        `json:"instances"`
-	Routes     map[string]json.RawMessage `json:"routes"`
-	Annotation string                     `json:"annotation"`
-	Image      string                     `json:"image"`
+	Instances    int                        `json:"instances"`
+	Routes       map[string]json.RawMessage `json:"routes"`
+	Annotation   string                     `json:"annotation"`
+	Image        string                     `json:"image"`
+	MemoryMB     *int64                     `json:"memory_mb,omitempty"`
+	DiskMB       *int64                     `json:"disk_mb,omitempty"`
+	Environment  map[string]string          `json:"environment,omitempty"`
+	Command      []string                   `json:"command,omitempty"`
+	StartCommand *string                    `json:"start_command,omitempty"`
+	HealthCheck  *HealthCheckUpdate         `json:"health_check,omitempty"`
+	Ports        []int32                    `json:"ports,omitempty"`
+	Autoscaling  *AutoscalingPolicy         `json:"autoscaling,omitempty"`
+}
+
+type HealthCheckUpdate struct {
+	Type                string `json:"type"`
+	HTTPEndpoint        string `json:"http_endpoint"`
+	TimeoutMs           uint   `json:"timeout_ms"`
+	StartTimeoutMs      uint   `json:"start_timeout_ms"`
+	InvocationTimeoutMs uint   `json:"invocation_timeout_ms"`
+	IntervalMs          uint   `json:"interval_ms"`
+	HTTPScheme          string `json:"http_scheme"`
+	HTTPHost            string `json:"http_host"`
+	LivenessFailures    int32  `json:"liveness_failure_threshold"`
+	ReadinessFailures   int32  `json:"readiness_failure_threshold"`
 }
 
 type GetInstancesResponse struct {
@@ -198,6 +274,7 @@ type Instance struct {
 	Since          int64  `json:"since"`
 	State          string `json:"state"`
 	PlacementError string `json:"placement_error,omitempty"`
+	UpdatePending  bool   `json:"update_pending,omitempty"`
 }
 
 type Route struct {
@@ -205,6 +282,30 @@ type Route struct {
 	Port     int32  `json:"port"`
 }
 
+type NetworkPoliciesRequest struct {
+	Namespace string          `json:"namespace,omitempty"`
+	Policies  []NetworkPolicy `json:"policies"`
+}
+
+type NetworkPoliciesResponse struct {
+	Policies []NetworkPolicy `json:"policies"`
+}
+
+type NetworkPolicy struct {
+	Source      NetworkPolicySource      `json:"source"`
+	Destination NetworkPolicyDestination `json:"destination"`
+}
+
+type NetworkPolicySource struct {
+	ID string `json:"id"`
+}
+
+type NetworkPolicyDestination struct {
+	ID       string    `json:"id"`
+	Protocol string    `json:"protocol"`
+	Ports    PortRange `json:"ports"`
+}
+
 type AppCrashedRequest struct {
 	Instance        string `json:"instance"`
 	Index           int    `json:"index"`
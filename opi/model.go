@@ -11,6 +11,14 @@ const (
 	CrashedState            = "CRASHED"
 	UnknownState            = "UNKNOWN"
 	InsufficientMemoryError = "Insufficient resources: memory"
+	PlacementTagMismatch    = "found no compatible cell with placement tags"
+)
+
+const (
+	HealthCheckTypeHTTP    = "http"
+	HealthCheckTypePort    = "port"
+	HealthCheckTypeProcess = "process"
+	HealthCheckTypeNone    = "none"
 )
 
 type LRPIdentifier struct {
@@ -46,10 +54,37 @@ type LRP struct {
 	RunsAsRoot             bool
 	CPUWeight              uint8
 	VolumeMounts           []VolumeMount
+	PlacementTags          []string
+	EgressRules            []EgressRule
+	Sidecars               []Sidecar
 	LRP                    string
 	AppURIs                []Route
+	InternalRoutes         []InternalRoute
 	LastUpdated            string
 	UserDefinedAnnotations map[string]string
+	UpdateStrategy         UpdateStrategy
+	Autoscaling            *AutoscalingPolicy
+	// TerminationGracePeriodSeconds is the time an instance is given to
+	// exit after receiving SIGTERM. Zero means the platform default.
+	TerminationGracePeriodSeconds int64
+	// OriginalImage is the image reference as requested when Image has
+	// been pinned to a digest.
+	OriginalImage string
+	// OwnedByCRD is set when the LRP has been desired through an LRP custom
+	// resource rather than by Cloud Controller.
+	OwnedByCRD bool
+}
+
+type UpdateStrategy struct {
+	MaxUnavailable  int32 `json:"maxUnavailable,omitempty"`
+	CanaryInstances int32 `json:"canaryInstances,omitempty"`
+}
+
+type AutoscalingPolicy struct {
+	MinInstances        int32 `json:"minInstances"`
+	MaxInstances        int32 `json:"maxInstances"`
+	TargetCPUPercent    int32 `json:"targetCPUPercent,omitempty"`
+	TargetMemoryPercent int32 `json:"targetMemoryPercent,omitempty"`
 }
 
 type Route struct {
@@ -57,6 +92,35 @@ type Route struct {
 	Port     int32  `json:"port"`
 }
 
+type InternalRoute struct {
+	Hostname string `json:"hostname"`
+}
+
+type Sidecar struct {
+	Name     string
+	Command  []string
+	MemoryMB int64
+	Env      map[string]string
+}
+
+type EgressRule struct {
+	Protocol     string     `json:"protocol"`
+	Destinations []string   `json:"destinations"`
+	Ports        []int32    `json:"ports,omitempty"`
+	PortRange    *PortRange `json:"portRange,omitempty"`
+	ICMPInfo     *ICMPInfo  `json:"icmpInfo,omitempty"`
+}
+
+type PortRange struct {
+	Start int32 `json:"start"`
+	End   int32 `json:"end"`
+}
+
+type ICMPInfo struct {
+	Type int32 `json:"type"`
+	Code int32 `json:"code"`
+}
+
 type PrivateRegistry struct {
 	Server   string
 	Username string
@@ -73,13 +137,30 @@ type Instance struct {
 	Since          int64
 	State          string
 	PlacementError string
+	UpdatePending  bool
 }
 
 type Healtcheck struct {
-	Type      string
-	Port      int32
-	Endpoint  string
-	TimeoutMs uint
+	Type                      string `json:"type"`
+	Port                      int32  `json:"port"`
+	Endpoint                  string `json:"endpoint,omitempty"`
+	TimeoutMs                 uint   `json:"timeoutMs,omitempty"`
+	StartTimeoutMs            uint   `json:"startTimeoutMs,omitempty"`
+	InvocationTimeoutMs       uint   `json:"invocationTimeoutMs,omitempty"`
+	IntervalMs                uint   `json:"intervalMs,omitempty"`
+	Scheme                    string `json:"scheme,omitempty"`
+	Host                      string `json:"host,omitempty"`
+	LivenessFailureThreshold  int32  `json:"livenessFailureThreshold,omitempty"`
+	ReadinessFailureThreshold int32  `json:"readinessFailureThreshold,omitempty"`
+}
+
+// A NetworkPolicy allows the instances of the source app to connect to
+// the instances of the destination app.
+type NetworkPolicy struct {
+	SourceAppGUID      string    `json:"sourceAppGUID"`
+	DestinationAppGUID string    `json:"destinationAppGUID"`
+	Protocol           string    `json:"protocol"`
+	Ports              PortRange `json:"ports"`
 }
 
 // A Task is a one-off process that is run exactly once and returns a
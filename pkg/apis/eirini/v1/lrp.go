@@ -1,6 +1,7 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -18,30 +19,50 @@ type LRP struct {
 }
 
 type LRPSpec struct {
-	GUID                   string            `json:"GUID"`
-	Version                string            `json:"version"`
-	ProcessType            string            `json:"processType"`
-	AppName                string            `json:"appName"`
-	AppGUID                string            `json:"appGUID"`
-	OrgName                string            `json:"orgName"`
-	OrgGUID                string            `json:"orgGUID"`
-	SpaceName              string            `json:"spaceName"`
-	SpaceGUID              string            `json:"spaceGUID"`
-	Image                  string            `json:"image"`
-	Command                []string          `json:"command,omitempty"`
-	PrivateRegistry        *PrivateRegistry  `json:"privateRegistry,omitempty"`
-	Env                    map[string]string `json:"env,omitempty"`
-	Health                 Healtcheck        `json:"health"`
-	Ports                  []int32           `json:"ports,omitempty"`
-	Instances              int               `json:"instances"`
-	MemoryMB               int64             `json:"memoryMB"`
-	DiskMB                 int64             `json:"diskMB"`
-	RunsAsRoot             bool              `json:"runsAsRoot"`
-	CPUWeight              uint8             `json:"cpuWeight"`
-	VolumeMounts           []VolumeMount     `json:"volumeMounts,omitempty"`
-	LastUpdated            string            `json:"lastUpdated"`
-	UserDefinedAnnotations map[string]string `json:"userDefinedAnnotations,omitempty"`
-	AppRoutes              []Route           `json:"appRoutes"`
+	GUID                          string             `json:"GUID"`
+	Version                       string             `json:"version"`
+	ProcessType                   string             `json:"processType"`
+	AppName                       string             `json:"appName"`
+	AppGUID                       string             `json:"appGUID"`
+	OrgName                       string             `json:"orgName"`
+	OrgGUID                       string             `json:"orgGUID"`
+	SpaceName                     string             `json:"spaceName"`
+	SpaceGUID                     string             `json:"spaceGUID"`
+	Image                         string             `json:"image"`
+	Command                       []string           `json:"command,omitempty"`
+	PrivateRegistry               *PrivateRegistry   `json:"privateRegistry,omitempty"`
+	Env                           map[string]string  `json:"env,omitempty"`
+	Health                        Healtcheck         `json:"health"`
+	Ports                         []int32            `json:"ports,omitempty"`
+	Instances                     int                `json:"instances"`
+	MemoryMB                      int64              `json:"memoryMB"`
+	DiskMB                        int64              `json:"diskMB"`
+	RunsAsRoot                    bool               `json:"runsAsRoot"`
+	CPUWeight                     uint8              `json:"cpuWeight"`
+	VolumeMounts                  []VolumeMount      `json:"volumeMounts,omitempty"`
+	PlacementTags                 []string           `json:"placementTags,omitempty"`
+	EgressRules                   []EgressRule       `json:"egressRules,omitempty"`
+	Sidecars                      []Sidecar          `json:"sidecars,omitempty"`
+	LastUpdated                   string             `json:"lastUpdated"`
+	UserDefinedAnnotations        map[string]string  `json:"userDefinedAnnotations,omitempty"`
+	AppRoutes                     []Route            `json:"appRoutes"`
+	InternalRoutes                []InternalRoute    `json:"internalRoutes,omitempty"`
+	UpdateStrategy                UpdateStrategy     `json:"updateStrategy,omitempty"`
+	Autoscaling                   *AutoscalingPolicy `json:"autoscaling,omitempty"`
+	TerminationGracePeriodSeconds int64              `json:"terminationGracePeriodSeconds,omitempty"`
+	OriginalImage                 string             `json:"originalImage,omitempty"`
+}
+
+type UpdateStrategy struct {
+	MaxUnavailable  int32 `json:"maxUnavailable,omitempty"`
+	CanaryInstances int32 `json:"canaryInstances,omitempty"`
+}
+
+type AutoscalingPolicy struct {
+	MinInstances        int32 `json:"minInstances,omitempty"`
+	MaxInstances        int32 `json:"maxInstances"`
+	TargetCPUPercent    int32 `json:"targetCPUPercent,omitempty"`
+	TargetMemoryPercent int32 `json:"targetMemoryPercent,omitempty"`
 }
 
 type LRPStatus struct {
@@ -53,10 +74,44 @@ type Route struct {
 	Port     int32  `json:"port"`
 }
 
+type InternalRoute struct {
+	Hostname string `json:"hostname"`
+}
+
+type Sidecar struct {
+	Name     string            `json:"name"`
+	Command  []string          `json:"command"`
+	MemoryMB int64             `json:"memoryMB"`
+	Env      map[string]string `json:"env,omitempty"`
+}
+
+type EgressRule struct {
+	Protocol     string     `json:"protocol"`
+	Destinations []string   `json:"destinations"`
+	Ports        []int32    `json:"ports,omitempty"`
+	PortRange    *PortRange `json:"portRange,omitempty"`
+	ICMPInfo     *ICMPInfo  `json:"icmpInfo,omitempty"`
+}
+
+type PortRange struct {
+	Start int32 `json:"start"`
+	End   int32 `json:"end"`
+}
+
+type ICMPInfo struct {
+	Type int32 `json:"type"`
+	Code int32 `json:"code"`
+}
+
 type PrivateRegistry struct {
 	Server   string `json:"server"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"password,omitempty"`
+	// PasswordSecretRef selects the key of a Secret holding the password
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+	// DockerConfigSecretRef references a kubernetes.io/dockerconfigjson
+	// Secret holding the credentials for the server
+	DockerConfigSecretRef *corev1.LocalObjectReference `json:"dockerConfigSecretRef,omitempty"`
 }
 
 type VolumeMount struct {
@@ -65,10 +120,17 @@ type VolumeMount struct {
 }
 
 type Healtcheck struct {
-	Type      string `json:"type"`
-	Port      int32  `json:"port"`
-	Endpoint  string `json:"endpoint"`
-	TimeoutMs uint   `json:"timeoutMs"`
+	Type                      string `json:"type"`
+	Port                      int32  `json:"port"`
+	Endpoint                  string `json:"endpoint"`
+	TimeoutMs                 uint   `json:"timeoutMs"`
+	StartTimeoutMs            uint   `json:"startTimeoutMs,omitempty"`
+	InvocationTimeoutMs       uint   `json:"invocationTimeoutMs,omitempty"`
+	IntervalMs                uint   `json:"intervalMs,omitempty"`
+	Scheme                    string `json:"scheme,omitempty"`
+	Host                      string `json:"host,omitempty"`
+	LivenessFailureThreshold  int32  `json:"livenessFailureThreshold,omitempty"`
+	ReadinessFailureThreshold int32  `json:"readinessFailureThreshold,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
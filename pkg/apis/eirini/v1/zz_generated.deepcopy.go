@@ -21,9 +21,62 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingPolicy) DeepCopyInto(out *AutoscalingPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingPolicy.
+func (in *AutoscalingPolicy) DeepCopy() *AutoscalingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressRule) DeepCopyInto(out *EgressRule) {
+	*out = *in
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.PortRange != nil {
+		in, out := &in.PortRange, &out.PortRange
+		*out = new(PortRange)
+		**out = **in
+	}
+	if in.ICMPInfo != nil {
+		in, out := &in.ICMPInfo, &out.ICMPInfo
+		*out = new(ICMPInfo)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressRule.
+func (in *EgressRule) DeepCopy() *EgressRule {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Healtcheck) DeepCopyInto(out *Healtcheck) {
 	*out = *in
@@ -40,6 +93,38 @@ func (in *Healtcheck) DeepCopy() *Healtcheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICMPInfo) DeepCopyInto(out *ICMPInfo) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMPInfo.
+func (in *ICMPInfo) DeepCopy() *ICMPInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(ICMPInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InternalRoute) DeepCopyInto(out *InternalRoute) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InternalRoute.
+func (in *InternalRoute) DeepCopy() *InternalRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(InternalRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LRP) DeepCopyInto(out *LRP) {
 	*out = *in
@@ -112,7 +197,7 @@ func (in *LRPSpec) DeepCopyInto(out *LRPSpec) {
 	if in.PrivateRegistry != nil {
 		in, out := &in.PrivateRegistry, &out.PrivateRegistry
 		*out = new(PrivateRegistry)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
@@ -132,6 +217,25 @@ func (in *LRPSpec) DeepCopyInto(out *LRPSpec) {
 		*out = make([]VolumeMount, len(*in))
 		copy(*out, *in)
 	}
+	if in.PlacementTags != nil {
+		in, out := &in.PlacementTags, &out.PlacementTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EgressRules != nil {
+		in, out := &in.EgressRules, &out.EgressRules
+		*out = make([]EgressRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]Sidecar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.UserDefinedAnnotations != nil {
 		in, out := &in.UserDefinedAnnotations, &out.UserDefinedAnnotations
 		*out = make(map[string]string, len(*in))
@@ -144,6 +248,17 @@ func (in *LRPSpec) DeepCopyInto(out *LRPSpec) {
 		*out = make([]Route, len(*in))
 		copy(*out, *in)
 	}
+	if in.InternalRoutes != nil {
+		in, out := &in.InternalRoutes, &out.InternalRoutes
+		*out = make([]InternalRoute, len(*in))
+		copy(*out, *in)
+	}
+	out.UpdateStrategy = in.UpdateStrategy
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(AutoscalingPolicy)
+		**out = **in
+	}
 	return
 }
 
@@ -173,9 +288,35 @@ func (in *LRPStatus) DeepCopy() *LRPStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRange) DeepCopyInto(out *PortRange) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRange.
+func (in *PortRange) DeepCopy() *PortRange {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrivateRegistry) DeepCopyInto(out *PrivateRegistry) {
 	*out = *in
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DockerConfigSecretRef != nil {
+		in, out := &in.DockerConfigSecretRef, &out.DockerConfigSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
 	return
 }
 
@@ -205,6 +346,34 @@ func (in *Route) DeepCopy() *Route {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Sidecar) DeepCopyInto(out *Sidecar) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Sidecar.
+func (in *Sidecar) DeepCopy() *Sidecar {
+	if in == nil {
+		return nil
+	}
+	out := new(Sidecar)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Task) DeepCopyInto(out *Task) {
 	*out = *in
@@ -271,7 +440,7 @@ func (in *TaskSpec) DeepCopyInto(out *TaskSpec) {
 	if in.PrivateRegistry != nil {
 		in, out := &in.PrivateRegistry, &out.PrivateRegistry
 		*out = new(PrivateRegistry)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
@@ -298,6 +467,22 @@ func (in *TaskSpec) DeepCopy() *TaskSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateStrategy.
+func (in *UpdateStrategy) DeepCopy() *UpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolumeMount) DeepCopyInto(out *VolumeMount) {
 	*out = *in
@@ -36,3 +36,44 @@ func Fetch(dockerRef string, sysCtx types.SystemContext) (*v1.ImageConfig, error
 
 	return &imgV1.Config, nil
 }
+
+func FetchDigest(dockerRef string, sysCtx types.SystemContext) (string, error) {
+	ref, err := docker.ParseReference(dockerRef)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse docker reference")
+	}
+
+	return fetchManifestDigest(context.Background(), ref, sysCtx)
+}
+
+func FetchSize(dockerRef string, sysCtx types.SystemContext) (int64, error) {
+	ref, err := docker.ParseReference(dockerRef)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse docker reference")
+	}
+
+	ctx := context.Background()
+
+	imgSrc, err := ref.NewImageSource(ctx, &sysCtx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get image source")
+	}
+	defer imgSrc.Close()
+
+	img, err := image.FromUnparsedImage(ctx, &sysCtx, image.UnparsedInstance(imgSrc, nil))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get image")
+	}
+
+	var size int64
+
+	for _, layer := range img.LayerInfos() {
+		if layer.Size < 0 {
+			return 0, errors.Errorf("unknown size of layer %s", layer.Digest)
+		}
+
+		size += layer.Size
+	}
+
+	return size, nil
+}
@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"github.com/containers/image/docker"
+	"github.com/containers/image/manifest"
+	"github.com/containers/image/pkg/blobinfocache/none"
+	"github.com/containers/image/types"
+	"github.com/docker/distribution/registry/api/errcode"
+	v2 "github.com/docker/distribution/registry/api/v2"
+	"github.com/docker/distribution/registry/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+	signatureTagSuffix  = ".sig"
+)
+
+// Signature is a cosign-style signature, stored in the registry as a layer
+// of an OCI artifact tagged after the digest of the signed image.
+type Signature struct {
+	Payload   []byte
+	Signature []byte
+}
+
+// FetchSignatures returns the manifest digest of the image and the
+// signatures stored alongside it. Images without signatures are rejected.
+func FetchSignatures(dockerRef string, sysCtx types.SystemContext) (string, []Signature, error) {
+	ref, err := docker.ParseReference(dockerRef)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to parse docker reference")
+	}
+
+	ctx := context.Background()
+
+	digest, err := fetchManifestDigest(ctx, ref, sysCtx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sigTag := strings.Replace(digest, ":", "-", 1) + signatureTagSuffix
+
+	sigRef, err := docker.ParseReference("//" + ref.DockerReference().Name() + ":" + sigTag)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to parse signature reference")
+	}
+
+	sigSrc, err := sigRef.NewImageSource(ctx, &sysCtx)
+	if isManifestUnknown(err) {
+		return "", nil, errors.Wrapf(eirini.ErrImageRejected, "image %q is not signed", dockerRef)
+	}
+
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get signature source")
+	}
+	defer sigSrc.Close()
+
+	rawManifest, _, err := sigSrc.GetManifest(ctx, nil)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get signature manifest")
+	}
+
+	var sigManifest v1.Manifest
+	if err = json.Unmarshal(rawManifest, &sigManifest); err != nil {
+		return "", nil, errors.Wrap(err, "failed to unmarshal signature manifest")
+	}
+
+	signatures := []Signature{}
+
+	for _, layer := range sigManifest.Layers {
+		encodedSignature, ok := layer.Annotations[signatureAnnotation]
+		if !ok {
+			continue
+		}
+
+		signature, decodeErr := base64.StdEncoding.DecodeString(encodedSignature)
+		if decodeErr != nil {
+			return "", nil, errors.Wrap(decodeErr, "failed to decode signature")
+		}
+
+		payload, fetchErr := fetchBlob(ctx, sigSrc, types.BlobInfo{Digest: layer.Digest, Size: layer.Size})
+		if fetchErr != nil {
+			return "", nil, fetchErr
+		}
+
+		signatures = append(signatures, Signature{Payload: payload, Signature: signature})
+	}
+
+	return digest, signatures, nil
+}
+
+func fetchManifestDigest(ctx context.Context, ref types.ImageReference, sysCtx types.SystemContext) (string, error) {
+	imgSrc, err := ref.NewImageSource(ctx, &sysCtx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get image source")
+	}
+	defer imgSrc.Close()
+
+	rawManifest, _, err := imgSrc.GetManifest(ctx, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get image manifest")
+	}
+
+	digest, err := manifest.Digest(rawManifest)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to compute manifest digest")
+	}
+
+	return digest.String(), nil
+}
+
+func fetchBlob(ctx context.Context, src types.ImageSource, info types.BlobInfo) ([]byte, error) {
+	blob, _, err := src.GetBlob(ctx, info, none.NoCache)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get signature payload")
+	}
+	defer blob.Close()
+
+	payload, err := ioutil.ReadAll(blob)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read signature payload")
+	}
+
+	return payload, nil
+}
+
+func isManifestUnknown(err error) bool {
+	switch cause := errors.Cause(err).(type) {
+	case errcode.Errors:
+		for _, e := range cause {
+			if isManifestUnknown(e) {
+				return true
+			}
+		}
+	case errcode.ErrorCoder:
+		return cause.ErrorCode() == v2.ErrorCodeManifestUnknown
+	case *client.UnexpectedHTTPResponseError:
+		return cause.StatusCode == http.StatusNotFound
+	}
+
+	return false
+}
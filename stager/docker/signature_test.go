@@ -0,0 +1,184 @@
+package docker_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/stager/docker"
+	"github.com/containers/image/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var _ = Describe("FetchSignatures", func() {
+	var (
+		registry      *httptest.Server
+		imageManifest []byte
+		imageDigest   string
+		payload       []byte
+		sigManifest   []byte
+		requests      []string
+
+		fetchedDigest string
+		signatures    []docker.Signature
+		fetchErr      error
+	)
+
+	manifestJSON := func(layers ...descriptor) []byte {
+		raw, err := json.Marshal(map[string]interface{}{
+			"schemaVersion": 2,
+			"config": descriptor{
+				MediaType: v1.MediaTypeImageConfig,
+				Digest:    sha256Digest([]byte("config")),
+				Size:      6,
+			},
+			"layers": layers,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		return raw
+	}
+
+	BeforeEach(func() {
+		requests = []string{}
+		imageManifest = manifestJSON(descriptor{
+			MediaType: v1.MediaTypeImageLayerGzip,
+			Digest:    sha256Digest([]byte("layer")),
+			Size:      5,
+		})
+		imageDigest = sha256Digest(imageManifest)
+		payload = []byte(`{"critical":{"image":{"docker-manifest-digest":"` + imageDigest + `"}}}`)
+		sigManifest = manifestJSON(
+			descriptor{
+				MediaType:   "application/vnd.dev.cosign.simplesigning.v1+json",
+				Digest:      sha256Digest(payload),
+				Size:        int64(len(payload)),
+				Annotations: map[string]string{"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString([]byte("the-signature"))},
+			},
+			descriptor{
+				MediaType: v1.MediaTypeImageLayerGzip,
+				Digest:    sha256Digest([]byte("unsigned")),
+				Size:      8,
+			},
+		)
+
+		registry = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.URL.Path)
+
+			writeManifest := func(raw []byte) {
+				w.Header().Set("Content-Type", v1.MediaTypeImageManifest)
+				_, _ = w.Write(raw)
+			}
+
+			switch r.URL.Path {
+			case "/v2/":
+				w.WriteHeader(http.StatusOK)
+			case "/v2/the-app/manifests/latest":
+				if imageManifest == nil {
+					w.WriteHeader(http.StatusNotFound)
+
+					return
+				}
+				writeManifest(imageManifest)
+			case "/v2/the-app/manifests/" + strings.Replace(imageDigest, ":", "-", 1) + ".sig":
+				if sigManifest == nil {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+
+					return
+				}
+				writeManifest(sigManifest)
+			case "/v2/the-app/blobs/" + sha256Digest(payload):
+				_, _ = w.Write(payload)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		registry.Close()
+	})
+
+	JustBeforeEach(func() {
+		host := strings.TrimPrefix(registry.URL, "https://")
+		fetchedDigest, signatures, fetchErr = docker.FetchSignatures("//"+host+"/the-app:latest", types.SystemContext{
+			DockerInsecureSkipTLSVerify: types.OptionalBoolTrue,
+		})
+	})
+
+	It("returns the manifest digest of the image", func() {
+		Expect(fetchErr).NotTo(HaveOccurred())
+		Expect(fetchedDigest).To(Equal(imageDigest))
+	})
+
+	It("returns the signatures stored in the signature manifest", func() {
+		Expect(signatures).To(ConsistOf(docker.Signature{
+			Payload:   payload,
+			Signature: []byte("the-signature"),
+		}))
+	})
+
+	It("fetches the signature manifest tagged after the image digest", func() {
+		Expect(requests).To(ContainElement("/v2/the-app/manifests/" + strings.Replace(imageDigest, ":", "-", 1) + ".sig"))
+	})
+
+	When("the image has no signature manifest", func() {
+		BeforeEach(func() {
+			sigManifest = nil
+		})
+
+		It("rejects the image", func() {
+			Expect(errors.Is(fetchErr, eirini.ErrImageRejected)).To(BeTrue())
+			Expect(fetchErr).To(MatchError(ContainSubstring("is not signed")))
+		})
+	})
+
+	When("a signature cannot be decoded", func() {
+		BeforeEach(func() {
+			sigManifest = manifestJSON(descriptor{
+				MediaType:   "application/vnd.dev.cosign.simplesigning.v1+json",
+				Digest:      sha256Digest(payload),
+				Size:        int64(len(payload)),
+				Annotations: map[string]string{"dev.cosignproject.cosign/signature": "not base64!"},
+			})
+		})
+
+		It("returns an error", func() {
+			Expect(fetchErr).To(MatchError(ContainSubstring("failed to decode signature")))
+			Expect(errors.Is(fetchErr, eirini.ErrImageRejected)).To(BeFalse())
+		})
+	})
+
+	When("the image does not exist", func() {
+		BeforeEach(func() {
+			imageManifest = nil
+		})
+
+		It("returns an error", func() {
+			Expect(fetchErr).To(MatchError(ContainSubstring("failed to get image")))
+		})
+	})
+})
+
+func sha256Digest(raw []byte) string {
+	sum := sha256.Sum256(raw)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
@@ -382,6 +382,54 @@ var _ = Describe("StatefulSets", func() {
 		})
 	})
 
+	Describe("GetByGUID", func() {
+		var guid, extraNs string
+
+		BeforeEach(func() {
+			guid = tests.GenerateGUID()
+
+			createStatefulSet(fixture.Namespace, "one", map[string]string{
+				k8s.LabelGUID:    guid,
+				k8s.LabelVersion: "41",
+			})
+			createStatefulSet(fixture.Namespace, "two", map[string]string{
+				k8s.LabelGUID:    guid,
+				k8s.LabelVersion: "42",
+			})
+			createStatefulSet(fixture.Namespace, "three", map[string]string{
+				k8s.LabelGUID:    tests.GenerateGUID(),
+				k8s.LabelVersion: "42",
+			})
+
+			extraNs = fixture.CreateExtraNamespace()
+
+			createStatefulSet(extraNs, "four", map[string]string{
+				k8s.LabelGUID:    guid,
+				k8s.LabelVersion: "43",
+			})
+		})
+
+		It("lists all versions of the StatefulSets matching the specified GUID", func() {
+			statefulSets, err := statefulSetClient.GetByGUID(guid)
+
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() []string { return statefulSetNames(statefulSets) }).Should(ConsistOf("one", "two", "four"))
+		})
+
+		When("multi namespaced support is disabled", func() {
+			BeforeEach(func() {
+				statefulSetClient = client.NewStatefulSet(fixture.Clientset, fixture.Namespace, false)
+			})
+
+			It("lists the StatefulSets in the specified namespace matching the specified GUID", func() {
+				statefulSets, err := statefulSetClient.GetByGUID(guid)
+
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(func() []string { return statefulSetNames(statefulSets) }).Should(ConsistOf("one", "two"))
+			})
+		})
+	})
+
 	Describe("Update", func() {
 		var statefulSet *appsv1.StatefulSet
 
@@ -59,6 +59,8 @@ var _ = Describe("StagingReporter", func() {
 			"",
 			"",
 			false,
+			nil,
+			eirini.SecurityProfile{},
 		)
 	})
 
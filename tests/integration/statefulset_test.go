@@ -44,11 +44,16 @@ var _ = Describe("StatefulSetDesirer", func() {
 			Secrets:                   client.NewSecret(fixture.Clientset),
 			StatefulSets:              client.NewStatefulSet(fixture.Clientset, "", true),
 			PodDisruptionBudgets:      client.NewPodDisruptionBudget(fixture.Clientset),
+			NetworkPolicies:           client.NewNetworkPolicy(fixture.Clientset),
+			HorizontalPodAutoscalers:  client.NewHorizontalPodAutoscaler(fixture.Clientset),
+			Services:                  client.NewService(fixture.Clientset),
+			ConfigMaps:                client.NewConfigMap(fixture.Clientset),
 			EventsClient:              client.NewEvent(fixture.Clientset, "", true),
 			StatefulSetToLRPMapper:    k8s.StatefulSetToLRP,
 			RegistrySecretName:        "registry-secret",
 			LivenessProbeCreator:      k8s.CreateLivenessProbe,
 			ReadinessProbeCreator:     k8s.CreateReadinessProbe,
+			StartupProbeCreator:       k8s.CreateStartupProbe,
 			Logger:                    logger,
 			ApplicationServiceAccount: tests.GetApplicationServiceAccount(),
 		}
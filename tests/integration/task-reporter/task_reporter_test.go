@@ -73,6 +73,8 @@ var _ = Describe("TaskReporter", func() {
 			"",
 			"",
 			false,
+			nil,
+			eirini.SecurityProfile{},
 		)
 
 		taskGUID := tests.GenerateGUID()